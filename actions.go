@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	iterator "github.com/jcchavezs/gh-iterator"
+	"github.com/jcchavezs/gh-iterator/exec"
+	"github.com/jcchavezs/gh-iterator/github"
+)
+
+// repoAction changes a repository through the API instead of its clone e.g. adding topics. It
+// returns the changes made, or the ones it would make with dryRun.
+type repoAction func(ctx context.Context, x exec.Execer, repo iterator.Repository, dryRun bool) ([]string, error)
+
+// repoActions returns the actions passed by flag, in the order they run.
+func repoActions() ([]repoAction, error) {
+	var actions []repoAction
+
+	if len(flags.addTopics) > 0 || len(flags.removeTopics) > 0 {
+		if slices.ContainsFunc(flags.addTopics, func(t string) bool { return slices.Contains(flags.removeTopics, t) }) {
+			return nil, errors.New("--add-topic and --remove-topic can't have the same topic")
+		}
+		actions = append(actions, topicsAction(flags.addTopics, flags.removeTopics))
+	}
+
+	if flags.applySettings != "" {
+		settings, err := loadRepoSettings(flags.applySettings)
+		if err != nil {
+			return nil, err
+		}
+		actions = append(actions, settingsAction(settings))
+	}
+
+	if len(flags.grantTeams) > 0 || len(flags.revokeTeams) > 0 {
+		var grant, revoke []teamAccess
+		for _, s := range flags.grantTeams {
+			team, err := parseTeamAccess(s, true)
+			if err != nil {
+				return nil, err
+			}
+			grant = append(grant, team)
+		}
+
+		for _, s := range flags.revokeTeams {
+			team, err := parseTeamAccess(s, false)
+			if err != nil {
+				return nil, err
+			}
+			revoke = append(revoke, team)
+		}
+
+		actions = append(actions, teamsAction(grant, revoke))
+	}
+
+	if flags.syncLabels != "" {
+		spec, err := loadLabelsSpec(flags.syncLabels)
+		if err != nil {
+			return nil, err
+		}
+		actions = append(actions, labelsAction(spec))
+	}
+
+	if flags.createTag != "" {
+		var notes string
+		if flags.releaseNotesFile != "" {
+			if !flags.release {
+				return nil, errors.New("--notes-file requires --release")
+			}
+
+			content, err := os.ReadFile(flags.releaseNotesFile)
+			if err != nil {
+				return nil, fmt.Errorf("reading release notes: %w", err)
+			}
+			notes = string(content)
+		}
+		actions = append(actions, tagAction(flags.createTag, flags.release, notes))
+	} else if flags.release {
+		return nil, errors.New("--release requires --create-tag")
+	}
+
+	if flags.dispatchWorkflow != "" {
+		inputs, err := parseWorkflowInputs(flags.workflowInputs)
+		if err != nil {
+			return nil, err
+		}
+		actions = append(actions, dispatchAction(flags.dispatchWorkflow, flags.ref, inputs))
+	} else if len(flags.workflowInputs) > 0 {
+		return nil, errors.New("--input requires --dispatch-workflow")
+	}
+
+	if flags.deleteMerged {
+		actions = append(actions, mergedBranchesAction(flags.protectBranches))
+	} else if len(flags.protectBranches) > 0 {
+		return nil, errors.New("--protect requires --delete-merged-branches")
+	}
+
+	if flags.mirror != "" {
+		dir, err := filepath.Abs(flags.mirror)
+		if err != nil {
+			return nil, fmt.Errorf("resolving mirror directory: %w", err)
+		}
+		actions = append(actions, mirrorAction(dir, flags.useHTTPS))
+	}
+
+	if flags.archive {
+		if !flags.yes && !flags.dryRun {
+			return nil, errors.New("--archive requires --yes or --dry-run")
+		}
+		actions = append(actions, archiveAction)
+	}
+
+	return actions, nil
+}
+
+// onlyActions tells whether the repositories are only changed through the API, so they don't
+// need to be cloned.
+func onlyActions() bool {
+	return flags.command == "" && flags.processor == "" && flags.grep == "" && len(flags.requireFiles) == 0 &&
+		flags.dependencyInventory == "" && flags.applyPatch == "" && len(flags.replace) == 0 && flags.syncFiles == "" &&
+		!flags.createPR && flags.commitMessage == "" && !flags.push && !flags.showDiff && flags.cloneFilter == ""
+}
+
+// runActions runs the actions on the repository, printing and recording the changes.
+func (p repoProcessor) runActions(ctx context.Context, x exec.Execer, repository string) error {
+	repo, ok := repositoryFromContext(ctx)
+	if !ok {
+		repo = iterator.Repository{Name: repository}
+	}
+
+	for _, action := range p.actions {
+		changes, err := action(ctx, x, repo, flags.dryRun)
+		if err != nil {
+			return fmt.Errorf("changing %q: %w", repository, err)
+		}
+
+		for _, c := range changes {
+			if flags.dryRun {
+				fmt.Fprintf(p.stdout, "%s: would %s\n", repository, c)
+			} else {
+				fmt.Fprintf(p.stdout, "%s: %s\n", repository, c)
+			}
+		}
+
+		p.results.update(repository, func(r *repoResult) { r.Changes = append(r.Changes, changes...) })
+	}
+
+	return nil
+}
+
+// apiRequest calls the REST API with the method, sending the body as JSON if not nil, and
+// returns the response.
+func apiRequest(ctx context.Context, x exec.Execer, method string, path string, body any) (string, error) {
+	args := []string{"api",
+		"-H", "Accept: application/vnd.github+json",
+		"-H", "X-GitHub-Api-Version: " + iterator.GithubAPIVersion,
+		"-X", method,
+	}
+
+	var stdin io.Reader
+	if body != nil {
+		content, err := json.Marshal(body)
+		if err != nil {
+			return "", fmt.Errorf("marshaling request: %w", err)
+		}
+		stdin = bytes.NewReader(content)
+		args = append(args, "--input", "-")
+	}
+
+	res, err := x.RunWithStdinX(ctx, stdin, "gh", append(args, path)...)
+	if err != nil {
+		return "", fmt.Errorf("%s %s: %w", method, path, github.ErrOrGHAPIErr(res, err))
+	}
+
+	return res, nil
+}
+
+// isNotFound tells whether the API request failed because the resource does not exist.
+func isNotFound(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	stderr, _ := exec.GetStderr(err)
+	return strings.Contains(err.Error(), "with status 404") || strings.Contains(stderr, "HTTP 404")
+}
+
+// decodePages decodes the JSON arrays of the pages returned by 'gh api --paginate'.
+func decodePages[T any](res string) ([]T, error) {
+	var items []T
+	dec := json.NewDecoder(strings.NewReader(res))
+	for {
+		var page []T
+		if err := dec.Decode(&page); errors.Is(err, io.EOF) {
+			return items, nil
+		} else if err != nil {
+			return nil, err
+		}
+		items = append(items, page...)
+	}
+}
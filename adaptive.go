@@ -0,0 +1,92 @@
+package main
+
+import (
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const autoWorkers = "auto"
+
+// adaptiveConcurrency limits the number of repositories processed at once, it increases the
+// limit by one after each repository and halves it when the clones slow down, the API rate
+// limit runs low or the CPU is overloaded.
+type adaptiveConcurrency struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	limit   int
+	max     int
+	running int
+	// avgClone is the moving average of the clone durations.
+	avgClone time.Duration
+}
+
+func newAdaptiveConcurrency(initial, max int) *adaptiveConcurrency {
+	a := &adaptiveConcurrency{limit: min(initial, max), max: max}
+	a.cond = sync.NewCond(&a.mu)
+	return a
+}
+
+// acquire blocks until a repository can be processed.
+func (a *adaptiveConcurrency) acquire() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for a.running >= a.limit {
+		a.cond.Wait()
+	}
+	a.running++
+}
+
+// release frees the slot of a processed repository and adjusts the limit out of its clone
+// duration and whether the system is under pressure.
+func (a *adaptiveConcurrency) release(cloneDuration time.Duration, underPressure bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.running--
+
+	slowClone := false
+	if cloneDuration > 0 {
+		slowClone = a.avgClone > 0 && cloneDuration > 2*a.avgClone
+		if a.avgClone == 0 {
+			a.avgClone = cloneDuration
+		} else {
+			a.avgClone = (3*a.avgClone + cloneDuration) / 4
+		}
+	}
+
+	if slowClone || underPressure {
+		a.limit = max(1, a.limit/2)
+	} else if a.limit < a.max {
+		a.limit++
+	}
+
+	a.cond.Broadcast()
+}
+
+// cpuOverloaded tells whether the load average of the last minute exceeds the number of CPUs.
+// It is false where the load average is not available.
+func cpuOverloaded() bool {
+	content, err := os.ReadFile("/proc/loadavg")
+	if err != nil {
+		return false
+	}
+
+	load, ok := parseLoadAverage(string(content))
+	return ok && load > float64(runtime.NumCPU())
+}
+
+// parseLoadAverage parses the load average of the last minute out of /proc/loadavg.
+func parseLoadAverage(content string) (float64, bool) {
+	fields := strings.Fields(content)
+	if len(fields) == 0 {
+		return 0, false
+	}
+
+	load, err := strconv.ParseFloat(fields[0], 64)
+	return load, err == nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdaptiveConcurrency(t *testing.T) {
+	a := newAdaptiveConcurrency(2, 4)
+
+	a.acquire()
+	a.release(time.Second, false)
+	require.Equal(t, 3, a.limit)
+
+	a.acquire()
+	a.release(time.Second, false)
+	a.acquire()
+	a.release(time.Second, false)
+	require.Equal(t, 4, a.limit, "limit is capped")
+
+	a.acquire()
+	a.release(10*time.Second, false)
+	require.Equal(t, 2, a.limit, "slow clones halve the limit")
+
+	a.acquire()
+	a.release(0, true)
+	require.Equal(t, 1, a.limit, "pressure halves the limit")
+
+	a.acquire()
+	a.release(0, true)
+	require.Equal(t, 1, a.limit, "limit is at least one")
+}
+
+func TestAdaptiveConcurrency_BlocksOverLimit(t *testing.T) {
+	a := newAdaptiveConcurrency(1, 1)
+	a.acquire()
+
+	acquired := make(chan struct{})
+	go func() {
+		a.acquire()
+		close(acquired)
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("acquired over the limit")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	a.release(0, false)
+	<-acquired
+}
+
+func TestParseLoadAverage(t *testing.T) {
+	load, ok := parseLoadAverage("3.52 2.10 1.05 2/345 12345\n")
+	require.True(t, ok)
+	require.Equal(t, 3.52, load)
+
+	_, ok = parseLoadAverage("")
+	require.False(t, ok)
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	iterator "github.com/jcchavezs/gh-iterator"
+	"github.com/jcchavezs/gh-iterator/exec"
+	"github.com/jcchavezs/gh-iterator/github"
+)
+
+// installationTokenRefreshMargin is how long before the installation token expires a new one
+// is created.
+var installationTokenRefreshMargin = 5 * time.Minute
+
+// installationToken creates an installation access token for the GitHub App and returns it
+// along with its expiration.
+func installationToken(ctx context.Context, x exec.Execer, appID string, privateKeyFile string, installationID string) (string, time.Time, error) {
+	keyPEM, err := os.ReadFile(privateKeyFile)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("reading app private key: %w", err)
+	}
+
+	key, err := parseAppPrivateKey(keyPEM)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	jwt, err := appJWT(appID, key, time.Now())
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	res, err := exec.TrimStdout(x.RunX(ctx, "gh", "api",
+		"-H", "Authorization: Bearer "+jwt,
+		"-H", "Accept: application/vnd.github+json",
+		"-H", "X-GitHub-Api-Version: "+iterator.GithubAPIVersion,
+		"-X", "POST",
+		"--jq", ".token + \" \" + .expires_at",
+		fmt.Sprintf("/app/installations/%s/access_tokens", installationID),
+	))
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("creating installation token: %w", github.ErrOrGHAPIErr(res, err))
+	}
+
+	token, expiresAt, _ := strings.Cut(res, " ")
+	expiration, err := time.Parse(time.RFC3339, expiresAt)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("parsing installation token expiration: %w", err)
+	}
+
+	return token, expiration, nil
+}
+
+// refreshInstallationToken replaces the installation token in GH_TOKEN before it expires until
+// ctx is done, as the tokens last an hour and a run can take longer. A command already running
+// keeps the token it started with. A failed refresh is retried after a minute.
+func refreshInstallationToken(ctx context.Context, x exec.Execer, appID string, privateKeyFile string, installationID string, expiresAt time.Time) {
+	wait := time.Until(expiresAt) - installationTokenRefreshMargin
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+
+		token, expiration, err := installationToken(ctx, x, appID, privateKeyFile, installationID)
+		if err == nil {
+			err = os.Setenv("GH_TOKEN", token)
+		}
+		if err != nil {
+			x.Log(ctx, slog.LevelWarn, "Failed to refresh the installation token", "expires_at", expiresAt, "error", err)
+			wait = time.Minute
+			continue
+		}
+
+		x.Log(ctx, slog.LevelDebug, "Refreshed the installation token", "expires_at", expiration)
+		expiresAt = expiration
+		wait = time.Until(expiresAt) - installationTokenRefreshMargin
+	}
+}
+
+// parseAppPrivateKey parses the PEM encoded private key of a GitHub App.
+func parseAppPrivateKey(keyPEM []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, errors.New("decoding app private key: no PEM data found")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parsing app private key: %w", err)
+	}
+
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("parsing app private key: not a RSA key")
+	}
+
+	return rsaKey, nil
+}
+
+// appJWT creates the JSON Web Token to authenticate as the GitHub App.
+func appJWT(appID string, key *rsa.PrivateKey, now time.Time) (string, error) {
+	enc := base64.RawURLEncoding
+
+	header := enc.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`))
+	claims, err := json.Marshal(map[string]any{
+		// issued in the past to allow for clock drift
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": appID,
+	})
+	if err != nil {
+		return "", fmt.Errorf("marshaling JWT claims: %w", err)
+	}
+
+	signingInput := header + "." + enc.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(signingInput))
+
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("signing JWT: %w", err)
+	}
+
+	return signingInput + "." + enc.EncodeToString(signature), nil
+}
+
+// listInstallationRepositories lists the repositories accessible to the GitHub App installation
+// the current token belongs to.
+func listInstallationRepositories(ctx context.Context, x exec.Execer) ([]iterator.Repository, error) {
+	ghArgs := append([]string{"api",
+		"-H", "Accept: application/vnd.github+json",
+		"-H", "X-GitHub-Api-Version: " + iterator.GithubAPIVersion,
+		"-X", "GET",
+		"--paginate",
+		"--jq", ".repositories | map({" + repositoryFields + "})",
+	}, apiCacheArgs()...)
+
+	res, err := x.RunX(ctx, "gh", append(ghArgs, fmt.Sprintf("/installation/repositories?per_page=%d", defaultPerPage))...)
+	if err != nil {
+		return nil, fmt.Errorf("listing installation repositories: %w", github.ErrOrGHAPIErr(res, err))
+	}
+
+	return decodeRepositoryPages(strings.NewReader(res))
+}
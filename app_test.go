@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jcchavezs/gh-iterator/exec"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAppJWT(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	parsedKey, err := parseAppPrivateKey(keyPEM)
+	require.NoError(t, err)
+
+	now := time.Unix(1700000000, 0)
+	jwt, err := appJWT("12345", parsedKey, now)
+	require.NoError(t, err)
+
+	parts := strings.Split(jwt, ".")
+	require.Len(t, parts, 3)
+
+	claimsJSON, err := base64.RawURLEncoding.DecodeString(parts[1])
+	require.NoError(t, err)
+
+	var claims map[string]any
+	require.NoError(t, json.Unmarshal(claimsJSON, &claims))
+	require.Equal(t, "12345", claims["iss"])
+	require.EqualValues(t, now.Add(-time.Minute).Unix(), claims["iat"])
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	require.NoError(t, err)
+
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	require.NoError(t, rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], signature))
+}
+
+func TestParseAppPrivateKey_Invalid(t *testing.T) {
+	_, err := parseAppPrivateKey([]byte("not a key"))
+	require.Error(t, err)
+}
+
+func TestRefreshInstallationToken(t *testing.T) {
+	defer func(d time.Duration) { installationTokenRefreshMargin = d }(installationTokenRefreshMargin)
+	installationTokenRefreshMargin = time.Hour
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	keyFile := filepath.Join(t.TempDir(), "key.pem")
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}), 0600))
+
+	calls := scriptedGH(t, "echo 'ghs_new 2099-01-01T00:00:00Z'\n")
+	t.Setenv("GH_TOKEN", "ghs_old")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		refreshInstallationToken(ctx, exec.NewExecerWithLogger(t.TempDir(), slog.New(slog.DiscardHandler)), "12345", keyFile, "678", time.Now())
+	}()
+
+	require.Eventually(t, func() bool { return os.Getenv("GH_TOKEN") == "ghs_new" }, 5*time.Second, 10*time.Millisecond)
+	cancel()
+	<-done
+
+	// the new token does not expire within the margin so it is not refreshed again.
+	require.Equal(t, 1, countLines(t, calls))
+	args, err := os.ReadFile(calls)
+	require.NoError(t, err)
+	require.Contains(t, string(args), "/app/installations/678/access_tokens")
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	iterator "github.com/jcchavezs/gh-iterator"
+	"github.com/jcchavezs/gh-iterator/exec"
+	"github.com/jcchavezs/gh-iterator/github"
+)
+
+// archiveAction archives the repositories not archived yet.
+func archiveAction(ctx context.Context, x exec.Execer, repo iterator.Repository, dryRun bool) ([]string, error) {
+	if repo.Archived {
+		return nil, nil
+	}
+
+	if dryRun {
+		return []string{"archive"}, nil
+	}
+
+	if res, err := x.RunX(ctx, "gh", "repo", "archive", repo.Name, "--yes"); err != nil {
+		return nil, fmt.Errorf("archiving: %w", github.ErrOrGHAPIErr(res, err))
+	}
+
+	return []string{"archive"}, nil
+}
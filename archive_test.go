@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"testing"
+
+	iterator "github.com/jcchavezs/gh-iterator"
+	"github.com/jcchavezs/gh-iterator/exec"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArchiveAction(t *testing.T) {
+	calls := scriptedGH(t, "")
+	x := exec.NewExecerWithLogger(t.TempDir(), slog.New(slog.DiscardHandler))
+
+	changes, err := archiveAction(context.Background(), x, iterator.Repository{Name: "acme/a"}, true)
+	require.NoError(t, err)
+	require.Equal(t, []string{"archive"}, changes)
+	require.NoFileExists(t, calls)
+
+	changes, err = archiveAction(context.Background(), x, iterator.Repository{Name: "acme/b", Archived: true}, false)
+	require.NoError(t, err)
+	require.Empty(t, changes)
+	require.NoFileExists(t, calls)
+
+	changes, err = archiveAction(context.Background(), x, iterator.Repository{Name: "acme/a"}, false)
+	require.NoError(t, err)
+	require.Equal(t, []string{"archive"}, changes)
+
+	content, err := os.ReadFile(calls)
+	require.NoError(t, err)
+	require.Equal(t, "repo archive acme/a --yes\n", string(content))
+}
+
+func TestRepoActions_ArchiveRequiresYes(t *testing.T) {
+	t.Cleanup(func() { flags.archive, flags.yes, flags.dryRun = false, false, false })
+	flags.archive = true
+
+	_, err := repoActions()
+	require.Error(t, err)
+
+	flags.dryRun = true
+	actions, err := repoActions()
+	require.NoError(t, err)
+	require.Len(t, actions, 1)
+}
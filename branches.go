@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	iterator "github.com/jcchavezs/gh-iterator"
+	"github.com/jcchavezs/gh-iterator/exec"
+	"github.com/jcchavezs/gh-iterator/github"
+)
+
+type branch struct {
+	Name      string `json:"name"`
+	Protected bool   `json:"protected"`
+}
+
+// repositoryBranches returns the branches of the repository.
+func repositoryBranches(ctx context.Context, x exec.Execer, repository string) ([]branch, error) {
+	res, err := x.RunX(ctx, "gh", "api", "--paginate",
+		"-H", "Accept: application/vnd.github+json",
+		"-H", "X-GitHub-Api-Version: "+iterator.GithubAPIVersion,
+		"/repos/"+repository+"/branches?per_page=100",
+	)
+	if err != nil {
+		return nil, fmt.Errorf("listing branches: %w", github.ErrOrGHAPIErr(res, err))
+	}
+
+	branches, err := decodePages[branch](res)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshaling branches: %w", err)
+	}
+
+	return branches, nil
+}
+
+// mergedInto tells whether the branch has no commits the base branch doesn't have. The branches
+// squashed or rebased on merge have, so they are not considered merged.
+func mergedInto(ctx context.Context, x exec.Execer, repository, base, head string) (bool, error) {
+	res, err := apiRequest(ctx, x, "GET", "/repos/"+repository+"/compare/"+url.PathEscape(base)+"..."+url.PathEscape(head), nil)
+	if err != nil {
+		return false, fmt.Errorf("comparing %s: %w", head, err)
+	}
+
+	var comparison struct {
+		AheadBy int `json:"ahead_by"`
+	}
+	if err := json.Unmarshal([]byte(res), &comparison); err != nil {
+		return false, fmt.Errorf("unmarshaling comparison: %w", err)
+	}
+
+	return comparison.AheadBy == 0, nil
+}
+
+// mergedBranchesAction deletes the branches merged into the default branch, except the
+// protected ones and the ones matching the protect globs.
+func mergedBranchesAction(protect []string) repoAction {
+	return func(ctx context.Context, x exec.Execer, repo iterator.Repository, dryRun bool) ([]string, error) {
+		if repo.DefaultBranchName == "" {
+			return nil, nil
+		}
+
+		branches, err := repositoryBranches(ctx, x, repo.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		var changes []string
+		for _, b := range branches {
+			if b.Name == repo.DefaultBranchName || b.Protected || matchAnyGlob(protect, b.Name) {
+				continue
+			}
+
+			merged, err := mergedInto(ctx, x, repo.Name, repo.DefaultBranchName, b.Name)
+			if err != nil {
+				return nil, err
+			}
+
+			if !merged {
+				continue
+			}
+
+			if !dryRun {
+				if _, err := apiRequest(ctx, x, "DELETE", "/repos/"+repo.Name+"/git/refs/heads/"+b.Name, nil); err != nil {
+					return nil, fmt.Errorf("deleting branch %s: %w", b.Name, err)
+				}
+			}
+
+			changes = append(changes, "delete branch "+b.Name)
+		}
+
+		return changes, nil
+	}
+}
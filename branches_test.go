@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"testing"
+
+	iterator "github.com/jcchavezs/gh-iterator"
+	"github.com/jcchavezs/gh-iterator/exec"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergedBranchesAction(t *testing.T) {
+	calls := scriptedGH(t, routedGH+`"GET /repos/acme/a/branches?per_page=100") echo '[{"name":"main","protected":true},{"name":"done"},{"name":"wip"}]'; echo '[{"name":"release/1.0"},{"name":"locked","protected":true}]';;
+"GET /repos/acme/a/compare/main...done") echo '{"ahead_by":0,"behind_by":3}';;
+"GET /repos/acme/a/compare/main...wip") echo '{"ahead_by":2,"behind_by":0}';;
+esac
+`)
+	x := exec.NewExecerWithLogger(t.TempDir(), slog.New(slog.DiscardHandler))
+	action := mergedBranchesAction([]string{"release/*"})
+	repo := iterator.Repository{Name: "acme/a", DefaultBranchName: "main"}
+
+	changes, err := action(context.Background(), x, repo, true)
+	require.NoError(t, err)
+	require.Equal(t, []string{"delete branch done"}, changes)
+
+	changes, err = action(context.Background(), x, repo, false)
+	require.NoError(t, err)
+	require.Equal(t, []string{"delete branch done"}, changes)
+
+	content, err := os.ReadFile(calls)
+	require.NoError(t, err)
+	require.Contains(t, string(content), "-X DELETE /repos/acme/a/git/refs/heads/done\n")
+	require.NotContains(t, string(content), "compare/main...release")
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+)
+
+// writeBundle packages the results of the run into a gzipped tarball in file: the results as
+// results.json, the failures as failures.json, the diffs captured with --show-diff under diffs/
+// and the repository logs of logDir, if set, under logs/.
+func (r *runResults) writeBundle(file string, command string, logDir string) error {
+	f, err := os.Create(file)
+	if err != nil {
+		return fmt.Errorf("creating results archive: %w", err)
+	}
+	defer f.Close() //nolint:errcheck
+
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	modTime := time.Now()
+
+	add := func(name string, content []byte) error {
+		h := &tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), ModTime: modTime}
+		if err := tw.WriteHeader(h); err != nil {
+			return fmt.Errorf("writing %s into results archive: %w", name, err)
+		}
+		if _, err := tw.Write(content); err != nil {
+			return fmt.Errorf("writing %s into results archive: %w", name, err)
+		}
+		return nil
+	}
+
+	var results bytes.Buffer
+	if err := r.writeJSON(&results); err != nil {
+		return err
+	}
+	if err := add("results.json", results.Bytes()); err != nil {
+		return err
+	}
+
+	fails := r.failures(command)
+	if fails == nil {
+		fails = []failure{}
+	}
+	failures, err := json.MarshalIndent(fails, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling failures: %w", err)
+	}
+	if err := add("failures.json", failures); err != nil {
+		return err
+	}
+
+	for _, res := range r.sorted() {
+		if res.Diff == "" {
+			continue
+		}
+		if err := add(path.Join("diffs", res.Repository+".diff"), []byte(res.Diff)); err != nil {
+			return err
+		}
+	}
+
+	if logDir != "" {
+		if err := addLogs(logDir, add); err != nil {
+			return err
+		}
+	}
+
+	if err := errors.Join(tw.Close(), gz.Close()); err != nil {
+		return fmt.Errorf("writing results archive: %w", err)
+	}
+
+	return f.Close()
+}
+
+// addLogs adds the log files in dir under logs/.
+func addLogs(dir string, add func(name string, content []byte) error) error {
+	return filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return fmt.Errorf("reading logs: %w", err)
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return fmt.Errorf("reading logs: %w", err)
+		}
+
+		content, err := os.ReadFile(p)
+		if err != nil {
+			return fmt.Errorf("reading logs: %w", err)
+		}
+
+		return add(path.Join("logs", filepath.ToSlash(rel)), content)
+	})
+}
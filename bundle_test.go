@@ -0,0 +1,60 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	iterator "github.com/jcchavezs/gh-iterator"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunResultsWriteBundle(t *testing.T) {
+	r := newRunResults()
+	r.addRepositories(
+		[]iterator.Repository{{Name: "acme/a"}, {Name: "acme/b"}},
+		[]iterator.Repository{{Name: "acme/a"}, {Name: "acme/b"}},
+		nil,
+	)
+	r.update("acme/a", func(res *repoResult) { res.Processed, res.CommandRan, res.Diff = true, true, "+new\n" })
+	r.update("acme/b", func(res *repoResult) { res.Processed, res.CommandRan, res.ExitCode = true, true, 1 })
+
+	dir := t.TempDir()
+	logDir := filepath.Join(dir, "logs")
+	require.NoError(t, os.MkdirAll(filepath.Join(logDir, "acme"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(logDir, "acme", "b.log"), []byte("cloning\n"), 0644))
+
+	archive := filepath.Join(dir, "results.tar.gz")
+	require.NoError(t, r.writeBundle(archive, "make", logDir))
+
+	f, err := os.Open(archive)
+	require.NoError(t, err)
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	require.NoError(t, err)
+
+	files := map[string]string{}
+	tr := tar.NewReader(gz)
+	for {
+		h, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+
+		content, err := io.ReadAll(tr)
+		require.NoError(t, err)
+		files[h.Name] = string(content)
+	}
+
+	require.Len(t, files, 4)
+	require.Contains(t, files["results.json"], `"repository": "acme/a"`)
+	require.JSONEq(t, `[{"repository": "acme/b", "command": "make", "exit_code": 1}]`, files["failures.json"])
+	require.Equal(t, "+new\n", files["diffs/acme/a.diff"])
+	require.Equal(t, "cloning\n", files["logs/acme/b.log"])
+}
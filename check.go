@@ -0,0 +1,44 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/spf13/cobra"
+)
+
+func newFilterCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "filter",
+		Short: "Work with the CEL conditions filtering the repositories",
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "check [CONDITION]",
+		Short: "Check a CEL condition compiles",
+		Long: `Checks the CEL condition passed as argument or in --search-filter compiles, without listing
+any repository.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cond := flags.searchFilter
+			if len(args) > 0 {
+				cond = args[0]
+			}
+
+			if cond == "" {
+				return errors.New("a condition is required")
+			}
+
+			logger := slog.New(newLogHandler(cmd.ErrOrStderr(), flags.logLevel))
+			if _, err := parseSearchFilterIn(cond, logger); err != nil {
+				return fmt.Errorf("invalid condition: %w", err)
+			}
+
+			fmt.Fprintln(cmd.OutOrStdout(), "valid")
+			return nil
+		},
+	})
+
+	return cmd
+}
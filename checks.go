@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jcchavezs/gh-iterator/exec"
+)
+
+// Outcomes of the pull request checks.
+const (
+	checksPass    = "pass"
+	checksFail    = "fail"
+	checksPending = "pending"
+	checksNone    = "none"
+)
+
+// checksGracePeriod is the time the checks of a new pull request have to show up, as the CI
+// takes a while to report them.
+var checksGracePeriod = 2 * time.Minute
+
+// waitPRChecks polls the checks of the pull request until all of them complete or the
+// timeout expires and returns the outcome. No checks reported is an outcome only once the
+// grace period, or the timeout if shorter, is over.
+func waitPRChecks(ctx context.Context, x exec.Execer, prURL string, timeout time.Duration, interval time.Duration) (string, error) {
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var (
+		start = time.Now()
+		// onTimeout is the outcome when the timeout expires, as of the last poll.
+		onTimeout = checksPending
+	)
+	for {
+		res, err := x.Run(waitCtx, "gh", "pr", "checks", prURL, "--json", "bucket")
+		if err != nil {
+			if ctx.Err() == nil && waitCtx.Err() != nil {
+				return onTimeout, nil
+			}
+			return "", fmt.Errorf("checking PR checks: %w", err)
+		}
+
+		var outcome string
+		if strings.Contains(res.Stderr, "no checks reported") {
+			outcome = checksNone
+		} else {
+			var checks []struct {
+				Bucket string `json:"bucket"`
+			}
+			if err := json.Unmarshal([]byte(res.Stdout), &checks); err != nil {
+				return "", fmt.Errorf("unmarshaling PR checks: %w: %s", err, res.Stderr)
+			}
+
+			buckets := make([]string, 0, len(checks))
+			for _, c := range checks {
+				buckets = append(buckets, c.Bucket)
+			}
+			outcome = checksOutcome(buckets)
+		}
+
+		if outcome == checksNone && time.Since(start) < checksGracePeriod {
+			// the checks may not have been reported yet.
+			onTimeout = checksNone
+		} else if outcome != checksPending {
+			return outcome, nil
+		} else {
+			onTimeout = checksPending
+		}
+
+		select {
+		case <-waitCtx.Done():
+			if ctx.Err() != nil {
+				return "", ctx.Err()
+			}
+			return onTimeout, nil
+		case <-time.After(interval):
+		}
+	}
+}
+
+// checksOutcome summarizes the buckets of the checks as reported by gh pr checks into an outcome.
+func checksOutcome(buckets []string) string {
+	if len(buckets) == 0 {
+		return checksNone
+	}
+
+	var pending bool
+	for _, b := range buckets {
+		switch b {
+		case "fail", "cancel":
+			return checksFail
+		case "pending":
+			pending = true
+		}
+	}
+
+	if pending {
+		return checksPending
+	}
+
+	return checksPass
+}
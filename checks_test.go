@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jcchavezs/gh-iterator/exec"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChecksOutcome(t *testing.T) {
+	require.Equal(t, checksNone, checksOutcome(nil))
+	require.Equal(t, checksPass, checksOutcome([]string{"pass", "skipping"}))
+	require.Equal(t, checksPending, checksOutcome([]string{"pass", "pending"}))
+	require.Equal(t, checksFail, checksOutcome([]string{"pending", "fail"}))
+	require.Equal(t, checksFail, checksOutcome([]string{"pass", "cancel"}))
+}
+
+func TestWaitPRChecks(t *testing.T) {
+	defer func(d time.Duration) { checksGracePeriod = d }(checksGracePeriod)
+	x := exec.NewExecerWithLogger(t.TempDir(), slog.New(slog.DiscardHandler))
+
+	// the checks show up after the first poll.
+	noChecksFirst := func(t *testing.T) string {
+		counter := filepath.Join(t.TempDir(), "count")
+		return scriptedGH(t, "echo x >> "+counter+"\n"+
+			"if [ $(wc -l < "+counter+") -le 1 ]; then echo \"no checks reported on the 'feat' branch\" >&2; exit 1; fi\n"+
+			"echo '[{\"bucket\":\"pass\"}]'\n")
+	}
+
+	t.Run("waits for the checks to show up", func(t *testing.T) {
+		checksGracePeriod = time.Minute
+		calls := noChecksFirst(t)
+
+		outcome, err := waitPRChecks(context.Background(), x, "https://github.com/acme/a/pull/1", time.Second, time.Millisecond)
+		require.NoError(t, err)
+		require.Equal(t, checksPass, outcome)
+		require.Equal(t, 2, countLines(t, calls))
+	})
+
+	t.Run("no checks after the grace period", func(t *testing.T) {
+		checksGracePeriod = 0
+		calls := noChecksFirst(t)
+
+		outcome, err := waitPRChecks(context.Background(), x, "https://github.com/acme/a/pull/1", time.Second, time.Millisecond)
+		require.NoError(t, err)
+		require.Equal(t, checksNone, outcome)
+		require.Equal(t, 1, countLines(t, calls))
+	})
+
+	t.Run("no checks until the timeout", func(t *testing.T) {
+		checksGracePeriod = time.Minute
+		scriptedGH(t, "echo \"no checks reported on the 'feat' branch\" >&2; exit 1\n")
+
+		outcome, err := waitPRChecks(context.Background(), x, "https://github.com/acme/a/pull/1", 50*time.Millisecond, 10*time.Millisecond)
+		require.NoError(t, err)
+		require.Equal(t, checksNone, outcome)
+	})
+}
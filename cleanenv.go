@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/jcchavezs/gh-iterator/exec"
+)
+
+// defaultEnvAllowlist are the variables passed to the commands with --clean-env besides the
+// ones passed in --env-allow.
+var defaultEnvAllowlist = []string{"PATH", "HOME", "USER", "SHELL", "TERM", "TMPDIR", "LANG", "LC_*", "TZ"}
+
+// allowedEnv returns the variables of environ whose name matches any of the allowed patterns.
+func allowedEnv(environ []string, allow []string) []string {
+	var env []string
+	for _, kv := range environ {
+		name, _, _ := strings.Cut(kv, "=")
+		for _, pattern := range allow {
+			if ok, _ := path.Match(pattern, name); ok {
+				env = append(env, kv)
+				break
+			}
+		}
+	}
+
+	return env
+}
+
+// cleanEnvDecorator starts the commands other than git and gh with only the allowed variables of
+// the environment and the ones passed with WithEnv e.g. the repository metadata, so the scripts
+// run in the repositories don't get the tokens and credentials in the environment. git and gh
+// still get the full environment to authenticate.
+type cleanEnvDecorator struct {
+	allow []string
+	env   []string
+}
+
+// withCleanEnv wraps the execer so the commands get only the allowed variables of the
+// environment, the defaultEnvAllowlist and the allow patterns.
+func withCleanEnv(x exec.Execer, allow []string) exec.Execer {
+	return decorate(x, cleanEnvDecorator{allow: append(defaultEnvAllowlist[:len(defaultEnvAllowlist):len(defaultEnvAllowlist)], allow...)})
+}
+
+func (d cleanEnvDecorator) withEnv(kv ...string) execerDecorator {
+	return cleanEnvDecorator{allow: d.allow, env: appendEnv(d.env, kv...)}
+}
+
+// environ returns the environment of the commands.
+func (d cleanEnvDecorator) environ() []string {
+	return append(allowedEnv(os.Environ(), d.allow), d.env...)
+}
+
+func (d cleanEnvDecorator) runWithStdin(ctx context.Context, next exec.Execer, stdin io.Reader, command string, args ...string) (exec.Result, error) {
+	if command == "git" || command == "gh" {
+		return next.RunWithStdin(ctx, stdin, command, args...)
+	}
+
+	// env -i starts the command with only the variables passed, the later ones overriding
+	// the earlier.
+	envArgs := append(append([]string{"-i"}, d.environ()...), command)
+	return next.RunWithStdin(ctx, stdin, "env", append(envArgs, args...)...)
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/jcchavezs/gh-iterator/exec"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAllowedEnv(t *testing.T) {
+	require.Equal(t,
+		[]string{"PATH=/bin", "LC_ALL=C"},
+		allowedEnv([]string{"PATH=/bin", "GH_TOKEN=secret", "LC_ALL=C", "AWS_SECRET_ACCESS_KEY=x"}, []string{"PATH", "LC_*"}),
+	)
+}
+
+func TestCleanEnvExecer(t *testing.T) {
+	t.Setenv("GH_TOKEN", "secret")
+	t.Setenv("GOPATH", "/go")
+
+	x := withCleanEnv(exec.NewExecerWithLogger(t.TempDir(), slog.New(slog.DiscardHandler)), []string{"GOPATH"}).
+		WithEnv("GH_ITERATOR_REPOSITORY", "acme/a")
+
+	sub, err := x.Sub(".")
+	require.NoError(t, err)
+
+	out, err := sub.RunX(context.Background(), os.Getenv("SHELL"), "-c", "env")
+	require.NoError(t, err)
+
+	env := strings.Split(strings.TrimSpace(out), "\n")
+	require.Contains(t, env, "GH_ITERATOR_REPOSITORY=acme/a")
+	require.Contains(t, env, "GOPATH=/go")
+	require.NotContains(t, out, "GH_TOKEN")
+}
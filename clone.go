@@ -0,0 +1,329 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	iterator "github.com/jcchavezs/gh-iterator"
+	"github.com/jcchavezs/gh-iterator/exec"
+	"github.com/spf13/afero"
+)
+
+// RefFallback is what to do with the repositories where the ref to check out does not exist.
+type RefFallback int
+
+const (
+	RefFallbackSkip RefFallback = iota
+	RefFallbackDefaultBranch
+	RefFallbackFail
+)
+
+// RefFallbackIds maps ref fallbacks to their corresponding string identifiers.
+var RefFallbackIds = map[RefFallback][]string{
+	RefFallbackSkip:          {"skip"},
+	RefFallbackDefaultBranch: {"default-branch"},
+	RefFallbackFail:          {"fail"},
+}
+
+// errRefNotFound is returned when the ref to check out does not exist in the repository.
+var errRefNotFound = errors.New("ref not found")
+
+// runWithClone clones the repository and runs the processor in the clone, which is removed
+// afterwards. Empty repositories are not cloned.
+func runWithClone(ctx context.Context, repo iterator.Repository, processor iterator.Processor, logger *slog.Logger, results *runResults, opts iterator.Options) error {
+	logger = logger.With("repository", repo.Name)
+
+	if repo.Size == 0 {
+		if cloneFilterIn != nil {
+			// there are no files to evaluate the clone filter on.
+			logger.Debug("Skipping empty repository")
+			results.update(repo.Name, func(r *repoResult) { r.Skipped = "empty" })
+			return nil
+		}
+
+		// there is nothing to clone, the command runs in an empty directory and gets
+		// GH_ITERATOR_EMPTY=true.
+		logger.Debug("Empty repository")
+		dir, err := makeWorkDir(repo.Name)
+		if err != nil {
+			return err
+		}
+		defer removeWorkDir(dir, logger)
+
+		if err := processor(ctx, repo.Name, true, withRetries(withTimeout(newExecer(dir, logger), flags.execTimeout), flags.apiRetries)); err != nil {
+			return fmt.Errorf("processing %q: processing empty repository: %w", repo.Name, err)
+		}
+
+		return nil
+	}
+
+	release, err := cloneSlots.acquire(ctx)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	dir, err := cloneRepository(ctx, repo, logger, opts)
+	results.update(repo.Name, func(r *repoResult) { r.CloneDuration = time.Since(start) })
+	release()
+	if errors.Is(err, errRefNotFound) && flags.refFallback == RefFallbackSkip {
+		logger.Warn("Skipping repository, ref not found", "ref", flags.ref)
+		results.update(repo.Name, func(r *repoResult) { r.Skipped = "ref not found" })
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("processing %q: %w", repo.Name, withCategory(categoryClone, err))
+	}
+	defer removeWorkDir(dir, logger)
+
+	if file, ok, err := optedOut(dir, flags.campaign); err != nil {
+		return fmt.Errorf("processing %q: %w", repo.Name, err)
+	} else if ok {
+		logger.Info("Skipping repository, it opts out of the run", "file", file)
+		results.update(repo.Name, func(r *repoResult) { r.Skipped = "opted out" })
+		return nil
+	}
+
+	if cloneFilterIn != nil {
+		ok, err := cloneFilterIn(repo, dir)
+		if err != nil {
+			logger.Warn("Failed to evaluate the clone filter", "error", err)
+		}
+
+		if !ok {
+			logger.Debug("Skipping repository, filtered out after clone")
+			results.update(repo.Name, func(r *repoResult) { r.Skipped = "filtered out after clone" })
+			return nil
+		}
+	}
+
+	if err := processor(ctx, repo.Name, false, withRetries(withTimeout(newExecer(dir, logger), flags.execTimeout), flags.apiRetries)); err != nil {
+		return fmt.Errorf("processing %q: %w", repo.Name, err)
+	}
+
+	return nil
+}
+
+// makeWorkDir creates the working directory of a repository under the directory passed in
+// --workdir, by default <tmp>/gh-iterator-run, locked by the run.
+func makeWorkDir(repository string) (string, error) {
+	baseDir := workBaseDir()
+	if err := os.MkdirAll(baseDir, 0755); err != nil {
+		return "", fmt.Errorf("creating base working directory: %w", err)
+	}
+
+	dir, err := os.MkdirTemp(baseDir, strings.ReplaceAll(repository, "/", "-")+"-")
+	if err != nil {
+		return "", fmt.Errorf("creating working directory: %w", err)
+	}
+
+	if err := lockWorkDir(dir); err != nil {
+		os.Remove(dir) //nolint:errcheck
+		return "", err
+	}
+
+	return dir, nil
+}
+
+// removeWorkDir removes the working directory of a repository unless --keep-clones is passed.
+// The lock is left when the directory can't be removed so a later run cleans it.
+func removeWorkDir(dir string, logger *slog.Logger) {
+	if flags.keepClones {
+		unlockWorkDir(dir, true)
+		logger.Info("Keeping working directory", "dir", dir)
+		return
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		logger.Warn("Failed to remove the working directory", "dir", dir, "error", err)
+	} else {
+		unlockWorkDir(dir, false)
+	}
+	clonesDisk.remove(dir)
+}
+
+// cloneRepository clones the repository into a new directory and checks out the ref passed
+// by flag, by default the default branch. With --tarball the archive is extracted instead.
+func cloneRepository(ctx context.Context, repo iterator.Repository, logger *slog.Logger, opts iterator.Options) (string, error) {
+	dir, err := makeWorkDir(repo.Name)
+	if err != nil {
+		return "", err
+	}
+
+	// the size of the repository in the API is in KB, the clone is measured once done as the
+	// working tree and the history take a different space.
+	if err = clonesDisk.reserve(dir, int64(repo.Size)*1024); err == nil {
+		if flags.tarball {
+			err = downloadTarball(ctx, repo, dir, logger)
+		} else {
+			err = initClone(ctx, withTimeout(newExecer(dir, logger), flags.execTimeout), repo, opts)
+		}
+	}
+
+	if err == nil {
+		err = clonesDisk.add(dir)
+	}
+
+	if err != nil {
+		if rErr := os.RemoveAll(dir); rErr != nil {
+			logger.Warn("Failed to remove the clone directory", "error", rErr)
+		} else {
+			unlockWorkDir(dir, false)
+		}
+		clonesDisk.remove(dir)
+		return "", err
+	}
+
+	return dir, nil
+}
+
+func initClone(ctx context.Context, x exec.Execer, repo iterator.Repository, opts iterator.Options) error {
+	if _, err := x.RunX(ctx, "git", "init"); err != nil {
+		return fmt.Errorf("cloning repository: %w", err)
+	}
+
+	repoURL := repo.SSHURL
+	if opts.UseHTTPS {
+		repoURL = repo.URL
+	}
+
+	if opts.UseHTTPS || hasEnvToken() {
+		// over SSH the helper still authenticates the HTTPS fetches e.g. of the submodules.
+		// The empty helper resets the ones configured globally for the host.
+		key := credentialHelperKey(repo)
+		if _, err := x.RunX(ctx, "git", "config", key, ""); err != nil {
+			return fmt.Errorf("setting credential helper: %w", err)
+		}
+
+		if _, err := x.RunX(ctx, "git", "config", "--add", key, credentialHelper()); err != nil {
+			return fmt.Errorf("setting credential helper: %w", err)
+		}
+	}
+
+	if _, err := x.RunX(ctx, "git", "remote", "add", "origin", repoURL); err != nil {
+		return fmt.Errorf("adding origin: %w", err)
+	}
+
+	if len(opts.CloningSubset) > 0 {
+		if _, err := x.RunX(ctx, "git", "config", "core.sparseCheckout", "true"); err != nil {
+			return fmt.Errorf("setting sparse checkout subset: %w", err)
+		}
+
+		subset := strings.Join(opts.CloningSubset, "\n") + "\n"
+		if err := afero.WriteFile(x.GenerateFS(), ".git/info/sparse-checkout", []byte(subset), 0644); err != nil {
+			return fmt.Errorf("setting cloning subset: %w", err)
+		}
+	}
+
+	if err := checkout(ctx, x, repo); err != nil {
+		return err
+	}
+
+	if flags.allBranches {
+		if _, err := x.RunX(ctx, "git", "fetch", "origin", "+refs/heads/*:refs/remotes/origin/*"); err != nil {
+			return fmt.Errorf("fetching all branches: %w", err)
+		}
+	}
+
+	if flags.recurseSubmodules {
+		if _, err := x.RunX(ctx, "git", "submodule", "update", "--init", "--recursive"); err != nil {
+			return fmt.Errorf("updating submodules: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// tokenCredentialHelper provides the token in GH_TOKEN or GITHUB_TOKEN to git. The token is read
+// when git asks for the credentials, so it is not written in the clone.
+const tokenCredentialHelper = `!f() { test "$1" = get && echo username=x-access-token && echo "password=${GH_TOKEN:-$GITHUB_TOKEN}"; }; f`
+
+// credentialHelper returns the git credential helper to clone over HTTPS with: the token in the
+// env when set, so neither gh nor its login are required, otherwise gh. It is installed for the
+// host of the repository only, see credentialHelperKey.
+func credentialHelper() string {
+	if hasEnvToken() {
+		return tokenCredentialHelper
+	}
+
+	return "!gh auth git-credential"
+}
+
+// hasEnvToken tells whether there is a token in GH_TOKEN or GITHUB_TOKEN.
+func hasEnvToken() bool {
+	return os.Getenv("GH_TOKEN") != "" || os.Getenv("GITHUB_TOKEN") != ""
+}
+
+// credentialHelperKey returns the git config key of the credential helper for the host of the
+// repository, so the token is not handed to other hosts e.g. the ones of the submodules.
+func credentialHelperKey(repo iterator.Repository) string {
+	host := cmp.Or(os.Getenv("GH_HOST"), "github.com")
+	if u, err := url.Parse(repo.URL); err == nil && u.Host != "" {
+		host = u.Host
+	}
+
+	return "credential.https://" + host + ".helper"
+}
+
+// checkout fetches and checks out the ref passed by flag or the default branch.
+func checkout(ctx context.Context, x exec.Execer, repo iterator.Repository) error {
+	if flags.ref != "" {
+		err := checkoutRef(ctx, x, flags.ref)
+		if !errors.Is(err, errRefNotFound) || flags.refFallback != RefFallbackDefaultBranch {
+			return err
+		}
+
+		x.Log(ctx, slog.LevelWarn, "Ref not found, checking out the default branch", "ref", flags.ref)
+	}
+
+	if repo.DefaultBranchName == "" {
+		return errors.New("no default branch")
+	}
+
+	if _, err := x.RunX(ctx, "git", "fetch", "origin", repo.DefaultBranchName); err != nil {
+		return fmt.Errorf("fetching HEAD: %w", err)
+	}
+
+	if _, err := x.RunX(ctx, "git", "checkout", repo.DefaultBranchName); err != nil {
+		return fmt.Errorf("checking out HEAD: %w", err)
+	}
+
+	return nil
+}
+
+// checkoutRef fetches and checks out a branch, tag or commit. Branches are checked out as local
+// branches tracking the remote one so the changes can be pushed, other refs in detached HEAD.
+func checkoutRef(ctx context.Context, x exec.Execer, ref string) error {
+	res, err := x.Run(ctx, "git", "fetch", "origin", ref)
+	if err != nil {
+		return fmt.Errorf("fetching ref: %w", err)
+	}
+
+	if res.ExitCode != 0 {
+		if strings.Contains(res.Stderr, "couldn't find remote ref") || strings.Contains(res.Stderr, "not our ref") {
+			return fmt.Errorf("%w: %s", errRefNotFound, ref)
+		}
+
+		return fmt.Errorf("fetching ref: %s", strings.TrimSpace(res.Stderr))
+	}
+
+	// fetching a branch by name updates its remote tracking branch.
+	if _, err := x.RunX(ctx, "git", "rev-parse", "--verify", "--quiet", "refs/remotes/origin/"+ref); err == nil {
+		if _, err := x.RunX(ctx, "git", "checkout", ref); err != nil {
+			return fmt.Errorf("checking out ref: %w", err)
+		}
+		return nil
+	}
+
+	if _, err := x.RunX(ctx, "git", "checkout", "--detach", "FETCH_HEAD"); err != nil {
+		return fmt.Errorf("checking out ref: %w", err)
+	}
+
+	return nil
+}
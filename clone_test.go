@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	osexec "os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	iterator "github.com/jcchavezs/gh-iterator"
+	"github.com/jcchavezs/gh-iterator/exec"
+	"github.com/stretchr/testify/require"
+)
+
+// newOriginRepository creates a repository with a main and a release/v2 branch and a v1 tag.
+func newOriginRepository(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-b", "main"},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--allow-empty", "-m", "first"},
+		{"tag", "v1"},
+		{"checkout", "-b", "release/v2"},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--allow-empty", "-m", "second"},
+		{"checkout", "main"},
+	} {
+		out, err := osexec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+
+	return dir
+}
+
+func currentRef(t *testing.T, dir string) string {
+	t.Helper()
+
+	out, err := osexec.Command("git", "-C", dir, "describe", "--all", "--exact-match").Output()
+	require.NoError(t, err)
+	return string(out)
+}
+
+func TestCloneRepository(t *testing.T) {
+	t.Cleanup(func() { flags.ref, flags.refFallback, flags.allBranches = "", RefFallbackSkip, false })
+
+	repo := iterator.Repository{Name: "acme/a", SSHURL: newOriginRepository(t), DefaultBranchName: "main", Size: 1}
+	logger := slog.New(slog.DiscardHandler)
+
+	clone := func(t *testing.T) string {
+		dir, err := cloneRepository(context.Background(), repo, logger, iterator.Options{})
+		require.NoError(t, err)
+		t.Cleanup(func() { os.RemoveAll(dir) })
+		return dir
+	}
+
+	t.Run("default branch", func(t *testing.T) {
+		require.Equal(t, "heads/main\n", currentRef(t, clone(t)))
+	})
+
+	t.Run("https", func(t *testing.T) {
+		t.Setenv("GH_TOKEN", "")
+		t.Setenv("GITHUB_TOKEN", "")
+		t.Setenv("GH_HOST", "")
+
+		dir, err := cloneRepository(context.Background(), iterator.Repository{Name: repo.Name, URL: repo.SSHURL, DefaultBranchName: "main", Size: 1}, logger, iterator.Options{UseHTTPS: true})
+		require.NoError(t, err)
+		t.Cleanup(func() { os.RemoveAll(dir) })
+
+		out, err := osexec.Command("git", "-C", dir, "config", "--local", "--get-all", "credential.https://github.com.helper").Output()
+		require.NoError(t, err)
+		require.Equal(t, "\n!gh auth git-credential\n", string(out))
+	})
+
+	t.Run("ssh with token", func(t *testing.T) {
+		t.Setenv("GH_TOKEN", "ghs_secret")
+		t.Setenv("GH_HOST", "")
+
+		dir := clone(t)
+		out, err := osexec.Command("git", "-C", dir, "config", "--local", "--get-all", "credential.https://github.com.helper").Output()
+		require.NoError(t, err)
+		require.Equal(t, "\n"+tokenCredentialHelper+"\n", string(out))
+	})
+
+	t.Run("all branches", func(t *testing.T) {
+		flags.allBranches = true
+		defer func() { flags.allBranches = false }()
+
+		out, err := osexec.Command("git", "-C", clone(t), "branch", "-r").Output()
+		require.NoError(t, err)
+		require.Contains(t, string(out), "origin/release/v2")
+	})
+
+	t.Run("branch", func(t *testing.T) {
+		flags.ref = "release/v2"
+		require.Equal(t, "heads/release/v2\n", currentRef(t, clone(t)))
+	})
+
+	t.Run("tag", func(t *testing.T) {
+		flags.ref = "v1"
+		out, err := osexec.Command("git", "-C", clone(t), "symbolic-ref", "-q", "HEAD").Output()
+		require.Error(t, err, string(out))
+	})
+
+	t.Run("missing ref", func(t *testing.T) {
+		flags.ref = "release/v3"
+
+		_, err := cloneRepository(context.Background(), repo, logger, iterator.Options{})
+		require.ErrorIs(t, err, errRefNotFound)
+
+		flags.refFallback = RefFallbackDefaultBranch
+		require.Equal(t, "heads/main\n", currentRef(t, clone(t)))
+	})
+}
+
+func TestRunWithClone_SkipsMissingRef(t *testing.T) {
+	t.Cleanup(func() { flags.ref = "" })
+	flags.ref = "release/v3"
+
+	repo := iterator.Repository{Name: "acme/a", SSHURL: newOriginRepository(t), DefaultBranchName: "main", Size: 1}
+	err := runWithClone(context.Background(), repo, func(context.Context, string, bool, exec.Execer) error {
+		t.Fatal("unexpected call")
+		return nil
+	}, slog.New(slog.DiscardHandler), nil, iterator.Options{})
+	require.NoError(t, err)
+}
+
+func TestMakeWorkDir(t *testing.T) {
+	t.Cleanup(func() { flags.workDir = "" })
+	flags.workDir = filepath.Join(t.TempDir(), "clones")
+
+	dir, err := makeWorkDir("acme/a")
+	require.NoError(t, err)
+	require.DirExists(t, dir)
+	require.Equal(t, flags.workDir, filepath.Dir(dir))
+	require.True(t, strings.HasPrefix(filepath.Base(dir), "acme-a-"))
+}
+
+func TestRemoveWorkDir(t *testing.T) {
+	t.Cleanup(func() { flags.workDir, flags.keepClones = "", false })
+	flags.workDir = filepath.Join(t.TempDir(), "clones")
+	logger := slog.New(slog.DiscardHandler)
+
+	dir, err := makeWorkDir("acme/a")
+	require.NoError(t, err)
+	removeWorkDir(dir, logger)
+	require.NoDirExists(t, dir)
+	require.NoFileExists(t, dir+workDirLockSuffix)
+
+	// the kept directories are marked so the clean command tells them apart.
+	flags.keepClones = true
+	dir, err = makeWorkDir("acme/a")
+	require.NoError(t, err)
+	removeWorkDir(dir, logger)
+	require.DirExists(t, dir)
+	require.NoFileExists(t, dir+workDirLockSuffix)
+	require.FileExists(t, dir+workDirKeepSuffix)
+}
+
+func TestTokenCredentialHelper(t *testing.T) {
+	t.Setenv("GH_TOKEN", "")
+	t.Setenv("GITHUB_TOKEN", "")
+	require.Equal(t, "!gh auth git-credential", credentialHelper())
+
+	t.Setenv("GITHUB_TOKEN", "ghs_secret")
+	require.Equal(t, tokenCredentialHelper, credentialHelper())
+
+	cmd := osexec.Command("git", "-c", "credential.helper=", "-c", "credential.helper="+tokenCredentialHelper, "credential", "fill")
+	cmd.Stdin = strings.NewReader("protocol=https\nhost=github.com\n\n")
+	out, err := cmd.Output()
+	require.NoError(t, err)
+	require.Contains(t, string(out), "username=x-access-token\npassword=ghs_secret\n")
+
+	// the helper installed for the host is not asked for the credentials of other hosts.
+	key := credentialHelperKey(iterator.Repository{URL: "https://github.com/acme/a.git"})
+	require.Equal(t, "credential.https://github.com.helper", key)
+
+	fill := func(host string) string {
+		cmd := osexec.Command("git", "-c", "credential.helper=", "-c", key+"="+tokenCredentialHelper, "-c", "credential.interactive=false", "credential", "fill")
+		cmd.Stdin = strings.NewReader("protocol=https\nhost=" + host + "\n\n")
+		cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "GIT_ASKPASS=")
+		out, _ := cmd.Output()
+		return string(out)
+	}
+	require.Contains(t, fill("github.com"), "password=ghs_secret")
+	require.NotContains(t, fill("evil.example.com"), "ghs_secret")
+}
+
+func TestCloneRepository_RecurseSubmodules(t *testing.T) {
+	t.Cleanup(func() { flags.recurseSubmodules = false })
+	// git refuses the local submodules by default.
+	t.Setenv("GIT_CONFIG_COUNT", "1")
+	t.Setenv("GIT_CONFIG_KEY_0", "protocol.file.allow")
+	t.Setenv("GIT_CONFIG_VALUE_0", "always")
+
+	sub := newOriginRepository(t)
+	origin := newOriginRepository(t)
+	for _, args := range [][]string{
+		{"submodule", "add", sub, "lib"},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-m", "add lib"},
+	} {
+		out, err := osexec.Command("git", append([]string{"-C", origin}, args...)...).CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+
+	repo := iterator.Repository{Name: "acme/a", SSHURL: origin, DefaultBranchName: "main", Size: 1}
+	clone := func(t *testing.T) string {
+		dir, err := cloneRepository(context.Background(), repo, slog.New(slog.DiscardHandler), iterator.Options{})
+		require.NoError(t, err)
+		t.Cleanup(func() { os.RemoveAll(dir) })
+		return dir
+	}
+
+	require.NoFileExists(t, filepath.Join(clone(t), "lib", ".git"))
+
+	flags.recurseSubmodules = true
+	require.FileExists(t, filepath.Join(clone(t), "lib", ".git"))
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	osexec "os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/common/types/traits"
+	iterator "github.com/jcchavezs/gh-iterator"
+	"gopkg.in/yaml.v3"
+)
+
+// cloneFilterIn is the condition evaluated in the clone of each repository before processing it,
+// nil unless --clone-filter is passed.
+var cloneFilterIn func(repo iterator.Repository, dir string) (bool, error)
+
+// parseCloneFilter compiles the condition evaluated in the clone of each repository. Besides the
+// repo variable of the search filter, it can parse the files of the clone with fileJSON, fileYAML
+// and fileTOML e.g. fileJSON("package.json").dependencies.has("lodash"), and inspect the history
+// of the default branch with the git variable e.g. git.commitCountSince(duration("720h")) > 10.
+func parseCloneFilter(cond string) (func(iterator.Repository, string) (bool, error), error) {
+	// the functions reading the files are bound to the clone, the condition is compiled upfront
+	// to fail before cloning any repository.
+	if _, err := compileCloneFilter(cond, ""); err != nil {
+		return nil, err
+	}
+
+	return func(repo iterator.Repository, dir string) (bool, error) {
+		prg, err := compileCloneFilter(cond, dir)
+		if err != nil {
+			return false, err
+		}
+
+		out, _, err := prg.Eval(map[string]any{"repo": repoVariable(repo), "git": gitVariable(dir)})
+		if err != nil {
+			return false, fmt.Errorf("evaluating clone filter: %w", err)
+		}
+
+		result, ok := out.Value().(bool)
+		return ok && result, nil
+	}, nil
+}
+
+func compileCloneFilter(cond string, dir string) (cel.Program, error) {
+	env, err := cel.NewEnv(cloneFilterOptions(dir)...)
+	if err != nil {
+		return nil, err
+	}
+
+	ast, issues := env.Compile(cond)
+	if issues != nil && issues.Err() != nil {
+		return nil, issues.Err()
+	}
+
+	return env.Program(ast)
+}
+
+// cloneFilterOptions declares the variables and functions of the clone filter, reading the files
+// from dir.
+func cloneFilterOptions(dir string) []cel.EnvOption {
+	return []cel.EnvOption{
+		cel.Variable("repo", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable("git", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Function("commitCountSince",
+			cel.MemberOverload("git_commitCountSince_duration", []*cel.Type{cel.MapType(cel.StringType, cel.DynType), cel.DurationType}, cel.IntType,
+				cel.BinaryBinding(func(_, since ref.Val) ref.Val {
+					n, err := commitCountSince(dir, since.(types.Duration).Duration)
+					if err != nil {
+						return types.NewErr("commitCountSince: %v", err)
+					}
+					return types.Int(n)
+				}),
+			),
+		),
+		cel.Function("has",
+			cel.MemberOverload("map_has_dyn", []*cel.Type{cel.MapType(cel.DynType, cel.DynType), cel.DynType}, cel.BoolType,
+				cel.BinaryBinding(func(m, key ref.Val) ref.Val {
+					return m.(traits.Mapper).Contains(key)
+				}),
+			),
+		),
+		cel.Function("fileJSON",
+			cel.Overload("fileJSON_string", []*cel.Type{cel.StringType}, cel.DynType,
+				cel.UnaryBinding(fileParser(dir, "fileJSON", json.Unmarshal)),
+			),
+		),
+		cel.Function("fileYAML",
+			cel.Overload("fileYAML_string", []*cel.Type{cel.StringType}, cel.DynType,
+				cel.UnaryBinding(fileParser(dir, "fileYAML", yaml.Unmarshal)),
+			),
+		),
+		cel.Function("fileTOML",
+			cel.Overload("fileTOML_string", []*cel.Type{cel.StringType}, cel.DynType,
+				cel.UnaryBinding(fileParser(dir, "fileTOML", unmarshalTOML)),
+			),
+		),
+	}
+}
+
+// fileParser returns the binding of a CEL function parsing the file of the clone at the path
+// passed as argument.
+func fileParser(dir string, name string, unmarshal func([]byte, any) error) func(ref.Val) ref.Val {
+	return func(arg ref.Val) ref.Val {
+		path := string(arg.(types.String))
+		if !filepath.IsLocal(path) {
+			return types.NewErr("%s: path %q is outside the repository", name, path)
+		}
+
+		content, err := os.ReadFile(filepath.Join(dir, path))
+		if err != nil {
+			return types.NewErr("%s: %v", name, err)
+		}
+
+		var v any
+		if err := unmarshal(content, &v); err != nil {
+			return types.NewErr("%s: parsing %q: %v", name, path, err)
+		}
+
+		return types.DefaultTypeAdapter.NativeToValue(v)
+	}
+}
+
+// gitVariable returns the git variable of the clone filter with the last commit of the clone,
+// empty if it is not a git repository e.g. with --tarball.
+func gitVariable(dir string) map[string]any {
+	out, err := osexec.Command("git", "-C", dir, "log", "-1", "--format=%an%n%cI").Output()
+	if err != nil {
+		return map[string]any{}
+	}
+
+	author, date, _ := strings.Cut(strings.TrimSpace(string(out)), "\n")
+	lastCommitDate, err := time.Parse(time.RFC3339, date)
+	if err != nil {
+		return map[string]any{}
+	}
+
+	return map[string]any{
+		"lastCommitAuthor": author,
+		"lastCommitDate":   lastCommitDate,
+	}
+}
+
+// commitCountSince counts the commits of the clone since the duration ago.
+func commitCountSince(dir string, since time.Duration) (int, error) {
+	after := time.Now().Add(-since).Unix()
+	out, err := osexec.Command("git", "-C", dir, "rev-list", "--count", "--since="+strconv.FormatInt(after, 10), "HEAD").Output()
+	if err != nil {
+		return 0, fmt.Errorf("counting commits: %w", err)
+	}
+
+	return strconv.Atoi(strings.TrimSpace(string(out)))
+}
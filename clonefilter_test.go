@@ -0,0 +1,70 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	iterator "github.com/jcchavezs/gh-iterator"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCloneFilter_FileJSON(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "package.json"), []byte(`{"dependencies": {"lodash": "^4.17.21"}}`), 0644))
+
+	repo := iterator.Repository{Name: "acme/web", Language: "TypeScript"}
+
+	filterIn, err := parseCloneFilter(`repo.language == "TypeScript" && fileJSON("package.json").dependencies.has("lodash")`)
+	require.NoError(t, err)
+	ok, err := filterIn(repo, dir)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	filterIn, err = parseCloneFilter(`fileJSON("package.json").dependencies.has("react")`)
+	require.NoError(t, err)
+	ok, err = filterIn(repo, dir)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	filterIn, err = parseCloneFilter(`fileJSON("composer.json").require.has("php")`)
+	require.NoError(t, err)
+	ok, err = filterIn(repo, dir)
+	require.ErrorContains(t, err, "no such file")
+	require.False(t, ok)
+
+	filterIn, err = parseCloneFilter(`fileJSON("../secrets.json").size() > 0`)
+	require.NoError(t, err)
+	_, err = filterIn(repo, dir)
+	require.ErrorContains(t, err, "outside the repository")
+
+	_, err = parseCloneFilter(`fileJSON(`)
+	require.Error(t, err)
+}
+
+func TestParseCloneFilter_FileYAMLAndTOML(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, ".github", "workflows"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".github", "workflows", "ci.yml"), []byte("on: [push]\njobs:\n  lint:\n    runs-on: ubuntu-latest\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "Cargo.toml"), []byte("[dependencies]\nserde = \"1.0\"\n"), 0644))
+
+	filterIn, err := parseCloneFilter(`fileYAML(".github/workflows/ci.yml").jobs.has("lint") && fileTOML("Cargo.toml").dependencies.has("serde")`)
+	require.NoError(t, err)
+	ok, err := filterIn(iterator.Repository{Name: "acme/api"}, dir)
+	require.NoError(t, err)
+	require.True(t, ok)
+}
+
+func TestParseCloneFilter_Git(t *testing.T) {
+	dir := newOriginRepository(t)
+
+	filterIn, err := parseCloneFilter(`git.lastCommitAuthor == "test" && git.lastCommitDate > timestamp("2020-01-01T00:00:00Z") && git.commitCountSince(duration("1h")) == 1`)
+	require.NoError(t, err)
+	ok, err := filterIn(iterator.Repository{Name: "acme/api"}, dir)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	// without history e.g. with --tarball.
+	_, err = filterIn(iterator.Repository{Name: "acme/api"}, t.TempDir())
+	require.Error(t, err)
+}
@@ -0,0 +1,564 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	osexec "os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jcchavezs/gh-iterator/exec"
+	"github.com/jcchavezs/gh-iterator/github"
+	"github.com/spf13/afero"
+)
+
+func renderCommand(s string, repository string) string {
+	return strings.ReplaceAll(s, "{{ .Repository }}", repository)
+}
+
+// loadCommandFile sets the command to the content of the file passed in --command-file, a
+// script that can span multiple lines and have comments.
+func loadCommandFile() error {
+	if flags.commandFile == "" {
+		return nil
+	}
+
+	if flags.command != "" {
+		return errors.New("--command and --command-file can't be used together")
+	}
+
+	command, err := os.ReadFile(flags.commandFile)
+	if err != nil {
+		return fmt.Errorf("reading command file: %w", err)
+	}
+	flags.command = string(command)
+
+	return nil
+}
+
+// appendCommandArgs appends the arguments passed after -- to the command, quoted for the shell.
+func appendCommandArgs(command string, args []string) string {
+	if len(args) == 0 {
+		return command
+	}
+
+	quoted := make([]string, 0, len(args))
+	for _, a := range args {
+		quoted = append(quoted, "'"+strings.ReplaceAll(a, "'", `'\''`)+"'")
+	}
+
+	return strings.TrimRight(command, " \t\r\n") + " " + strings.Join(quoted, " ")
+}
+
+// repoProcessor processes the repositories with the options passed by flag.
+type repoProcessor struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+
+	// patchFile is the absolute path of the patch to apply.
+	patchFile string
+	// replacements to apply to the files.
+	replacements []replacement
+	// prOptions are the options to create the pull requests with.
+	prOptions github.PROptions
+	// prs collects the pull requests created during the run.
+	prs *prReport
+	// issueBody is the body of the issues to create.
+	issueBody string
+	// results records the time spent running the command.
+	results *runResults
+	// plugin is the absolute path of the processor plugin, if set.
+	plugin string
+	// grep is the pattern to search the repositories for, if set.
+	grep *regexp.Regexp
+	// project is the project to export the repositories to, if set.
+	project *projectRef
+	// actions change the repositories through the API.
+	actions []repoAction
+	// templateFiles are the files to synchronize into the repositories.
+	templateFiles []templateFile
+}
+
+// process runs the pre command hook, changes the repository through the API, applies the patch
+// and replacements, syncs the template files, searches for --grep,
+// audits the required files, scans the dependencies, runs the command or the processor plugin,
+// shows the diff, commits
+// the changes or creates the pull request, creates the issue, exports it to the project and runs
+// the post command hook for a repository. The post command runs even if the command fails and
+// gets its exit code in the GH_ITERATOR_EXIT_CODE env variable. The metadata of the repository
+// attached to the context is passed to the commands in env variables.
+func (p repoProcessor) process(ctx context.Context, repository string, isEmpty bool, x exec.Execer) error {
+	if w, ok := repoLogFromContext(ctx); ok && flags.logStderr {
+		p.stderr = w
+	}
+
+	if flags.cleanEnv {
+		x = withCleanEnv(x, flags.envAllow)
+	}
+	// the commands run outside of the execer with --stream and --interactive get its env too.
+	x = trackEnv(x)
+
+	if repo, ok := repositoryFromContext(ctx); ok {
+		env, err := repositoryEnv(repo)
+		if err != nil {
+			return err
+		}
+		x = x.WithEnv(env...)
+	}
+
+	if skip, err := p.alreadyDone(ctx, x, repository, isEmpty); err != nil {
+		return err
+	} else if skip {
+		return nil
+	}
+
+	if flags.preCommand != "" {
+		if err := runHook(ctx, x, "pre", flags.preCommand, repository, p.stdout, p.stderr); err != nil {
+			return err
+		}
+	}
+
+	if len(p.actions) > 0 {
+		if err := p.runActions(ctx, x, repository); err != nil {
+			return err
+		}
+	}
+
+	if p.patchFile != "" || len(p.replacements) > 0 || len(p.templateFiles) > 0 {
+		if isEmpty {
+			x.Log(ctx, slog.LevelWarn, "Skipping changes on empty repository")
+		} else if err := p.applyChanges(ctx, x, repository); err != nil {
+			return err
+		}
+	}
+
+	if p.grep != nil && !isEmpty {
+		if err := p.grepRepository(ctx, x, repository); err != nil {
+			return err
+		}
+	}
+
+	if len(flags.requireFiles) > 0 {
+		if err := p.auditRequiredFiles(ctx, x, repository); err != nil {
+			return err
+		}
+	}
+
+	if flags.dependencyInventory != "" && !isEmpty {
+		if err := p.scanRepositoryDependencies(ctx, x, repository); err != nil {
+			return err
+		}
+	}
+
+	var (
+		exitCode int
+		err      error
+	)
+	if flags.command != "" {
+		start := time.Now()
+		var res exec.Result
+		res, err = runCommand(ctx, x, repository, p.stdin, p.stdout, p.stderr)
+		exitCode = res.ExitCode
+		if err != nil {
+			exitCode = -1
+			err = withCategory(categoryCommand, err)
+		}
+
+		p.results.update(repository, func(r *repoResult) {
+			r.CommandDuration = time.Since(start)
+			r.CommandRan = true
+			r.ExitCode = exitCode
+			r.Stdout = truncateOutput(res.Stdout)
+			r.Stderr = truncateOutput(res.Stderr)
+			if flags.sarif != "" {
+				r.Findings = parseFindings(res.Stdout)
+			}
+		})
+	} else if p.plugin != "" {
+		var skipped bool
+		if exitCode, skipped, err = p.runPlugin(ctx, x, repository); skipped {
+			x.Log(ctx, slog.LevelInfo, "Skipping repository, skipped by the processor")
+			return nil
+		}
+	}
+
+	if flags.showDiff && err == nil && !isEmpty {
+		var diff string
+		diff, err = showDiff(ctx, x, repository, p.stdout)
+		p.results.update(repository, func(r *repoResult) { r.Diff = diff[:min(len(diff), maxResultOutput)] })
+	}
+
+	if (flags.createPR || flags.commitMessage != "" || flags.push) && err == nil && exitCode == 0 {
+		if isEmpty {
+			x.Log(ctx, slog.LevelWarn, "Skipping commit on empty repository")
+		} else if flags.createPR {
+			err = withCategory(categoryPR, p.createPR(ctx, x, repository))
+		} else {
+			err = commitAndPush(ctx, x, flags.branchName, flags.commitMessage, flags.commitAll, flags.push)
+		}
+	}
+
+	if flags.createIssue && err == nil && exitCode == 0 {
+		err = p.createIssue(ctx, x, repository)
+	}
+
+	if p.project != nil && err == nil && exitCode == 0 {
+		err = p.exportToProject(ctx, x, repository)
+	}
+
+	if flags.postCommand != "" {
+		px := x.WithEnv("GH_ITERATOR_EXIT_CODE", strconv.Itoa(exitCode))
+		if hErr := runHook(ctx, px, "post", flags.postCommand, repository, p.stdout, p.stderr); hErr != nil {
+			return errors.Join(err, hErr)
+		}
+	}
+
+	return err
+}
+
+// alreadyDone checks whether a previous run already processed the repository i.e. the
+// branch exists or has an open pull request.
+func (p repoProcessor) alreadyDone(ctx context.Context, x exec.Execer, repository string, isEmpty bool) (bool, error) {
+	if flags.skipIfBranchExists && !isEmpty {
+		exists, err := remoteBranchExists(ctx, x, flags.branchName)
+		if err != nil {
+			return false, err
+		}
+
+		if exists {
+			x.Log(ctx, slog.LevelInfo, "Skipping repository, branch already exists", "branch", flags.branchName)
+			p.results.update(repository, func(r *repoResult) { r.Skipped = "branch already exists" })
+			return true, nil
+		}
+	}
+
+	if flags.skipIfPROpen {
+		prURL, err := forge.OpenPRURL(ctx, x, repository, flags.branchName)
+		if err != nil {
+			return false, err
+		}
+
+		if prURL != "" {
+			x.Log(ctx, slog.LevelInfo, "Skipping repository, PR already open", "url", prURL)
+			p.results.update(repository, func(r *repoResult) {
+				r.Skipped = "PR already open"
+				r.PRURL = prURL
+			})
+			if flags.createPR {
+				p.prs.add(prReportEntry{Repository: repository, URL: prURL, Status: prStatusSkipped})
+			}
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+const prChecksPollInterval = 30 * time.Second
+
+// createPR creates the pull request with the changes in the repository and reports it.
+func (p repoProcessor) createPR(ctx context.Context, x exec.Execer, repository string) error {
+	prURL, isNew, err := forge.CreatePR(ctx, x, flags.branchName, p.prOptions)
+	if err != nil {
+		return err
+	}
+
+	if prURL == "" {
+		p.prs.add(prReportEntry{Repository: repository, Status: prStatusSkipped})
+		return nil
+	}
+
+	p.results.update(repository, func(r *repoResult) { r.PRURL = prURL })
+
+	entry := prReportEntry{Repository: repository, URL: prURL, Status: prStatusUpdated}
+	if isNew {
+		entry.Status = prStatusNew
+		fmt.Fprintf(p.stdout, "%s: created PR %s\n", repository, prURL)
+	} else {
+		fmt.Fprintf(p.stdout, "%s: updated PR %s\n", repository, prURL)
+	}
+
+	if flags.prWaitChecks {
+		if entry.Checks, err = waitPRChecks(ctx, x, prURL, flags.prWaitChecksTimeout, prChecksPollInterval); err != nil {
+			return err
+		}
+	}
+
+	p.prs.add(entry)
+	return nil
+}
+
+// createIssue opens the issue in the repository and reports it.
+func (p repoProcessor) createIssue(ctx context.Context, x exec.Execer, repository string) error {
+	issueURL, isNew, err := createIssueIfNotExist(ctx, x, repository, flags.issueTitle, p.issueBody)
+	if err != nil {
+		return err
+	}
+
+	if isNew {
+		fmt.Fprintf(p.stdout, "%s: created issue %s\n", repository, issueURL)
+	} else {
+		fmt.Fprintf(p.stdout, "%s: issue already open %s\n", repository, issueURL)
+	}
+
+	return nil
+}
+
+// applyChanges applies the patch and the replacements to the repository and synchronizes the
+// template files.
+func (p repoProcessor) applyChanges(ctx context.Context, x exec.Execer, repository string) error {
+	if p.patchFile != "" {
+		if err := applyPatch(ctx, x, p.patchFile); err != nil {
+			return err
+		}
+	}
+
+	if len(p.replacements) > 0 {
+		changed, err := replaceInFiles(x.GenerateFS(), flags.replaceIn, p.replacements)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(p.stdout, "%s: %d files changed\n", repository, changed)
+	}
+
+	if len(p.templateFiles) > 0 {
+		if err := p.syncTemplateFiles(ctx, x, repository); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// runHook runs a pre or post command hook and fails if it exits with non zero code.
+func runHook(ctx context.Context, x exec.Execer, name string, hook string, repository string, stdout, stderr io.Writer) error {
+	res, err := x.Run(ctx, os.Getenv("SHELL"), "-c", renderCommand(hook, repository))
+	if err != nil {
+		return fmt.Errorf("running %s command: %w", name, err)
+	}
+
+	io.WriteString(stdout, res.Stdout)
+
+	if res.ExitCode != 0 {
+		io.WriteString(stderr, res.Stderr)
+		return fmt.Errorf("%s command: exit code %d", name, res.ExitCode)
+	}
+
+	return nil
+}
+
+// runCommand runs the command passed by flag in the repository directory, or in the directories
+// matching --for-each, forwards or stores its output and returns its result.
+func runCommand(ctx context.Context, x exec.Execer, repository string, stdin io.Reader, stdout, stderr io.Writer) (exec.Result, error) {
+	var (
+		res exec.Result
+		err error
+	)
+	if flags.forEach != "" {
+		res, err = execCommandForEach(ctx, x, repository, stdin, stdout, stderr)
+	} else {
+		res, err = execCommand(ctx, x, repository, renderCommand(flags.command, repository), stdin, stdout, stderr)
+	}
+	if err != nil {
+		io.WriteString(stderr, res.Stderr)
+		return res, err
+	}
+
+	if flags.outputDir != "" {
+		return res, writeCommandOutput(flags.outputDir, repository, res)
+	}
+
+	if !flags.stream && !flags.interactive {
+		io.WriteString(stdout, res.Stdout)
+	}
+
+	return res, nil
+}
+
+// execCommand runs the command in the execer directory, interactively or streaming its output
+// if so passed by flag.
+func execCommand(ctx context.Context, x exec.Execer, repository string, command string, stdin io.Reader, stdout, stderr io.Writer) (exec.Result, error) {
+	var (
+		res exec.Result
+		err error
+	)
+	if flags.interactive {
+		res, err = interactiveCommand(ctx, x, command, stdin, stdout, stderr)
+	} else if flags.stream {
+		res, err = streamCommand(ctx, x, repository, command, stdout, stderr)
+	} else {
+		res, err = x.Run(ctx, os.Getenv("SHELL"), "-c", command)
+	}
+	if err != nil {
+		return res, err
+	}
+
+	if res.ExitCode != 0 && flags.debugShellOnFailure {
+		x.DebugShell(ctx)
+	}
+
+	return res, nil
+}
+
+// outputMux serializes the lines written by concurrent workers.
+var outputMux sync.Mutex
+
+// streamCommand runs the command writing its output line by line, prefixed with the
+// repository name, as it is produced.
+func streamCommand(ctx context.Context, x exec.Execer, repository string, command string, stdout, stderr io.Writer) (exec.Result, error) {
+	dir, err := commandDir(x)
+	if err != nil {
+		return exec.Result{}, err
+	}
+
+	var (
+		outBuf, errBuf bytes.Buffer
+		prefix         = "[" + repository + "] "
+		outW           = &prefixWriter{mu: &outputMux, w: stdout, prefix: prefix}
+		errW           = &prefixWriter{mu: &outputMux, w: stderr, prefix: prefix}
+	)
+
+	exitCode, err := runShell(ctx, dir, commandEnv(x), command, nil, io.MultiWriter(outW, &outBuf), io.MultiWriter(errW, &errBuf))
+	_ = outW.Flush()
+	_ = errW.Flush()
+
+	return exec.Result{Stdout: outBuf.String(), Stderr: errBuf.String(), ExitCode: exitCode}, err
+}
+
+// interactiveCommand runs the command connected to the user's terminal so it can prompt
+// for input. The output is not captured.
+func interactiveCommand(ctx context.Context, x exec.Execer, command string, stdin io.Reader, stdout, stderr io.Writer) (exec.Result, error) {
+	dir, err := commandDir(x)
+	if err != nil {
+		return exec.Result{}, err
+	}
+
+	exitCode, err := runShell(ctx, dir, commandEnv(x), command, stdin, stdout, stderr)
+	return exec.Result{ExitCode: exitCode}, err
+}
+
+// commandDir returns the directory where the execer runs the commands.
+func commandDir(x exec.Execer) (string, error) {
+	fs, ok := x.GenerateFS().(*afero.BasePathFs)
+	if !ok {
+		return "", errors.New("unexpected execer filesystem")
+	}
+
+	return fs.RealPath(".")
+}
+
+// envDecorator records the env variables passed to the execer with WithEnv, so the commands run
+// outside of it get them too.
+type envDecorator struct {
+	env []string
+}
+
+// trackEnv wraps the execer to record the env variables passed to it from now on.
+func trackEnv(x exec.Execer) exec.Execer {
+	return decorate(x, envDecorator{})
+}
+
+func (d envDecorator) runWithStdin(ctx context.Context, next exec.Execer, stdin io.Reader, command string, args ...string) (exec.Result, error) {
+	return next.RunWithStdin(ctx, stdin, command, args...)
+}
+
+func (d envDecorator) withEnv(kv ...string) execerDecorator {
+	return envDecorator{env: appendEnv(d.env, kv...)}
+}
+
+// commandEnv returns the env variables passed to the execer, if tracked.
+func commandEnv(x exec.Execer) []string {
+	if dx, ok := x.(decoratedExecer); ok {
+		if d, ok := dx.decorator.(envDecorator); ok {
+			return d.env
+		}
+	}
+
+	return nil
+}
+
+// runShell runs the command using $SHELL in dir with the given stdio and the env variables added
+// to the environment, and returns the exit code.
+func runShell(ctx context.Context, dir string, env []string, command string, stdin io.Reader, stdout, stderr io.Writer) (int, error) {
+	c := osexec.CommandContext(ctx, os.Getenv("SHELL"), "-c", command)
+	c.Dir = dir
+	c.Env = os.Environ()
+	if flags.cleanEnv {
+		c.Env = allowedEnv(c.Env, append(defaultEnvAllowlist, flags.envAllow...))
+	}
+	c.Env = append(c.Env, env...)
+	c.Stdin = stdin
+	c.Stdout = stdout
+	c.Stderr = stderr
+	if stdin == nil {
+		// the interactive commands read from the terminal so they stay in the process group
+		// of the run.
+		startInProcessGroup(c)
+	}
+
+	if err := c.Run(); err != nil {
+		var exitErr *osexec.ExitError
+		if errors.As(err, &exitErr) {
+			return exitErr.ExitCode(), nil
+		}
+
+		return 0, err
+	}
+
+	return 0, nil
+}
+
+// prefixWriter writes complete lines into w prefixed with prefix. Lines from
+// writers sharing the same mutex are never interleaved.
+type prefixWriter struct {
+	mu     *sync.Mutex
+	w      io.Writer
+	prefix string
+	buf    []byte
+}
+
+func (pw *prefixWriter) Write(p []byte) (int, error) {
+	pw.buf = append(pw.buf, p...)
+	for {
+		i := bytes.IndexByte(pw.buf, '\n')
+		if i < 0 {
+			break
+		}
+
+		if err := pw.writeLine(pw.buf[:i+1]); err != nil {
+			return 0, err
+		}
+		pw.buf = pw.buf[i+1:]
+	}
+
+	return len(p), nil
+}
+
+// Flush writes the pending incomplete line, if any.
+func (pw *prefixWriter) Flush() error {
+	if len(pw.buf) == 0 {
+		return nil
+	}
+
+	line := append(pw.buf, '\n')
+	pw.buf = nil
+	return pw.writeLine(line)
+}
+
+func (pw *prefixWriter) writeLine(line []byte) error {
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+
+	_, err := io.WriteString(pw.w, pw.prefix+string(line))
+	return err
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	osexec "os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jcchavezs/gh-iterator/exec"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrefixWriter(t *testing.T) {
+	var (
+		out bytes.Buffer
+		mu  sync.Mutex
+	)
+
+	pw := &prefixWriter{mu: &mu, w: &out, prefix: "[acme/repo] "}
+
+	_, err := pw.Write([]byte("first line\nsecond "))
+	require.NoError(t, err)
+	require.Equal(t, "[acme/repo] first line\n", out.String())
+
+	_, err = pw.Write([]byte("line\nthird"))
+	require.NoError(t, err)
+	require.Equal(t, "[acme/repo] first line\n[acme/repo] second line\n", out.String())
+
+	require.NoError(t, pw.Flush())
+	require.Equal(t, "[acme/repo] first line\n[acme/repo] second line\n[acme/repo] third\n", out.String())
+}
+
+func TestLoadCommandFile(t *testing.T) {
+	t.Cleanup(func() { flags.command, flags.commandFile = "", "" })
+
+	path := filepath.Join(t.TempDir(), "cmd.tmpl")
+	require.NoError(t, os.WriteFile(path, []byte("# bumps the version\necho '{{ .Repository }}' \\\n  \"done\"\n"), 0o644))
+
+	flags.command, flags.commandFile = "ls", path
+	require.Error(t, loadCommandFile())
+
+	flags.command = ""
+	require.NoError(t, loadCommandFile())
+
+	res, err := osexec.Command("sh", "-c", renderCommand(flags.command, "acme/a")).Output()
+	require.NoError(t, err)
+	require.Equal(t, "acme/a done\n", string(res))
+}
+
+func TestAppendCommandArgs(t *testing.T) {
+	require.Equal(t, "./script.sh", appendCommandArgs("./script.sh", nil))
+	require.Equal(t, `./script.sh '--flag' 'it'\''s'`, appendCommandArgs("./script.sh\n", []string{"--flag", "it's"}))
+
+	res, err := osexec.Command("sh", "-c", appendCommandArgs(`printf '%s|' "$0"`, []string{"a b", "it's"})).Output()
+	require.NoError(t, err)
+	require.Equal(t, "sh|a b|it's|", string(res))
+}
+
+func TestExecCommand_Stream(t *testing.T) {
+	t.Setenv("SHELL", "/bin/sh")
+	t.Setenv("GH_ITERATOR_TEST_INHERITED", "inherited")
+	t.Cleanup(func() { flags.stream = false })
+	flags.stream = true
+
+	x := trackEnv(exec.NewExecer(t.TempDir())).WithEnv("GH_ITERATOR_REPOSITORY", "acme/a")
+
+	var out bytes.Buffer
+	res, err := execCommand(context.Background(), x, "acme/a", `echo "$GH_ITERATOR_REPOSITORY $GH_ITERATOR_TEST_INHERITED"`, nil, &out, io.Discard)
+	require.NoError(t, err)
+	require.Equal(t, "acme/a inherited\n", res.Stdout)
+	require.Equal(t, "[acme/a] acme/a inherited\n", out.String())
+}
+
+func TestProcess_Hooks(t *testing.T) {
+	t.Setenv("SHELL", "/bin/sh")
+	t.Cleanup(func() { flags.preCommand, flags.command, flags.postCommand = "", "", "" })
+	flags.command = "echo command >> order; exit $EXIT"
+	flags.postCommand = `echo "post $GH_ITERATOR_EXIT_CODE" >> order`
+
+	process := func(t *testing.T, pre string, exit string) (string, error) {
+		flags.preCommand = pre
+		dir := t.TempDir()
+		p := repoProcessor{stdout: io.Discard, stderr: io.Discard, results: newRunResults()}
+		err := p.process(context.Background(), "acme/a", false, exec.NewExecer(dir).WithEnv("EXIT", exit))
+		order, _ := os.ReadFile(filepath.Join(dir, "order"))
+		return string(order), err
+	}
+
+	order, err := process(t, "echo pre >> order", "0")
+	require.NoError(t, err)
+	require.Equal(t, "pre\ncommand\npost 0\n", order)
+
+	// the post command runs after a failed command too.
+	order, err = process(t, "echo pre >> order", "3")
+	require.NoError(t, err)
+	require.Equal(t, "pre\ncommand\npost 3\n", order)
+
+	// a failed pre command skips the repository.
+	order, err = process(t, "echo pre >> order; exit 1", "0")
+	require.ErrorContains(t, err, "pre command: exit code 1")
+	require.Equal(t, "pre\n", order)
+}
+
+func TestExecCommand_Interactive(t *testing.T) {
+	t.Setenv("SHELL", "/bin/sh")
+	t.Cleanup(func() { flags.interactive = false })
+	flags.interactive = true
+
+	// the repositories are processed one at a time so the prompts do not interleave.
+	require.Equal(t, 1, numberOfWorkers())
+
+	var out bytes.Buffer
+	res, err := execCommand(context.Background(), exec.NewExecer(t.TempDir()), "acme/a", `read answer; echo "got $answer"; exit 2`, strings.NewReader("yes\n"), &out, io.Discard)
+	require.NoError(t, err)
+	require.Equal(t, 2, res.ExitCode)
+	// the output goes to the terminal, it is not captured.
+	require.Equal(t, "got yes\n", out.String())
+	require.Empty(t, res.Stdout)
+}
+
+// debugShellExecer counts the debug shells started.
+type debugShellExecer struct {
+	exec.Execer
+	shells *int
+}
+
+func (x debugShellExecer) DebugShell(context.Context) {
+	*x.shells++
+}
+
+func TestExecCommand_DebugShellOnFailure(t *testing.T) {
+	t.Setenv("SHELL", "/bin/sh")
+	t.Cleanup(func() { flags.debugShellOnFailure = false })
+
+	var shells int
+	x := debugShellExecer{Execer: exec.NewExecer(t.TempDir()), shells: &shells}
+
+	_, err := execCommand(context.Background(), x, "acme/a", "exit 1", nil, io.Discard, io.Discard)
+	require.NoError(t, err)
+	require.Zero(t, shells)
+
+	flags.debugShellOnFailure = true
+	require.Equal(t, 1, numberOfWorkers())
+
+	_, err = execCommand(context.Background(), x, "acme/a", "true", nil, io.Discard, io.Discard)
+	require.NoError(t, err)
+	require.Zero(t, shells)
+
+	res, err := execCommand(context.Background(), x, "acme/a", "exit 1", nil, io.Discard, io.Discard)
+	require.NoError(t, err)
+	require.Equal(t, 1, res.ExitCode)
+	require.Equal(t, 1, shells)
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/mail"
+
+	"github.com/jcchavezs/gh-iterator/exec"
+	"github.com/jcchavezs/gh-iterator/github"
+)
+
+// commitChanges commits the changes in the repository and returns whether a commit was
+// created. If all is true every change in the working tree is staged before, otherwise only
+// the changes already staged e.g. by the command are committed.
+func commitChanges(ctx context.Context, x exec.Execer, message string, all bool) (bool, error) {
+	if all {
+		hasChanges, err := github.HasChanges(ctx, x)
+		if err != nil {
+			return false, err
+		}
+
+		if !hasChanges {
+			return false, nil
+		}
+
+		if err := github.AddFiles(ctx, x, "."); err != nil {
+			return false, err
+		}
+	} else {
+		hasStaged, err := hasStagedChanges(ctx, x)
+		if err != nil {
+			return false, err
+		}
+
+		if !hasStaged {
+			return false, nil
+		}
+	}
+
+	x, args, err := commitIdentity(ctx, x)
+	if err != nil {
+		return false, err
+	}
+
+	if err := github.Commit(ctx, x, message, args...); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// SigningFormat is the format of the commit signatures.
+type SigningFormat int
+
+const (
+	SigningFormatGPG SigningFormat = iota
+	SigningFormatSSH
+)
+
+// SigningFormatIds maps signing formats to their corresponding string identifiers.
+var SigningFormatIds = map[SigningFormat][]string{
+	SigningFormatGPG: {"gpg"},
+	SigningFormatSSH: {"ssh"},
+}
+
+// parseIdentity parses an identity in the form 'Name <email>'.
+func parseIdentity(s string) (*mail.Address, error) {
+	addr, err := mail.ParseAddress(s)
+	if err != nil || addr.Name == "" {
+		return nil, fmt.Errorf("invalid identity %q, expected 'Name <email>'", s)
+	}
+
+	return addr, nil
+}
+
+// commitIdentity configures the clone to sign the commits and returns the execer with the
+// committer and the git commit flags with the author, as passed by flag.
+func commitIdentity(ctx context.Context, x exec.Execer) (exec.Execer, []string, error) {
+	var args []string
+
+	if flags.commitAuthor != "" {
+		args = append(args, "--author="+flags.commitAuthor)
+	}
+
+	if flags.committer != "" {
+		addr, err := parseIdentity(flags.committer)
+		if err != nil {
+			return nil, nil, err
+		}
+		x = x.WithEnv("GIT_COMMITTER_NAME", addr.Name, "GIT_COMMITTER_EMAIL", addr.Address)
+	}
+
+	if flags.signCommits || flags.signingKey != "" {
+		if flags.signingFormat == SigningFormatSSH {
+			if _, err := x.RunX(ctx, "git", "config", "gpg.format", "ssh"); err != nil {
+				return nil, nil, fmt.Errorf("setting signing format: %w", err)
+			}
+		}
+
+		if flags.signingKey != "" {
+			if _, err := x.RunX(ctx, "git", "config", "user.signingkey", flags.signingKey); err != nil {
+				return nil, nil, fmt.Errorf("setting signing key: %w", err)
+			}
+		}
+
+		args = append(args, "--gpg-sign")
+	}
+
+	return x, args, nil
+}
+
+// hasStagedChanges returns true if there are changes in the index.
+func hasStagedChanges(ctx context.Context, x exec.Execer) (bool, error) {
+	res, err := x.Run(ctx, "git", "diff", "--cached", "--quiet")
+	if err != nil {
+		return false, fmt.Errorf("checking staged changes: %w", err)
+	}
+
+	return res.ExitCode != 0, nil
+}
+
+// commitAndPush commits the changes in the repository, in a new branch if branchName is
+// passed, and pushes the current branch.
+func commitAndPush(ctx context.Context, x exec.Execer, branchName string, message string, all bool, push bool) error {
+	if branchName != "" {
+		if err := github.CheckoutNewBranch(ctx, x, branchName); err != nil {
+			return err
+		}
+	}
+
+	if message != "" {
+		committed, err := commitChanges(ctx, x, message, all)
+		if err != nil {
+			return err
+		}
+
+		if !committed {
+			x.Log(ctx, slog.LevelInfo, "No changes to commit")
+		}
+	}
+
+	if !push {
+		return nil
+	}
+
+	branch, err := github.CurrentBranch(ctx, x)
+	if err != nil {
+		return err
+	}
+
+	return github.Push(ctx, x, branch, github.PushNoForce)
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"io"
+	"os"
+	osexec "os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/jcchavezs/gh-iterator/exec"
+	"github.com/stretchr/testify/require"
+)
+
+func gitOutput(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+
+	out, err := osexec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput()
+	require.NoError(t, err, string(out))
+	return string(out)
+}
+
+func TestCommitChanges_Identity(t *testing.T) {
+	t.Cleanup(func() { flags.commitAuthor, flags.committer = "", "" })
+	flags.commitAuthor = "Release Bot <bot@acme.com>"
+	flags.committer = "Platform Team <platform@acme.com>"
+
+	dir := newOriginRepository(t)
+	gitOutput(t, dir, "config", "user.name", "test")
+	gitOutput(t, dir, "config", "user.email", "test@example.com")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "NOTICE"), []byte("hello\n"), 0o644))
+
+	committed, err := commitChanges(context.Background(), exec.NewExecer(dir), "add notice", true)
+	require.NoError(t, err)
+	require.True(t, committed)
+	require.Equal(t, "Release Bot <bot@acme.com>|Platform Team <platform@acme.com>\n", gitOutput(t, dir, "log", "-1", "--format=%an <%ae>|%cn <%ce>"))
+}
+
+func TestCommitChanges_SSHSigning(t *testing.T) {
+	t.Cleanup(func() { flags.signingKey, flags.signingFormat = "", SigningFormatGPG })
+
+	key := filepath.Join(t.TempDir(), "id_ed25519")
+	out, err := osexec.Command("ssh-keygen", "-q", "-t", "ed25519", "-N", "", "-f", key).CombinedOutput()
+	if err != nil {
+		t.Skipf("generating SSH key: %s", out)
+	}
+	flags.signingKey, flags.signingFormat = key, SigningFormatSSH
+
+	dir := newOriginRepository(t)
+	gitOutput(t, dir, "config", "user.name", "test")
+	gitOutput(t, dir, "config", "user.email", "test@example.com")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "NOTICE"), []byte("hello\n"), 0o644))
+
+	_, err = commitChanges(context.Background(), exec.NewExecer(dir), "add notice", true)
+	require.NoError(t, err)
+	require.Contains(t, gitOutput(t, dir, "cat-file", "-p", "HEAD"), "-----BEGIN SSH SIGNATURE-----")
+}
+
+func TestParseIdentity(t *testing.T) {
+	_, err := parseIdentity("bot@acme.com")
+	require.Error(t, err)
+
+	addr, err := parseIdentity("Release Bot <bot@acme.com>")
+	require.NoError(t, err)
+	require.Equal(t, "bot@acme.com", addr.Address)
+}
+
+// newOriginClone clones the repository of newOriginRepository, returning the clone and the origin.
+func newOriginClone(t *testing.T) (string, string) {
+	t.Helper()
+
+	origin := newOriginRepository(t)
+	dir := filepath.Join(t.TempDir(), "clone")
+	out, err := osexec.Command("git", "clone", "-q", origin, dir).CombinedOutput()
+	require.NoError(t, err, string(out))
+	gitOutput(t, dir, "config", "user.name", "test")
+	gitOutput(t, dir, "config", "user.email", "test@example.com")
+
+	return dir, origin
+}
+
+func TestProcess_CommitAndPush(t *testing.T) {
+	t.Setenv("SHELL", "/bin/sh")
+	t.Cleanup(func() {
+		flags.command, flags.commitMessage, flags.commitAll, flags.branchName, flags.push = "", "", false, "", false
+	})
+	flags.commitMessage, flags.commitAll = "add notice", true
+
+	process := func(t *testing.T, dir string) repoResult {
+		p := repoProcessor{stdout: io.Discard, stderr: io.Discard, results: newRunResults()}
+		require.NoError(t, p.process(context.Background(), "acme/a", false, exec.NewExecer(dir)))
+		res, _ := p.results.get("acme/a")
+		return res
+	}
+
+	t.Run("push", func(t *testing.T) {
+		dir, origin := newOriginClone(t)
+		flags.command, flags.branchName, flags.push = "echo hello > NOTICE", "chore/notice", true
+
+		process(t, dir)
+		require.Equal(t, "add notice\n", gitOutput(t, origin, "log", "-1", "--format=%s", "chore/notice"))
+	})
+
+	t.Run("without push", func(t *testing.T) {
+		dir, origin := newOriginClone(t)
+		flags.command, flags.branchName, flags.push = "echo hello > NOTICE", "chore/notice", false
+
+		process(t, dir)
+		require.Equal(t, "add notice\n", gitOutput(t, dir, "log", "-1", "--format=%s"))
+		require.Empty(t, gitOutput(t, origin, "branch", "--list", "chore/notice"))
+	})
+
+	t.Run("command failing", func(t *testing.T) {
+		dir, origin := newOriginClone(t)
+		flags.command, flags.branchName, flags.push = "echo hello > NOTICE; exit 1", "chore/notice", true
+
+		// the changes of a failed command are neither committed nor pushed.
+		require.Equal(t, 1, process(t, dir).ExitCode)
+		require.Equal(t, "first\n", gitOutput(t, dir, "log", "-1", "--format=%s"))
+		require.Empty(t, gitOutput(t, origin, "branch", "--list", "chore/notice"))
+	})
+
+	t.Run("no changes", func(t *testing.T) {
+		dir, origin := newOriginClone(t)
+		flags.command, flags.branchName, flags.push = "true", "", true
+
+		process(t, dir)
+		require.Equal(t, "first\n", gitOutput(t, dir, "log", "-1", "--format=%s"))
+		require.Equal(t, "first\n", gitOutput(t, origin, "log", "-1", "--format=%s", "main"))
+	})
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	projectConfigFile = ".gh-iterator-run.yaml"
+	presetsKey        = "presets"
+)
+
+// configPaths returns the config files to load, the later ones overriding the earlier ones:
+// the user config and the project config, or the file passed in --config instead of the latter.
+func configPaths() []string {
+	var paths []string
+	if dir, err := os.UserConfigDir(); err == nil {
+		paths = append(paths, filepath.Join(dir, "gh-iterator-run", "config.yaml"))
+	}
+
+	if flags.config != "" {
+		return append(paths, flags.config)
+	}
+
+	return append(paths, projectConfigFile)
+}
+
+// loadConfig reads the flag values from the config files, skipping the missing ones except the
+// file passed in --config, and applies the values of the preset on top of them.
+func loadConfig(paths []string, preset string) (map[string]any, error) {
+	values := map[string]any{}
+	presets := map[string]map[string]any{}
+
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) && path != flags.config {
+				continue
+			}
+			return nil, fmt.Errorf("reading config: %w", err)
+		}
+
+		var file map[string]any
+		if err := yaml.Unmarshal(content, &file); err != nil {
+			return nil, fmt.Errorf("parsing config %s: %w", path, err)
+		}
+
+		if p, ok := file[presetsKey]; ok {
+			var filePresets map[string]map[string]any
+			if err := decodeConfigValue(p, &filePresets); err != nil {
+				return nil, fmt.Errorf("parsing presets in config %s: %w", path, err)
+			}
+			maps.Copy(presets, filePresets)
+			delete(file, presetsKey)
+		}
+
+		maps.Copy(values, file)
+	}
+
+	if preset != "" {
+		p, ok := presets[preset]
+		if !ok {
+			return nil, fmt.Errorf("unknown preset %q", preset)
+		}
+		maps.Copy(values, p)
+	}
+
+	return values, nil
+}
+
+// decodeConfigValue decodes a value of the config into out.
+func decodeConfigValue(v any, out any) error {
+	content, err := yaml.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	return yaml.Unmarshal(content, out)
+}
+
+// applyConfig sets the flags of the command not passed in the command line to the values in the
+// config. The values of the flags of other commands are ignored, lists set repeated flags.
+func applyConfig(cmd *cobra.Command, values map[string]any) error {
+	known := map[string]bool{}
+	var collect func(*cobra.Command)
+	collect = func(c *cobra.Command) {
+		c.Flags().VisitAll(func(f *pflag.Flag) { known[f.Name] = true })
+		c.PersistentFlags().VisitAll(func(f *pflag.Flag) { known[f.Name] = true })
+		for _, sub := range c.Commands() {
+			collect(sub)
+		}
+	}
+	collect(cmd.Root())
+
+	for _, name := range slices.Sorted(maps.Keys(values)) {
+		if !known[name] {
+			return fmt.Errorf("unknown flag %q in config", name)
+		}
+
+		f := cmd.Flags().Lookup(name)
+		if f == nil || f.Changed {
+			continue
+		}
+
+		list, isList := values[name].([]any)
+		if !isList {
+			list = []any{values[name]}
+		}
+
+		for _, v := range list {
+			if err := cmd.Flags().Set(name, fmt.Sprint(v)); err != nil {
+				return fmt.Errorf("setting %q from config: %w", name, err)
+			}
+		}
+	}
+
+	return nil
+}
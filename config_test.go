@@ -0,0 +1,67 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+
+	user := filepath.Join(dir, "user.yaml")
+	require.NoError(t, os.WriteFile(user, []byte(`
+workers: 4
+output: json
+presets:
+  go:
+    search-filter: repo.language == "Go"
+`), 0644))
+
+	project := filepath.Join(dir, "project.yaml")
+	require.NoError(t, os.WriteFile(project, []byte(`
+output: csv
+org: [acme, umbrella]
+`), 0644))
+
+	values, err := loadConfig([]string{user, project, filepath.Join(dir, "missing.yaml")}, "")
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"workers": 4, "output": "csv", "org": []any{"acme", "umbrella"}}, values)
+
+	values, err = loadConfig([]string{user, project}, "go")
+	require.NoError(t, err)
+	require.Equal(t, `repo.language == "Go"`, values["search-filter"])
+
+	_, err = loadConfig([]string{user}, "java")
+	require.ErrorContains(t, err, `unknown preset "java"`)
+}
+
+func TestApplyConfig(t *testing.T) {
+	var (
+		workers int
+		orgs    []string
+		output  string
+		fields  []string
+	)
+
+	root := &cobra.Command{Use: "root", RunE: func(*cobra.Command, []string) error { return nil }}
+	root.PersistentFlags().StringArrayVar(&orgs, "org", nil, "")
+	root.Flags().IntVar(&workers, "workers", 10, "")
+	root.Flags().StringVar(&output, "output", "text", "")
+	list := &cobra.Command{Use: "list", RunE: func(*cobra.Command, []string) error { return nil }}
+	list.Flags().StringSliceVar(&fields, "fields", []string{"name"}, "")
+	root.AddCommand(list)
+
+	root.SetArgs([]string{"--output", "json"})
+	require.NoError(t, root.Execute())
+	require.NoError(t, applyConfig(root, map[string]any{"workers": 4, "output": "csv", "org": []any{"acme", "umbrella"}, "fields": []any{"name", "size"}}))
+	require.Equal(t, 4, workers)
+	require.Equal(t, "json", output)
+	require.Equal(t, []string{"acme", "umbrella"}, orgs)
+	require.Equal(t, []string{"name"}, fields)
+
+	require.ErrorContains(t, applyConfig(root, map[string]any{"unknown": true}), `unknown flag "unknown" in config`)
+}
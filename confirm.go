@@ -0,0 +1,27 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// confirm asks whether to continue processing the matching repositories and reads the answer,
+// anything other than y or yes is a no.
+func confirm(in io.Reader, out io.Writer, count int) (bool, error) {
+	fmt.Fprintf(out, "%d repositories match, continue? [y/N] ", count)
+
+	answer, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return false, fmt.Errorf("reading confirmation: %w", err)
+	}
+
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true, nil
+	default:
+		return false, nil
+	}
+}
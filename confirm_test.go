@@ -0,0 +1,25 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfirm(t *testing.T) {
+	for answer, expected := range map[string]bool{
+		"y\n":   true,
+		"YES\n": true,
+		"n\n":   false,
+		"\n":    false,
+		"":      false,
+	} {
+		out := &bytes.Buffer{}
+		ok, err := confirm(strings.NewReader(answer), out, 3)
+		require.NoError(t, err)
+		require.Equal(t, expected, ok, answer)
+		require.Equal(t, "3 repositories match, continue? [y/N] ", out.String())
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+
+	iterator "github.com/jcchavezs/gh-iterator"
+	"github.com/jcchavezs/gh-iterator/exec"
+	"github.com/spf13/cobra"
+	"github.com/thediveo/enumflag/v2"
+)
+
+// CountGroup is the field to group the count of repositories by.
+type CountGroup int
+
+const (
+	CountGroupNone CountGroup = iota
+	CountGroupLanguage
+	CountGroupVisibility
+)
+
+// CountGroupIds maps count groups to their corresponding string identifiers.
+var CountGroupIds = map[CountGroup][]string{
+	CountGroupNone:       {"none"},
+	CountGroupLanguage:   {"language"},
+	CountGroupVisibility: {"visibility"},
+}
+
+func newCountCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "count [OWNER...]",
+		Short: "Print the number of repositories passing the filter",
+		Long: `Prints the number of repositories passing the filter without processing them e.g. to size
+a campaign.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			logger := slog.New(newLogHandler(cmd.ErrOrStderr(), flags.logLevel))
+
+			owners, err := setupSources(ctx, args, logger)
+			if err != nil {
+				return err
+			}
+
+			stages, err := filterStages(logger)
+			if err != nil {
+				return err
+			}
+
+			_, selected, _, err := matchingRepositories(ctx, withRetries(exec.NewExecerWithLogger(".", logger), flags.apiRetries), owners, cmd.InOrStdin(), stages)
+			if err != nil {
+				return err
+			}
+
+			return writeCount(cmd.OutOrStdout(), selected, flags.countBy)
+		},
+	}
+
+	cmd.Flags().Var(
+		enumflag.New(&flags.countBy, "string", CountGroupIds, enumflag.EnumCaseInsensitive),
+		"by",
+		"Field to group the count by: none, language or visibility",
+	)
+
+	return cmd
+}
+
+// writeCount writes the number of repositories or, when grouped, a line per group with the
+// number of repositories in it, the largest group first.
+func writeCount(w io.Writer, repos []iterator.Repository, by CountGroup) error {
+	var group func(iterator.Repository) string
+	switch by {
+	case CountGroupLanguage:
+		group = func(r iterator.Repository) string { return r.Language }
+	case CountGroupVisibility:
+		group = func(r iterator.Repository) string { return r.Visibility }
+	default:
+		if _, err := fmt.Fprintln(w, len(repos)); err != nil {
+			return fmt.Errorf("writing count: %w", err)
+		}
+		return nil
+	}
+
+	byGroup := map[string]int{}
+	for _, r := range repos {
+		byGroup[cmp.Or(group(r), "none")]++
+	}
+
+	groups := make([]string, 0, len(byGroup))
+	for g := range byGroup {
+		groups = append(groups, g)
+	}
+
+	slices.SortFunc(groups, func(a, b string) int {
+		return cmp.Or(cmp.Compare(byGroup[b], byGroup[a]), strings.Compare(a, b))
+	})
+
+	for _, g := range groups {
+		if _, err := fmt.Fprintf(w, "%s\t%d\n", g, byGroup[g]); err != nil {
+			return fmt.Errorf("writing count: %w", err)
+		}
+	}
+
+	return nil
+}
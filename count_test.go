@@ -0,0 +1,30 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	iterator "github.com/jcchavezs/gh-iterator"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteCount(t *testing.T) {
+	repos := []iterator.Repository{
+		{Name: "acme/a", Language: "Go", Visibility: "public"},
+		{Name: "acme/b", Language: "Java", Visibility: "private"},
+		{Name: "acme/c", Language: "Go", Visibility: "private"},
+		{Name: "acme/d", Visibility: "private"},
+	}
+
+	out := &bytes.Buffer{}
+	require.NoError(t, writeCount(out, repos, CountGroupNone))
+	require.Equal(t, "4\n", out.String())
+
+	out.Reset()
+	require.NoError(t, writeCount(out, repos, CountGroupLanguage))
+	require.Equal(t, "Go\t2\nJava\t1\nnone\t1\n", out.String())
+
+	out.Reset()
+	require.NoError(t, writeCount(out, repos, CountGroupVisibility))
+	require.Equal(t, "private\t3\npublic\t1\n", out.String())
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"io"
+
+	"github.com/jcchavezs/gh-iterator/exec"
+)
+
+// execerDecorator intercepts the invocations of the execer it decorates.
+type execerDecorator interface {
+	// runWithStdin runs the command with next, the decorated execer.
+	runWithStdin(ctx context.Context, next exec.Execer, stdin io.Reader, command string, args ...string) (exec.Result, error)
+}
+
+// envAwareDecorator is implemented by the decorators that need the env variables passed with
+// WithEnv, withEnv returns the decorator for the derived execer.
+type envAwareDecorator interface {
+	execerDecorator
+	withEnv(kv ...string) execerDecorator
+}
+
+// decoratedExecer routes the invocations of the execer through the decorator and keeps it
+// around the execers derived with WithEnv, WithLogFields and Sub.
+type decoratedExecer struct {
+	exec.Execer
+	decorator execerDecorator
+}
+
+// decorate wraps the execer with the decorator.
+func decorate(x exec.Execer, d execerDecorator) exec.Execer {
+	return decoratedExecer{Execer: x, decorator: d}
+}
+
+func (x decoratedExecer) Run(ctx context.Context, command string, args ...string) (exec.Result, error) {
+	return x.RunWithStdin(ctx, nil, command, args...)
+}
+
+func (x decoratedExecer) RunX(ctx context.Context, command string, args ...string) (string, error) {
+	return x.RunWithStdinX(ctx, nil, command, args...)
+}
+
+func (x decoratedExecer) RunWithStdin(ctx context.Context, stdin io.Reader, command string, args ...string) (exec.Result, error) {
+	return x.decorator.runWithStdin(ctx, x.Execer, stdin, command, args...)
+}
+
+func (x decoratedExecer) RunWithStdinX(ctx context.Context, stdin io.Reader, command string, args ...string) (string, error) {
+	res, err := x.RunWithStdin(ctx, stdin, command, args...)
+	return resultX(res, err, command, args)
+}
+
+func (x decoratedExecer) WithEnv(kv ...string) exec.Execer {
+	d := x.decorator
+	if ed, ok := d.(envAwareDecorator); ok {
+		d = ed.withEnv(kv...)
+	}
+
+	return decoratedExecer{Execer: x.Execer.WithEnv(kv...), decorator: d}
+}
+
+func (x decoratedExecer) WithLogFields(kvFields ...any) exec.Execer {
+	return decoratedExecer{Execer: x.Execer.WithLogFields(kvFields...), decorator: x.decorator}
+}
+
+func (x decoratedExecer) Sub(subpath string) (exec.Execer, error) {
+	sub, err := x.Execer.Sub(subpath)
+	if err != nil {
+		return nil, err
+	}
+
+	return decoratedExecer{Execer: sub, decorator: x.decorator}, nil
+}
+
+// appendEnv appends the key value pairs passed to WithEnv to env as KEY=VALUE.
+func appendEnv(env []string, kv ...string) []string {
+	env = env[:len(env):len(env)]
+	for i := 0; i+1 < len(kv); i += 2 {
+		env = append(env, kv[i]+"="+kv[i+1])
+	}
+
+	return env
+}
@@ -0,0 +1,309 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/jcchavezs/gh-iterator/exec"
+)
+
+// dependency is a dependency declared in a manifest of a repository.
+type dependency struct {
+	Name    string
+	Version string
+	// File is the manifest declaring the dependency.
+	File string
+}
+
+// manifestParsers parse the dependencies out of the manifests, by file name.
+var manifestParsers = map[string]func([]byte) ([]dependency, error){
+	"go.mod":           parseGoMod,
+	"package.json":     parsePackageJSON,
+	"requirements.txt": parseRequirements,
+	"pom.xml":          parsePOM,
+}
+
+// scanDependencies parses the dependencies of the manifests in the clone in root, skipping the
+// vendored ones. The manifests failing to parse are logged and skipped.
+func scanDependencies(ctx context.Context, x exec.Execer, root string) ([]dependency, error) {
+	var deps []dependency
+
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			switch d.Name() {
+			case ".git", "node_modules", "vendor":
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		parse, ok := manifestParsers[d.Name()]
+		if !ok {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		content, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+
+		fileDeps, err := parse(content)
+		if err != nil {
+			x.Log(ctx, slog.LevelWarn, "Failed to parse manifest", "file", rel, "error", err)
+			return nil
+		}
+
+		for _, dep := range fileDeps {
+			dep.File = rel
+			deps = append(deps, dep)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("scanning dependencies: %w", err)
+	}
+
+	return deps, nil
+}
+
+// parseGoMod parses the required modules of a go.mod.
+func parseGoMod(content []byte) ([]dependency, error) {
+	var (
+		deps    []dependency
+		inBlock bool
+	)
+
+	s := bufio.NewScanner(bytes.NewReader(content))
+	for s.Scan() {
+		line, _, _ := strings.Cut(s.Text(), "//")
+		fields := strings.Fields(line)
+
+		switch {
+		case inBlock && len(fields) == 1 && fields[0] == ")":
+			inBlock = false
+			continue
+		case len(fields) == 2 && fields[0] == "require" && fields[1] == "(":
+			inBlock = true
+			continue
+		case !inBlock && len(fields) > 0 && fields[0] == "require":
+			fields = fields[1:]
+		case !inBlock:
+			continue
+		}
+
+		if len(fields) >= 2 {
+			deps = append(deps, dependency{Name: fields[0], Version: fields[1]})
+		}
+	}
+
+	return deps, s.Err()
+}
+
+// parsePackageJSON parses the dependencies of all the kinds of a package.json.
+func parsePackageJSON(content []byte) ([]dependency, error) {
+	var pkg map[string]json.RawMessage
+	if err := json.Unmarshal(content, &pkg); err != nil {
+		return nil, err
+	}
+
+	var deps []dependency
+	for _, kind := range []string{"dependencies", "devDependencies", "peerDependencies", "optionalDependencies"} {
+		raw, ok := pkg[kind]
+		if !ok {
+			continue
+		}
+
+		var versions map[string]string
+		if err := json.Unmarshal(raw, &versions); err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", kind, err)
+		}
+
+		for _, name := range slices.Sorted(maps.Keys(versions)) {
+			deps = append(deps, dependency{Name: name, Version: versions[name]})
+		}
+	}
+
+	return deps, nil
+}
+
+// requirementRe matches a requirement of a requirements.txt, with its extras and version
+// specifier e.g. requests[socks]>=2.0.
+var requirementRe = regexp.MustCompile(`^([A-Za-z0-9][A-Za-z0-9._-]*)\s*(?:\[[^\]]*\])?\s*(.*)$`)
+
+// parseRequirements parses the requirements of a requirements.txt. The version is the pinned one,
+// or the version specifier otherwise.
+func parseRequirements(content []byte) ([]dependency, error) {
+	var deps []dependency
+
+	s := bufio.NewScanner(bytes.NewReader(content))
+	for s.Scan() {
+		line, _, _ := strings.Cut(s.Text(), "#")
+		line, _, _ = strings.Cut(line, ";")
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "-") {
+			continue
+		}
+
+		m := requirementRe.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+
+		version := strings.TrimSpace(m[2])
+		if pinned, ok := strings.CutPrefix(version, "=="); ok && !strings.Contains(pinned, ",") {
+			version = strings.TrimSpace(pinned)
+		}
+
+		deps = append(deps, dependency{Name: m[1], Version: version})
+	}
+
+	return deps, s.Err()
+}
+
+type pomProject struct {
+	Version    string `xml:"version"`
+	Properties struct {
+		Entries []struct {
+			XMLName xml.Name
+			Value   string `xml:",chardata"`
+		} `xml:",any"`
+	} `xml:"properties"`
+	Dependencies        []pomDependency `xml:"dependencies>dependency"`
+	ManagedDependencies []pomDependency `xml:"dependencyManagement>dependencies>dependency"`
+	ParentVersion       string          `xml:"parent>version"`
+}
+
+type pomDependency struct {
+	GroupID    string `xml:"groupId"`
+	ArtifactID string `xml:"artifactId"`
+	Version    string `xml:"version"`
+}
+
+// pomPropertyRe matches a property reference e.g. ${junit.version}.
+var pomPropertyRe = regexp.MustCompile(`\$\{([^}]+)\}`)
+
+// parsePOM parses the dependencies of a pom.xml as groupId:artifactId, resolving the versions
+// set in the properties of the same file.
+func parsePOM(content []byte) ([]dependency, error) {
+	var p pomProject
+	if err := xml.Unmarshal(content, &p); err != nil {
+		return nil, err
+	}
+
+	props := map[string]string{
+		"project.version": cmp.Or(p.Version, p.ParentVersion),
+	}
+	for _, e := range p.Properties.Entries {
+		props[e.XMLName.Local] = strings.TrimSpace(e.Value)
+	}
+
+	var deps []dependency
+	for _, d := range append(p.Dependencies, p.ManagedDependencies...) {
+		version := pomPropertyRe.ReplaceAllStringFunc(strings.TrimSpace(d.Version), func(ref string) string {
+			if v, ok := props[ref[2:len(ref)-1]]; ok {
+				return v
+			}
+			return ref
+		})
+
+		deps = append(deps, dependency{Name: strings.TrimSpace(d.GroupID) + ":" + strings.TrimSpace(d.ArtifactID), Version: version})
+	}
+
+	return deps, nil
+}
+
+// scanRepositoryDependencies records the dependencies of the repository for the inventory.
+func (p repoProcessor) scanRepositoryDependencies(ctx context.Context, x exec.Execer, repository string) error {
+	root, err := commandDir(x)
+	if err != nil {
+		return err
+	}
+
+	deps, err := scanDependencies(ctx, x, root)
+	if err != nil {
+		return err
+	}
+
+	p.results.update(repository, func(r *repoResult) { r.Dependencies = deps })
+	return nil
+}
+
+// dependencyInventory returns the repositories using each version of each dependency, only the
+// dependency passed if any.
+func (r *runResults) dependencyInventory(only string) map[string]map[string][]string {
+	inventory := map[string]map[string][]string{}
+	for _, res := range r.sorted() {
+		for _, dep := range res.Dependencies {
+			if only != "" && dep.Name != only {
+				continue
+			}
+
+			versions, ok := inventory[dep.Name]
+			if !ok {
+				versions = map[string][]string{}
+				inventory[dep.Name] = versions
+			}
+
+			if !slices.Contains(versions[dep.Version], res.Repository) {
+				versions[dep.Version] = append(versions[dep.Version], res.Repository)
+			}
+		}
+	}
+
+	return inventory
+}
+
+// writeDependencyInventory writes the dependency inventory in path as a markdown table if the
+// extension is .md, otherwise as JSON.
+func (r *runResults) writeDependencyInventory(path string, only string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("creating dependency inventory: %w", err)
+	}
+	defer f.Close() //nolint:errcheck
+
+	inventory := r.dependencyInventory(only)
+
+	if filepath.Ext(path) == ".md" {
+		fmt.Fprintln(f, "| Dependency | Version | Repositories |")
+		fmt.Fprintln(f, "| --- | --- | --- |")
+		for _, name := range slices.Sorted(maps.Keys(inventory)) {
+			for _, version := range slices.Sorted(maps.Keys(inventory[name])) {
+				fmt.Fprintf(f, "| %s | %s | %s |\n", name, version, strings.Join(inventory[name][version], ", "))
+			}
+		}
+		return nil
+	}
+
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(inventory); err != nil {
+		return fmt.Errorf("writing dependency inventory: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseGoMod(t *testing.T) {
+	deps, err := parseGoMod([]byte(`module github.com/acme/a
+
+go 1.22
+
+require github.com/pkg/errors v0.9.1
+
+require (
+	github.com/stretchr/testify v1.9.0
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+`))
+	require.NoError(t, err)
+	require.Equal(t, []dependency{
+		{Name: "github.com/pkg/errors", Version: "v0.9.1"},
+		{Name: "github.com/stretchr/testify", Version: "v1.9.0"},
+		{Name: "gopkg.in/yaml.v3", Version: "v3.0.1"},
+	}, deps)
+}
+
+func TestParsePackageJSON(t *testing.T) {
+	deps, err := parsePackageJSON([]byte(`{"dependencies": {"lodash": "^4.17.21"}, "devDependencies": {"jest": "29.0.0"}}`))
+	require.NoError(t, err)
+	require.Equal(t, []dependency{{Name: "lodash", Version: "^4.17.21"}, {Name: "jest", Version: "29.0.0"}}, deps)
+
+	_, err = parsePackageJSON([]byte(`{`))
+	require.Error(t, err)
+}
+
+func TestParseRequirements(t *testing.T) {
+	deps, err := parseRequirements([]byte(`# pinned
+requests[socks]==2.31.0
+flask >=2.0,<3 ; python_version > "3.8"
+-r dev.txt
+numpy
+`))
+	require.NoError(t, err)
+	require.Equal(t, []dependency{
+		{Name: "requests", Version: "2.31.0"},
+		{Name: "flask", Version: ">=2.0,<3"},
+		{Name: "numpy", Version: ""},
+	}, deps)
+}
+
+func TestParsePOM(t *testing.T) {
+	deps, err := parsePOM([]byte(`<project>
+  <version>1.2.0</version>
+  <properties><log4j.version>2.17.1</log4j.version></properties>
+  <dependencies>
+    <dependency><groupId>org.apache.logging.log4j</groupId><artifactId>log4j-core</artifactId><version>${log4j.version}</version></dependency>
+    <dependency><groupId>com.acme</groupId><artifactId>common</artifactId><version>${project.version}</version></dependency>
+  </dependencies>
+</project>`))
+	require.NoError(t, err)
+	require.Equal(t, []dependency{
+		{Name: "org.apache.logging.log4j:log4j-core", Version: "2.17.1"},
+		{Name: "com.acme:common", Version: "1.2.0"},
+	}, deps)
+}
+
+func TestWriteDependencyInventory(t *testing.T) {
+	r := newRunResults()
+	r.update("acme/a", func(res *repoResult) {
+		res.Dependencies = []dependency{{Name: "lodash", Version: "4.17.21", File: "package.json"}, {Name: "jest", Version: "29.0.0"}}
+	})
+	r.update("acme/b", func(res *repoResult) {
+		res.Dependencies = []dependency{{Name: "lodash", Version: "4.17.21", File: "web/package.json"}, {Name: "lodash", Version: "4.17.21", File: "api/package.json"}}
+	})
+	r.update("acme/c", func(res *repoResult) { res.Dependencies = []dependency{{Name: "lodash", Version: "3.10.1"}} })
+
+	require.Equal(t, map[string]map[string][]string{
+		"lodash": {"4.17.21": {"acme/a", "acme/b"}, "3.10.1": {"acme/c"}},
+	}, r.dependencyInventory("lodash"))
+
+	path := filepath.Join(t.TempDir(), "inventory.md")
+	require.NoError(t, r.writeDependencyInventory(path, ""))
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, `| Dependency | Version | Repositories |
+| --- | --- | --- |
+| jest | 29.0.0 | acme/a |
+| lodash | 3.10.1 | acme/c |
+| lodash | 4.17.21 | acme/a, acme/b |
+`, string(content))
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/jcchavezs/gh-iterator/exec"
+)
+
+// workingTreeDiff returns the diff of the working tree of the clone, including the new files.
+func workingTreeDiff(ctx context.Context, x exec.Execer) (string, error) {
+	// the new files are added with intent to add so the diff includes them, in a copy of the
+	// index so the one of the clone is left as the command did.
+	dir, err := commandDir(x)
+	if err != nil {
+		return "", err
+	}
+
+	index, err := exec.TrimStdout(x.RunX(ctx, "git", "rev-parse", "--git-path", "index"))
+	if err != nil {
+		return "", fmt.Errorf("finding index: %w", err)
+	}
+	if !filepath.IsAbs(index) {
+		index = filepath.Join(dir, index)
+	}
+
+	tmpDir, err := os.MkdirTemp("", "gh-iterator-diff-")
+	if err != nil {
+		return "", fmt.Errorf("creating temporary index: %w", err)
+	}
+	defer os.RemoveAll(tmpDir) //nolint:errcheck
+
+	tmpIndex := filepath.Join(tmpDir, "index")
+	if content, err := os.ReadFile(index); err == nil {
+		if err := os.WriteFile(tmpIndex, content, 0644); err != nil {
+			return "", fmt.Errorf("creating temporary index: %w", err)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("reading index: %w", err)
+	}
+
+	ix := x.WithEnv("GIT_INDEX_FILE", tmpIndex)
+	if _, err := ix.RunX(ctx, "git", "add", "--all", "--intent-to-add"); err != nil {
+		return "", fmt.Errorf("adding new files to the diff: %w", err)
+	}
+
+	res, err := ix.RunX(ctx, "git", "diff", "--no-color", "--no-ext-diff")
+	if err != nil {
+		return "", fmt.Errorf("diffing working tree: %w", err)
+	}
+
+	return res, nil
+}
+
+// showDiff prints the diff of the working tree of the repository after the command, or writes it
+// under <output-dir>/<org>/<repo>/diff when --output-dir is passed, and returns it.
+func showDiff(ctx context.Context, x exec.Execer, repository string, stdout io.Writer) (string, error) {
+	diff, err := workingTreeDiff(ctx, x)
+	if err != nil {
+		return "", err
+	}
+
+	if flags.outputDir != "" {
+		repoDir := filepath.Join(flags.outputDir, filepath.FromSlash(repository))
+		if err := os.MkdirAll(repoDir, 0755); err != nil {
+			return "", fmt.Errorf("creating output directory: %w", err)
+		}
+
+		if err := os.WriteFile(filepath.Join(repoDir, "diff"), []byte(diff), 0644); err != nil {
+			return "", fmt.Errorf("writing diff: %w", err)
+		}
+		return diff, nil
+	}
+
+	if diff == "" {
+		return "", nil
+	}
+
+	outputMux.Lock()
+	defer outputMux.Unlock()
+
+	_, err = fmt.Fprintf(stdout, "# %s\n%s", repository, diff)
+	return diff, err
+}
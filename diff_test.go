@@ -0,0 +1,44 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	osexec "os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/jcchavezs/gh-iterator/exec"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShowDiff(t *testing.T) {
+	dir := newOriginRepository(t)
+	x := exec.NewExecer(dir)
+
+	out := &bytes.Buffer{}
+	diff, err := showDiff(context.Background(), x, "acme/a", out)
+	require.NoError(t, err)
+	require.Empty(t, diff)
+	require.Empty(t, out.String())
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "NOTICE"), []byte("hello\n"), 0o644))
+	diff, err = showDiff(context.Background(), x, "acme/a", out)
+	require.NoError(t, err)
+	require.Contains(t, diff, "+hello\n")
+	require.Contains(t, out.String(), "# acme/a\ndiff --git a/NOTICE b/NOTICE\n")
+	require.Contains(t, out.String(), "+hello\n")
+
+	// the new file is left untracked in the index of the clone.
+	status, err := osexec.Command("git", "-C", dir, "status", "--porcelain").Output()
+	require.NoError(t, err)
+	require.Equal(t, "?? NOTICE\n", string(status))
+
+	t.Cleanup(func() { flags.outputDir = "" })
+	flags.outputDir = t.TempDir()
+	_, err = showDiff(context.Background(), x, "acme/a", out)
+	require.NoError(t, err)
+	written, err := os.ReadFile(filepath.Join(flags.outputDir, "acme", "a", "diff"))
+	require.NoError(t, err)
+	require.Contains(t, string(written), "+hello\n")
+}
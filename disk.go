@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// diskGuard tracks the disk space used by the clones so the run aborts before it exceeds the
+// limit passed in --max-disk.
+type diskGuard struct {
+	mu sync.Mutex
+	// limit is the maximum number of bytes, zero means no limit.
+	limit int64
+	used  map[string]int64
+}
+
+// clonesDisk tracks the disk space used by the clones of the run.
+var clonesDisk = &diskGuard{used: map[string]int64{}}
+
+// reserve records the expected size of the clone in the directory before cloning it, so the
+// run fails before the clone exceeds the limit. The size is corrected by add once cloned.
+func (g *diskGuard) reserve(dir string, size int64) error {
+	if g.limit == 0 {
+		return nil
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.set(dir, size)
+}
+
+// add records the space used by the directory and fails if the total exceeds the limit.
+func (g *diskGuard) add(dir string) error {
+	if g.limit == 0 {
+		return nil
+	}
+
+	size, err := dirSize(dir)
+	if err != nil {
+		return fmt.Errorf("measuring clone size: %w", err)
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.set(dir, size)
+}
+
+// set records the size of the directory, replacing the previous one, unless the total
+// exceeds the limit.
+func (g *diskGuard) set(dir string, size int64) error {
+	var total int64
+	for d, s := range g.used {
+		if d != dir {
+			total += s
+		}
+	}
+
+	if total+size > g.limit {
+		return fmt.Errorf("disk used by the clones would exceed %s, use --max-disk to raise the limit", formatSize(g.limit))
+	}
+
+	g.used[dir] = size
+	return nil
+}
+
+// remove stops tracking the directory once it is deleted.
+func (g *diskGuard) remove(dir string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	delete(g.used, dir)
+}
+
+// dirSize returns the size of the files in the directory.
+func dirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			size += info.Size()
+		}
+
+		return nil
+	})
+
+	return size, err
+}
+
+var sizeUnits = []string{"B", "KB", "MB", "GB", "TB"}
+
+// parseSize parses a size like 20GB or 512MB, units are multiples of 1024.
+func parseSize(s string) (int64, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+
+	for i := len(sizeUnits) - 1; i >= 0; i-- {
+		if n, ok := strings.CutSuffix(s, sizeUnits[i]); ok {
+			v, err := strconv.ParseFloat(strings.TrimSpace(n), 64)
+			if err != nil || v <= 0 {
+				return 0, fmt.Errorf("invalid size %q", s)
+			}
+
+			return int64(v * float64(int64(1)<<(10*i))), nil
+		}
+	}
+
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || v <= 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+
+	return v, nil
+}
+
+// formatSize formats a number of bytes with the largest unit that keeps it over one.
+func formatSize(size int64) string {
+	i := 0
+	v := float64(size)
+	for v >= 1024 && i < len(sizeUnits)-1 {
+		v /= 1024
+		i++
+	}
+
+	return strconv.FormatFloat(v, 'f', -1, 64) + sizeUnits[i]
+}
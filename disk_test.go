@@ -0,0 +1,61 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSize(t *testing.T) {
+	for s, expected := range map[string]int64{
+		"512":   512,
+		"2KB":   2048,
+		"1.5mb": 1536 * 1024,
+		"20GB":  20 << 30,
+		"1 TB":  1 << 40,
+	} {
+		size, err := parseSize(s)
+		require.NoError(t, err, s)
+		require.Equal(t, expected, size, s)
+	}
+
+	for _, invalid := range []string{"", "GB", "-1GB", "10XB", "0"} {
+		_, err := parseSize(invalid)
+		require.Error(t, err, invalid)
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	require.Equal(t, "512B", formatSize(512))
+	require.Equal(t, "20GB", formatSize(20<<30))
+	require.Equal(t, "1.5MB", formatSize(1536*1024))
+}
+
+func TestDiskGuard(t *testing.T) {
+	dirA, dirB := t.TempDir(), t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dirA, "a"), make([]byte, 600), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dirB, "b"), make([]byte, 600), 0644))
+
+	g := &diskGuard{limit: 1000, used: map[string]int64{}}
+	require.NoError(t, g.add(dirA))
+	require.Error(t, g.add(dirB))
+
+	g.remove(dirA)
+	require.NoError(t, g.add(dirB))
+}
+
+func TestDiskGuardReserve(t *testing.T) {
+	dirA, dirB := t.TempDir(), t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dirA, "a"), make([]byte, 300), 0644))
+
+	g := &diskGuard{limit: 1000, used: map[string]int64{}}
+	require.NoError(t, g.reserve(dirA, 800))
+	// the clones in progress count against the limit.
+	require.Error(t, g.reserve(dirB, 500))
+
+	// the measurement once cloned corrects the reservation.
+	require.NoError(t, g.add(dirA))
+	require.NoError(t, g.reserve(dirB, 500))
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	iterator "github.com/jcchavezs/gh-iterator"
+	"github.com/jcchavezs/gh-iterator/exec"
+)
+
+// parseWorkflowInputs parses the inputs of a workflow dispatch in the form key=value.
+func parseWorkflowInputs(inputs []string) (map[string]string, error) {
+	parsed := map[string]string{}
+	for _, in := range inputs {
+		k, v, ok := strings.Cut(in, "=")
+		if !ok || k == "" {
+			return nil, fmt.Errorf("invalid workflow input %q, expected key=value", in)
+		}
+		parsed[k] = v
+	}
+
+	return parsed, nil
+}
+
+// dispatchAction triggers a workflow_dispatch event of the workflow in the repositories, on the
+// ref when not empty, otherwise on the default branch.
+func dispatchAction(workflow, ref string, inputs map[string]string) repoAction {
+	return func(ctx context.Context, x exec.Execer, repo iterator.Repository, dryRun bool) ([]string, error) {
+		if ref == "" {
+			ref = repo.DefaultBranchName
+		}
+
+		if ref == "" {
+			return nil, errors.New("dispatching workflow: no default branch")
+		}
+
+		change := fmt.Sprintf("dispatch %s on %s", workflow, ref)
+		if dryRun {
+			return []string{change}, nil
+		}
+
+		path := "/repos/" + repo.Name + "/actions/workflows/" + url.PathEscape(workflow) + "/dispatches"
+		_, err := apiRequest(ctx, x, "POST", path, map[string]any{"ref": ref, "inputs": inputs})
+		if isNotFound(err) {
+			return nil, fmt.Errorf("dispatching workflow: workflow %s not found", workflow)
+		} else if err != nil {
+			return nil, fmt.Errorf("dispatching workflow: %w", err)
+		}
+
+		return []string{change}, nil
+	}
+}
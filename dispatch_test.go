@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"testing"
+
+	iterator "github.com/jcchavezs/gh-iterator"
+	"github.com/jcchavezs/gh-iterator/exec"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseWorkflowInputs(t *testing.T) {
+	inputs, err := parseWorkflowInputs([]string{"env=prod", "args=a=b"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"env": "prod", "args": "a=b"}, inputs)
+
+	_, err = parseWorkflowInputs([]string{"env"})
+	require.Error(t, err)
+}
+
+func TestDispatchAction(t *testing.T) {
+	calls := scriptedGH(t, `cat > "$GH_CALLS.body"
+case "$*" in
+*/acme/b/*) echo '{"message":"Not Found","status":"404"}'; exit 1;;
+esac
+`)
+	t.Setenv("GH_CALLS", calls)
+	x := exec.NewExecerWithLogger(t.TempDir(), slog.New(slog.DiscardHandler))
+	action := dispatchAction("ci.yml", "", map[string]string{"env": "prod"})
+
+	changes, err := action(context.Background(), x, iterator.Repository{Name: "acme/a", DefaultBranchName: "main"}, false)
+	require.NoError(t, err)
+	require.Equal(t, []string{"dispatch ci.yml on main"}, changes)
+
+	body, err := os.ReadFile(calls + ".body")
+	require.NoError(t, err)
+	require.JSONEq(t, `{"ref":"main","inputs":{"env":"prod"}}`, string(body))
+
+	_, err = action(context.Background(), x, iterator.Repository{Name: "acme/b", DefaultBranchName: "main"}, false)
+	require.ErrorContains(t, err, "workflow ci.yml not found")
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/jcchavezs/gh-iterator/exec"
+)
+
+// errorCategory is the stable category of a failure, so the automation wrapping the tool can
+// branch on it.
+type errorCategory string
+
+const (
+	categoryAuth      errorCategory = "auth"
+	categoryRateLimit errorCategory = "rate-limit"
+	categoryClone     errorCategory = "clone"
+	categoryCommand   errorCategory = "command"
+	categoryPR        errorCategory = "pr"
+	categoryTimeout   errorCategory = "timeout"
+	categoryOther     errorCategory = "other"
+)
+
+// categoryExitCodes are the process exit codes of the runs failing with a single category.
+var categoryExitCodes = map[errorCategory]int{
+	categoryCommand:   3,
+	categoryClone:     4,
+	categoryPR:        5,
+	categoryAuth:      6,
+	categoryRateLimit: 7,
+	categoryTimeout:   8,
+}
+
+var (
+	authErrRe      = regexp.MustCompile(`(?i)bad credentials|HTTP 401|requires authentication|authentication failed|permission denied \(publickey|could not read username|gh auth login`)
+	rateLimitErrRe = regexp.MustCompile(`(?i)rate limit|HTTP 429`)
+)
+
+// categorizedError is an error with its category.
+type categorizedError struct {
+	category errorCategory
+	err      error
+}
+
+func (e categorizedError) Error() string {
+	return e.err.Error()
+}
+
+func (e categorizedError) Unwrap() error {
+	return e.err
+}
+
+// withCategory attaches the category to the error, unless it is nil or already has one.
+func withCategory(category errorCategory, err error) error {
+	var cErr categorizedError
+	if err == nil || errors.As(err, &cErr) {
+		return err
+	}
+
+	return categorizedError{category: category, err: err}
+}
+
+// categoryOf returns the category of the error. Timeouts, rate limits and authentication
+// failures are detected out of the error and the stderr of the command failing, as they can
+// happen at any stage, otherwise it is the category attached to the error.
+func categoryOf(err error) errorCategory {
+	if errors.Is(err, errExecTimeout) || errors.Is(err, context.DeadlineExceeded) {
+		return categoryTimeout
+	}
+
+	msg := err.Error()
+	if stderr, ok := exec.GetStderr(err); ok {
+		msg += "\n" + stderr
+	}
+
+	switch {
+	case rateLimitErrRe.MatchString(msg):
+		return categoryRateLimit
+	case authErrRe.MatchString(msg):
+		return categoryAuth
+	}
+
+	var cErr categorizedError
+	if errors.As(err, &cErr) {
+		return cErr.category
+	}
+
+	return categoryOther
+}
+
+// exitCode returns the process exit code of the run error: the one of its category when all
+// the errors joined in it have the same one, otherwise 1.
+func exitCode(err error) int {
+	if errors.Is(err, errInterrupted) {
+		return exitCodeInterrupted
+	}
+
+	var categories []errorCategory
+	var walk func(error)
+	walk = func(err error) {
+		if joined, ok := err.(interface{ Unwrap() []error }); ok {
+			for _, e := range joined.Unwrap() {
+				walk(e)
+			}
+			return
+		}
+
+		categories = append(categories, categoryOf(err))
+	}
+	walk(err)
+
+	for _, c := range categories {
+		if c != categories[0] {
+			return 1
+		}
+	}
+
+	if code, ok := categoryExitCodes[categories[0]]; ok {
+		return code
+	}
+
+	return 1
+}
+
+// failedCommandsError returns the error failing the run with --keep-going when commands exited
+// with non zero, as they don't fail the processing of their repositories. The processing errors
+// are returned by the run already.
+func failedCommandsError(results *runResults) error {
+	var failed int
+	for _, res := range results.sorted() {
+		if res.Matched && res.Error == "" && res.CommandRan && res.ExitCode != 0 {
+			failed++
+		}
+	}
+
+	if failed == 0 {
+		return nil
+	}
+
+	return withCategory(categoryCommand, fmt.Errorf("the command failed in %d repositories", failed))
+}
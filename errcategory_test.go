@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	iterator "github.com/jcchavezs/gh-iterator"
+	"github.com/jcchavezs/gh-iterator/exec"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCategoryOf(t *testing.T) {
+	for name, tc := range map[string]struct {
+		err      error
+		category errorCategory
+	}{
+		"timeout":         {fmt.Errorf("cloning: %w", errExecTimeout), categoryTimeout},
+		"deadline":        {context.DeadlineExceeded, categoryTimeout},
+		"rate limit":      {exec.NewExecErr("gh api: exit code 1", "gh: API rate limit exceeded (HTTP 403)", 1), categoryRateLimit},
+		"auth in clone":   {withCategory(categoryClone, exec.NewExecErr("git fetch: exit code 128", "git@github.com: Permission denied (publickey).", 128)), categoryAuth},
+		"clone":           {fmt.Errorf("processing %q: %w", "acme/a", withCategory(categoryClone, errors.New("no default branch"))), categoryClone},
+		"pr":              {withCategory(categoryPR, errors.New("creating PR")), categoryPR},
+		"uncategorized":   {errors.New("boom"), categoryOther},
+		"first category":  {withCategory(categoryPR, withCategory(categoryCommand, errors.New("boom"))), categoryCommand},
+		"bad credentials": {errors.New("gh: Bad credentials (HTTP 401)"), categoryAuth},
+	} {
+		t.Run(name, func(t *testing.T) {
+			require.Equal(t, tc.category, categoryOf(tc.err))
+		})
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	clone := withCategory(categoryClone, errors.New("clone"))
+	pr := withCategory(categoryPR, errors.New("pr"))
+
+	require.Equal(t, 4, exitCode(clone))
+	require.Equal(t, 4, exitCode(errors.Join(clone, clone)))
+	require.Equal(t, 1, exitCode(errors.Join(clone, pr)))
+	require.Equal(t, 1, exitCode(errors.New("invalid flag")))
+	require.Equal(t, exitCodeInterrupted, exitCode(errors.Join(clone, errInterrupted)))
+}
+
+func TestFailedCommandsError(t *testing.T) {
+	r := newRunResults()
+	r.addRepositories(
+		[]iterator.Repository{{Name: "acme/a"}, {Name: "acme/b"}, {Name: "acme/c"}},
+		[]iterator.Repository{{Name: "acme/a"}, {Name: "acme/b"}, {Name: "acme/c"}},
+		nil,
+	)
+	r.update("acme/a", func(res *repoResult) { res.Processed, res.CommandRan = true, true })
+	require.NoError(t, failedCommandsError(r))
+
+	// the processing errors are returned by the run.
+	r.update("acme/b", func(res *repoResult) { res.Processed, res.Error = true, "cloning repository: timeout" })
+	require.NoError(t, failedCommandsError(r))
+
+	r.update("acme/c", func(res *repoResult) { res.Processed, res.CommandRan, res.ExitCode = true, true, 2 })
+	err := failedCommandsError(r)
+	require.EqualError(t, err, "the command failed in 1 repositories")
+	require.Equal(t, 3, exitCode(err))
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	osexec "os/exec"
+	"path/filepath"
+	"strconv"
+
+	iterator "github.com/jcchavezs/gh-iterator"
+)
+
+// eventCommands returns the hooks running onFailure once a repository fails and onSuccess once
+// it is processed successfully. The commands get the repository, its status, exit code and error
+// and the paths of the failures report and the repository log, if any, in the env. A command
+// failing is logged and does not stop the run.
+func eventCommands(ctx context.Context, onFailure, onSuccess string, stdout, stderr io.Writer, logger *slog.Logger) runHooks {
+	if onFailure == "" && onSuccess == "" {
+		return runHooks{}
+	}
+
+	// the commands run for the repositories finished while the run stops too.
+	ctx = context.WithoutCancel(ctx)
+
+	return runHooks{
+		OnRepoFinished: func(repo iterator.Repository, res repoResult, err error) {
+			command, status := onSuccess, "succeeded"
+			if err != nil || res.failed() {
+				command, status = onFailure, "failed"
+			}
+			if command == "" {
+				return
+			}
+
+			errMsg := res.Error
+			if err != nil {
+				errMsg = err.Error()
+			}
+
+			env := []string{
+				"GH_ITERATOR_REPOSITORY=" + repo.Name,
+				"GH_ITERATOR_STATUS=" + status,
+				"GH_ITERATOR_EXIT_CODE=" + strconv.Itoa(res.ExitCode),
+				"GH_ITERATOR_ERROR=" + errMsg,
+				"GH_ITERATOR_ERROR_CATEGORY=" + string(res.Category),
+			}
+			if flags.failuresReport != "" {
+				report, _ := filepath.Abs(flags.failuresReport)
+				env = append(env, "GH_ITERATOR_REPORT="+report)
+			}
+			if flags.logDir != "" {
+				log, _ := filepath.Abs(filepath.Join(flags.logDir, filepath.FromSlash(repo.Name)+".log"))
+				env = append(env, "GH_ITERATOR_LOG="+log)
+			}
+
+			if err := runEventCommand(ctx, command, env, stdout, stderr); err != nil {
+				logger.Warn("Failed to run the command for the repository", "repository", repo.Name, "status", status, "error", err)
+			}
+		},
+	}
+}
+
+// runEventCommand runs the command in the shell with the variables added to the environment.
+func runEventCommand(ctx context.Context, command string, env []string, stdout, stderr io.Writer) error {
+	c := osexec.CommandContext(ctx, os.Getenv("SHELL"), "-c", command)
+	c.Env = os.Environ()
+	if flags.cleanEnv {
+		c.Env = allowedEnv(c.Env, append(defaultEnvAllowlist, flags.envAllow...))
+	}
+	c.Env = append(c.Env, env...)
+	c.Stdout = stdout
+	c.Stderr = stderr
+	startInProcessGroup(c)
+
+	return c.Run()
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	iterator "github.com/jcchavezs/gh-iterator"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventCommands(t *testing.T) {
+	t.Setenv("SHELL", "/bin/sh")
+	flags.failuresReport = "failures.json"
+	t.Cleanup(func() { flags.failuresReport = "" })
+
+	require.Nil(t, eventCommands(context.Background(), "", "", io.Discard, io.Discard, slog.New(slog.DiscardHandler)).OnRepoFinished)
+
+	dir := t.TempDir()
+	out := filepath.Join(dir, "out")
+	hooks := eventCommands(
+		context.Background(),
+		`echo "failure $GH_ITERATOR_REPOSITORY $GH_ITERATOR_STATUS $GH_ITERATOR_EXIT_CODE $GH_ITERATOR_ERROR_CATEGORY $(basename $GH_ITERATOR_REPORT)" >> `+out,
+		`echo "success $GH_ITERATOR_REPOSITORY $GH_ITERATOR_STATUS" >> `+out+`; exit 1`,
+		io.Discard, io.Discard, slog.New(slog.DiscardHandler),
+	)
+
+	hooks.repoDone(iterator.Repository{Name: "acme/a"}, repoResult{Processed: true, CommandRan: true, ExitCode: 2, Category: categoryCommand}, nil)
+	hooks.repoDone(iterator.Repository{Name: "acme/b"}, repoResult{Processed: true, CommandRan: true}, nil)
+	hooks.repoDone(iterator.Repository{Name: "acme/c"}, repoResult{Skipped: "empty"}, nil)
+
+	content, err := os.ReadFile(out)
+	require.NoError(t, err)
+	require.Equal(t, "failure acme/a failed 2 command failures.json\nsuccess acme/b succeeded\n", string(content))
+}
+
+func TestRunHooksJoin(t *testing.T) {
+	var calls []string
+	hooks := runHooks{
+		OnRepoFinished: func(repo iterator.Repository, _ repoResult, _ error) { calls = append(calls, "first "+repo.Name) },
+	}.join(runHooks{
+		OnRepoFinished: func(repo iterator.Repository, _ repoResult, _ error) { calls = append(calls, "second "+repo.Name) },
+		OnRepoSkipped:  func(repo iterator.Repository, reason string) { calls = append(calls, "skipped "+reason) },
+	})
+
+	hooks.repoStart(iterator.Repository{Name: "acme/a"})
+	hooks.repoDone(iterator.Repository{Name: "acme/a"}, repoResult{}, nil)
+	hooks.repoDone(iterator.Repository{Name: "acme/b"}, repoResult{Skipped: "empty"}, nil)
+	hooks.runFinished(runTotals{}, nil)
+	require.Equal(t, []string{"first acme/a", "second acme/a", "skipped empty"}, calls)
+}
@@ -0,0 +1,17 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// ghExtensionName is the name the tool is invoked with when installed as a gh extension with
+// 'gh extension install jcchavezs/gh-iterator-run'.
+const ghExtensionName = "gh iterator-run"
+
+// isGHExtension tells whether the executable was installed as a gh extension, which gh keeps in
+// its extensions directory e.g. ~/.local/share/gh/extensions/gh-iterator-run/. The gh auth and
+// host configuration apply as the repositories are listed and cloned with gh.
+func isGHExtension(executable string) bool {
+	return strings.Contains(filepath.ToSlash(executable), "/gh/extensions/")
+}
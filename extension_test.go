@@ -0,0 +1,13 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsGHExtension(t *testing.T) {
+	require.True(t, isGHExtension("/home/jane/.local/share/gh/extensions/gh-iterator-run/gh-iterator-run"))
+	require.False(t, isGHExtension("/usr/local/bin/gh-iterator-run"))
+	require.False(t, isGHExtension("gh-iterator-run"))
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+type failure struct {
+	Repository string `json:"repository"`
+	Category   string `json:"category,omitempty"`
+	Command    string `json:"command,omitempty"`
+	ExitCode   int    `json:"exit_code"`
+	Error      string `json:"error,omitempty"`
+	Stderr     string `json:"stderr,omitempty"`
+}
+
+// failures returns the matched repositories that failed, sorted by name.
+func (r *runResults) failures(command string) []failure {
+	var fs []failure
+	for _, res := range r.sorted() {
+		if !res.Matched || !res.failed() {
+			continue
+		}
+
+		f := failure{Repository: res.Repository, Category: string(res.Category), ExitCode: res.ExitCode, Error: res.Error, Stderr: res.Stderr}
+		if res.CommandRan {
+			f.Command = renderCommand(command, res.Repository)
+		}
+		fs = append(fs, f)
+	}
+
+	if len(r.retried) == 0 {
+		return fs
+	}
+
+	r.mu.Lock()
+	for _, f := range r.retried {
+		if res, ok := r.byRepo[f.Repository]; !ok || !(res.Processed || res.failed()) {
+			fs = append(fs, f)
+		}
+	}
+	r.mu.Unlock()
+
+	slices.SortFunc(fs, func(a, b failure) int {
+		return strings.Compare(a.Repository, b.Repository)
+	})
+
+	return fs
+}
+
+// readFailures reads the failures JSON report of a previous run.
+func readFailures(path string) ([]failure, error) {
+	if filepath.Ext(path) == ".md" {
+		return nil, errors.New("only the JSON failures report can be retried")
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading failures report: %w", err)
+	}
+
+	var fs []failure
+	if err := json.Unmarshal(content, &fs); err != nil {
+		return nil, fmt.Errorf("unmarshaling failures report: %w", err)
+	}
+
+	return fs, nil
+}
+
+// writeFailures writes the failed repositories in path as markdown if the extension is .md,
+// otherwise as JSON.
+func (r *runResults) writeFailures(path string, command string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("creating failures report: %w", err)
+	}
+	defer f.Close() //nolint:errcheck
+
+	fs := r.failures(command)
+
+	if filepath.Ext(path) == ".md" {
+		for _, fl := range fs {
+			fmt.Fprintf(f, "## %s\n\n", fl.Repository)
+			if fl.Category != "" {
+				fmt.Fprintf(f, "Category: %s\n\n", fl.Category)
+			}
+			if fl.Command != "" {
+				fmt.Fprintf(f, "Command: `%s`\n\nExit code: %d\n\n", fl.Command, fl.ExitCode)
+			}
+			if fl.Error != "" {
+				fmt.Fprintf(f, "Error: %s\n\n", fl.Error)
+			}
+			if fl.Stderr != "" {
+				fmt.Fprintf(f, "```\n%s\n```\n\n", strings.TrimRight(fl.Stderr, "\n"))
+			}
+		}
+		return nil
+	}
+
+	if fs == nil {
+		fs = []failure{}
+	}
+
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(fs); err != nil {
+		return fmt.Errorf("writing failures report: %w", err)
+	}
+
+	return nil
+}
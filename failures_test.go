@@ -0,0 +1,80 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	iterator "github.com/jcchavezs/gh-iterator"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunResultsWriteFailures(t *testing.T) {
+	r := newRunResults()
+	r.addRepositories(
+		[]iterator.Repository{{Name: "acme/a"}, {Name: "acme/b"}, {Name: "acme/c"}},
+		[]iterator.Repository{{Name: "acme/a"}, {Name: "acme/b"}, {Name: "acme/c"}},
+		nil,
+	)
+	r.update("acme/a", func(res *repoResult) { res.Processed, res.CommandRan = true, true })
+	r.update("acme/b", func(res *repoResult) {
+		res.Processed, res.CommandRan, res.ExitCode, res.Stderr = true, true, 1, "boom\n"
+	})
+	r.update("acme/c", func(res *repoResult) { res.Error = "cloning repository: timeout" })
+
+	dir := t.TempDir()
+
+	jsonPath := filepath.Join(dir, "failures.json")
+	require.NoError(t, r.writeFailures(jsonPath, "make -C {{ .Repository }}"))
+	content, err := os.ReadFile(jsonPath)
+	require.NoError(t, err)
+	require.JSONEq(t, `[
+		{"repository": "acme/b", "command": "make -C acme/b", "exit_code": 1, "stderr": "boom\n"},
+		{"repository": "acme/c", "exit_code": 0, "error": "cloning repository: timeout"}
+	]`, string(content))
+
+	mdPath := filepath.Join(dir, "failures.md")
+	require.NoError(t, r.writeFailures(mdPath, "make"))
+	content, err = os.ReadFile(mdPath)
+	require.NoError(t, err)
+	require.Contains(t, string(content), "## acme/b\n\nCommand: `make`\n\nExit code: 1\n\n```\nboom\n```")
+	require.Contains(t, string(content), "## acme/c\n\nError: cloning repository: timeout")
+}
+
+func TestReadFailures(t *testing.T) {
+	dir := t.TempDir()
+
+	path := filepath.Join(dir, "failures.json")
+	require.NoError(t, os.WriteFile(path, []byte(`[{"repository": "acme/b", "exit_code": 1}]`), 0o600))
+	fs, err := readFailures(path)
+	require.NoError(t, err)
+	require.Equal(t, []failure{{Repository: "acme/b", ExitCode: 1}}, fs)
+
+	_, err = readFailures(filepath.Join(dir, "failures.md"))
+	require.Error(t, err)
+
+	_, err = readFailures(filepath.Join(dir, "missing.json"))
+	require.Error(t, err)
+}
+
+func TestRunResultsFailuresRetried(t *testing.T) {
+	r := newRunResults()
+	r.retried = []failure{
+		{Repository: "acme/a", ExitCode: 1},
+		{Repository: "acme/b", ExitCode: 2},
+		{Repository: "acme/c", Error: "cloning repository: timeout"},
+	}
+	r.addRepositories(
+		[]iterator.Repository{{Name: "acme/a"}, {Name: "acme/b"}},
+		[]iterator.Repository{{Name: "acme/a"}, {Name: "acme/b"}},
+		nil,
+	)
+	r.update("acme/a", func(res *repoResult) { res.Processed, res.CommandRan = true, true })
+	r.update("acme/b", func(res *repoResult) { res.Processed, res.CommandRan, res.ExitCode = true, true, 3 })
+
+	// acme/a succeeded this time, acme/b still fails and acme/c was not processed again.
+	require.Equal(t, []failure{
+		{Repository: "acme/b", Command: "make", ExitCode: 3},
+		{Repository: "acme/c", Error: "cloning repository: timeout"},
+	}, r.failures("make"))
+}
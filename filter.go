@@ -1,7 +1,12 @@
 package main
 
 import (
+	"fmt"
 	"log/slog"
+	"path"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/google/cel-go/cel"
 	iterator "github.com/jcchavezs/gh-iterator"
@@ -34,17 +39,7 @@ func parseSearchFilterIn(cond string, l *slog.Logger) (func(iterator.Repository)
 	}
 
 	return func(r iterator.Repository) bool {
-		repoMap := map[string]any{
-			"name":       r.Name,
-			"archived":   r.Archived,
-			"language":   r.Language,
-			"visibility": r.Visibility,
-			"fork":       r.Fork,
-			"isEmpty":    r.Size == 0,
-			"pushedAt":   r.PushedAt,
-		}
-
-		out, _, err := prg.Eval(map[string]any{"repo": repoMap})
+		out, _, err := prg.Eval(map[string]any{"repo": repoVariable(r)})
 		if err != nil {
 			l.Error("Failed to evaluate CEL expression", "error", err)
 			return false
@@ -54,3 +49,160 @@ func parseSearchFilterIn(cond string, l *slog.Logger) (func(iterator.Repository)
 		return ok && result
 	}, nil
 }
+
+// repoVariable returns the repo variable of the CEL conditions for the repository.
+func repoVariable(r iterator.Repository) map[string]any {
+	repoMap := map[string]any{
+		"name":       r.Name,
+		"archived":   r.Archived,
+		"language":   r.Language,
+		"visibility": r.Visibility,
+		"fork":       r.Fork,
+		"isEmpty":    r.Size == 0,
+		"pushedAt":   r.PushedAt,
+	}
+
+	// only listing with --graphql retrieves the metadata, otherwise the fields are empty.
+	m := repositoriesMetadata.get(r.Name)
+	repoMap["topics"] = nonNil(m.Topics)
+	repoMap["languages"] = nonNil(m.Languages)
+	repoMap["license"] = m.License
+	repoMap["protectedDefaultBranch"] = m.ProtectedDefaultBranch
+	repoMap["latestRelease"] = m.LatestRelease
+	repoMap["lastCommitAt"] = m.LastCommitAt
+
+	return repoMap
+}
+
+// nonNil returns an empty list instead of nil, so the CEL list functions can be applied.
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
+
+// excludeRepos wraps the filter to leave out the repositories whose name matches any of the
+// patterns. Patterns without owner e.g. 'legacy-*' match the repositories of any owner.
+func excludeRepos(filterIn func(iterator.Repository) bool, patterns []string) (func(iterator.Repository) bool, error) {
+	if len(patterns) == 0 {
+		return filterIn, nil
+	}
+
+	if err := validateRepoPatterns(patterns); err != nil {
+		return nil, err
+	}
+
+	return func(r iterator.Repository) bool {
+		return !matchAnyRepoPattern(patterns, r.Name) && filterIn(r)
+	}, nil
+}
+
+// includeRepos wraps the filter to leave out the repositories whose name does not match any
+// of the patterns. Patterns without owner e.g. 'api-*' match the repositories of any owner.
+func includeRepos(filterIn func(iterator.Repository) bool, patterns []string) (func(iterator.Repository) bool, error) {
+	if len(patterns) == 0 {
+		return filterIn, nil
+	}
+
+	if err := validateRepoPatterns(patterns); err != nil {
+		return nil, err
+	}
+
+	return func(r iterator.Repository) bool {
+		return matchAnyRepoPattern(patterns, r.Name) && filterIn(r)
+	}, nil
+}
+
+func validateRepoPatterns(patterns []string) error {
+	for _, p := range patterns {
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("invalid repository pattern %q: %w", p, err)
+		}
+	}
+
+	return nil
+}
+
+func matchAnyRepoPattern(patterns []string, repository string) bool {
+	for _, p := range patterns {
+		name := repository
+		if !strings.Contains(p, "/") {
+			_, name, _ = strings.Cut(repository, "/")
+		}
+
+		if matchGlob(p, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// literalRepoNames returns the owner/repo names the patterns resolve to, using the owners for the
+// patterns without owner. It returns false if any pattern contains wildcards.
+func literalRepoNames(patterns []string, owners []string) ([]string, bool) {
+	var names []string
+	for _, p := range patterns {
+		if strings.ContainsAny(p, `*?[\`) {
+			return nil, false
+		}
+
+		if strings.Contains(p, "/") {
+			names = append(names, p)
+			continue
+		}
+
+		if len(owners) == 0 {
+			return nil, false
+		}
+
+		for _, owner := range owners {
+			names = append(names, owner+"/"+p)
+		}
+	}
+
+	return names, true
+}
+
+// pushedAfter wraps the filter to leave out the repositories pushed before t.
+func pushedAfter(filterIn func(iterator.Repository) bool, t time.Time) func(iterator.Repository) bool {
+	return func(r iterator.Repository) bool {
+		return !r.PushedAt.Before(t) && filterIn(r)
+	}
+}
+
+// parseDate parses a date in the 2006-01-02 or RFC3339 formats.
+func parseDate(s string) (time.Time, error) {
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q, expected YYYY-MM-DD or RFC3339", s)
+	}
+
+	return t, nil
+}
+
+// parseDuration parses a duration accepting days and weeks e.g. 90d or 2w besides the units
+// supported by time.ParseDuration.
+func parseDuration(s string) (time.Duration, error) {
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			v, err := strconv.Atoi(n)
+			if err != nil {
+				return 0, fmt.Errorf("invalid duration %q", s)
+			}
+			return time.Duration(v) * unit, nil
+		}
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+
+	return d, nil
+}
@@ -331,3 +331,69 @@ func TestParseSearchFilter_NonBooleanExpression(t *testing.T) {
 		require.False(t, filterFn(repo))
 	})
 }
+
+func TestExcludeRepos(t *testing.T) {
+	filterFn, err := excludeRepos(defaultSearchFilterIn, []string{"acme/legacy-*", "sandbox"})
+	require.NoError(t, err)
+
+	require.True(t, filterFn(iterator.Repository{Name: "acme/api", Size: 1}))
+	require.False(t, filterFn(iterator.Repository{Name: "acme/legacy-api", Size: 1}))
+	require.True(t, filterFn(iterator.Repository{Name: "other/legacy-api", Size: 1}))
+	require.False(t, filterFn(iterator.Repository{Name: "other/sandbox", Size: 1}))
+	require.False(t, filterFn(iterator.Repository{Name: "acme/archived", Size: 1, Archived: true}))
+
+	_, err = excludeRepos(defaultSearchFilterIn, []string{"acme/[legacy"})
+	require.Error(t, err)
+}
+
+func TestIncludeRepos(t *testing.T) {
+	filterFn, err := includeRepos(defaultSearchFilterIn, []string{"acme/api-*", "web"})
+	require.NoError(t, err)
+
+	require.True(t, filterFn(iterator.Repository{Name: "acme/api-users", Size: 1}))
+	require.False(t, filterFn(iterator.Repository{Name: "acme/worker", Size: 1}))
+	require.True(t, filterFn(iterator.Repository{Name: "other/web", Size: 1}))
+	require.False(t, filterFn(iterator.Repository{Name: "acme/api-old", Size: 1, Archived: true}))
+}
+
+func TestLiteralRepoNames(t *testing.T) {
+	names, ok := literalRepoNames([]string{"acme/api", "web"}, []string{"acme", "other"})
+	require.True(t, ok)
+	require.Equal(t, []string{"acme/api", "acme/web", "other/web"}, names)
+
+	_, ok = literalRepoNames([]string{"acme/api-*"}, nil)
+	require.False(t, ok)
+
+	_, ok = literalRepoNames([]string{"web"}, nil)
+	require.False(t, ok)
+}
+
+func TestPushedAfter(t *testing.T) {
+	since, err := parseDate("2024-01-01")
+	require.NoError(t, err)
+
+	filterFn := pushedAfter(defaultSearchFilterIn, since)
+	require.True(t, filterFn(iterator.Repository{Size: 1, PushedAt: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)}))
+	require.False(t, filterFn(iterator.Repository{Size: 1, PushedAt: time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)}))
+	require.False(t, filterFn(iterator.Repository{Size: 1, Fork: true, PushedAt: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)}))
+
+	_, err = parseDate("01/01/2024")
+	require.Error(t, err)
+}
+
+func TestParseDuration(t *testing.T) {
+	d, err := parseDuration("90d")
+	require.NoError(t, err)
+	require.Equal(t, 90*24*time.Hour, d)
+
+	d, err = parseDuration("2w")
+	require.NoError(t, err)
+	require.Equal(t, 14*24*time.Hour, d)
+
+	d, err = parseDuration("36h")
+	require.NoError(t, err)
+	require.Equal(t, 36*time.Hour, d)
+
+	_, err = parseDuration("xd")
+	require.Error(t, err)
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/jcchavezs/gh-iterator/exec"
+)
+
+// forEachDirs returns the directories of the clone in root matching the glob, relative to root
+// and in lexical order. The directory of the files matching the glob is returned instead of the
+// file e.g. **/go.mod returns the directories of the Go modules.
+func forEachDirs(root string, glob string) ([]string, error) {
+	var (
+		dirs []string
+		seen = map[string]bool{}
+	)
+
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() && d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+
+		rel = filepath.ToSlash(rel)
+		if rel == "." || !matchGlob(glob, rel) {
+			return nil
+		}
+
+		dir := rel
+		if !d.IsDir() {
+			dir = path.Dir(rel)
+		}
+
+		if !seen[dir] {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("looking for the directories matching %q: %w", glob, err)
+	}
+
+	return dirs, nil
+}
+
+// execCommandForEach runs the command once in each directory matching --for-each, which is passed
+// in the GH_ITERATOR_DIR env variable and replaces {{ .Dir }} in the command. The output of the
+// runs is concatenated and the exit code is the first non zero one.
+func execCommandForEach(ctx context.Context, x exec.Execer, repository string, stdin io.Reader, stdout, stderr io.Writer) (exec.Result, error) {
+	root, err := commandDir(x)
+	if err != nil {
+		return exec.Result{}, err
+	}
+
+	dirs, err := forEachDirs(root, flags.forEach)
+	if err != nil {
+		return exec.Result{}, err
+	}
+
+	if len(dirs) == 0 {
+		x.Log(ctx, slog.LevelInfo, "No directories matching --for-each", "glob", flags.forEach)
+	}
+
+	var merged exec.Result
+	for _, dir := range dirs {
+		sx, err := x.Sub(dir)
+		if err != nil {
+			return merged, fmt.Errorf("running command in %q: %w", dir, err)
+		}
+
+		command := strings.ReplaceAll(renderCommand(flags.command, repository), "{{ .Dir }}", dir)
+		res, err := execCommand(ctx, sx.WithEnv("GH_ITERATOR_DIR", dir).WithLogFields("dir", dir), repository, command, stdin, stdout, stderr)
+		merged.Stdout += res.Stdout
+		merged.Stderr += res.Stderr
+		if err != nil {
+			return merged, fmt.Errorf("running command in %q: %w", dir, err)
+		}
+
+		if merged.ExitCode == 0 {
+			merged.ExitCode = res.ExitCode
+		}
+	}
+
+	return merged, nil
+}
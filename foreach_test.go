@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jcchavezs/gh-iterator/exec"
+	"github.com/stretchr/testify/require"
+)
+
+func TestForEachDirs(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"go.mod", "tools/go.mod", "tools/lint/go.mod", "docs/README.md", ".git/go.mod"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(root, filepath.Dir(name)), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(root, name), nil, 0o644))
+	}
+
+	dirs, err := forEachDirs(root, "**/go.mod")
+	require.NoError(t, err)
+	require.Equal(t, []string{".", "tools", "tools/lint"}, dirs)
+
+	dirs, err = forEachDirs(root, "docs")
+	require.NoError(t, err)
+	require.Equal(t, []string{"docs"}, dirs)
+}
+
+func TestExecCommandForEach(t *testing.T) {
+	t.Setenv("SHELL", "/bin/sh")
+	t.Cleanup(func() { flags.command, flags.forEach = "", "" })
+
+	root := t.TempDir()
+	for _, name := range []string{"a/go.mod", "b/go.mod"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(root, filepath.Dir(name)), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(root, name), nil, 0o644))
+	}
+
+	flags.command, flags.forEach = `echo "{{ .Dir }} $GH_ITERATOR_DIR $(basename $PWD)"; [ "$GH_ITERATOR_DIR" = a ]`, "*/go.mod"
+	res, err := execCommandForEach(context.Background(), exec.NewExecer(root), "acme/a", nil, io.Discard, io.Discard)
+	require.NoError(t, err)
+	require.Equal(t, "a a a\nb b b\n", res.Stdout)
+	require.Equal(t, 1, res.ExitCode)
+}
+
+func TestExecCommandForEach_Stream(t *testing.T) {
+	t.Setenv("SHELL", "/bin/sh")
+	t.Cleanup(func() { flags.command, flags.forEach, flags.stream = "", "", false })
+
+	root := t.TempDir()
+	for _, name := range []string{"a/go.mod", "b/go.mod"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(root, filepath.Dir(name)), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(root, name), nil, 0o644))
+	}
+
+	flags.command, flags.forEach, flags.stream = `echo "$GH_ITERATOR_REPOSITORY $GH_ITERATOR_DIR $(basename $PWD)"`, "*/go.mod", true
+	x := trackEnv(exec.NewExecer(root)).WithEnv("GH_ITERATOR_REPOSITORY", "acme/a")
+
+	var out bytes.Buffer
+	res, err := execCommandForEach(context.Background(), x, "acme/a", nil, &out, io.Discard)
+	require.NoError(t, err)
+	require.Equal(t, "acme/a a a\nacme/a b b\n", res.Stdout)
+	require.Equal(t, "[acme/a] acme/a a a\n[acme/a] acme/a b b\n", out.String())
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchGlob(t *testing.T) {
+	testCases := []struct {
+		pattern string
+		name    string
+		matches bool
+	}{
+		{"*.go", "main.go", true},
+		{"*.go", "cmd/main.go", false},
+		{"**/*.go", "main.go", true},
+		{"**/*.go", "cmd/app/main.go", true},
+		{"cmd/**", "cmd/app/main.go", true},
+		{"cmd/**/main.go", "cmd/main.go", true},
+		{"cmd/**/main.go", "pkg/main.go", false},
+		{"acme/legacy-*", "acme/legacy-api", true},
+		{"acme/legacy-*", "acme/api", false},
+		{"**/go.mod", "go.mod", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.pattern+" "+tc.name, func(t *testing.T) {
+			require.Equal(t, tc.matches, matchGlob(tc.pattern, tc.name))
+		})
+	}
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	iterator "github.com/jcchavezs/gh-iterator"
+	"github.com/jcchavezs/gh-iterator/exec"
+	"github.com/jcchavezs/gh-iterator/github"
+)
+
+// graphqlRepositoriesQuery lists the repositories of an owner along with the metadata the REST
+// API would need a call per repository for.
+const graphqlRepositoriesQuery = `query($owner: String!, $perPage: Int!, $endCursor: String) {
+  repositoryOwner(login: $owner) {
+    repositories(first: $perPage, after: $endCursor) {
+      nodes {
+        nameWithOwner
+        url
+        sshUrl
+        isArchived
+        isFork
+        isEmpty
+        visibility
+        diskUsage
+        pushedAt
+        primaryLanguage { name }
+        defaultBranchRef {
+          name
+          branchProtectionRule { id }
+          target { ... on Commit { committedDate } }
+        }
+        repositoryTopics(first: 20) { nodes { topic { name } } }
+        languages(first: 20, orderBy: {field: SIZE, direction: DESC}) { nodes { name } }
+        licenseInfo { spdxId }
+        latestRelease { tagName }
+      }
+      pageInfo { hasNextPage endCursor }
+    }
+  }
+}`
+
+// repositoryMetadata is the metadata of a repository only retrieved when listing with GraphQL.
+type repositoryMetadata struct {
+	Topics                 []string
+	Languages              []string
+	License                string
+	ProtectedDefaultBranch bool
+	LatestRelease          string
+	LastCommitAt           time.Time
+}
+
+// metadataStore keeps the GraphQL metadata of the listed repositories for the filter.
+type metadataStore struct {
+	mu     sync.RWMutex
+	byRepo map[string]repositoryMetadata
+}
+
+var repositoriesMetadata = &metadataStore{byRepo: map[string]repositoryMetadata{}}
+
+func (s *metadataStore) set(repository string, m repositoryMetadata) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.byRepo[repository] = m
+}
+
+// get returns the metadata of the repository, empty if it was not listed with GraphQL.
+func (s *metadataStore) get(repository string) repositoryMetadata {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.byRepo[repository]
+}
+
+type graphqlRepository struct {
+	NameWithOwner   string    `json:"nameWithOwner"`
+	URL             string    `json:"url"`
+	SSHURL          string    `json:"sshUrl"`
+	IsArchived      bool      `json:"isArchived"`
+	IsFork          bool      `json:"isFork"`
+	IsEmpty         bool      `json:"isEmpty"`
+	Visibility      string    `json:"visibility"`
+	DiskUsage       int       `json:"diskUsage"`
+	PushedAt        time.Time `json:"pushedAt"`
+	PrimaryLanguage *struct {
+		Name string `json:"name"`
+	} `json:"primaryLanguage"`
+	DefaultBranchRef *struct {
+		Name                 string `json:"name"`
+		BranchProtectionRule *struct {
+			ID string `json:"id"`
+		} `json:"branchProtectionRule"`
+		Target struct {
+			CommittedDate time.Time `json:"committedDate"`
+		} `json:"target"`
+	} `json:"defaultBranchRef"`
+	RepositoryTopics struct {
+		Nodes []struct {
+			Topic struct {
+				Name string `json:"name"`
+			} `json:"topic"`
+		} `json:"nodes"`
+	} `json:"repositoryTopics"`
+	Languages struct {
+		Nodes []struct {
+			Name string `json:"name"`
+		} `json:"nodes"`
+	} `json:"languages"`
+	LicenseInfo *struct {
+		SpdxID string `json:"spdxId"`
+	} `json:"licenseInfo"`
+	LatestRelease *struct {
+		TagName string `json:"tagName"`
+	} `json:"latestRelease"`
+}
+
+// toRepository returns the repository as returned by the REST API and its GraphQL metadata.
+func (gr graphqlRepository) toRepository() (iterator.Repository, repositoryMetadata) {
+	repo := iterator.Repository{
+		Name:       gr.NameWithOwner,
+		URL:        gr.URL + ".git",
+		SSHURL:     gr.SSHURL,
+		Archived:   gr.IsArchived,
+		Fork:       gr.IsFork,
+		Visibility: strings.ToLower(gr.Visibility),
+		Size:       gr.DiskUsage,
+		PushedAt:   gr.PushedAt,
+	}
+
+	// the disk usage lags behind the pushes, empty repositories are the ones without commits.
+	if gr.IsEmpty {
+		repo.Size = 0
+	} else if repo.Size == 0 {
+		repo.Size = 1
+	}
+
+	var m repositoryMetadata
+
+	if gr.PrimaryLanguage != nil {
+		repo.Language = gr.PrimaryLanguage.Name
+	}
+
+	if gr.DefaultBranchRef != nil {
+		repo.DefaultBranchName = gr.DefaultBranchRef.Name
+		m.ProtectedDefaultBranch = gr.DefaultBranchRef.BranchProtectionRule != nil
+		m.LastCommitAt = gr.DefaultBranchRef.Target.CommittedDate
+	}
+
+	for _, n := range gr.RepositoryTopics.Nodes {
+		m.Topics = append(m.Topics, n.Topic.Name)
+	}
+
+	for _, n := range gr.Languages.Nodes {
+		m.Languages = append(m.Languages, n.Name)
+	}
+
+	if gr.LicenseInfo != nil {
+		m.License = gr.LicenseInfo.SpdxID
+	}
+
+	if gr.LatestRelease != nil {
+		m.LatestRelease = gr.LatestRelease.TagName
+	}
+
+	return repo, m
+}
+
+// graphqlMaxPerPage is the maximum number of nodes the GraphQL API returns per page.
+const graphqlMaxPerPage = 100
+
+// graphqlPageSize returns the page size to list the repositories with the GraphQL API, the
+// default one if not set and at most graphqlMaxPerPage, as the API rejects bigger pages.
+func graphqlPageSize(perPage int) int {
+	if perPage <= 0 {
+		return defaultPerPage
+	}
+
+	return min(perPage, graphqlMaxPerPage)
+}
+
+// listRepositoriesGraphQL lists all the repositories of the owner with the GraphQL API, storing
+// their metadata for the filter.
+func listRepositoriesGraphQL(ctx context.Context, x exec.Execer, owner string, perPage int) ([]iterator.Repository, error) {
+	res, err := x.RunX(ctx, "gh", "api", "graphql", "--paginate",
+		"-F", "owner="+owner,
+		"-F", "perPage="+strconv.Itoa(graphqlPageSize(perPage)),
+		"-f", "query="+graphqlRepositoriesQuery,
+		"--jq", ".data.repositoryOwner.repositories.nodes",
+	)
+	if err != nil {
+		return nil, fmt.Errorf("fetching repositories: %w", github.ErrOrGHAPIErr(res, err))
+	}
+
+	repos, err := decodeGraphQLPages(strings.NewReader(res))
+	if err != nil {
+		return nil, fmt.Errorf("processing repositories pages: %w", err)
+	}
+
+	return repos, nil
+}
+
+// decodeGraphQLPages decodes the pages of repositories returned by the GraphQL API, storing their
+// metadata for the filter.
+func decodeGraphQLPages(r io.Reader) ([]iterator.Repository, error) {
+	var repos []iterator.Repository
+
+	dec := json.NewDecoder(r)
+	for {
+		var page []graphqlRepository
+		if err := dec.Decode(&page); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("unmarshaling repositories: %w", err)
+		}
+
+		for _, gr := range page {
+			repo, m := gr.toRepository()
+			repositoriesMetadata.set(repo.Name, m)
+			repos = append(repos, repo)
+		}
+	}
+
+	return repos, nil
+}
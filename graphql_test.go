@@ -0,0 +1,65 @@
+package main
+
+import (
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	iterator "github.com/jcchavezs/gh-iterator"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeGraphQLPages(t *testing.T) {
+	pages := `[{
+		"nameWithOwner": "acme/api",
+		"url": "https://github.com/acme/api",
+		"sshUrl": "git@github.com:acme/api.git",
+		"visibility": "PRIVATE",
+		"diskUsage": 120,
+		"pushedAt": "2024-01-02T03:04:05Z",
+		"primaryLanguage": {"name": "Go"},
+		"defaultBranchRef": {"name": "main", "branchProtectionRule": {"id": "x"}, "target": {"committedDate": "2024-01-01T00:00:00Z"}},
+		"repositoryTopics": {"nodes": [{"topic": {"name": "payments"}}]},
+		"languages": {"nodes": [{"name": "Go"}, {"name": "Shell"}]},
+		"licenseInfo": {"spdxId": "MIT"},
+		"latestRelease": {"tagName": "v1.2.0"}
+	}]
+	[{"nameWithOwner": "acme/empty", "url": "https://github.com/acme/empty", "visibility": "PUBLIC", "isEmpty": true, "defaultBranchRef": null}]`
+
+	repos, err := decodeGraphQLPages(strings.NewReader(pages))
+	require.NoError(t, err)
+	require.Equal(t, []iterator.Repository{
+		{
+			Name:              "acme/api",
+			URL:               "https://github.com/acme/api.git",
+			SSHURL:            "git@github.com:acme/api.git",
+			DefaultBranchName: "main",
+			Language:          "Go",
+			Visibility:        "private",
+			Size:              120,
+			PushedAt:          time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		},
+		{Name: "acme/empty", URL: "https://github.com/acme/empty.git", Visibility: "public"},
+	}, repos)
+
+	require.Equal(t, repositoryMetadata{
+		Topics:                 []string{"payments"},
+		Languages:              []string{"Go", "Shell"},
+		License:                "MIT",
+		ProtectedDefaultBranch: true,
+		LatestRelease:          "v1.2.0",
+		LastCommitAt:           time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	}, repositoriesMetadata.get("acme/api"))
+
+	filterIn, err := parseSearchFilterIn(`"payments" in repo.topics && repo.license == "MIT" && repo.protectedDefaultBranch`, slog.Default())
+	require.NoError(t, err)
+	require.True(t, filterIn(repos[0]))
+	require.False(t, filterIn(repos[1]))
+}
+
+func TestGraphQLPageSize(t *testing.T) {
+	require.Equal(t, defaultPerPage, graphqlPageSize(0))
+	require.Equal(t, 30, graphqlPageSize(30))
+	require.Equal(t, graphqlMaxPerPage, graphqlPageSize(500))
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/jcchavezs/gh-iterator/exec"
+)
+
+// maxGrepLine is the longest line searched, the files with longer lines e.g. minified ones are
+// skipped.
+const maxGrepLine = 1 << 20
+
+// grepMatch is a line of a file of the repository matching --grep.
+type grepMatch struct {
+	File string `json:"file"`
+	Line int    `json:"line"`
+	Text string `json:"text"`
+}
+
+// grepFiles searches the files of the clone in root matching any of the globs, all of them if
+// none is passed, for the lines matching re. The binary files are skipped.
+func grepFiles(root string, re *regexp.Regexp, globs []string) ([]grepMatch, error) {
+	var matches []grepMatch
+
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+
+		rel = filepath.ToSlash(rel)
+		if len(globs) > 0 && !matchAnyGlob(globs, rel) {
+			return nil
+		}
+
+		fileMatches, err := grepFile(p, rel, re)
+		if err != nil {
+			return err
+		}
+		matches = append(matches, fileMatches...)
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("searching files: %w", err)
+	}
+
+	return matches, nil
+}
+
+func grepFile(path string, name string, re *regexp.Regexp) ([]grepMatch, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	if head, _ := r.Peek(8000); bytes.IndexByte(head, 0) >= 0 {
+		return nil, nil
+	}
+
+	var matches []grepMatch
+	s := bufio.NewScanner(r)
+	s.Buffer(nil, maxGrepLine)
+	for line := 1; s.Scan(); line++ {
+		if re.Match(s.Bytes()) {
+			matches = append(matches, grepMatch{File: name, Line: line, Text: s.Text()})
+		}
+	}
+
+	if err := s.Err(); errors.Is(err, bufio.ErrTooLong) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", name, err)
+	}
+
+	return matches, nil
+}
+
+// grepRepository searches the clone for --grep, recording the matches in the results and
+// printing them as repository:file:line:text.
+func (p repoProcessor) grepRepository(ctx context.Context, x exec.Execer, repository string) error {
+	root, err := commandDir(x)
+	if err != nil {
+		return err
+	}
+
+	matches, err := grepFiles(root, p.grep, flags.grepGlobs)
+	if err != nil {
+		return err
+	}
+
+	p.results.update(repository, func(r *repoResult) {
+		r.Matches = matches
+		if flags.sarif != "" {
+			for _, m := range matches {
+				r.Findings = append(r.Findings, finding{Path: m.File, Line: m.Line, Message: m.Text})
+			}
+		}
+	})
+
+	outputMux.Lock()
+	defer outputMux.Unlock()
+
+	for _, m := range matches {
+		if _, err := fmt.Fprintf(p.stdout, "%s:%s:%d:%s\n", repository, m.File, m.Line, m.Text); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
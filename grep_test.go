@@ -0,0 +1,38 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGrepFiles(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"main.go":          "package main\n\n// TODO: remove\nfunc main() {}\n",
+		"internal/x/x.go":  "package x // TODO: document\n",
+		"README.md":        "TODO: write docs\n",
+		"bin/tool":         "TODO\x00binary",
+		"dist/min.js":      "TODO" + strings.Repeat("a", maxGrepLine),
+		".git/COMMIT_EDIT": "TODO\n",
+	}
+	for name, content := range files {
+		require.NoError(t, os.MkdirAll(filepath.Join(root, filepath.Dir(name)), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(root, name), []byte(content), 0o644))
+	}
+
+	matches, err := grepFiles(root, regexp.MustCompile(`TODO`), []string{"**/*.go"})
+	require.NoError(t, err)
+	require.Equal(t, []grepMatch{
+		{File: "internal/x/x.go", Line: 1, Text: "package x // TODO: document"},
+		{File: "main.go", Line: 3, Text: "// TODO: remove"},
+	}, matches)
+
+	matches, err = grepFiles(root, regexp.MustCompile(`^TODO`), nil)
+	require.NoError(t, err)
+	require.Equal(t, []grepMatch{{File: "README.md", Line: 1, Text: "TODO: write docs"}}, matches)
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// installGH installs a fake gh running the shell script body ahead of the real one in the PATH.
+// It returns the directory the fake gh is in, removed when the test ends.
+func installGH(t *testing.T, body string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "gh"), []byte("#!/bin/sh\n"+body), 0755))
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	return dir
+}
+
+// scriptedGH installs a gh script recording its arguments, one invocation per line, in the
+// returned file before running the body.
+func scriptedGH(t *testing.T, body string) string {
+	t.Helper()
+
+	calls := filepath.Join(t.TempDir(), "calls")
+	installGH(t, "echo \"$@\" >> "+calls+"\n"+body)
+
+	return calls
+}
+
+// countLines returns the number of lines in the file.
+func countLines(t *testing.T, file string) int {
+	t.Helper()
+
+	b, err := os.ReadFile(file)
+	require.NoError(t, err)
+	return strings.Count(string(b), "\n")
+}
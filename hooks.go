@@ -0,0 +1,75 @@
+package main
+
+import (
+	iterator "github.com/jcchavezs/gh-iterator"
+)
+
+// runHooks are the callbacks notified along the run e.g. to render the progress or to keep track
+// of the repositories, the ones not set are ignored. The repository callbacks are called
+// concurrently from the workers.
+type runHooks struct {
+	// OnRepoStart is called before processing a selected repository.
+	OnRepoStart func(repo iterator.Repository)
+	// OnRepoFinished is called once a repository is processed, successfully or not, along with
+	// its result and the processing error.
+	OnRepoFinished func(repo iterator.Repository, res repoResult, err error)
+	// OnRepoSkipped is called for the selected repositories skipped while running e.g. because
+	// the ref is not found or the clone filter does not match.
+	OnRepoSkipped func(repo iterator.Repository, reason string)
+	// OnRunFinished is called once all the repositories are processed or the run stopped.
+	OnRunFinished func(t runTotals, err error)
+}
+
+func (h runHooks) repoStart(repo iterator.Repository) {
+	if h.OnRepoStart != nil {
+		h.OnRepoStart(repo)
+	}
+}
+
+// repoDone calls OnRepoSkipped or OnRepoFinished depending on the result of the repository.
+func (h runHooks) repoDone(repo iterator.Repository, res repoResult, err error) {
+	if res.Skipped != "" && err == nil {
+		if h.OnRepoSkipped != nil {
+			h.OnRepoSkipped(repo, res.Skipped)
+		}
+		return
+	}
+
+	if h.OnRepoFinished != nil {
+		h.OnRepoFinished(repo, res, err)
+	}
+}
+
+func (h runHooks) runFinished(t runTotals, err error) {
+	if h.OnRunFinished != nil {
+		h.OnRunFinished(t, err)
+	}
+}
+
+// join returns the hooks calling h and then other.
+func (h runHooks) join(other runHooks) runHooks {
+	return runHooks{
+		OnRepoStart: func(repo iterator.Repository) {
+			h.repoStart(repo)
+			other.repoStart(repo)
+		},
+		OnRepoFinished: func(repo iterator.Repository, res repoResult, err error) {
+			for _, f := range []func(iterator.Repository, repoResult, error){h.OnRepoFinished, other.OnRepoFinished} {
+				if f != nil {
+					f(repo, res, err)
+				}
+			}
+		},
+		OnRepoSkipped: func(repo iterator.Repository, reason string) {
+			for _, f := range []func(iterator.Repository, string){h.OnRepoSkipped, other.OnRepoSkipped} {
+				if f != nil {
+					f(repo, reason)
+				}
+			}
+		},
+		OnRunFinished: func(t runTotals, err error) {
+			h.runFinished(t, err)
+			other.runFinished(t, err)
+		},
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"html/template"
+	"io"
+	"slices"
+	"time"
+)
+
+// htmlReport is the data of the HTML report.
+type htmlReport struct {
+	GeneratedAt  time.Time
+	Totals       runTotals
+	Statuses     []htmlBar
+	Languages    []htmlBar
+	Repositories []repoResult
+}
+
+// htmlBar is a bar of the charts of the HTML report.
+type htmlBar struct {
+	Label   string
+	Count   int
+	Percent float64
+}
+
+// bars returns the bars of the counts sorted by count, with their percent of the total.
+func bars(counts map[string]int, total int) []htmlBar {
+	var bs []htmlBar
+	for label, count := range counts {
+		bs = append(bs, htmlBar{Label: label, Count: count, Percent: 100 * float64(count) / float64(max(total, 1))})
+	}
+
+	slices.SortFunc(bs, func(a, b htmlBar) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.Label, b.Label))
+	})
+
+	return bs
+}
+
+// writeHTML prints the results of the matched repositories as a self-contained HTML page with
+// the totals, charts of the statuses and languages and a sortable table of the repositories
+// with their output, diff and changes.
+func (r *runResults) writeHTML(w io.Writer) error {
+	report := htmlReport{GeneratedAt: time.Now().UTC(), Totals: totals(r.sorted())}
+
+	statuses, languages := map[string]int{}, map[string]int{}
+	for _, res := range r.sorted() {
+		if !res.Matched {
+			continue
+		}
+
+		report.Repositories = append(report.Repositories, res)
+		statuses[res.status()]++
+		languages[cmp.Or(res.Language, "none")]++
+	}
+	report.Statuses = bars(statuses, len(report.Repositories))
+	report.Languages = bars(languages, len(report.Repositories))
+
+	if err := htmlReportTemplate.Execute(w, report); err != nil {
+		return fmt.Errorf("writing HTML report: %w", err)
+	}
+
+	return nil
+}
+
+var htmlReportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"seconds": formatSeconds,
+	"status":  func(r repoResult) string { return r.status() },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>gh-iterator-run report</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em; color: #1f2328; }
+h1 { font-size: 1.5em; }
+.totals span { display: inline-block; margin-right: 2em; }
+.charts { display: flex; gap: 4em; margin: 1em 0 2em; }
+.bar { display: flex; align-items: center; margin: .2em 0; }
+.bar .label { width: 8em; }
+.bar .track { width: 15em; background: #eaeef2; margin-right: .5em; }
+.bar .fill { display: block; background: #0969da; height: 1em; min-width: 2px; }
+.bar.failed .fill { background: #cf222e; }
+.bar.skipped .fill, .bar.pending .fill { background: #9a6700; }
+table { border-collapse: collapse; width: 100%; }
+th, td { border-bottom: 1px solid #d0d7de; padding: .4em .6em; text-align: left; vertical-align: top; }
+th { cursor: pointer; user-select: none; background: #f6f8fa; }
+td.failed { color: #cf222e; font-weight: bold; }
+pre { max-height: 20em; overflow: auto; background: #f6f8fa; padding: .5em; }
+</style>
+</head>
+<body>
+<h1>gh-iterator-run report</h1>
+<p>Generated at {{ .GeneratedAt.Format "2006-01-02 15:04:05 UTC" }}</p>
+<p class="totals">
+<span>Matched: {{ .Totals.Matched }}</span>
+<span>Succeeded: {{ .Totals.Succeeded }}</span>
+<span>Failed: {{ .Totals.Failed }}</span>
+<span>Skipped: {{ .Totals.Skipped }}</span>
+</p>
+<div class="charts">
+<div>
+<h2>Status</h2>
+{{ range .Statuses }}<div class="bar {{ .Label }}"><span class="label">{{ .Label }}</span><span class="track"><span class="fill" style="width: {{ printf "%.1f" .Percent }}%"></span></span>{{ .Count }}</div>
+{{ end }}</div>
+<div>
+<h2>Language</h2>
+{{ range .Languages }}<div class="bar"><span class="label">{{ .Label }}</span><span class="track"><span class="fill" style="width: {{ printf "%.1f" .Percent }}%"></span></span>{{ .Count }}</div>
+{{ end }}</div>
+</div>
+<table id="results">
+<thead>
+<tr><th>Repository</th><th>Language</th><th>Status</th><th>Exit code</th><th data-type="number">Duration (s)</th><th>Details</th></tr>
+</thead>
+<tbody>
+{{ range .Repositories }}<tr>
+<td>{{ .Repository }}</td>
+<td>{{ .Language }}</td>
+<td class="{{ status . }}">{{ status . }}{{ if .Skipped }}: {{ .Skipped }}{{ end }}{{ if .Category }} ({{ .Category }}){{ end }}</td>
+<td>{{ if .CommandRan }}{{ .ExitCode }}{{ end }}</td>
+<td>{{ seconds .Duration }}</td>
+<td>
+{{ if .PRURL }}<a href="{{ .PRURL }}">{{ .PRURL }}</a>{{ end }}
+{{ if .Error }}<div>{{ .Error }}</div>{{ end }}
+{{ range .Changes }}<div>{{ . }}</div>{{ end }}
+{{ if .Stdout }}<details><summary>stdout</summary><pre>{{ .Stdout }}</pre></details>{{ end }}
+{{ if .Stderr }}<details><summary>stderr</summary><pre>{{ .Stderr }}</pre></details>{{ end }}
+{{ if .Diff }}<details><summary>diff</summary><pre>{{ .Diff }}</pre></details>{{ end }}
+</td>
+</tr>
+{{ end }}</tbody>
+</table>
+<script>
+document.querySelectorAll("#results th").forEach(function (th, i) {
+  var asc = true;
+  th.addEventListener("click", function () {
+    var tbody = document.querySelector("#results tbody");
+    var rows = Array.from(tbody.rows);
+    var numeric = th.dataset.type === "number";
+    rows.sort(function (a, b) {
+      var x = a.cells[i].textContent.trim(), y = b.cells[i].textContent.trim();
+      var c = numeric ? parseFloat(x || 0) - parseFloat(y || 0) : x.localeCompare(y);
+      return asc ? c : -c;
+    });
+    asc = !asc;
+    rows.forEach(function (row) { tbody.appendChild(row); });
+  });
+});
+</script>
+</body>
+</html>
+`))
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	iterator "github.com/jcchavezs/gh-iterator"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBars(t *testing.T) {
+	require.Equal(t, []htmlBar{
+		{Label: "succeeded", Count: 3, Percent: 75},
+		{Label: "failed", Count: 1, Percent: 25},
+	}, bars(map[string]int{"failed": 1, "succeeded": 3}, 4))
+	require.Empty(t, bars(nil, 0))
+}
+
+func TestRunResultsWriteHTML(t *testing.T) {
+	r := newRunResults()
+	r.addRepositories(
+		[]iterator.Repository{{Name: "acme/a", Language: "Go"}, {Name: "acme/b", Language: "Go"}, {Name: "acme/c"}},
+		[]iterator.Repository{{Name: "acme/a", Language: "Go"}, {Name: "acme/b", Language: "Go"}},
+		nil,
+	)
+	r.update("acme/a", func(res *repoResult) {
+		res.Processed, res.CommandRan, res.CommandDuration = true, true, time.Second
+		res.Diff = "+<script>alert(1)</script>\n"
+		res.PRURL = "https://github.com/acme/a/pull/1"
+	})
+	r.update("acme/b", func(res *repoResult) {
+		res.Processed, res.CommandRan, res.ExitCode, res.Stderr = true, true, 3, "missing file\n"
+		res.Category = categoryCommand
+	})
+
+	out := &bytes.Buffer{}
+	require.NoError(t, r.writeHTML(out))
+
+	html := out.String()
+	require.Contains(t, html, "<span>Matched: 2</span>")
+	require.Contains(t, html, "<span>Failed: 1</span>")
+	require.Contains(t, html, "<td>acme/a</td>")
+	require.Contains(t, html, "<td>1.000</td>")
+	require.Contains(t, html, `<a href="https://github.com/acme/a/pull/1">`)
+	require.Contains(t, html, "<summary>diff</summary><pre>&#43;&lt;script&gt;alert(1)&lt;/script&gt;\n</pre>")
+	require.Contains(t, html, `<td class="failed">failed (command)</td>`)
+	require.Contains(t, html, `<span class="label">Go</span>`)
+	require.NotContains(t, html, "acme/c")
+}
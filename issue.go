@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/jcchavezs/gh-iterator/exec"
+	"github.com/jcchavezs/gh-iterator/github"
+)
+
+// createIssueIfNotExist opens an issue in the repository unless there is an open one with
+// the same title. It returns the issue URL and whether the issue is new.
+func createIssueIfNotExist(ctx context.Context, x exec.Execer, repository string, title string, body string) (string, bool, error) {
+	res, err := x.RunX(ctx, "gh", "issue", "list",
+		"--repo", repository,
+		"--state", "open",
+		"--search", title+" in:title",
+		"--json", "title,url",
+	)
+	if err != nil {
+		return "", false, fmt.Errorf("listing issues: %w", github.ErrOrGHAPIErr(res, err))
+	}
+
+	var issues []struct {
+		Title string `json:"title"`
+		URL   string `json:"url"`
+	}
+	if err := json.Unmarshal([]byte(res), &issues); err != nil {
+		return "", false, fmt.Errorf("unmarshaling issues: %w", err)
+	}
+
+	for _, issue := range issues {
+		if issue.Title == title {
+			return issue.URL, false, nil
+		}
+	}
+
+	res, err = x.RunWithStdinX(ctx, strings.NewReader(body), "gh", "issue", "create",
+		"--repo", repository,
+		"--title", title,
+		"--body-file", "-",
+	)
+	if err != nil {
+		return "", false, fmt.Errorf("creating issue: %w", github.ErrOrGHAPIErr(res, err))
+	}
+
+	return strings.TrimSpace(res), true, nil
+}
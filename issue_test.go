@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jcchavezs/gh-iterator/exec"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcess_CreateIssue(t *testing.T) {
+	t.Setenv("SHELL", "/bin/sh")
+	t.Cleanup(func() { flags.command, flags.createIssue, flags.issueTitle = "", false, "" })
+	flags.createIssue, flags.issueTitle = true, "Upgrade Go"
+
+	body := filepath.Join(t.TempDir(), "body")
+	calls := scriptedGH(t, `case "$*" in
+"issue list --repo acme/a "*) echo '[{"title":"Upgrade Go 1.21","url":"https://github.com/acme/a/issues/1"}]' ;;
+"issue list --repo acme/b "*) echo '[{"title":"Upgrade Go","url":"https://github.com/acme/b/issues/2"}]' ;;
+"issue create "*) cat > `+body+`; echo https://github.com/acme/a/issues/3 ;;
+esac
+`)
+
+	process := func(t *testing.T, repository string, command string) (string, error) {
+		flags.command = command
+		out := &bytes.Buffer{}
+		p := repoProcessor{stdout: out, stderr: io.Discard, results: newRunResults(), issueBody: "Go 1.21 is EOL"}
+		err := p.process(context.Background(), repository, false, exec.NewExecer(t.TempDir()))
+		return out.String(), err
+	}
+
+	out, err := process(t, "acme/a", "true")
+	require.NoError(t, err)
+	require.Equal(t, "acme/a: created issue https://github.com/acme/a/issues/3\n", out)
+	content, err := os.ReadFile(body)
+	require.NoError(t, err)
+	require.Equal(t, "Go 1.21 is EOL", string(content))
+
+	// the issue with the same title is not opened again.
+	out, err = process(t, "acme/b", "true")
+	require.NoError(t, err)
+	require.Equal(t, "acme/b: issue already open https://github.com/acme/b/issues/2\n", out)
+
+	// no issue is opened when the command fails.
+	require.NoError(t, os.Remove(calls))
+	out, err = process(t, "acme/c", "exit 1")
+	require.NoError(t, err)
+	require.Empty(t, out)
+	require.NoFileExists(t, calls)
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	iterator "github.com/jcchavezs/gh-iterator"
+	"github.com/jcchavezs/gh-iterator/exec"
+	"github.com/jcchavezs/gh-iterator/github"
+	"gopkg.in/yaml.v3"
+)
+
+// labelsSpec are the issue labels the repositories must have e.g.
+//
+//	prune: true
+//	labels:
+//	  - name: bug
+//	    color: d73a4a
+//	    description: Something isn't working
+type labelsSpec struct {
+	// Prune deletes the labels not in the spec.
+	Prune  bool    `yaml:"prune"`
+	Labels []label `yaml:"labels"`
+}
+
+type label struct {
+	Name        string `yaml:"name" json:"name"`
+	Color       string `yaml:"color" json:"color"`
+	Description string `yaml:"description" json:"description"`
+}
+
+func loadLabelsSpec(path string) (labelsSpec, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return labelsSpec{}, fmt.Errorf("reading labels: %w", err)
+	}
+
+	var spec labelsSpec
+	dec := yaml.NewDecoder(bytes.NewReader(content))
+	dec.KnownFields(true)
+	if err := dec.Decode(&spec); err != nil {
+		return labelsSpec{}, fmt.Errorf("parsing labels: %w", err)
+	}
+
+	for i, l := range spec.Labels {
+		if l.Name == "" {
+			return labelsSpec{}, fmt.Errorf("parsing labels: label %d has no name", i+1)
+		}
+		spec.Labels[i].Color = strings.ToLower(strings.TrimPrefix(l.Color, "#"))
+	}
+
+	return spec, nil
+}
+
+// repositoryLabels returns the labels of the repository.
+func repositoryLabels(ctx context.Context, x exec.Execer, repository string) ([]label, error) {
+	res, err := x.RunX(ctx, "gh", "api", "--paginate",
+		"-H", "Accept: application/vnd.github+json",
+		"-H", "X-GitHub-Api-Version: "+iterator.GithubAPIVersion,
+		"/repos/"+repository+"/labels?per_page=100",
+	)
+	if err != nil {
+		return nil, fmt.Errorf("listing labels: %w", github.ErrOrGHAPIErr(res, err))
+	}
+
+	labels, err := decodePages[label](res)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshaling labels: %w", err)
+	}
+
+	return labels, nil
+}
+
+// labelOp is a change to the labels of a repository.
+type labelOp struct {
+	method string
+	// name is the current name of the label, empty when creating it.
+	name  string
+	label label
+}
+
+func (op labelOp) String() string {
+	switch op.method {
+	case "POST":
+		return "create label " + op.label.Name
+	case "DELETE":
+		return "delete label " + op.name
+	default:
+		return "update label " + op.name
+	}
+}
+
+// labelsDiff returns the changes to make the current labels match the spec. Labels are matched
+// by name, case-insensitively as GitHub does.
+func labelsDiff(spec labelsSpec, current []label) []labelOp {
+	var ops []labelOp
+
+	byName := map[string]label{}
+	for _, l := range current {
+		byName[strings.ToLower(l.Name)] = l
+	}
+
+	wanted := map[string]bool{}
+	for _, want := range spec.Labels {
+		wanted[strings.ToLower(want.Name)] = true
+
+		existing, ok := byName[strings.ToLower(want.Name)]
+		switch {
+		case !ok:
+			ops = append(ops, labelOp{method: "POST", label: want})
+		case existing.Name != want.Name || strings.ToLower(existing.Color) != want.Color || existing.Description != want.Description:
+			ops = append(ops, labelOp{method: "PATCH", name: existing.Name, label: want})
+		}
+	}
+
+	if spec.Prune {
+		for _, l := range current {
+			if !wanted[strings.ToLower(l.Name)] {
+				ops = append(ops, labelOp{method: "DELETE", name: l.Name})
+			}
+		}
+	}
+
+	return ops
+}
+
+// labelsAction makes the labels of the repositories match the spec.
+func labelsAction(spec labelsSpec) repoAction {
+	return func(ctx context.Context, x exec.Execer, repo iterator.Repository, dryRun bool) ([]string, error) {
+		current, err := repositoryLabels(ctx, x, repo.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		var changes []string
+		for _, op := range labelsDiff(spec, current) {
+			if !dryRun {
+				path := "/repos/" + repo.Name + "/labels"
+				var body any
+				switch op.method {
+				case "POST":
+					body = op.label
+				case "PATCH":
+					path += "/" + url.PathEscape(op.name)
+					body = map[string]string{"new_name": op.label.Name, "color": op.label.Color, "description": op.label.Description}
+				case "DELETE":
+					path += "/" + url.PathEscape(op.name)
+				}
+
+				if _, err := apiRequest(ctx, x, op.method, path, body); err != nil {
+					return nil, fmt.Errorf("syncing labels: %w", err)
+				}
+			}
+
+			changes = append(changes, op.String())
+		}
+
+		return changes, nil
+	}
+}
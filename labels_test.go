@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	iterator "github.com/jcchavezs/gh-iterator"
+	"github.com/jcchavezs/gh-iterator/exec"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLabelsDiff(t *testing.T) {
+	spec := labelsSpec{Labels: []label{
+		{Name: "bug", Color: "d73a4a", Description: "Something isn't working"},
+		{Name: "security", Color: "ff0000"},
+		{Name: "Docs", Color: "0075ca"},
+	}}
+	current := []label{
+		{Name: "bug", Color: "D73A4A", Description: "Something isn't working"},
+		{Name: "docs", Color: "0075ca"},
+		{Name: "wontfix", Color: "ffffff"},
+	}
+
+	require.Equal(t, []labelOp{
+		{method: "POST", label: spec.Labels[1]},
+		{method: "PATCH", name: "docs", label: spec.Labels[2]},
+	}, labelsDiff(spec, current))
+
+	spec.Prune = true
+	ops := labelsDiff(spec, current)
+	require.Len(t, ops, 3)
+	require.Equal(t, "delete label wontfix", ops[2].String())
+}
+
+func TestLabelsAction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "labels.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("prune: true\nlabels:\n  - name: bug\n    color: '#D73A4A'\n  - name: good first issue\n    color: 7057ff\n"), 0644))
+
+	spec, err := loadLabelsSpec(path)
+	require.NoError(t, err)
+	require.Equal(t, "d73a4a", spec.Labels[0].Color)
+
+	calls := scriptedGH(t, routedGH+`"GET /repos/acme/a/labels?per_page=100") echo '[{"name":"bug","color":"d73a4a","description":""}]'; echo '[{"name":"old stuff","color":"eeeeee"}]';;
+esac
+`)
+	x := exec.NewExecerWithLogger(t.TempDir(), slog.New(slog.DiscardHandler))
+
+	changes, err := labelsAction(spec)(context.Background(), x, iterator.Repository{Name: "acme/a"}, false)
+	require.NoError(t, err)
+	require.Equal(t, []string{"create label good first issue", "delete label old stuff"}, changes)
+
+	content, err := os.ReadFile(calls)
+	require.NoError(t, err)
+	require.Contains(t, string(content), "-X POST --input - /repos/acme/a/labels\n")
+	require.Contains(t, string(content), "-X DELETE /repos/acme/a/labels/old%20stuff\n")
+}
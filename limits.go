@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+
+	iterator "github.com/jcchavezs/gh-iterator"
+	"github.com/jcchavezs/gh-iterator/exec"
+	"github.com/jcchavezs/gh-iterator/github"
+	"github.com/spf13/cobra"
+)
+
+// rateLimitResources are the rate limits printed by the limits command.
+var rateLimitResources = []string{"core", "search", "graphql"}
+
+// rateLimitResource is the rate limit of an API resource.
+type rateLimitResource struct {
+	Limit     int   `json:"limit"`
+	Remaining int   `json:"remaining"`
+	Reset     int64 `json:"reset"`
+}
+
+// apiBudget is the number of requests a run is expected to make per API.
+type apiBudget struct {
+	Core    int
+	GraphQL int
+}
+
+func newLimitsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "limits [OWNER...]",
+		Short: "Print the API rate limits and the budget a run would consume",
+		Long: `Prints the remaining requests of the core, search and GraphQL API rate limits and when they
+reset. When owners are passed, as arguments or with --org, it also estimates the requests a run
+over all their repositories would make with the flags passed. The estimate does not take into
+account the repositories found with --search or listed in --repos-file.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			logger := slog.New(newLogHandler(cmd.ErrOrStderr(), flags.logLevel))
+			x := withRetries(exec.NewExecerWithLogger(".", logger), flags.apiRetries)
+
+			if flags.hostname != "" {
+				if err := os.Setenv("GH_HOST", flags.hostname); err != nil {
+					return fmt.Errorf("setting GH_HOST: %w", err)
+				}
+			}
+
+			limits, err := fetchRateLimits(ctx, x)
+			if err != nil {
+				return err
+			}
+
+			if err := writeRateLimits(cmd.OutOrStdout(), limits, time.Now()); err != nil {
+				return err
+			}
+
+			owners := append(slices.Clone(args), flags.owners...)
+			if len(owners) == 0 {
+				return nil
+			}
+
+			total := 0
+			for _, owner := range owners {
+				n, err := countOwnerRepositories(ctx, x, owner)
+				if err != nil {
+					return err
+				}
+				total += n
+			}
+
+			return writeBudget(cmd.OutOrStdout(), total, estimateBudget(total), limits)
+		},
+	}
+
+	// the flags of the run changing the API requests made per repository.
+	cmd.Flags().BoolVar(&flags.createPR, "create-pr", false, "Estimates the requests to open the pull requests")
+	cmd.Flags().BoolVar(&flags.createIssue, "create-issue", false, "Estimates the requests to open the issues")
+	cmd.Flags().BoolVar(&flags.skipIfPROpen, "skip-if-pr-open", false, "Estimates the requests to look for open pull requests")
+	cmd.Flags().BoolVar(&flags.tarball, "tarball", false, "Estimates the requests to download the archives of the repositories")
+
+	return cmd
+}
+
+// fetchRateLimits retrieves the rate limits of the API resources, which does not count against
+// them.
+func fetchRateLimits(ctx context.Context, x exec.Execer) (map[string]rateLimitResource, error) {
+	res, err := x.RunX(ctx, "gh", "api",
+		"-H", "Accept: application/vnd.github+json",
+		"-H", "X-GitHub-Api-Version: "+iterator.GithubAPIVersion,
+		"--jq", ".resources",
+		"/rate_limit",
+	)
+	if err != nil {
+		return nil, fmt.Errorf("fetching rate limits: %w", github.ErrOrGHAPIErr(res, err))
+	}
+
+	var limits map[string]rateLimitResource
+	if err := json.Unmarshal([]byte(res), &limits); err != nil {
+		return nil, fmt.Errorf("unmarshaling rate limits: %w", err)
+	}
+
+	return limits, nil
+}
+
+// writeRateLimits writes a line per API resource with the remaining requests and the time left
+// until the limit resets.
+func writeRateLimits(w io.Writer, limits map[string]rateLimitResource, now time.Time) error {
+	for _, name := range rateLimitResources {
+		l, ok := limits[name]
+		if !ok {
+			continue
+		}
+
+		reset := time.Unix(l.Reset, 0)
+		if _, err := fmt.Fprintf(w, "%s: %d/%d remaining, resets in %s\n", name, l.Remaining, l.Limit, max(reset.Sub(now), 0).Round(time.Second)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// countOwnerRepositories returns the number of repositories of the owner visible to the token.
+func countOwnerRepositories(ctx context.Context, x exec.Execer, owner string) (int, error) {
+	ownerType, err := detectOwnerType(ctx, x, owner)
+	if err != nil {
+		return 0, err
+	}
+
+	path := "/users/" + owner
+	if ownerType == OwnerTypeOrg {
+		path = "/orgs/" + owner
+	}
+
+	res, err := exec.TrimStdout(x.RunX(ctx, "gh", "api", path, "--jq", ".public_repos + (.total_private_repos // 0)"))
+	if err != nil {
+		return 0, fmt.Errorf("counting repositories of %q: %w", owner, github.ErrOrGHAPIErr(res, err))
+	}
+
+	return strconv.Atoi(res)
+}
+
+// estimateBudget estimates the requests of a run over n repositories with the flags passed. The
+// pull requests and issues are managed by gh with the GraphQL API.
+func estimateBudget(n int) apiBudget {
+	var b apiBudget
+
+	perPage := flags.perPage
+	if perPage <= 0 || perPage > maxPerPage {
+		perPage = defaultPerPage
+	}
+	pages := (n + perPage - 1) / perPage
+	if flags.graphql {
+		b.GraphQL += pages
+	} else {
+		b.Core += pages
+	}
+
+	if flags.createPR || flags.createIssue {
+		// the token scopes check.
+		b.Core++
+	}
+
+	if flags.createPR {
+		// looking for the pull request and opening or updating it.
+		b.GraphQL += 2 * n
+	}
+
+	if flags.createIssue {
+		// looking for an open issue and opening it.
+		b.GraphQL += 2 * n
+	}
+
+	if flags.skipIfPROpen {
+		b.GraphQL += n
+	}
+
+	if flags.tarball {
+		b.Core += n
+	}
+
+	return b
+}
+
+// writeBudget writes the estimated budget of the run and warns when it exceeds the remaining
+// requests.
+func writeBudget(w io.Writer, n int, b apiBudget, limits map[string]rateLimitResource) error {
+	if _, err := fmt.Fprintf(w, "\nEstimated requests for %d repositories: %d core, %d graphql\n", n, b.Core, b.GraphQL); err != nil {
+		return err
+	}
+
+	for _, r := range []struct {
+		name     string
+		requests int
+	}{{"core", b.Core}, {"graphql", b.GraphQL}} {
+		if l, ok := limits[r.name]; ok && r.requests > l.Remaining {
+			if _, err := fmt.Fprintf(w, "Warning: the run would exceed the %d remaining %s requests\n", l.Remaining, r.name); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteRateLimits(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	out := &bytes.Buffer{}
+	require.NoError(t, writeRateLimits(out, map[string]rateLimitResource{
+		"core":    {Limit: 5000, Remaining: 4990, Reset: now.Add(42 * time.Minute).Unix()},
+		"graphql": {Limit: 5000, Remaining: 0, Reset: now.Add(-time.Minute).Unix()},
+		"scim":    {Limit: 15000, Remaining: 15000},
+	}, now))
+	require.Equal(t, "core: 4990/5000 remaining, resets in 42m0s\ngraphql: 0/5000 remaining, resets in 0s\n", out.String())
+}
+
+func TestEstimateBudget(t *testing.T) {
+	t.Cleanup(func() { flags.perPage, flags.createPR, flags.skipIfPROpen = 100, false, false })
+
+	flags.perPage = 100
+	require.Equal(t, apiBudget{Core: 3}, estimateBudget(250))
+
+	// the API returns at most 100 repositories per page.
+	flags.perPage = 500
+	require.Equal(t, apiBudget{Core: 3}, estimateBudget(250))
+	flags.perPage = 100
+
+	flags.createPR, flags.skipIfPROpen = true, true
+	b := estimateBudget(250)
+	require.Equal(t, apiBudget{Core: 4, GraphQL: 750}, b)
+
+	out := &bytes.Buffer{}
+	require.NoError(t, writeBudget(out, 250, b, map[string]rateLimitResource{"core": {Remaining: 100}, "graphql": {Remaining: 500}}))
+	require.Equal(t, "\nEstimated requests for 250 repositories: 4 core, 750 graphql\n"+
+		"Warning: the run would exceed the 500 remaining graphql requests\n", out.String())
+}
+
+func TestLimitsCommand_Org(t *testing.T) {
+	t.Cleanup(func() { flags.owners = nil })
+	scriptedGH(t, `case "$*" in
+*rate_limit*) echo '{"core":{"limit":5000,"remaining":5000,"reset":0}}' ;;
+*"--jq .type"*) echo Organization ;;
+*) echo 250 ;;
+esac
+`)
+
+	// the owners passed with --org are estimated as the positional ones.
+	flags.owners = []string{"acme"}
+	cmd := newLimitsCommand()
+	out := &bytes.Buffer{}
+	cmd.SetOut(out)
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{})
+	require.NoError(t, cmd.Execute())
+	require.Contains(t, out.String(), "Estimated requests for 250 repositories")
+}
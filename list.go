@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	iterator "github.com/jcchavezs/gh-iterator"
+	"github.com/jcchavezs/gh-iterator/exec"
+	"github.com/spf13/cobra"
+)
+
+// listFields maps the fields the list command can print to their values.
+var listFields = map[string]func(iterator.Repository) any{
+	"name":           func(r iterator.Repository) any { return r.Name },
+	"url":            func(r iterator.Repository) any { return r.URL },
+	"ssh_url":        func(r iterator.Repository) any { return r.SSHURL },
+	"default_branch": func(r iterator.Repository) any { return r.DefaultBranchName },
+	"language":       func(r iterator.Repository) any { return r.Language },
+	"visibility":     func(r iterator.Repository) any { return r.Visibility },
+	"archived":       func(r iterator.Repository) any { return r.Archived },
+	"fork":           func(r iterator.Repository) any { return r.Fork },
+	"size":           func(r iterator.Repository) any { return r.Size },
+	"pushed_at":      func(r iterator.Repository) any { return r.PushedAt },
+}
+
+func newListCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list [OWNER...]",
+		Short: "Print the repositories passing the filter",
+		Long: `Prints the repositories passing the filter, one per line or as JSON, without processing them
+e.g. to pipe them to other tools.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			logger := slog.New(newLogHandler(cmd.ErrOrStderr(), flags.logLevel))
+
+			for _, f := range flags.listFields {
+				if _, ok := listFields[f]; !ok {
+					return fmt.Errorf("unknown field %q", f)
+				}
+			}
+
+			owners, err := setupSources(ctx, args, logger)
+			if err != nil {
+				return err
+			}
+
+			stages, err := filterStages(logger)
+			if err != nil {
+				return err
+			}
+
+			_, selected, _, err := matchingRepositories(ctx, withRetries(exec.NewExecerWithLogger(".", logger), flags.apiRetries), owners, cmd.InOrStdin(), stages)
+			if err != nil {
+				return err
+			}
+
+			if flags.listJSON {
+				return writeRepositoriesJSON(cmd.OutOrStdout(), selected, flags.listFields)
+			}
+
+			return writeRepositoriesLines(cmd.OutOrStdout(), selected, flags.listFields)
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&flags.listFields, "fields", []string{"name"}, "Fields of the repositories to print out of name, url, ssh_url, default_branch, language, visibility, archived, fork, size and pushed_at")
+	cmd.Flags().BoolVar(&flags.listJSON, "json", false, "Prints the repositories as a JSON array of objects with the fields")
+
+	return cmd
+}
+
+// writeRepositoriesLines writes a line per repository with the values of the fields separated
+// by tabs.
+func writeRepositoriesLines(w io.Writer, repos []iterator.Repository, fields []string) error {
+	for _, repo := range repos {
+		values := make([]string, 0, len(fields))
+		for _, f := range fields {
+			values = append(values, formatListValue(listFields[f](repo)))
+		}
+
+		if _, err := fmt.Fprintln(w, strings.Join(values, "\t")); err != nil {
+			return fmt.Errorf("writing repositories: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// writeRepositoriesJSON writes the repositories as a JSON array of objects with the fields.
+func writeRepositoriesJSON(w io.Writer, repos []iterator.Repository, fields []string) error {
+	objects := make([]map[string]any, 0, len(repos))
+	for _, repo := range repos {
+		o := make(map[string]any, len(fields))
+		for _, f := range fields {
+			o[f] = listFields[f](repo)
+		}
+		objects = append(objects, o)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(objects); err != nil {
+		return fmt.Errorf("writing repositories: %w", err)
+	}
+
+	return nil
+}
+
+func formatListValue(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case int:
+		return strconv.Itoa(v)
+	case time.Time:
+		return v.Format(time.RFC3339)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	iterator "github.com/jcchavezs/gh-iterator"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteRepositories(t *testing.T) {
+	repos := []iterator.Repository{
+		{Name: "acme/a", Language: "Go", Size: 3, PushedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{Name: "acme/b", Archived: true},
+	}
+
+	out := &bytes.Buffer{}
+	require.NoError(t, writeRepositoriesLines(out, repos, []string{"name", "language", "archived", "size", "pushed_at"}))
+	require.Equal(t, "acme/a\tGo\tfalse\t3\t2024-01-02T03:04:05Z\nacme/b\t\ttrue\t0\t0001-01-01T00:00:00Z\n", out.String())
+
+	out.Reset()
+	require.NoError(t, writeRepositoriesJSON(out, repos, []string{"name", "archived"}))
+	require.JSONEq(t, `[{"name": "acme/a", "archived": false}, {"name": "acme/b", "archived": true}]`, out.String())
+}
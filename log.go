@@ -1,7 +1,10 @@
 package main
 
 import (
+	"bytes"
+	"io"
 	"log/slog"
+	"os"
 )
 
 // LevelIds maps slog log levels to their corresponding string identifiers.
@@ -11,3 +14,104 @@ var LevelIds = map[slog.Level][]string{
 	slog.LevelWarn:  {"warn"},
 	slog.LevelError: {"error"},
 }
+
+// LogFormat is the format of the logs.
+type LogFormat int
+
+const (
+	LogFormatJSON LogFormat = iota
+	LogFormatText
+)
+
+// LogFormatIds maps log formats to their corresponding string identifiers.
+var LogFormatIds = map[LogFormat][]string{
+	LogFormatJSON: {"json"},
+	LogFormatText: {"text"},
+}
+
+// ColorMode is when to color the logs.
+type ColorMode int
+
+const (
+	ColorAuto ColorMode = iota
+	ColorAlways
+	ColorNever
+)
+
+// ColorModeIds maps color modes to their corresponding string identifiers.
+var ColorModeIds = map[ColorMode][]string{
+	ColorAuto:   {"auto"},
+	ColorAlways: {"always"},
+	ColorNever:  {"never"},
+}
+
+// newLogHandler returns the handler writing the logs at level to w in the format passed in
+// --log-format. Text logs are colored as passed in --color, by default when w is a terminal and
+// NO_COLOR is not set.
+func newLogHandler(w io.Writer, level slog.Leveler) slog.Handler {
+	return formatLogHandler(w, level, useColor(w))
+}
+
+// formatLogHandler returns the handler writing the logs at level to w in the format passed in
+// --log-format, coloring the levels of the text logs if color.
+func formatLogHandler(w io.Writer, level slog.Leveler, color bool) slog.Handler {
+	opts := &slog.HandlerOptions{Level: level}
+	if flags.logFormat == LogFormatJSON {
+		return slog.NewJSONHandler(w, opts)
+	}
+
+	if color {
+		w = levelColorWriter{w: w}
+	}
+
+	return slog.NewTextHandler(w, opts)
+}
+
+// useColor tells whether to color the logs written to w.
+func useColor(w io.Writer) bool {
+	switch flags.color {
+	case ColorAlways:
+		return true
+	case ColorNever:
+		return false
+	default:
+		return os.Getenv("NO_COLOR") == "" && isTerminal(w)
+	}
+}
+
+// levelColors are the ANSI colors of the levels in the text logs.
+var levelColors = []struct {
+	level []byte
+	color string
+}{
+	{[]byte("level=DEBUG"), "\033[90m"},
+	{[]byte("level=INFO"), "\033[36m"},
+	{[]byte("level=WARN"), "\033[33m"},
+	{[]byte("level=ERROR"), "\033[31m"},
+}
+
+// levelColorWriter colors the level of the text log lines, which the text handler writes one
+// per call.
+type levelColorWriter struct {
+	w io.Writer
+}
+
+func (cw levelColorWriter) Write(p []byte) (int, error) {
+	for _, lc := range levelColors {
+		if i := bytes.Index(p, lc.level); i >= 0 {
+			colored := make([]byte, 0, len(p)+len(lc.color)+4)
+			colored = append(colored, p[:i]...)
+			colored = append(colored, lc.color...)
+			colored = append(colored, lc.level...)
+			colored = append(colored, "\033[0m"...)
+			colored = append(colored, p[i+len(lc.level):]...)
+
+			if _, err := cw.w.Write(colored); err != nil {
+				return 0, err
+			}
+			return len(p), nil
+		}
+	}
+
+	return cw.w.Write(p)
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewLogHandler(t *testing.T) {
+	t.Cleanup(func() { flags.logFormat, flags.color = LogFormatJSON, ColorAuto })
+
+	var buf bytes.Buffer
+	slog.New(newLogHandler(&buf, slog.LevelInfo)).Warn("Careful", "repository", "acme/a")
+	require.Contains(t, buf.String(), `"level":"WARN","msg":"Careful","repository":"acme/a"`)
+
+	flags.logFormat = LogFormatText
+	buf.Reset()
+	slog.New(newLogHandler(&buf, slog.LevelInfo)).Warn("Careful", "repository", "acme/a")
+	// the buffer is not a terminal.
+	require.Contains(t, buf.String(), `level=WARN msg=Careful repository=acme/a`)
+
+	flags.color = ColorAlways
+	buf.Reset()
+	slog.New(newLogHandler(&buf, slog.LevelInfo)).Warn("Careful", "repository", "acme/a")
+	require.Contains(t, buf.String(), "\033[33mlevel=WARN\033[0m msg=Careful repository=acme/a")
+
+	flags.color = ColorNever
+	buf.Reset()
+	slog.New(newLogHandler(&buf, slog.LevelInfo)).Error("Failed")
+	require.Contains(t, buf.String(), "level=ERROR msg=Failed")
+	require.NotContains(t, buf.String(), "\033[")
+}
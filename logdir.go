@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+)
+
+// openRepoLog creates the log file of the repository in --log-dir i.e. <log-dir>/<org>/<repo>.log,
+// truncating the one of a previous run.
+func openRepoLog(dir string, repository string) (*os.File, error) {
+	path := filepath.Join(dir, filepath.FromSlash(repository)+".log")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("creating log directory: %w", err)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("creating log file: %w", err)
+	}
+
+	return f, nil
+}
+
+// repoLogger returns the logger writing the debug logs of the repository to w.
+func repoLogger(w io.Writer) *slog.Logger {
+	return slog.New(formatLogHandler(w, slog.LevelDebug, false))
+}
+
+type repoLogContextKey struct{}
+
+// withRepoLog attaches the log file of the repository being processed to the context, so the
+// processor writes the stderr of the commands to it with --log-stderr.
+func withRepoLog(ctx context.Context, w io.Writer) context.Context {
+	return context.WithValue(ctx, repoLogContextKey{}, w)
+}
+
+// repoLogFromContext returns the log file attached to the context by withRepoLog.
+func repoLogFromContext(ctx context.Context) (io.Writer, bool) {
+	w, ok := ctx.Value(repoLogContextKey{}).(io.Writer)
+	return w, ok
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	iterator "github.com/jcchavezs/gh-iterator"
+	"github.com/jcchavezs/gh-iterator/exec"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunForRepositories_LogDir(t *testing.T) {
+	t.Cleanup(func() { flags.noClone, flags.logDir = false, "" })
+	flags.noClone = true
+	flags.logDir = t.TempDir()
+
+	err := runForRepositories(
+		context.Background(),
+		[]iterator.Repository{{Name: "acme/a", Size: 10}},
+		func(ctx context.Context, _ string, _ bool, x exec.Execer) error {
+			_, ok := repoLogFromContext(ctx)
+			require.True(t, ok)
+
+			x.Log(ctx, slog.LevelDebug, "Processing")
+			return nil
+		},
+		newRunResults(),
+		runHooks{},
+		iterator.Options{LogHandler: slog.DiscardHandler},
+	)
+	require.NoError(t, err)
+
+	content, err := os.ReadFile(filepath.Join(flags.logDir, "acme", "a.log"))
+	require.NoError(t, err)
+	require.Contains(t, string(content), `"msg":"Processing"`)
+	require.Contains(t, string(content), `"repository":"acme/a"`)
+}
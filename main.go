@@ -1,108 +1,296 @@
 package main
 
 import (
-	"context"
 	"fmt"
-	"io"
 	"log/slog"
 	"os"
 	"strconv"
-	"strings"
+	"time"
 
-	iterator "github.com/jcchavezs/gh-iterator"
-	"github.com/jcchavezs/gh-iterator/exec"
 	"github.com/spf13/cobra"
 	"github.com/thediveo/enumflag/v2"
 )
 
 var flags struct {
-	perPage       int
-	page          string
-	cloningSubset []string
-	searchFilter  string
-	command       string
-	logLevel      slog.Level
+	owners              []string
+	ownerType           OwnerType
+	reposFile           string
+	reposJSON           string
+	search              string
+	hostname            string
+	appID               string
+	appPrivateKey       string
+	appInstallationID   string
+	perPage             int
+	apiCache            time.Duration
+	metadataCache       string
+	pageConcurrency     int
+	minRateLimit        int
+	apiRetries          int
+	yes                 bool
+	noClone             bool
+	ref                 string
+	refFallback         RefFallback
+	allBranches         bool
+	recurseSubmodules   bool
+	keepClones          bool
+	workDir             string
+	maxDisk             string
+	noProgress          bool
+	quiet               bool
+	slowest             int
+	workers             string
+	largestFirst        bool
+	cloneConcurrency    int
+	output              OutputFormat
+	columns             []string
+	sarif               string
+	sarifRuleID         string
+	notifyURL           string
+	notifyFailures      bool
+	metricsListen       string
+	metricsPushURL      string
+	tarball             bool
+	useHTTPS            bool
+	limit               int
+	sort                SortField
+	order               SortOrder
+	sample              int
+	state               string
+	seed                uint64
+	page                string
+	cloningSubset       []string
+	excludeRepos        []string
+	includeRepos        []string
+	pushedSince         string
+	pushedWithin        string
+	searchFilter        string
+	command             string
+	preCommand          string
+	postCommand         string
+	applyPatch          string
+	replace             []string
+	replaceIn           []string
+	replaceRegex        bool
+	createPR            bool
+	prTitle             string
+	prBody              string
+	prBodyFile          string
+	prDraft             bool
+	branchName          string
+	prReport            string
+	failuresReport      string
+	retryFailed         string
+	archiveResults      string
+	onFailure           string
+	onSuccess           string
+	prWaitChecks        bool
+	prWaitChecksTimeout time.Duration
+	commitMessage       string
+	commitAll           bool
+	push                bool
+	skipIfBranchExists  bool
+	skipIfPROpen        bool
+	createIssue         bool
+	issueTitle          string
+	issueBodyFile       string
+	outputDir           string
+	stream              bool
+	interactive         bool
+	debugShellOnFailure bool
+	logLevel            slog.Level
+	listFields          []string
+	listJSON            bool
+	countBy             CountGroup
+	config              string
+	preset              string
+	graphql             bool
+	skipScopeCheck      bool
+	cloneFilter         string
+	keepGoing           bool
+	campaign            string
+	commandFile         string
+	forEach             string
+	grep                string
+	grepGlobs           []string
+	requireFiles        []string
+	dependencyInventory string
+	dependency          string
+	processor           string
+	provider            string
+	selectRepos         bool
+	pick                bool
+	showDiff            bool
+	cleanOlderThan      time.Duration
+	cleanIncludeKept    bool
+	includeEmpty        bool
+	skipEmpty           bool
+	shutdownGrace       time.Duration
+	logDir              string
+	logStderr           bool
+	logFormat           LogFormat
+	color               ColorMode
+	record              string
+	replay              string
+	fixtures            string
+	statsJSON           bool
+	exportProject       string
+	addTopics           []string
+	removeTopics        []string
+	dryRun              bool
+	archive             bool
+	applySettings       string
+	grantTeams          []string
+	revokeTeams         []string
+	syncLabels          string
+	mirror              string
+	createTag           string
+	release             bool
+	releaseNotesFile    string
+	dispatchWorkflow    string
+	workflowInputs      []string
+	deleteMerged        bool
+	protectBranches     []string
+	syncFiles           string
+	syncPolicies        []string
+	execTimeout         time.Duration
+	cleanEnv            bool
+	envAllow            []string
+	signCommits         bool
+	signingKey          string
+	signingFormat       SigningFormat
+	commitAuthor        string
+	committer           string
+	topics              []string
+	visibility          Visibility
+	noForks             bool
+	serializeBy         string
 }
 
-func renderCommand(s string, repository string) string {
-	return strings.ReplaceAll(s, "{{ .Repository }}", repository)
+// numberOfWorkers returns the number of workers to process the repositories with,
+// zero means the iterator default.
+func numberOfWorkers() int {
+	if flags.interactive || flags.debugShellOnFailure {
+		return 1
+	}
+
+	// invalid values are rejected when validating the flags.
+	n, _ := strconv.Atoi(flags.workers)
+	return n
 }
 
 func main() {
-	var rootCmd = &cobra.Command{
-		Use:   "gh-iterator-run",
-		Short: "Filter GitHub repositories using CEL expressions",
-		Long: `A CLI tool that iterates over GitHub organization repositories 
-and filters them using CEL (Common Expression Language) conditions.`,
-		Args: cobra.ExactArgs(1),
-		RunE: func(cmd *cobra.Command, args []string) error {
-			ctx := cmd.Context()
-
-			logHandler := slog.NewJSONHandler(cmd.ErrOrStderr(), &slog.HandlerOptions{Level: flags.logLevel})
-			logger := slog.New(logHandler)
-
-			searchFilterIn, err := parseSearchFilterIn(flags.searchFilter, logger)
-			if err != nil {
-				return err
-			}
-
-			var p int
-			if flags.page == "all" {
-				p = -1
-			} else if flags.page != "" {
-				if p, err = strconv.Atoi(flags.page); err != nil {
-					return err
-				}
-			}
+	if err := newRootCommand().Execute(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(exitCode(err))
+	}
+}
 
-			res, err := iterator.RunForOrganization(
-				ctx, args[0],
-				iterator.SearchOptions{
-					FilterIn: searchFilterIn,
-					PerPage:  flags.perPage,
-					Page:     iterator.PageN(p),
-				},
-				func(ctx context.Context, repository string, isEmpty bool, exec exec.Execer) error {
-					if flags.command != "" {
-						res, err := exec.Run(ctx, os.Getenv("SHELL"), "-c", renderCommand(flags.command, repository))
-						if err != nil {
-							io.WriteString(cmd.ErrOrStderr(), res.Stderr)
-							return err
-						}
+// newRootCommand returns the root command, which runs the run command without a subcommand.
+func newRootCommand() *cobra.Command {
+	runCmd := newRunCommand()
 
-						io.WriteString(cmd.OutOrStdout(), res.Stdout)
-					}
-					return nil
-				},
-				iterator.Options{
-					LogHandler:    logHandler,
-					CloningSubset: flags.cloningSubset,
-				},
-			)
+	var rootCmd = &cobra.Command{
+		Use:   "gh-iterator-run [OWNER...] [-- ARGS...]",
+		Short: "Filter GitHub repositories using CEL expressions",
+		Long: `A CLI tool that iterates over GitHub organization or user repositories 
+and filters them using CEL (Common Expression Language) conditions. Without a subcommand
+it runs the run command.
 
+When processing the repositories fails with a single category of error the exit code tells
+it: 3 command, 4 clone, 5 pull request, 6 authentication, 7 rate limit and 8 timeout. Other
+failures exit with 1 and interrupted runs with 130.`,
+		// the positional arguments are the owners, not subcommands.
+		Args: cobra.ArbitraryArgs,
+		// the config is applied before any command runs, the flags passed in the command line
+		// take precedence.
+		PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+			values, err := loadConfig(configPaths(), flags.preset)
 			if err != nil {
 				return err
 			}
 
-			fmt.Printf("Processed %d repositories\n", res.Processed)
-			fmt.Printf("Filtered %d repositories\n", res.Inspected)
-			return nil
+			return applyConfig(cmd, values)
 		},
+		RunE: runCmd.RunE,
 	}
 
-	rootCmd.Flags().StringVarP(&flags.searchFilter, "search-filter", "s", "", "CEL condition(s) to search repositories. By default, it filters out archived, forked, and empty repositories.")
-	rootCmd.Flags().StringVarP(&flags.command, "command", "c", "", "CEL condition(s) to search repositories.")
-	rootCmd.Flags().StringVar(&flags.page, "page", "all", "Page number to fetch, or 'all' to fetch all pages")
-	rootCmd.Flags().IntVar(&flags.perPage, "per-page", 100, "Number of repositories to fetch per page")
-	rootCmd.Flags().StringArrayVar(&flags.cloningSubset, "cloning-subset", nil, "")
+	if isGHExtension(os.Args[0]) {
+		rootCmd.Annotations = map[string]string{cobra.CommandDisplayNameAnnotation: ghExtensionName}
+	}
+
+	// the root command accepts the run flags as it runs the run command by default.
+	rootCmd.Flags().AddFlagSet(runCmd.Flags())
+
+	rootCmd.PersistentFlags().StringVarP(&flags.searchFilter, "search-filter", "s", "", "CEL condition(s) to search repositories. By default, it filters out archived, forked, and empty repositories.")
+	rootCmd.PersistentFlags().StringArrayVar(&flags.excludeRepos, "exclude-repos", nil, "Glob of the repositories to leave out e.g. 'acme/legacy-*', it can be repeated. Patterns without owner match any owner")
+	rootCmd.PersistentFlags().StringArrayVar(&flags.includeRepos, "include-repos", nil, "Glob of the repositories to process e.g. 'acme/api-*', it can be repeated. Patterns without owner match any owner. When no pattern has wildcards the repositories are fetched directly instead of listing the owners repositories")
+	rootCmd.PersistentFlags().StringVar(&flags.pushedSince, "pushed-since", "", "Only processes the repositories pushed since the date e.g. 2024-01-01, in addition to the search filter")
+	rootCmd.PersistentFlags().StringVar(&flags.pushedWithin, "pushed-within", "", "Only processes the repositories pushed within the duration e.g. 90d, in addition to the search filter")
+	rootCmd.PersistentFlags().StringVar(&flags.provider, "provider", "github", "Forge to list the repositories from and open the pull requests in")
+	rootCmd.PersistentFlags().BoolVar(&flags.includeEmpty, "include-empty", false, "Keeps the empty repositories the default search filter leaves out. The command runs in an empty directory for them, with GH_ITERATOR_EMPTY=true")
+	rootCmd.PersistentFlags().BoolVar(&flags.skipEmpty, "skip-empty", false, "Leaves out the empty repositories, also when the search filter matches them")
+	rootCmd.PersistentFlags().IntVar(&flags.limit, "limit", 0, "Maximum number of repositories to process out of the ones passing the filter, useful to pilot a campaign. By default, no limit")
+	rootCmd.PersistentFlags().Var(
+		enumflag.New(&flags.sort, "string", SortFieldIds, enumflag.EnumCaseInsensitive),
+		"sort",
+		"Field to sort the repositories by before processing them: pushed, name, size or none",
+	)
+	rootCmd.PersistentFlags().Var(
+		enumflag.New(&flags.order, "string", SortOrderIds, enumflag.EnumCaseInsensitive),
+		"order",
+		"Order to sort the repositories in: asc or desc",
+	)
+	rootCmd.PersistentFlags().IntVar(&flags.sample, "sample", 0, "Number of repositories to randomly pick out of the ones passing the filter")
+	rootCmd.PersistentFlags().Uint64Var(&flags.seed, "seed", 0, "Seed to pick the sample with, so it can be reproduced. By default, a random seed")
+	rootCmd.PersistentFlags().StringArrayVar(&flags.owners, "org", nil, "Organization or user owning the repositories, it can be repeated and combined with the positional arguments")
+	rootCmd.PersistentFlags().StringVar(&flags.search, "search", "", "GitHub search query to find the repositories e.g. 'org:acme topic:payments language:go'")
+	rootCmd.PersistentFlags().StringVar(&flags.hostname, "hostname", "", "GitHub host to use e.g. a GitHub Enterprise Server instance. By default, GH_HOST or github.com")
+	rootCmd.PersistentFlags().StringVar(&flags.appID, "app-id", "", "ID of the GitHub App to authenticate as")
+	rootCmd.PersistentFlags().StringVar(&flags.appPrivateKey, "app-private-key", "", "File with the PEM encoded private key of the GitHub App")
+	rootCmd.PersistentFlags().StringVar(&flags.appInstallationID, "app-installation-id", "", "ID of the GitHub App installation to authenticate as and whose repositories are processed. The installation token is refreshed before it expires")
+	rootCmd.PersistentFlags().StringVar(&flags.reposFile, "repos-file", "", "File with the owner/repo names to process, one per line, or '-' to read them from stdin")
+	rootCmd.PersistentFlags().StringVar(&flags.reposJSON, "repos-json", "", "File with the repositories as JSON objects or arrays e.g. the output of 'gh api' or 'gh search repos --json', or '-' to read them from stdin")
+	rootCmd.PersistentFlags().Var(
+		enumflag.New(&flags.ownerType, "string", OwnerTypeIds, enumflag.EnumCaseInsensitive),
+		"owner-type",
+		"Type of the account owning the repositories: org, user or auto to detect it",
+	)
+	rootCmd.PersistentFlags().StringSliceVar(&flags.topics, "topic", nil, "Topic the repositories of the owners must have, it can be repeated. The repositories are then listed with the search API, which narrows them down server-side but returns up to 1000")
+	rootCmd.PersistentFlags().Var(
+		enumflag.New(&flags.visibility, "string", VisibilityIds, enumflag.EnumCaseInsensitive),
+		"visibility",
+		"Visibility of the repositories of the owners to list: all, public, private or internal. Other than all lists them with the search API",
+	)
+	rootCmd.PersistentFlags().BoolVar(&flags.noForks, "no-forks", false, "Leaves the forks out when listing the repositories of the owners, with the search API")
+	rootCmd.PersistentFlags().StringVar(&flags.page, "page", "all", "Page number or range of pages e.g. 3-7 to fetch, or 'all' to fetch all pages")
+	rootCmd.PersistentFlags().IntVar(&flags.perPage, "per-page", 100, "Number of repositories to fetch per page")
+	rootCmd.PersistentFlags().BoolVar(&flags.graphql, "graphql", false, "Lists the repositories of the owners with the GraphQL API, which also retrieves the metadata available in the filter as repo.topics, repo.languages, repo.license, repo.protectedDefaultBranch, repo.latestRelease and repo.lastCommitAt")
+	rootCmd.PersistentFlags().IntVar(&flags.pageConcurrency, "page-concurrency", 4, "Number of pages fetched concurrently when listing all the repositories of an owner, 1 fetches them one after the other")
+	rootCmd.PersistentFlags().IntVar(&flags.apiRetries, "api-retries", 3, "Number of times the gh invocations failing with secondary rate limits or server errors are retried, with exponential backoff")
+	rootCmd.PersistentFlags().StringVar(&flags.fixtures, "fixtures", "", "File with the repositories as JSON, like --repos-json, to run the filter on without network nor credentials e.g. in CI. The run command does not clone them, the command runs in an empty directory and without it the processing is a no-op")
+	rootCmd.PersistentFlags().StringVar(&flags.record, "record", "", "File to record the GitHub API responses listing the repositories in, to replay them with --replay")
+	rootCmd.PersistentFlags().StringVar(&flags.replay, "replay", "", "File with the GitHub API responses recorded with --record to list the repositories from instead of calling the API, e.g. to work on a filter offline")
+	rootCmd.PersistentFlags().StringVar(&flags.metadataCache, "metadata-cache", "", "JSON file caching the repositories listed per owner across runs. The owners are listed again only when a repository was updated, pushed or created since, or after 24h, and the cached repositories are used when the check fails e.g. offline")
+	rootCmd.PersistentFlags().DurationVar(&flags.apiCache, "api-cache", 0, "Cache the GitHub API responses listing repositories for the given duration e.g. 1h")
+	rootCmd.AddCommand(runCmd, newListCommand(), newCountCommand(), newStatsCommand(), newFilterCommand(), newLimitsCommand(), newCleanCommand(), newVersionCommand())
+	rootCmd.PersistentFlags().StringVar(&flags.config, "config", "", "Config file with the default values of the flags, instead of .gh-iterator-run.yaml in the current directory. The values in ~/.config/gh-iterator-run/config.yaml are applied first")
+	rootCmd.PersistentFlags().StringVar(&flags.preset, "preset", "", "Preset of the config files to apply on top of their values")
 	rootCmd.PersistentFlags().Var(
 		enumflag.New(&flags.logLevel, "string", LevelIds, enumflag.EnumCaseInsensitive),
 		"log-level",
 		"Sets the log level",
 	)
+	rootCmd.PersistentFlags().Var(
+		enumflag.New(&flags.logFormat, "string", LogFormatIds, enumflag.EnumCaseInsensitive),
+		"log-format",
+		"Format of the logs: json or text",
+	)
+	rootCmd.PersistentFlags().Var(
+		enumflag.New(&flags.color, "string", ColorModeIds, enumflag.EnumCaseInsensitive),
+		"color",
+		"When to color the text logs: auto, when stderr is a terminal and NO_COLOR is not set, always or never",
+	)
 
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
-	}
+	return rootCmd
 }
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRootCommand_FlagPrecedence(t *testing.T) {
+	saved := flags
+	t.Cleanup(func() { flags = saved })
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	dir := t.TempDir()
+	fixtures := filepath.Join(dir, "repos.json")
+	require.NoError(t, os.WriteFile(fixtures, []byte(`[{"full_name":"acme/a","default_branch":"main","size":3}]`), 0644))
+	config := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(config, []byte("output: csv\ncommand: echo hello\n"), 0644))
+
+	execute := func(t *testing.T, args ...string) string {
+		t.Helper()
+
+		cmd := newRootCommand()
+		out := &bytes.Buffer{}
+		cmd.SetOut(out)
+		cmd.SetErr(io.Discard)
+		cmd.SetIn(strings.NewReader(""))
+		cmd.SetArgs(append([]string{"--fixtures=" + fixtures, "--config=" + config}, args...))
+		require.NoError(t, cmd.Execute())
+		return out.String()
+	}
+
+	// the flags passed in the command line take precedence over the config, with and without
+	// the run subcommand.
+	for name, args := range map[string][]string{
+		"root": {"--output", "json"},
+		"run":  {"run", "--output", "json"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			out := execute(t, args...)
+			var results struct {
+				Repositories []struct {
+					Repository string `json:"repository"`
+					Stdout     string `json:"stdout"`
+				} `json:"repositories"`
+			}
+			require.NoError(t, json.Unmarshal([]byte(out), &results), out)
+			require.Len(t, results.Repositories, 1)
+			require.Equal(t, "acme/a", results.Repositories[0].Repository)
+			// the values not passed in the command line come from the config.
+			require.Equal(t, "hello\n", results.Repositories[0].Stdout)
+		})
+	}
+
+	t.Run("config", func(t *testing.T) {
+		out := execute(t, "run")
+		require.True(t, strings.HasPrefix(out, "repository,"), out)
+		require.Contains(t, out, "acme/a")
+	})
+}
+
+func TestRunCommand_Quiet(t *testing.T) {
+	saved := flags
+	t.Cleanup(func() { flags = saved })
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("SHELL", "/bin/sh")
+
+	fixtures := filepath.Join(t.TempDir(), "repos.json")
+	require.NoError(t, os.WriteFile(fixtures, []byte(`[{"full_name":"acme/a","default_branch":"main","size":3}]`), 0644))
+
+	execute := func(args ...string) (string, string, error) {
+		flags = saved
+		cmd := newRootCommand()
+		out, errOut := &bytes.Buffer{}, &bytes.Buffer{}
+		cmd.SetOut(out)
+		cmd.SetErr(errOut)
+		cmd.SetIn(strings.NewReader(""))
+		cmd.SetArgs(append([]string{"run", "--fixtures=" + fixtures, "--command", "echo hello"}, args...))
+		err := cmd.Execute()
+		return out.String(), errOut.String(), err
+	}
+
+	out, _, err := execute()
+	require.NoError(t, err)
+	require.Contains(t, out, "hello\n")
+
+	// only the summary is printed, through the command writer.
+	out, errOut, err := execute("--quiet")
+	require.NoError(t, err)
+	require.Equal(t, "Processed 1 repositories\nFiltered 1 repositories\n", out)
+	require.NotContains(t, errOut, "hello")
+
+	_, _, err = execute("--quiet", "--interactive")
+	require.EqualError(t, err, "--quiet can't be used with --interactive")
+}
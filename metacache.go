@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	iterator "github.com/jcchavezs/gh-iterator"
+	"github.com/jcchavezs/gh-iterator/exec"
+	"github.com/jcchavezs/gh-iterator/github"
+)
+
+// metadataCacheMaxAge is the age after which the repositories of an owner are listed again even
+// if the stamp did not change, as the deleted and transferred repositories do not change it.
+const metadataCacheMaxAge = 24 * time.Hour
+
+// metadataCache keeps the repositories listed per owner across runs in the file passed in
+// --metadata-cache, so the owners whose repositories did not change are not listed again.
+type metadataCache struct {
+	mu     sync.Mutex
+	path   string
+	Owners map[string]cachedListing `json:"owners"`
+}
+
+type cachedListing struct {
+	// Stamp identifies the state of the repositories of the owner when they were listed.
+	Stamp        string                `json:"stamp"`
+	ListedAt     time.Time             `json:"listed_at"`
+	Repositories []iterator.Repository `json:"repositories"`
+}
+
+// loadMetadataCache reads the cache from path, an empty cache is returned if the file does not
+// exist.
+func loadMetadataCache(path string) (*metadataCache, error) {
+	c := &metadataCache{path: path, Owners: map[string]cachedListing{}}
+
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("reading metadata cache: %w", err)
+	}
+
+	if err := json.Unmarshal(content, c); err != nil {
+		return nil, fmt.Errorf("unmarshaling metadata cache: %w", err)
+	}
+
+	if c.Owners == nil {
+		c.Owners = map[string]cachedListing{}
+	}
+
+	return c, nil
+}
+
+func (c *metadataCache) get(owner string) (cachedListing, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	l, ok := c.Owners[owner]
+	return l, ok
+}
+
+// set records the listing of the owner and writes the cache into its file.
+func (c *metadataCache) set(owner string, l cachedListing) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.Owners[owner] = l
+
+	content, err := json.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("marshaling metadata cache: %w", err)
+	}
+
+	if err := os.WriteFile(c.path, content, 0644); err != nil {
+		return fmt.Errorf("writing metadata cache: %w", err)
+	}
+
+	return nil
+}
+
+// cachingProvider lists all the repositories of the owners through the cache, the rest goes
+// to the provider.
+type cachingProvider struct {
+	provider
+	cache *metadataCache
+}
+
+func (p cachingProvider) ListRepositories(ctx context.Context, x exec.Execer, owner string, pages []iterator.Page) ([]iterator.Repository, error) {
+	if !slices.Equal(pages, []iterator.Page{iterator.AllPages}) || ownerSearchQuery(owner) != "" {
+		// only complete listings are cached.
+		return p.provider.ListRepositories(ctx, x, owner, pages)
+	}
+
+	cached, ok := p.cache.get(owner)
+
+	stamp, err := listingStamp(ctx, x, owner)
+	if err != nil {
+		if !ok {
+			return nil, err
+		}
+
+		// works offline with the repositories of the last run.
+		x.Log(ctx, slog.LevelWarn, "Failed to check the repositories for changes, using the cached ones", "owner", owner, "listed_at", cached.ListedAt, "error", err)
+		return cached.Repositories, nil
+	}
+
+	if ok && cached.Stamp == stamp && time.Since(cached.ListedAt) < metadataCacheMaxAge {
+		x.Log(ctx, slog.LevelDebug, "Using the cached repositories", "owner", owner, "listed_at", cached.ListedAt)
+		return cached.Repositories, nil
+	}
+
+	listedAt := time.Now()
+	repos, err := p.provider.ListRepositories(ctx, x, owner, pages)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.cache.set(owner, cachedListing{Stamp: stamp, ListedAt: listedAt, Repositories: repos}); err != nil {
+		return nil, err
+	}
+
+	return repos, nil
+}
+
+// listingStamp returns the last update and push of the repositories of the owner, which change
+// with any change in the metadata, the code or the repositories created.
+func listingStamp(ctx context.Context, x exec.Execer, owner string) (string, error) {
+	target, err := reposPath(ctx, x, owner, flags.ownerType)
+	if err != nil {
+		return "", err
+	}
+
+	var stamp []string
+	for _, sort := range []string{"updated", "pushed"} {
+		res, err := exec.TrimStdout(x.RunX(ctx, "gh", "api",
+			"-H", "Accept: application/vnd.github+json",
+			"-H", "X-GitHub-Api-Version: "+iterator.GithubAPIVersion,
+			"-X", "GET",
+			"--jq", ".[0] | .full_name + \"@\" + ."+sort+"_at",
+			withQuery(target, "sort="+sort+"&direction=desc&per_page=1"),
+		))
+		if err != nil {
+			return "", fmt.Errorf("checking repositories for changes: %w", github.ErrOrGHAPIErr(res, err))
+		}
+
+		stamp = append(stamp, sort+":"+res)
+	}
+
+	return strings.Join(stamp, " "), nil
+}
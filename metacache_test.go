@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	iterator "github.com/jcchavezs/gh-iterator"
+	"github.com/jcchavezs/gh-iterator/exec"
+	"github.com/stretchr/testify/require"
+)
+
+// countingProvider counts the listings of the repositories.
+type countingProvider struct {
+	fakeProvider
+	listings *int
+}
+
+func (p countingProvider) ListRepositories(ctx context.Context, x exec.Execer, owner string, pages []iterator.Page) ([]iterator.Repository, error) {
+	*p.listings++
+	return p.fakeProvider.ListRepositories(ctx, x, owner, pages)
+}
+
+func TestCachingProvider(t *testing.T) {
+	flags.ownerType = OwnerTypeOrg
+	t.Cleanup(func() { flags.ownerType = OwnerTypeAuto })
+
+	dir := t.TempDir()
+	stampFile := filepath.Join(dir, "stamp")
+	require.NoError(t, os.WriteFile(stampFile, []byte("acme/a@2024-01-01T00:00:00Z\n"), 0644))
+	calls := scriptedGH(t, "cat "+stampFile+"\n")
+
+	cachePath := filepath.Join(dir, "cache.json")
+	cache, err := loadMetadataCache(cachePath)
+	require.NoError(t, err)
+
+	var listings int
+	p := cachingProvider{
+		provider: countingProvider{fakeProvider{repos: map[string][]iterator.Repository{"acme": {{Name: "acme/a"}}}}, &listings},
+		cache:    cache,
+	}
+	x := exec.NewExecerWithLogger(dir, slog.New(slog.DiscardHandler))
+	allPages := []iterator.Page{iterator.AllPages}
+
+	repos, err := p.ListRepositories(context.Background(), x, "acme", allPages)
+	require.NoError(t, err)
+	require.Equal(t, []iterator.Repository{{Name: "acme/a"}}, repos)
+	require.Equal(t, 1, listings)
+	content, err := os.ReadFile(calls)
+	require.NoError(t, err)
+	require.Contains(t, string(content), "/orgs/acme/repos?sort=updated&direction=desc&per_page=1")
+
+	// nothing changed, the listing is read from the file by the next runs.
+	cache, err = loadMetadataCache(cachePath)
+	require.NoError(t, err)
+	p.cache = cache
+	repos, err = p.ListRepositories(context.Background(), x, "acme", allPages)
+	require.NoError(t, err)
+	require.Equal(t, []iterator.Repository{{Name: "acme/a"}}, repos)
+	require.Equal(t, 1, listings)
+
+	// partial listings are not cached.
+	_, err = p.ListRepositories(context.Background(), x, "acme", []iterator.Page{1})
+	require.NoError(t, err)
+	require.Equal(t, 2, listings)
+
+	require.NoError(t, os.WriteFile(stampFile, []byte("acme/b@2024-02-01T00:00:00Z\n"), 0644))
+	_, err = p.ListRepositories(context.Background(), x, "acme", allPages)
+	require.NoError(t, err)
+	require.Equal(t, 3, listings)
+
+	// the stale listings are refreshed even if the stamp did not change.
+	l, _ := cache.get("acme")
+	l.ListedAt = time.Now().Add(-metadataCacheMaxAge)
+	require.NoError(t, cache.set("acme", l))
+	_, err = p.ListRepositories(context.Background(), x, "acme", allPages)
+	require.NoError(t, err)
+	require.Equal(t, 4, listings)
+
+	t.Run("offline", func(t *testing.T) {
+		scriptedGH(t, "exit 1\n")
+
+		repos, err := p.ListRepositories(context.Background(), x, "acme", allPages)
+		require.NoError(t, err)
+		require.Equal(t, []iterator.Repository{{Name: "acme/a"}}, repos)
+		require.Equal(t, 4, listings)
+
+		_, err = p.ListRepositories(context.Background(), x, "other", allPages)
+		require.Error(t, err)
+	})
+}
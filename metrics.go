@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// runMetrics are the metrics of the run not tracked in the results.
+var runMetrics struct {
+	apiCalls atomic.Int64
+	// rateLimitRemaining is the last known number of remaining API requests, -1 if unknown.
+	rateLimitRemaining atomic.Int64
+}
+
+func init() {
+	runMetrics.rateLimitRemaining.Store(-1)
+}
+
+// durationBuckets are the upper bounds in seconds of the duration histograms.
+var durationBuckets = []float64{1, 5, 15, 30, 60, 120, 300, 600, 1800}
+
+// writeMetrics writes the metrics of the run in the Prometheus text exposition format.
+func writeMetrics(w io.Writer, results *runResults) {
+	sorted := results.sorted()
+	t := totals(sorted)
+
+	gauge := func(name, help string, value int64) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n%s %d\n", name, help, name, name, value)
+	}
+	counter := func(name, help string, value int64) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n%s %d\n", name, help, name, name, value)
+	}
+
+	gauge("gh_iterator_run_repositories_found", "Number of repositories found.", int64(t.Found))
+	gauge("gh_iterator_run_repositories_matched", "Number of repositories selected to be processed.", int64(t.Matched))
+	counter("gh_iterator_run_repositories_processed_total", "Number of repositories processed.", int64(t.Succeeded+t.Failed))
+	counter("gh_iterator_run_repositories_failed_total", "Number of repositories whose processing or command failed.", int64(t.Failed))
+
+	fmt.Fprintln(w, "# HELP gh_iterator_run_repositories_skipped Number of repositories skipped per reason.")
+	fmt.Fprintln(w, "# TYPE gh_iterator_run_repositories_skipped gauge")
+	for _, c := range t.SkippedByReason {
+		fmt.Fprintf(w, "gh_iterator_run_repositories_skipped{reason=%q} %d\n", c.Reason, c.Count)
+	}
+
+	var cloneDurations, commandDurations []time.Duration
+	for _, res := range sorted {
+		if res.CloneDuration > 0 {
+			cloneDurations = append(cloneDurations, res.CloneDuration)
+		}
+		if res.CommandRan {
+			commandDurations = append(commandDurations, res.CommandDuration)
+		}
+	}
+	writeHistogram(w, "gh_iterator_run_clone_duration_seconds", "Time spent cloning the repositories.", cloneDurations)
+	writeHistogram(w, "gh_iterator_run_command_duration_seconds", "Time spent running the command in the repositories.", commandDurations)
+
+	counter("gh_iterator_run_api_calls_total", "Number of gh invocations.", runMetrics.apiCalls.Load())
+	if remaining := runMetrics.rateLimitRemaining.Load(); remaining >= 0 {
+		gauge("gh_iterator_run_rate_limit_remaining", "Last known number of remaining API requests.", remaining)
+	}
+}
+
+func writeHistogram(w io.Writer, name, help string, durations []time.Duration) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", name, help, name)
+
+	var sum float64
+	for _, d := range durations {
+		sum += d.Seconds()
+	}
+
+	for _, le := range durationBuckets {
+		count := 0
+		for _, d := range durations {
+			if d.Seconds() <= le {
+				count++
+			}
+		}
+		fmt.Fprintf(w, "%s_bucket{le=\"%g\"} %d\n", name, le, count)
+	}
+
+	fmt.Fprintf(w, "%s_bucket{le=\"+Inf\"} %d\n", name, len(durations))
+	fmt.Fprintf(w, "%s_sum %g\n", name, sum)
+	fmt.Fprintf(w, "%s_count %d\n", name, len(durations))
+}
+
+// serveMetrics exposes the metrics in addr under /metrics until the returned function is called.
+func serveMetrics(addr string, results *runResults) (func(context.Context) error, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("listening for metrics: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		writeMetrics(w, results)
+	})
+
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go srv.Serve(ln) //nolint:errcheck
+
+	return srv.Shutdown, nil
+}
+
+// pushMetrics pushes the metrics to a Prometheus Pushgateway under the gh-iterator-run job.
+func pushMetrics(ctx context.Context, gatewayURL string, results *runResults) error {
+	var body bytes.Buffer
+	writeMetrics(&body, results)
+
+	ctx, cancel := context.WithTimeout(ctx, notifyTimeout)
+	defer cancel()
+
+	url := strings.TrimSuffix(gatewayURL, "/") + "/metrics/job/gh-iterator-run"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, url, &body)
+	if err != nil {
+		return fmt.Errorf("creating metrics request: %w", err)
+	}
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("pushing metrics: %w", err)
+	}
+	defer res.Body.Close() //nolint:errcheck
+
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("pushing metrics: unexpected status %s", res.Status)
+	}
+
+	return nil
+}
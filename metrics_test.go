@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	iterator "github.com/jcchavezs/gh-iterator"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteMetrics(t *testing.T) {
+	r := newRunResults()
+	r.addRepositories(
+		[]iterator.Repository{{Name: "acme/a"}, {Name: "acme/b"}, {Name: "acme/c"}},
+		[]iterator.Repository{{Name: "acme/a"}, {Name: "acme/b"}},
+		map[string]string{"acme/c": "archived"},
+	)
+	r.update("acme/a", func(res *repoResult) {
+		res.Processed, res.CommandRan = true, true
+		res.CloneDuration, res.CommandDuration = 2*time.Second, 20*time.Second
+	})
+	r.update("acme/b", func(res *repoResult) { res.Processed, res.Error = true, "boom" })
+
+	out := &bytes.Buffer{}
+	writeMetrics(out, r)
+
+	for _, line := range []string{
+		"gh_iterator_run_repositories_found 3\n",
+		"gh_iterator_run_repositories_matched 2\n",
+		"gh_iterator_run_repositories_processed_total 2\n",
+		"gh_iterator_run_repositories_failed_total 1\n",
+		"gh_iterator_run_repositories_skipped{reason=\"archived\"} 1\n",
+		"gh_iterator_run_clone_duration_seconds_bucket{le=\"1\"} 0\n",
+		"gh_iterator_run_clone_duration_seconds_bucket{le=\"5\"} 1\n",
+		"gh_iterator_run_command_duration_seconds_bucket{le=\"15\"} 0\n",
+		"gh_iterator_run_command_duration_seconds_bucket{le=\"30\"} 1\n",
+		"gh_iterator_run_command_duration_seconds_sum 20\n",
+		"gh_iterator_run_command_duration_seconds_count 1\n",
+	} {
+		require.Contains(t, out.String(), line)
+	}
+}
+
+func TestPushMetrics(t *testing.T) {
+	var path, body string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPut, r.Method)
+		path = r.URL.Path
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+	}))
+	defer srv.Close()
+
+	require.NoError(t, pushMetrics(context.Background(), srv.URL+"/", newRunResults()))
+	require.Equal(t, "/metrics/job/gh-iterator-run", path)
+	require.Contains(t, body, "gh_iterator_run_repositories_found 0\n")
+}
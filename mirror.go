@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	iterator "github.com/jcchavezs/gh-iterator"
+	"github.com/jcchavezs/gh-iterator/exec"
+)
+
+// mirrorAction clones the repositories as mirrors into <dir>/<org>/<repo>.git, updating the
+// mirrors already there, so a filtered backup of an organization can be kept.
+func mirrorAction(dir string, useHTTPS bool) repoAction {
+	return func(ctx context.Context, x exec.Execer, repo iterator.Repository, dryRun bool) ([]string, error) {
+		path := filepath.Join(dir, repo.Name+".git")
+
+		if _, err := os.Stat(path); err == nil {
+			if dryRun {
+				return []string{"update mirror " + path}, nil
+			}
+
+			if _, err := x.RunX(ctx, "git", "-C", path, "remote", "update", "--prune"); err != nil {
+				return nil, fmt.Errorf("updating mirror: %w", err)
+			}
+
+			return []string{"update mirror " + path}, nil
+		} else if !os.IsNotExist(err) {
+			return nil, fmt.Errorf("checking mirror: %w", err)
+		}
+
+		if dryRun {
+			return []string{"mirror to " + path}, nil
+		}
+
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return nil, fmt.Errorf("creating mirror directory: %w", err)
+		}
+
+		args := []string{"clone", "--mirror"}
+		repoURL := repo.SSHURL
+		if useHTTPS {
+			// the helper is kept in the mirror config for the updates, the empty one resets
+			// the ones configured globally.
+			repoURL = repo.URL
+			key := credentialHelperKey(repo)
+			args = append(args, "-c", key+"=", "-c", key+"="+credentialHelper())
+		}
+
+		if _, err := x.RunX(ctx, "git", append(args, repoURL, path)...); err != nil {
+			os.RemoveAll(path) //nolint:errcheck
+			return nil, fmt.Errorf("cloning mirror: %w", err)
+		}
+
+		return []string{"mirror to " + path}, nil
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	osexec "os/exec"
+	"path/filepath"
+	"testing"
+
+	iterator "github.com/jcchavezs/gh-iterator"
+	"github.com/jcchavezs/gh-iterator/exec"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMirrorAction(t *testing.T) {
+	origin := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "main"},
+		{"-c", "user.name=a", "-c", "user.email=a@b.c", "commit", "-q", "--allow-empty", "-m", "first"},
+	} {
+		require.NoError(t, osexec.Command("git", append([]string{"-C", origin}, args...)...).Run())
+	}
+
+	dir := t.TempDir()
+	x := exec.NewExecerWithLogger(t.TempDir(), slog.New(slog.DiscardHandler))
+	repo := iterator.Repository{Name: "acme/a", SSHURL: origin}
+	path := filepath.Join(dir, "acme", "a.git")
+
+	changes, err := mirrorAction(dir, false)(context.Background(), x, repo, true)
+	require.NoError(t, err)
+	require.Equal(t, []string{"mirror to " + path}, changes)
+	require.NoDirExists(t, path)
+
+	changes, err = mirrorAction(dir, false)(context.Background(), x, repo, false)
+	require.NoError(t, err)
+	require.Equal(t, []string{"mirror to " + path}, changes)
+	require.FileExists(t, filepath.Join(path, "HEAD"))
+
+	require.NoError(t, osexec.Command("git", "-C", origin, "branch", "feature").Run())
+
+	changes, err = mirrorAction(dir, false)(context.Background(), x, repo, false)
+	require.NoError(t, err)
+	require.Equal(t, []string{"update mirror " + path}, changes)
+	require.NoError(t, osexec.Command("git", "-C", path, "rev-parse", "--verify", "refs/heads/feature").Run())
+}
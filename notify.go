@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const notifyTimeout = 30 * time.Second
+
+type notification struct {
+	// Status is succeeded or failed.
+	Status   string           `json:"status"`
+	Error    string           `json:"error,omitempty"`
+	Totals   runTotals        `json:"totals"`
+	Failures []jsonRepoResult `json:"failures,omitempty"`
+}
+
+// notify posts the summary of the run to the URL as JSON, including the failed repositories
+// when withFailures is set.
+func notify(ctx context.Context, url string, results *runResults, runErr error, withFailures bool) error {
+	sorted := results.sorted()
+
+	n := notification{Status: "succeeded", Totals: totals(sorted)}
+	if runErr != nil {
+		n.Status, n.Error = "failed", runErr.Error()
+	}
+
+	if withFailures {
+		for _, res := range sorted {
+			if res.Matched && res.failed() {
+				n.Failures = append(n.Failures, res.toJSON())
+			}
+		}
+	}
+
+	body, err := json.Marshal(n)
+	if err != nil {
+		return fmt.Errorf("marshaling notification: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, notifyTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("creating notification request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("sending notification: %w", err)
+	}
+	defer res.Body.Close() //nolint:errcheck
+
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("sending notification: unexpected status %s", res.Status)
+	}
+
+	return nil
+}
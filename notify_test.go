@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	iterator "github.com/jcchavezs/gh-iterator"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotify(t *testing.T) {
+	var received notification
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		require.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+	}))
+	defer srv.Close()
+
+	r := newRunResults()
+	r.addRepositories([]iterator.Repository{{Name: "acme/a"}, {Name: "acme/b"}}, []iterator.Repository{{Name: "acme/a"}, {Name: "acme/b"}}, nil)
+	r.update("acme/a", func(res *repoResult) { res.Processed = true })
+	r.update("acme/b", func(res *repoResult) { res.Processed, res.Error = true, "boom" })
+
+	require.NoError(t, notify(context.Background(), srv.URL, r, errors.New("boom"), true))
+	require.Equal(t, "failed", received.Status)
+	require.Equal(t, "boom", received.Error)
+	require.Equal(t, 1, received.Totals.Succeeded)
+	require.Len(t, received.Failures, 1)
+	require.Equal(t, "acme/b", received.Failures[0].Repository)
+}
+
+func TestNotify_UnexpectedStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	require.Error(t, notify(context.Background(), srv.URL, newRunResults(), nil, false))
+}
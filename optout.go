@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// optOutFiles are the files a repository opts out of the runs with. They list the campaigns the
+// repository opts out of one per line, or none to opt out of all of them.
+var optOutFiles = []string{".github/gh-iterator-ignore", ".gh-iterator-ignore"}
+
+// optedOut tells whether the clone in dir opts out of the campaign, returning the opt-out file.
+// A repository listing campaigns does not opt out of the runs without --campaign.
+func optedOut(dir string, campaign string) (string, bool, error) {
+	for _, name := range optOutFiles {
+		content, err := os.ReadFile(filepath.Join(dir, name))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return "", false, fmt.Errorf("reading opt-out file: %w", err)
+		}
+
+		campaigns := readOptOutCampaigns(content)
+		if len(campaigns) == 0 || (campaign != "" && slices.Contains(campaigns, campaign)) {
+			return name, true, nil
+		}
+	}
+
+	return "", false, nil
+}
+
+// readOptOutCampaigns returns the campaigns listed in the opt-out file, skipping the blank lines
+// and the comments.
+func readOptOutCampaigns(content []byte) []string {
+	var campaigns []string
+	s := bufio.NewScanner(bytes.NewReader(content))
+	for s.Scan() {
+		line, _, _ := strings.Cut(s.Text(), "#")
+		if line = strings.TrimSpace(line); line != "" {
+			campaigns = append(campaigns, line)
+		}
+	}
+
+	return campaigns
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOptedOut(t *testing.T) {
+	dir := t.TempDir()
+
+	_, ok, err := optedOut(dir, "")
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, ".github"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".github", "gh-iterator-ignore"), []byte("# managed by hand\nlicense-headers\n\nci-migration # until Q3\n"), 0o644))
+
+	for campaign, expected := range map[string]bool{"": false, "license-headers": true, "ci-migration": true, "renovate": false} {
+		_, ok, err := optedOut(dir, campaign)
+		require.NoError(t, err)
+		require.Equal(t, expected, ok, campaign)
+	}
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".gh-iterator-ignore"), nil, 0o644))
+	file, ok, err := optedOut(dir, "renovate")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, ".gh-iterator-ignore", file)
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/jcchavezs/gh-iterator/exec"
+)
+
+// writeCommandOutput stores the stdout, stderr and exit code of a command run for a repository
+// under <dir>/<org>/<repo>/.
+func writeCommandOutput(dir string, repository string, res exec.Result) error {
+	repoDir := filepath.Join(dir, filepath.FromSlash(repository))
+	if err := os.MkdirAll(repoDir, 0755); err != nil {
+		return fmt.Errorf("creating output directory: %w", err)
+	}
+
+	files := map[string]string{
+		"stdout":    res.Stdout,
+		"stderr":    res.Stderr,
+		"exit_code": strconv.Itoa(res.ExitCode) + "\n",
+	}
+
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(repoDir, name), []byte(content), 0644); err != nil {
+			return fmt.Errorf("writing %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
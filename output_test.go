@@ -0,0 +1,33 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jcchavezs/gh-iterator/exec"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteCommandOutput(t *testing.T) {
+	dir := t.TempDir()
+
+	err := writeCommandOutput(dir, "acme/my-repo", exec.Result{
+		Stdout:   "hello\n",
+		Stderr:   "oops\n",
+		ExitCode: 2,
+	})
+	require.NoError(t, err)
+
+	stdout, err := os.ReadFile(filepath.Join(dir, "acme", "my-repo", "stdout"))
+	require.NoError(t, err)
+	require.Equal(t, "hello\n", string(stdout))
+
+	stderr, err := os.ReadFile(filepath.Join(dir, "acme", "my-repo", "stderr"))
+	require.NoError(t, err)
+	require.Equal(t, "oops\n", string(stderr))
+
+	exitCode, err := os.ReadFile(filepath.Join(dir, "acme", "my-repo", "exit_code"))
+	require.NoError(t, err)
+	require.Equal(t, "2\n", string(exitCode))
+}
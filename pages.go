@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/textproto"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	iterator "github.com/jcchavezs/gh-iterator"
+	"github.com/jcchavezs/gh-iterator/exec"
+	"github.com/jcchavezs/gh-iterator/github"
+)
+
+// listRepositoriesConcurrently lists all the repositories in target, the API path returned by
+// listTarget. The first page tells the number of pages in its Link header and the rest of them
+// are fetched concurrently.
+func listRepositoriesConcurrently(ctx context.Context, x exec.Execer, target string, concurrency int) ([]iterator.Repository, error) {
+	ghArgs := append([]string{"api",
+		"-H", "Accept: application/vnd.github+json",
+		"-H", "X-GitHub-Api-Version: " + iterator.GithubAPIVersion,
+		"-X", "GET",
+		"--include",
+	}, apiCacheArgs()...)
+
+	res, err := x.RunX(ctx, "gh", append(ghArgs, withQuery(target, "page=1"))...)
+	if err != nil {
+		return nil, fmt.Errorf("fetching repositories: %w", github.ErrOrGHAPIErr(res, err))
+	}
+
+	header, body, err := splitIncludedResponse(res)
+	if err != nil {
+		return nil, err
+	}
+
+	firstPage, err := decodeRepositoryPages(body)
+	if err != nil {
+		return nil, fmt.Errorf("processing repositories pages: %w", err)
+	}
+
+	nOfPages := lastPage(header.Get("Link"))
+	if nOfPages <= 1 {
+		return firstPage, nil
+	}
+
+	// the first failing page cancels the fetches of the rest.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		pages    = make([][]iterator.Repository, nOfPages)
+		sem      = make(chan struct{}, concurrency)
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+	pages[0] = firstPage
+
+	for i := 1; i < nOfPages; i++ {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			page, err := listRepositories(ctx, x, target, iterator.PageN(i+1))
+			if err != nil {
+				errOnce.Do(func() {
+					firstErr = err
+					cancel()
+				})
+				return
+			}
+			pages[i] = page
+		}()
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var repos []iterator.Repository
+	for _, page := range pages {
+		repos = append(repos, page...)
+	}
+
+	return repos, nil
+}
+
+// splitIncludedResponse splits the output of gh api --include into the response headers and
+// the body.
+func splitIncludedResponse(res string) (textproto.MIMEHeader, io.Reader, error) {
+	r := textproto.NewReader(bufio.NewReader(strings.NewReader(res)))
+
+	// skips the status line
+	if _, err := r.ReadLine(); err != nil {
+		return nil, nil, fmt.Errorf("reading response status: %w", err)
+	}
+
+	header, err := r.ReadMIMEHeader()
+	if err != nil && err != io.EOF {
+		return nil, nil, fmt.Errorf("reading response headers: %w", err)
+	}
+
+	return header, r.R, nil
+}
+
+var lastPageRe = regexp.MustCompile(`[?&]page=(\d+)[^>]*>;\s*rel="last"`)
+
+// lastPage returns the number of the last page out of a Link header, or zero if there is
+// no last page.
+func lastPage(link string) int {
+	m := lastPageRe.FindStringSubmatch(link)
+	if m == nil {
+		return 0
+	}
+
+	n, _ := strconv.Atoi(m[1])
+	return n
+}
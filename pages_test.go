@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/jcchavezs/gh-iterator/exec"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitIncludedResponse(t *testing.T) {
+	header, body, err := splitIncludedResponse("HTTP/2.0 200 OK\r\n" +
+		"Content-Type: application/json; charset=utf-8\r\n" +
+		"Link: <https://api.github.com/organizations/1/repos?per_page=100&page=2>; rel=\"next\"\r\n" +
+		"\r\n" +
+		`[{"full_name":"acme/a"}]`)
+	require.NoError(t, err)
+	require.Contains(t, header.Get("Link"), `rel="next"`)
+
+	b, err := io.ReadAll(body)
+	require.NoError(t, err)
+	require.Equal(t, `[{"full_name":"acme/a"}]`, string(b))
+}
+
+func TestLastPage(t *testing.T) {
+	require.Equal(t, 34, lastPage(`<https://api.github.com/organizations/1/repos?per_page=100&page=2>; rel="next", `+
+		`<https://api.github.com/organizations/1/repos?per_page=100&page=34>; rel="last"`))
+	require.Equal(t, 5, lastPage(`<https://api.github.com/user/repos?page=5&per_page=100>; rel="last"`))
+	require.Zero(t, lastPage(`<https://api.github.com/organizations/1/repos?page=1>; rel="prev"`))
+	require.Zero(t, lastPage(""))
+}
+
+func TestListRepositoriesConcurrently_CancelsOnError(t *testing.T) {
+	calls := scriptedGH(t, `case "$*" in
+*--include*) printf 'HTTP/2.0 200 OK\r\nLink: <https://api.github.com/orgs/acme/repos?per_page=100&page=20>; rel="last"\r\n\r\n[{"full_name":"acme/a"}]' ;;
+*"&page=2"*) echo "HTTP 500: boom" >&2; exit 1 ;;
+*) sleep 0.5; echo '[]' ;;
+esac`)
+
+	x := exec.NewExecerWithLogger(t.TempDir(), slog.New(slog.DiscardHandler))
+	_, err := listRepositoriesConcurrently(context.Background(), x, "orgs/acme/repos?per_page=100", 2)
+	require.ErrorContains(t, err, "&page=2")
+
+	// the pages after the failing one are not fetched.
+	require.Less(t, countLines(t, calls), 5)
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/jcchavezs/gh-iterator/exec"
+)
+
+// resolvePatchFile returns the absolute path of the patch file so it can be applied
+// from within the repositories directories.
+func resolvePatchFile(patchFile string) (string, error) {
+	absPatchFile, err := filepath.Abs(patchFile)
+	if err != nil {
+		return "", fmt.Errorf("resolving patch file: %w", err)
+	}
+
+	if _, err := os.Stat(absPatchFile); err != nil {
+		return "", fmt.Errorf("reading patch file: %w", err)
+	}
+
+	return absPatchFile, nil
+}
+
+// applyPatch applies the unified diff in patchFile to the repository working tree.
+func applyPatch(ctx context.Context, x exec.Execer, patchFile string) error {
+	if _, err := x.RunX(ctx, "git", "apply", patchFile); err != nil {
+		if stderr, ok := exec.StderrNotEmpty(exec.GetStderr(err)); ok {
+			return fmt.Errorf("applying patch: %w: %s", err, stderr)
+		}
+
+		return fmt.Errorf("applying patch: %w", err)
+	}
+
+	return nil
+}
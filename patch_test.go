@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jcchavezs/gh-iterator/exec"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcess_ApplyPatch(t *testing.T) {
+	t.Setenv("SHELL", "/bin/sh")
+	t.Cleanup(func() { flags.command = "" })
+	flags.command = "cat NOTICE"
+
+	patch := filepath.Join(t.TempDir(), "notice.patch")
+	require.NoError(t, os.WriteFile(patch, []byte(`diff --git a/NOTICE b/NOTICE
+new file mode 100644
+--- /dev/null
++++ b/NOTICE
+@@ -0,0 +1 @@
++hello
+`), 0644))
+	patchFile, err := resolvePatchFile(patch)
+	require.NoError(t, err)
+
+	dir, _ := newOriginClone(t)
+	p := repoProcessor{stdout: io.Discard, stderr: io.Discard, results: newRunResults(), patchFile: patchFile}
+
+	// the command runs on the patched working tree.
+	require.NoError(t, p.process(context.Background(), "acme/a", false, exec.NewExecer(dir)))
+	res, _ := p.results.get("acme/a")
+	require.Equal(t, "hello\n", res.Stdout)
+
+	// the patch no longer applies so the repository fails before running the command.
+	p.results = newRunResults()
+	require.ErrorContains(t, p.process(context.Background(), "acme/a", false, exec.NewExecer(dir)), "applying patch")
+	res, _ = p.results.get("acme/a")
+	require.False(t, res.CommandRan)
+
+	_, err = resolvePatchFile(filepath.Join(t.TempDir(), "missing.patch"))
+	require.Error(t, err)
+}
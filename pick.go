@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	osexec "os/exec"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	iterator "github.com/jcchavezs/gh-iterator"
+)
+
+// maxPickCandidates is the number of best matches listed by the built-in picker.
+const maxPickCandidates = 20
+
+// pickRepositories lets the user pick the repositories to process with fzf when it is installed,
+// otherwise with the built-in fuzzy picker.
+func pickRepositories(ctx context.Context, in io.Reader, out io.Writer, repos []iterator.Repository) ([]iterator.Repository, error) {
+	if fzf, err := osexec.LookPath("fzf"); err == nil {
+		return pickWithFZF(ctx, fzf, repos)
+	}
+
+	return pickFuzzy(in, out, repos)
+}
+
+// pickWithFZF runs fzf with the repository names, which reads the keys from the terminal.
+func pickWithFZF(ctx context.Context, fzf string, repos []iterator.Repository) ([]iterator.Repository, error) {
+	var names bytes.Buffer
+	for _, repo := range repos {
+		fmt.Fprintln(&names, repo.Name)
+	}
+
+	c := osexec.CommandContext(ctx, fzf, "--multi", "--prompt", "repository> ")
+	c.Stdin = &names
+	c.Stderr = os.Stderr
+	res, err := c.Output()
+	if err != nil {
+		// fzf exits with 130 when the user aborts and with 1 when there is no match.
+		if exitErr, ok := err.(*osexec.ExitError); ok && (exitErr.ExitCode() == 130 || exitErr.ExitCode() == 1) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("running fzf: %w", err)
+	}
+
+	picked := map[string]bool{}
+	for _, name := range strings.Fields(string(res)) {
+		picked[name] = true
+	}
+
+	return slices.DeleteFunc(slices.Clone(repos), func(r iterator.Repository) bool { return !picked[r.Name] }), nil
+}
+
+// pickFuzzy asks for a query, lists the repositories best matching it and reads the numbers of
+// the ones to pick. An empty query lists the first repositories. Nothing is picked when the input
+// ends or no number is entered.
+func pickFuzzy(in io.Reader, out io.Writer, repos []iterator.Repository) ([]iterator.Repository, error) {
+	s := bufio.NewScanner(in)
+
+	fmt.Fprint(out, "Search repositories: ")
+	if !s.Scan() {
+		return nil, s.Err()
+	}
+	candidates := fuzzyMatches(strings.TrimSpace(s.Text()), repos)
+	if len(candidates) == 0 {
+		fmt.Fprintln(out, "No repositories match")
+		return nil, s.Err()
+	}
+
+	for i, repo := range candidates {
+		fmt.Fprintf(out, "%d\t%s\n", i+1, repo.Name)
+	}
+
+	fmt.Fprint(out, "Pick repositories by number e.g. 1 3, or press enter to abort: ")
+	if !s.Scan() {
+		if err := s.Err(); err != nil {
+			return nil, fmt.Errorf("reading picked repositories: %w", err)
+		}
+		return nil, nil
+	}
+
+	answer := strings.Fields(s.Text())
+
+	var picked []iterator.Repository
+	for _, field := range answer {
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 1 || n > len(candidates) {
+			return nil, fmt.Errorf("invalid repository number %q", field)
+		}
+		picked = append(picked, candidates[n-1])
+	}
+
+	return picked, nil
+}
+
+// fuzzyMatches returns the repositories matching the query, best first, up to
+// maxPickCandidates.
+func fuzzyMatches(query string, repos []iterator.Repository) []iterator.Repository {
+	type match struct {
+		repo  iterator.Repository
+		score int
+	}
+
+	var matches []match
+	for _, repo := range repos {
+		if score, ok := fuzzyScore(query, repo.Name); ok {
+			matches = append(matches, match{repo, score})
+		}
+	}
+
+	slices.SortStableFunc(matches, func(a, b match) int { return cmp.Compare(b.score, a.score) })
+
+	var best []iterator.Repository
+	for _, m := range matches[:min(len(matches), maxPickCandidates)] {
+		best = append(best, m.repo)
+	}
+
+	return best
+}
+
+// fuzzyScore tells whether the characters of the query appear in order in the name, ignoring
+// the case, and scores the best match: consecutive characters and characters starting a word
+// score higher, the longer names lower.
+func fuzzyScore(query string, name string) (int, bool) {
+	q := []rune(strings.ToLower(query))
+	n := []rune(strings.ToLower(name))
+	if len(q) == 0 {
+		return -len(n), true
+	}
+
+	best, found := 0, false
+	for start := range n {
+		if n[start] != q[0] {
+			continue
+		}
+
+		if score, ok := fuzzyScoreFrom(q, n, start); ok && (!found || score > best) {
+			best, found = score, true
+		}
+	}
+
+	return best*100 - len(n), found
+}
+
+// fuzzyScoreFrom matches the query greedily in the name from start.
+func fuzzyScoreFrom(q []rune, n []rune, start int) (int, bool) {
+	score, qi, prev := 0, 0, -2
+	for ni := start; ni < len(n) && qi < len(q); ni++ {
+		if n[ni] != q[qi] {
+			continue
+		}
+
+		score++
+		if ni == prev+1 {
+			score += 4
+		}
+		if ni == 0 || !unicode.IsLetter(n[ni-1]) && !unicode.IsDigit(n[ni-1]) {
+			score += 2
+		}
+
+		prev = ni
+		qi++
+	}
+
+	return score, qi == len(q)
+}
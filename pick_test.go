@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	iterator "github.com/jcchavezs/gh-iterator"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFuzzyMatches(t *testing.T) {
+	repos := []iterator.Repository{{Name: "acme/payments-api"}, {Name: "acme/api"}, {Name: "acme/web"}, {Name: "acme/apps-infra"}}
+
+	var names []string
+	for _, r := range fuzzyMatches("api", repos) {
+		names = append(names, r.Name)
+	}
+	require.Equal(t, []string{"acme/api", "acme/payments-api", "acme/apps-infra"}, names)
+
+	require.Empty(t, fuzzyMatches("xyz", repos))
+	require.Len(t, fuzzyMatches("", repos), 4)
+}
+
+func TestPickFuzzy(t *testing.T) {
+	repos := []iterator.Repository{{Name: "acme/payments-api"}, {Name: "acme/api"}, {Name: "acme/web"}}
+
+	out := &bytes.Buffer{}
+	picked, err := pickFuzzy(strings.NewReader("api\n2\n"), out, repos)
+	require.NoError(t, err)
+	require.Equal(t, []iterator.Repository{{Name: "acme/payments-api"}}, picked)
+	require.Contains(t, out.String(), "1\tacme/api\n2\tacme/payments-api\n")
+
+	// an empty selection or the end of the input picks nothing.
+	picked, err = pickFuzzy(strings.NewReader("web\n\n"), &bytes.Buffer{}, repos)
+	require.NoError(t, err)
+	require.Empty(t, picked)
+
+	picked, err = pickFuzzy(strings.NewReader("web\n"), &bytes.Buffer{}, repos)
+	require.NoError(t, err)
+	require.Empty(t, picked)
+
+	picked, err = pickFuzzy(strings.NewReader(""), &bytes.Buffer{}, repos)
+	require.NoError(t, err)
+	require.Empty(t, picked)
+
+	_, err = pickFuzzy(strings.NewReader("api\n7\n"), &bytes.Buffer{}, repos)
+	require.Error(t, err)
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+
+	iterator "github.com/jcchavezs/gh-iterator"
+	"github.com/jcchavezs/gh-iterator/exec"
+)
+
+// stageLimiter limits the number of repositories in a stage of the processing at the same
+// time. The nil limiter has no limit.
+type stageLimiter chan struct{}
+
+// cloneSlots limits the concurrent clones to --clone-concurrency, if passed.
+var cloneSlots stageLimiter
+
+func newStageLimiter(n int) stageLimiter {
+	return make(stageLimiter, n)
+}
+
+// acquire waits for a free slot in the stage and returns the function releasing it.
+func (l stageLimiter) acquire(ctx context.Context) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+
+	select {
+	case l <- struct{}{}:
+		return func() { <-l }, nil
+	case <-ctx.Done():
+		return nil, context.Cause(ctx)
+	}
+}
+
+// limit wraps the processor to run it once there is a free slot in the stage.
+func (l stageLimiter) limit(processor iterator.Processor) iterator.Processor {
+	return func(ctx context.Context, repository string, isEmpty bool, x exec.Execer) error {
+		release, err := l.acquire(ctx)
+		if err != nil {
+			return err
+		}
+		defer release()
+
+		return processor(ctx, repository, isEmpty, x)
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	iterator "github.com/jcchavezs/gh-iterator"
+	"github.com/jcchavezs/gh-iterator/exec"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStageLimiter(t *testing.T) {
+	release, err := stageLimiter(nil).acquire(context.Background())
+	require.NoError(t, err)
+	release()
+
+	l := newStageLimiter(1)
+	release, err = l.acquire(context.Background())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	cancel(errInterrupted)
+	_, err = l.acquire(ctx)
+	require.True(t, errors.Is(err, errInterrupted))
+
+	release()
+	release, err = l.acquire(context.Background())
+	require.NoError(t, err)
+	release()
+}
+
+func TestRunForRepositories_CloneConcurrency(t *testing.T) {
+	flags.cloneConcurrency = 2
+	t.Cleanup(func() { flags.cloneConcurrency = 0 })
+
+	var running, maxRunning atomic.Int32
+	err := runForRepositories(
+		context.Background(),
+		// the empty repositories are not cloned, the commands run in an empty directory.
+		[]iterator.Repository{{Name: "acme/a"}, {Name: "acme/b"}, {Name: "acme/c"}, {Name: "acme/d"}},
+		func(context.Context, string, bool, exec.Execer) error {
+			n := running.Add(1)
+			defer running.Add(-1)
+
+			for {
+				m := maxRunning.Load()
+				if n <= m || maxRunning.CompareAndSwap(m, n) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			return nil
+		},
+		newRunResults(),
+		runHooks{},
+		iterator.Options{LogHandler: slog.DiscardHandler, NumberOfWorkers: 1},
+	)
+	require.NoError(t, err)
+	require.Equal(t, int32(1), maxRunning.Load())
+	require.Nil(t, cloneSlots)
+}
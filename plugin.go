@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	osexec "os/exec"
+	"path/filepath"
+	"time"
+
+	iterator "github.com/jcchavezs/gh-iterator"
+	"github.com/jcchavezs/gh-iterator/exec"
+)
+
+// pluginResult is the JSON a processor plugin prints on stdout, all the fields are optional and
+// an empty output is a success.
+type pluginResult struct {
+	// Skipped is the reason the plugin skipped the repository, if so.
+	Skipped string `json:"skipped"`
+	// Error is the reason processing the repository failed, if so.
+	Error string `json:"error"`
+	// Findings are reported in the SARIF file.
+	Findings []finding `json:"findings"`
+}
+
+// resolvePlugin returns the absolute path of the processor plugin, looking it up in the PATH if
+// it is a name, as it runs in the clone directory.
+func resolvePlugin(name string) (string, error) {
+	path, err := osexec.LookPath(name)
+	if err != nil {
+		return "", fmt.Errorf("looking up processor: %w", err)
+	}
+
+	return filepath.Abs(path)
+}
+
+// runPlugin runs the processor plugin in the repository directory, passing the metadata of the
+// repository as JSON on stdin, and records its result. It returns whether the plugin skipped the
+// repository.
+func (p repoProcessor) runPlugin(ctx context.Context, x exec.Execer, repository string) (int, bool, error) {
+	repo, ok := repositoryFromContext(ctx)
+	if !ok {
+		repo = iterator.Repository{Name: repository}
+	}
+
+	input, err := json.Marshal(repo)
+	if err != nil {
+		return -1, false, fmt.Errorf("marshaling repository: %w", err)
+	}
+
+	start := time.Now()
+	res, err := x.RunWithStdin(ctx, bytes.NewReader(input), p.plugin)
+	if err != nil {
+		io.WriteString(p.stderr, res.Stderr)
+		return -1, false, fmt.Errorf("running processor: %w", err)
+	}
+
+	var out pluginResult
+	if err := decodePluginResult(res.Stdout, &out); err != nil {
+		io.WriteString(p.stderr, res.Stderr)
+		return res.ExitCode, false, err
+	}
+
+	p.results.update(repository, func(r *repoResult) {
+		r.CommandDuration = time.Since(start)
+		r.CommandRan = true
+		r.ExitCode = res.ExitCode
+		r.Stderr = truncateOutput(res.Stderr)
+		r.Skipped = out.Skipped
+		r.Findings = out.Findings
+	})
+
+	if out.Error != "" {
+		return res.ExitCode, false, fmt.Errorf("processor: %s", out.Error)
+	}
+
+	return res.ExitCode, out.Skipped != "", nil
+}
+
+// decodePluginResult decodes the stdout of the plugin into out, leaving it empty if there is no
+// output.
+func decodePluginResult(stdout string, out *pluginResult) error {
+	if len(bytes.TrimSpace([]byte(stdout))) == 0 {
+		return nil
+	}
+
+	if err := json.Unmarshal([]byte(stdout), out); err != nil {
+		return fmt.Errorf("decoding processor result: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	iterator "github.com/jcchavezs/gh-iterator"
+	"github.com/jcchavezs/gh-iterator/exec"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunPlugin(t *testing.T) {
+	plugin := filepath.Join(t.TempDir(), "plugin")
+	require.NoError(t, os.WriteFile(plugin, []byte(`#!/bin/sh
+input=$(cat)
+case "$input" in
+  *'"full_name":"acme/skipped"'*) echo '{"skipped": "not a service"}' ;;
+  *'"full_name":"acme/broken"'*) echo '{"error": "no go.mod"}'; exit 1 ;;
+  *) echo '{"findings": [{"path": "go.mod", "line": 3, "message": "outdated"}]}' ;;
+esac
+`), 0o755))
+
+	path, err := resolvePlugin(plugin)
+	require.NoError(t, err)
+
+	p := repoProcessor{plugin: path, stderr: io.Discard, results: newRunResults()}
+	x := exec.NewExecer(t.TempDir())
+
+	for _, name := range []string{"acme/a", "acme/skipped", "acme/broken"} {
+		ctx := withRepository(context.Background(), iterator.Repository{Name: name})
+		exitCode, skipped, err := p.runPlugin(ctx, x, name)
+
+		res, _ := p.results.get(name)
+		switch name {
+		case "acme/a":
+			require.NoError(t, err)
+			require.False(t, skipped)
+			require.Equal(t, []finding{{Path: "go.mod", Line: 3, Message: "outdated"}}, res.Findings)
+		case "acme/skipped":
+			require.NoError(t, err)
+			require.True(t, skipped)
+			require.Equal(t, "not a service", res.Skipped)
+		case "acme/broken":
+			require.EqualError(t, err, "processor: no go.mod")
+			require.Equal(t, 1, exitCode)
+		}
+	}
+
+	_, err = resolvePlugin(filepath.Join(t.TempDir(), "missing"))
+	require.Error(t, err)
+}
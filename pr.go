@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/jcchavezs/gh-iterator/exec"
+	"github.com/jcchavezs/gh-iterator/github"
+)
+
+// makePROptions creates the options for the pull requests out of the flags.
+func makePROptions() (github.PROptions, error) {
+	if flags.branchName == "" {
+		return github.PROptions{}, errors.New("--branch-name is required to create pull requests")
+	}
+
+	opts := github.PROptions{
+		Title: flags.prTitle,
+		Body:  flags.prBody,
+		Draft: flags.prDraft,
+	}
+
+	if flags.prBodyFile != "" {
+		if flags.prBody != "" {
+			return github.PROptions{}, errors.New("--pr-body and --pr-body-file are mutually exclusive")
+		}
+
+		body, err := os.ReadFile(flags.prBodyFile)
+		if err != nil {
+			return github.PROptions{}, fmt.Errorf("reading PR body file: %w", err)
+		}
+		opts.Body = string(body)
+	}
+
+	return opts, nil
+}
+
+// createPR commits the changes in the repository to a new branch, pushes it and opens
+// or updates the pull request. It returns the PR URL, empty if there are no changes, and
+// whether the PR is new.
+func createPR(ctx context.Context, x exec.Execer, branchName string, opts github.PROptions) (string, bool, error) {
+	hasChanges, err := github.HasChanges(ctx, x)
+	if err != nil {
+		return "", false, err
+	}
+
+	if !hasChanges {
+		x.Log(ctx, slog.LevelInfo, "No changes, skipping PR creation")
+		return "", false, nil
+	}
+
+	// the checked out ref is the base of the PR when --ref resolves to a branch, other refs
+	// (tags, commits) are not valid bases hence the PR targets the default branch.
+	var base string
+	if flags.ref != "" {
+		if base, err = checkedOutBranch(ctx, x); err != nil {
+			return "", false, err
+		}
+	}
+
+	if err := github.CheckoutNewBranch(ctx, x, branchName); err != nil {
+		return "", false, err
+	}
+
+	if base != "" {
+		// gh pr create uses the merge base configured for the branch as the base of the PR.
+		if _, err := x.RunX(ctx, "git", "config", "branch."+branchName+".gh-merge-base", base); err != nil {
+			return "", false, fmt.Errorf("setting PR base: %w", err)
+		}
+	}
+
+	commitMessage := flags.commitMessage
+	if commitMessage == "" {
+		commitMessage = opts.Title
+	}
+	if commitMessage == "" {
+		commitMessage = branchName
+	}
+
+	if _, err := commitChanges(ctx, x, commitMessage, true); err != nil {
+		return "", false, err
+	}
+
+	// the branch is recreated from the checked-out ref on every run hence we need to force
+	// the push when it already exists.
+	if err := github.Push(ctx, x, branchName, github.PushForce); err != nil {
+		return "", false, err
+	}
+
+	return github.CreatePRIfNotExist(ctx, x, opts)
+}
+
+// checkedOutBranch returns the branch checked out in the repository, empty in detached HEAD.
+func checkedOutBranch(ctx context.Context, x exec.Execer) (string, error) {
+	res, err := x.Run(ctx, "git", "symbolic-ref", "--quiet", "--short", "HEAD")
+	if err != nil {
+		return "", fmt.Errorf("getting checked out branch: %w", err)
+	}
+
+	if res.ExitCode != 0 {
+		return "", nil
+	}
+
+	return strings.TrimSpace(res.Stdout), nil
+}
+
+// remoteBranchExists returns true if the branch exists in the origin remote.
+func remoteBranchExists(ctx context.Context, x exec.Execer, branchName string) (bool, error) {
+	res, err := x.Run(ctx, "git", "ls-remote", "--exit-code", "--heads", "origin", branchName)
+	if err != nil {
+		return false, fmt.Errorf("checking remote branch: %w", err)
+	}
+
+	switch res.ExitCode {
+	case 0:
+		return true, nil
+	case 2:
+		return false, nil
+	default:
+		return false, fmt.Errorf("checking remote branch: exit code %d: %s", res.ExitCode, res.Stderr)
+	}
+}
+
+// openPRURL returns the URL of the open pull request for the branch in the repository,
+// empty if there is none.
+func openPRURL(ctx context.Context, x exec.Execer, repository string, branchName string) (string, error) {
+	res, err := exec.TrimStdout(x.RunX(ctx, "gh", "pr", "list",
+		"--repo", repository,
+		"--head", branchName,
+		"--state", "open",
+		"--json", "url",
+		"--jq", ".[0].url // empty",
+	))
+	if err != nil {
+		return "", fmt.Errorf("checking open PR: %w", github.ErrOrGHAPIErr(res, err))
+	}
+
+	return res, nil
+}
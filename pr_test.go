@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jcchavezs/gh-iterator/exec"
+	"github.com/jcchavezs/gh-iterator/github"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcess_CreatePR(t *testing.T) {
+	t.Setenv("SHELL", "/bin/sh")
+	t.Cleanup(func() { flags.command, flags.createPR, flags.branchName = "", false, "" })
+	flags.createPR, flags.branchName = true, "chore/notice"
+
+	calls := scriptedGH(t, `case "$1 $2" in
+"pr view") exit 1 ;;
+"pr create") echo https://github.com/acme/a/pull/1 ;;
+esac
+`)
+
+	process := func(t *testing.T, dir string) *prReport {
+		prs := &prReport{}
+		p := repoProcessor{stdout: io.Discard, stderr: io.Discard, results: newRunResults(), prs: prs, prOptions: github.PROptions{Title: "Add notice"}}
+		require.NoError(t, p.process(context.Background(), "acme/a", false, exec.NewExecer(dir)))
+		return prs
+	}
+
+	t.Run("changes", func(t *testing.T) {
+		dir, origin := newOriginClone(t)
+		flags.command = "echo hello > NOTICE"
+
+		prs := process(t, dir)
+		require.Equal(t, []prReportEntry{{Repository: "acme/a", URL: "https://github.com/acme/a/pull/1", Status: prStatusNew}}, prs.entries)
+		// the changes are committed with the title and pushed to the branch the PR is opened from.
+		require.Equal(t, "Add notice\n", gitOutput(t, origin, "log", "-1", "--format=%s", "chore/notice"))
+		args, err := os.ReadFile(calls)
+		require.NoError(t, err)
+		require.Contains(t, string(args), "pr create --title Add notice --fill")
+	})
+
+	t.Run("ref branch as base", func(t *testing.T) {
+		t.Cleanup(func() { flags.ref = "" })
+		flags.ref = "release/v2"
+		// the fake gh records the base configured for the branch of the PR.
+		base := filepath.Join(t.TempDir(), "base")
+		scriptedGH(t, `case "$1 $2" in
+"pr view") exit 1 ;;
+"pr create") git config --get branch.chore/notice.gh-merge-base > `+base+`; echo https://github.com/acme/a/pull/2 ;;
+esac
+`)
+		dir, _ := newOriginClone(t)
+		gitOutput(t, dir, "fetch", "origin", "release/v2")
+		gitOutput(t, dir, "checkout", "release/v2")
+		flags.command = "echo hello > NOTICE"
+
+		process(t, dir)
+		b, err := os.ReadFile(base)
+		require.NoError(t, err)
+		require.Equal(t, "release/v2\n", string(b))
+	})
+
+	t.Run("no changes", func(t *testing.T) {
+		require.NoError(t, os.Remove(calls))
+		dir, origin := newOriginClone(t)
+		flags.command = "true"
+
+		prs := process(t, dir)
+		require.Equal(t, []prReportEntry{{Repository: "acme/a", Status: prStatusSkipped}}, prs.entries)
+		require.Empty(t, gitOutput(t, origin, "branch", "--list", "chore/notice"))
+		require.NoFileExists(t, calls)
+	})
+}
+
+func TestProcess_SkipIfAlreadyDone(t *testing.T) {
+	t.Setenv("SHELL", "/bin/sh")
+	t.Cleanup(func() {
+		flags.command, flags.branchName, flags.skipIfBranchExists, flags.skipIfPROpen = "", "", false, false
+	})
+	flags.command = "echo hello > NOTICE"
+
+	process := func(t *testing.T, dir string) repoResult {
+		p := repoProcessor{stdout: io.Discard, stderr: io.Discard, results: newRunResults(), prs: &prReport{}}
+		require.NoError(t, p.process(context.Background(), "acme/a", false, exec.NewExecer(dir)))
+		res, _ := p.results.get("acme/a")
+		return res
+	}
+
+	t.Run("branch exists", func(t *testing.T) {
+		flags.skipIfBranchExists, flags.skipIfPROpen = true, false
+		dir, _ := newOriginClone(t)
+
+		flags.branchName = "release/v2"
+		res := process(t, dir)
+		require.Equal(t, "branch already exists", res.Skipped)
+		require.False(t, res.CommandRan)
+
+		flags.branchName = "chore/notice"
+		res = process(t, dir)
+		require.Empty(t, res.Skipped)
+		require.True(t, res.CommandRan)
+	})
+
+	t.Run("PR open", func(t *testing.T) {
+		flags.skipIfBranchExists, flags.skipIfPROpen, flags.branchName = false, true, "chore/notice"
+		scriptedGH(t, `case "$*" in
+*"--head chore/notice"*) echo https://github.com/acme/a/pull/1 ;;
+esac
+`)
+
+		res := process(t, t.TempDir())
+		require.Equal(t, "PR already open", res.Skipped)
+		require.Equal(t, "https://github.com/acme/a/pull/1", res.PRURL)
+		require.False(t, res.CommandRan)
+
+		flags.branchName = "chore/other"
+		res = process(t, t.TempDir())
+		require.Empty(t, res.Skipped)
+		require.True(t, res.CommandRan)
+	})
+}
+
+func TestProcess_CreatePRDraft(t *testing.T) {
+	t.Setenv("SHELL", "/bin/sh")
+	saved := flags
+	t.Cleanup(func() { flags = saved })
+	flags.createPR, flags.branchName, flags.prTitle, flags.prDraft = true, "chore/notice", "Add notice", true
+	flags.command = "echo hello > NOTICE"
+
+	opts, err := makePROptions()
+	require.NoError(t, err)
+	require.True(t, opts.Draft)
+
+	process := func(t *testing.T) {
+		dir, _ := newOriginClone(t)
+		p := repoProcessor{stdout: io.Discard, stderr: io.Discard, results: newRunResults(), prs: &prReport{}, prOptions: opts}
+		require.NoError(t, p.process(context.Background(), "acme/a", false, exec.NewExecer(dir)))
+	}
+
+	t.Run("new PR", func(t *testing.T) {
+		calls := scriptedGH(t, `case "$1 $2" in
+"pr view") exit 1 ;;
+"pr create") echo https://github.com/acme/a/pull/1 ;;
+esac
+`)
+		process(t)
+		args, err := os.ReadFile(calls)
+		require.NoError(t, err)
+		require.Contains(t, string(args), "pr create --draft --title Add notice --fill")
+	})
+
+	t.Run("existing PR marked as draft", func(t *testing.T) {
+		calls := scriptedGH(t, `case "$1 $2" in
+"pr view") echo '{"url":"https://github.com/acme/a/pull/1","state":"OPEN","isDraft":false}' ;;
+esac
+`)
+		process(t)
+		args, err := os.ReadFile(calls)
+		require.NoError(t, err)
+		require.Contains(t, string(args), "pr ready https://github.com/acme/a/pull/1 --undo")
+	})
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	osexec "os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jcchavezs/gh-iterator/exec"
+	"github.com/spf13/afero"
+)
+
+// groupExecer runs the commands like the execer of gh-iterator but each in its own process
+// group, so the interrupt sent by the terminal to the run does not reach them and the
+// repositories being processed finish while the run drains. The group is killed once the
+// context is cancelled, on the second interrupt or when the grace period passes.
+type groupExecer struct {
+	dir    string
+	logger *slog.Logger
+	env    []string
+}
+
+// newExecer creates the execer running the commands in dir in their own process group.
+func newExecer(dir string, logger *slog.Logger) exec.Execer {
+	return groupExecer{dir: dir, logger: logger}
+}
+
+func (e groupExecer) Run(ctx context.Context, command string, args ...string) (exec.Result, error) {
+	return e.RunWithStdin(ctx, nil, command, args...)
+}
+
+func (e groupExecer) RunX(ctx context.Context, command string, args ...string) (string, error) {
+	return e.RunWithStdinX(ctx, nil, command, args...)
+}
+
+func (e groupExecer) RunWithStdin(ctx context.Context, stdin io.Reader, command string, args ...string) (exec.Result, error) {
+	cmdS := strings.Join(append([]string{command}, args...), " ")
+
+	// the commands are not started once the run is cancelled.
+	if err := ctx.Err(); err != nil {
+		return exec.Result{ExitCode: -1, Cancelled: errors.Is(err, context.Canceled)}, fmt.Errorf("%s: %w", cmdS, err)
+	}
+
+	c := osexec.CommandContext(ctx, command, args...)
+	c.Dir = e.dir
+	c.Env = mergeEnv(os.Environ(), e.env)
+	c.Stdin = stdin
+	var stdout, stderr bytes.Buffer
+	c.Stdout = &stdout
+	c.Stderr = &stderr
+	// the processes left behind holding the output don't block the run once it is cancelled.
+	c.WaitDelay = time.Second
+	startInProcessGroup(c)
+
+	e.logger.Debug("Executing command", "command", cmdS)
+	if err := c.Start(); err != nil {
+		return exec.Result{}, fmt.Errorf("%s: %w", cmdS, err)
+	}
+
+	exitCode := 0
+	var exitErr *osexec.ExitError
+	if err := c.Wait(); errors.As(err, &exitErr) {
+		exitCode = exitErr.ExitCode()
+	}
+
+	res := exec.Result{Stdout: stdout.String(), Stderr: stderr.String(), ExitCode: exitCode, Cancelled: errors.Is(ctx.Err(), context.Canceled)}
+	if err := ctx.Err(); err != nil {
+		return res, fmt.Errorf("%s: %w", cmdS, err)
+	}
+
+	return res, nil
+}
+
+func (e groupExecer) RunWithStdinX(ctx context.Context, stdin io.Reader, command string, args ...string) (string, error) {
+	res, err := e.RunWithStdin(ctx, stdin, command, args...)
+	return resultX(res, err, command, args)
+}
+
+func (e groupExecer) Log(ctx context.Context, level slog.Level, msg string, fields ...any) {
+	e.logger.Log(ctx, level, msg, fields...)
+}
+
+// DebugShell starts the shell of gh-iterator, which needs the terminal so it is not run in its
+// own process group.
+func (e groupExecer) DebugShell(ctx context.Context) {
+	var kv []string
+	for _, v := range e.env {
+		name, value, _ := strings.Cut(v, "=")
+		kv = append(kv, name, value)
+	}
+
+	exec.NewExecerWithLogger(e.dir, e.logger).WithEnv(kv...).DebugShell(ctx)
+}
+
+func (e groupExecer) WithEnv(kv ...string) exec.Execer {
+	env := e.env[:len(e.env):len(e.env)]
+	for i := 0; i+1 < len(kv); i += 2 {
+		env = append(env, kv[i]+"="+kv[i+1])
+	}
+
+	return groupExecer{dir: e.dir, logger: e.logger, env: env}
+}
+
+func (e groupExecer) WithLogFields(kvFields ...any) exec.Execer {
+	return groupExecer{dir: e.dir, logger: e.logger.With(kvFields...), env: e.env}
+}
+
+func (e groupExecer) Sub(subpath string) (exec.Execer, error) {
+	dir := filepath.Join(e.dir, subpath)
+	if info, err := os.Stat(dir); err != nil {
+		return nil, err
+	} else if !info.IsDir() {
+		return nil, fmt.Errorf("subpath %s is not a directory", dir)
+	}
+
+	return groupExecer{dir: dir, logger: e.logger, env: e.env}, nil
+}
+
+func (e groupExecer) GenerateFS() afero.Fs {
+	return afero.NewBasePathFs(afero.NewOsFs(), e.dir)
+}
+
+// mergeEnv returns environ with the variables in env, which take precedence.
+func mergeEnv(environ []string, env []string) []string {
+	if len(env) == 0 {
+		return nil
+	}
+
+	overridden := map[string]bool{}
+	for _, kv := range env {
+		name, _, _ := strings.Cut(kv, "=")
+		overridden[name] = true
+	}
+
+	merged := env[:len(env):len(env)]
+	for _, kv := range environ {
+		if name, _, _ := strings.Cut(kv, "="); !overridden[name] {
+			merged = append(merged, kv)
+		}
+	}
+
+	return merged
+}
+
+// resultX returns the stdout of the result like the X variants of the execer methods, failing
+// when the command exits with a non zero code.
+func resultX(res exec.Result, err error, command string, args []string) (string, error) {
+	if err != nil {
+		return "", err
+	}
+
+	if res.ExitCode != 0 {
+		return res.Stdout, exec.NewExecErr(
+			fmt.Sprintf("%s: exit code %d", strings.Join(append([]string{command}, args...), " "), res.ExitCode),
+			res.Stderr, res.ExitCode,
+		)
+	}
+
+	return res.Stdout, nil
+}
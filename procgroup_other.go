@@ -0,0 +1,8 @@
+//go:build !unix
+
+package main
+
+import osexec "os/exec"
+
+// startInProcessGroup is a no-op where there are no process groups.
+func startInProcessGroup(*osexec.Cmd) {}
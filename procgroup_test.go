@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/jcchavezs/gh-iterator/exec"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGroupExecer(t *testing.T) {
+	t.Setenv("GH_ITERATOR_TEST_INHERITED", "inherited")
+	x := newExecer(t.TempDir(), slog.New(slog.DiscardHandler)).WithEnv("GH_ITERATOR_REPOSITORY", "acme/a")
+
+	out, err := x.RunX(context.Background(), "sh", "-c", `echo "$GH_ITERATOR_REPOSITORY $GH_ITERATOR_TEST_INHERITED"`)
+	require.NoError(t, err)
+	require.Equal(t, "acme/a inherited\n", out)
+
+	res, err := x.Run(context.Background(), "sh", "-c", "echo failed >&2; exit 3")
+	require.NoError(t, err)
+	require.Equal(t, 3, res.ExitCode)
+
+	_, err = x.RunX(context.Background(), "sh", "-c", "echo failed >&2; exit 3")
+	stderr, ok := exec.GetStderr(err)
+	require.True(t, ok)
+	require.Equal(t, "failed\n", stderr)
+}
+
+func TestGroupExecer_ProcessGroup(t *testing.T) {
+	if _, err := os.Stat("/proc/self/stat"); err != nil {
+		t.Skip("no /proc")
+	}
+
+	x := newExecer(t.TempDir(), slog.New(slog.DiscardHandler))
+
+	// the interrupt of the terminal is sent to the process group of the run.
+	stat, err := x.RunX(context.Background(), "cat", "/proc/self/stat")
+	require.NoError(t, err)
+	pgid, err := strconv.Atoi(strings.Fields(stat[strings.LastIndex(stat, ")")+1:])[2])
+	require.NoError(t, err)
+	require.NotEqual(t, syscall.Getpgrp(), pgid)
+}
+
+func TestGroupExecer_Cancel(t *testing.T) {
+	dir := t.TempDir()
+	x := newExecer(dir, slog.New(slog.DiscardHandler))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	// the processes started by the command are killed along with it.
+	_, err := x.Run(ctx, "sh", "-c", "sleep 30 & echo $! > pid; wait")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	content, err := os.ReadFile(filepath.Join(dir, "pid"))
+	require.NoError(t, err)
+	pid, err := strconv.Atoi(strings.TrimSpace(string(content)))
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return syscall.Kill(pid, 0) != nil }, 5*time.Second, 10*time.Millisecond)
+
+	_, err = x.Run(ctx, "true")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -0,0 +1,17 @@
+//go:build unix
+
+package main
+
+import (
+	osexec "os/exec"
+	"syscall"
+)
+
+// startInProcessGroup makes the command start in its own process group and kills the whole
+// group, including the processes it started, when its context is cancelled.
+func startInProcessGroup(c *osexec.Cmd) {
+	c.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	c.Cancel = func() error {
+		return syscall.Kill(-c.Process.Pid, syscall.SIGKILL)
+	}
+}
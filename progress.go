@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	iterator "github.com/jcchavezs/gh-iterator"
+)
+
+// progress renders the number of processed repositories, the failures and the estimated time
+// left in a single line that is rewritten as the repositories are processed.
+type progress struct {
+	mu    sync.Mutex
+	w     io.Writer
+	total int
+	done  int
+	// failed are the repositories whose processing or command failed.
+	failed map[string]bool
+	start  time.Time
+}
+
+func newProgress(w io.Writer, total int) *progress {
+	p := &progress{w: w, total: total, start: time.Now(), failed: map[string]bool{}}
+	p.render()
+	return p
+}
+
+// isTerminal tells whether w is a terminal.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// hooks returns the hooks updating the progress as the repositories are processed.
+func (p *progress) hooks() runHooks {
+	return runHooks{
+		OnRepoFinished: func(repo iterator.Repository, res repoResult, err error) {
+			p.repoDone(repo.Name, err != nil || res.failed())
+		},
+		OnRepoSkipped: func(repo iterator.Repository, _ string) { p.repoDone(repo.Name, false) },
+		OnRunFinished: func(runTotals, error) { p.finish() },
+	}
+}
+
+// addTotal adds n repositories to the total, for the repositories processed as they are listed.
+func (p *progress) addTotal(n int) {
+	p.mu.Lock()
+	p.total += n
+	p.mu.Unlock()
+
+	p.render()
+}
+
+// repoDone counts the repository as done, and as failed if so.
+func (p *progress) repoDone(repository string, failed bool) {
+	p.mu.Lock()
+	p.done++
+	if failed {
+		p.failed[repository] = true
+	}
+	p.mu.Unlock()
+
+	p.render()
+}
+
+func (p *progress) render() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	fmt.Fprintf(p.w, "\r\033[K%s", p.line(time.Since(p.start)))
+}
+
+// line returns the progress line after elapsed time.
+func (p *progress) line(elapsed time.Duration) string {
+	line := fmt.Sprintf("[%d/%d] %d failed", p.done, p.total, len(p.failed))
+	if p.done > 0 && p.done < p.total {
+		eta := elapsed / time.Duration(p.done) * time.Duration(p.total-p.done)
+		line += ", ETA " + eta.Round(time.Second).String()
+	}
+
+	return line
+}
+
+// finish ends the progress line.
+func (p *progress) finish() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	fmt.Fprintln(p.w)
+}
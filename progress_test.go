@@ -0,0 +1,36 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	iterator "github.com/jcchavezs/gh-iterator"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProgressLine(t *testing.T) {
+	p := &progress{total: 10, failed: map[string]bool{}}
+	require.Equal(t, "[0/10] 0 failed", p.line(0))
+
+	p.done, p.failed["acme/a"] = 4, true
+	require.Equal(t, "[4/10] 1 failed, ETA 1m30s", p.line(time.Minute))
+
+	p.done = 10
+	require.Equal(t, "[10/10] 1 failed", p.line(2*time.Minute))
+}
+
+func TestProgressHooks(t *testing.T) {
+	out := &bytes.Buffer{}
+	hooks := newProgress(out, 3).hooks()
+	require.Contains(t, out.String(), "[0/3] 0 failed")
+
+	hooks.repoDone(iterator.Repository{Name: "acme/a"}, repoResult{CommandRan: true, ExitCode: 1}, nil)
+	hooks.repoDone(iterator.Repository{Name: "acme/b"}, repoResult{}, errors.New("failed"))
+	hooks.repoDone(iterator.Repository{Name: "acme/c"}, repoResult{Skipped: "ref not found"}, nil)
+	hooks.runFinished(runTotals{}, nil)
+
+	require.True(t, strings.HasSuffix(out.String(), "[3/3] 2 failed\n"))
+}
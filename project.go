@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jcchavezs/gh-iterator/exec"
+	"github.com/jcchavezs/gh-iterator/github"
+)
+
+// projectRef is a GitHub Project (v2) passed as OWNER/NUMBER.
+type projectRef struct {
+	Owner  string
+	Number int
+}
+
+func parseProjectRef(s string) (projectRef, error) {
+	owner, number, ok := strings.Cut(s, "/")
+	n, err := strconv.Atoi(number)
+	if !ok || owner == "" || err != nil || n <= 0 {
+		return projectRef{}, fmt.Errorf("invalid project %q, expected OWNER/NUMBER e.g. acme/12", s)
+	}
+
+	return projectRef{Owner: owner, Number: n}, nil
+}
+
+// addProjectItem adds the issue or pull request to the project, adding an existing one is a
+// no-op.
+func addProjectItem(ctx context.Context, x exec.Execer, p projectRef, url string) error {
+	res, err := x.RunX(ctx, "gh", "project", "item-add", strconv.Itoa(p.Number),
+		"--owner", p.Owner,
+		"--url", url,
+	)
+	if err != nil {
+		return fmt.Errorf("adding project item: %w", github.ErrOrGHAPIErr(res, err))
+	}
+
+	return nil
+}
+
+// addProjectDraft adds a draft issue titled with the repository name to the project, as
+// repositories can't be project items.
+func addProjectDraft(ctx context.Context, x exec.Execer, p projectRef, repository string, body string) error {
+	res, err := x.RunX(ctx, "gh", "project", "item-create", strconv.Itoa(p.Number),
+		"--owner", p.Owner,
+		"--title", repository,
+		"--body", body,
+	)
+	if err != nil {
+		return fmt.Errorf("creating project draft item: %w", github.ErrOrGHAPIErr(res, err))
+	}
+
+	return nil
+}
+
+// exportToProject adds the pull request created for the repository to the project passed in
+// --export-project or, when there is none, a draft issue for the repository.
+func (p repoProcessor) exportToProject(ctx context.Context, x exec.Execer, repository string) error {
+	if res, _ := p.results.get(repository); res.PRURL != "" {
+		return addProjectItem(ctx, x, *p.project, res.PRURL)
+	}
+
+	var body string
+	if repo, ok := repositoryFromContext(ctx); ok {
+		body = strings.TrimSuffix(repo.URL, ".git")
+	}
+
+	return addProjectDraft(ctx, x, *p.project, repository, body)
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"testing"
+
+	iterator "github.com/jcchavezs/gh-iterator"
+	"github.com/jcchavezs/gh-iterator/exec"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseProjectRef(t *testing.T) {
+	p, err := parseProjectRef("acme/12")
+	require.NoError(t, err)
+	require.Equal(t, projectRef{Owner: "acme", Number: 12}, p)
+
+	for _, s := range []string{"acme", "acme/", "/12", "acme/x", "acme/0"} {
+		_, err := parseProjectRef(s)
+		require.Error(t, err, s)
+	}
+}
+
+func TestExportToProject(t *testing.T) {
+	calls := scriptedGH(t, "")
+
+	p := repoProcessor{project: &projectRef{Owner: "acme", Number: 12}, results: newRunResults()}
+	p.results.addRepositories([]iterator.Repository{{Name: "acme/a"}, {Name: "acme/b"}}, nil, nil)
+	p.results.update("acme/a", func(r *repoResult) { r.PRURL = "https://github.com/acme/a/pull/1" })
+
+	x := exec.NewExecerWithLogger(t.TempDir(), slog.New(slog.DiscardHandler))
+	ctx := withRepository(context.Background(), iterator.Repository{Name: "acme/b", URL: "https://github.com/acme/b.git"})
+
+	require.NoError(t, p.exportToProject(ctx, x, "acme/a"))
+	require.NoError(t, p.exportToProject(ctx, x, "acme/b"))
+
+	content, err := os.ReadFile(calls)
+	require.NoError(t, err)
+	require.Equal(t, "project item-add 12 --owner acme --url https://github.com/acme/a/pull/1\n"+
+		"project item-create 12 --owner acme --title acme/b --body https://github.com/acme/b\n", string(content))
+}
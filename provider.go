@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+
+	iterator "github.com/jcchavezs/gh-iterator"
+	"github.com/jcchavezs/gh-iterator/exec"
+	"github.com/jcchavezs/gh-iterator/github"
+)
+
+// provider is the forge hosting the repositories. The pipeline lists the repositories, fetches
+// their metadata and opens the pull requests through it, so other forges can be supported by
+// registering a provider.
+type provider interface {
+	// ListRepositories lists the pages of the repositories of the owner.
+	ListRepositories(ctx context.Context, x exec.Execer, owner string, pages []iterator.Page) ([]iterator.Repository, error)
+	// FetchRepository fetches the repository by its full name e.g. acme/api.
+	FetchRepository(ctx context.Context, x exec.Execer, name string) (iterator.Repository, error)
+	// CreatePR opens, or updates, the pull request for the branch pushed from the clone x runs
+	// in. It returns the URL of the pull request and whether it was created.
+	CreatePR(ctx context.Context, x exec.Execer, branchName string, opts github.PROptions) (string, bool, error)
+	// OpenPRURL returns the URL of the open pull request of the branch, empty if there is none.
+	OpenPRURL(ctx context.Context, x exec.Execer, repository string, branchName string) (string, error)
+}
+
+// pageLister is implemented by the providers listing the repositories of an owner page by page,
+// so the run can process them as the pages arrive instead of waiting for all of them.
+type pageLister interface {
+	// ListRepositoryPages calls yield with the repositories of each of the pages of the owner.
+	ListRepositoryPages(ctx context.Context, x exec.Execer, owner string, pages []iterator.Page, yield func([]iterator.Repository) error) error
+}
+
+// providers are the registered providers by name.
+var providers = map[string]provider{}
+
+// registerProvider makes the provider available in --provider under the name.
+func registerProvider(name string, p provider) {
+	if _, ok := providers[name]; ok {
+		panic(fmt.Sprintf("provider %q already registered", name))
+	}
+
+	providers[name] = p
+}
+
+func init() {
+	registerProvider("github", githubProvider{})
+}
+
+// forge is the provider passed in --provider.
+var forge provider = githubProvider{}
+
+// setupProvider sets the forge to the provider registered under the name.
+func setupProvider(name string) error {
+	p, ok := providers[name]
+	if !ok {
+		return fmt.Errorf("unknown provider %q, available: %s", name, strings.Join(slices.Sorted(maps.Keys(providers)), ", "))
+	}
+
+	forge = p
+	return nil
+}
+
+// githubProvider lists the repositories with the GitHub REST or GraphQL API as passed by flag
+// and opens the pull requests with gh.
+type githubProvider struct{}
+
+func (githubProvider) ListRepositories(ctx context.Context, x exec.Execer, owner string, pages []iterator.Page) ([]iterator.Repository, error) {
+	if query := ownerSearchQuery(owner); query != "" {
+		if flags.graphql {
+			return nil, errors.New("--topic, --visibility and --no-forks can't be used with --graphql")
+		}
+
+		// the repositories are narrowed down by the search API instead of listing all of them.
+		repos, err := listPages(pages, func(page iterator.Page) ([]iterator.Repository, error) {
+			return searchRepositories(ctx, x, query, page)
+		})
+		if len(repos) >= maxSearchResults {
+			x.Log(ctx, slog.LevelWarn, "The search API returns up to 1000 repositories, some may be missing", "query", query)
+		}
+		return repos, err
+	}
+
+	allPages := slices.Equal(pages, []iterator.Page{iterator.AllPages})
+	if flags.graphql {
+		if !allPages {
+			return nil, errors.New("--page can't be used with --graphql")
+		}
+		return listRepositoriesGraphQL(ctx, x, owner, flags.perPage)
+	}
+
+	// the owner type and login are resolved once for all the pages.
+	target, err := listTarget(ctx, x, owner, flags.ownerType, flags.perPage)
+	if err != nil {
+		return nil, err
+	}
+
+	if allPages && flags.pageConcurrency > 1 {
+		return listRepositoriesConcurrently(ctx, x, target, flags.pageConcurrency)
+	}
+
+	return listPages(pages, func(page iterator.Page) ([]iterator.Repository, error) {
+		return listRepositories(ctx, x, target, page)
+	})
+}
+
+// ListRepositoryPages lists the pages of the REST API one at a time. The search and GraphQL
+// listings and the pages fetched concurrently are yielded at once.
+func (p githubProvider) ListRepositoryPages(ctx context.Context, x exec.Execer, owner string, pages []iterator.Page, yield func([]iterator.Repository) error) error {
+	allPages := slices.Equal(pages, []iterator.Page{iterator.AllPages})
+	if ownerSearchQuery(owner) != "" || flags.graphql || (allPages && flags.pageConcurrency > 1) {
+		repos, err := p.ListRepositories(ctx, x, owner, pages)
+		if err != nil {
+			return err
+		}
+
+		return yield(repos)
+	}
+
+	target, err := listTarget(ctx, x, owner, flags.ownerType, flags.perPage)
+	if err != nil {
+		return err
+	}
+
+	for i := 0; allPages || i < len(pages); i++ {
+		page := iterator.PageN(i + 1)
+		if !allPages {
+			page = pages[i]
+		}
+
+		repos, err := listRepositories(ctx, x, target, page)
+		if err != nil || len(repos) == 0 {
+			return err
+		}
+
+		if err := yield(repos); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (githubProvider) FetchRepository(ctx context.Context, x exec.Execer, name string) (iterator.Repository, error) {
+	return fetchRepository(ctx, x, name)
+}
+
+func (githubProvider) CreatePR(ctx context.Context, x exec.Execer, branchName string, opts github.PROptions) (string, bool, error) {
+	return createPR(ctx, x, branchName, opts)
+}
+
+func (githubProvider) OpenPRURL(ctx context.Context, x exec.Execer, repository string, branchName string) (string, error) {
+	return openPRURL(ctx, x, repository, branchName)
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+
+	iterator "github.com/jcchavezs/gh-iterator"
+	"github.com/jcchavezs/gh-iterator/exec"
+	"github.com/jcchavezs/gh-iterator/github"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeProvider struct {
+	repos map[string][]iterator.Repository
+}
+
+func (p fakeProvider) ListRepositories(_ context.Context, _ exec.Execer, owner string, _ []iterator.Page) ([]iterator.Repository, error) {
+	return p.repos[owner], nil
+}
+
+func (fakeProvider) FetchRepository(_ context.Context, _ exec.Execer, name string) (iterator.Repository, error) {
+	return iterator.Repository{Name: name}, nil
+}
+
+func (fakeProvider) CreatePR(context.Context, exec.Execer, string, github.PROptions) (string, bool, error) {
+	return "", false, nil
+}
+
+func (fakeProvider) OpenPRURL(context.Context, exec.Execer, string, string) (string, error) {
+	return "", nil
+}
+
+func TestSetupProvider(t *testing.T) {
+	t.Cleanup(func() {
+		delete(providers, "fake")
+		forge = githubProvider{}
+	})
+
+	registerProvider("fake", fakeProvider{repos: map[string][]iterator.Repository{
+		"acme": {{Name: "acme/a"}, {Name: "acme/b"}},
+	}})
+	require.Panics(t, func() { registerProvider("fake", fakeProvider{}) })
+
+	require.EqualError(t, setupProvider("bitbucket"), `unknown provider "bitbucket", available: fake, github`)
+
+	require.NoError(t, setupProvider("fake"))
+	repos, err := collectRepositories(context.Background(), exec.NewExecerWithLogger(".", slog.New(slog.DiscardHandler)), []string{"acme"}, nil, []iterator.Page{iterator.AllPages})
+	require.NoError(t, err)
+	require.Equal(t, []iterator.Repository{{Name: "acme/a"}, {Name: "acme/b"}}, repos)
+}
+
+func TestGithubProvider_ServerSideFilters(t *testing.T) {
+	t.Cleanup(func() { flags.topics, flags.visibility, flags.noForks = nil, VisibilityAll, false })
+	require.Empty(t, ownerSearchQuery("acme"))
+
+	flags.topics, flags.visibility = []string{"payments", "go"}, VisibilityInternal
+	require.Equal(t, "user:acme topic:payments topic:go is:internal fork:true", ownerSearchQuery("acme"))
+
+	flags.noForks = true
+	require.Equal(t, "user:acme topic:payments topic:go is:internal", ownerSearchQuery("acme"))
+
+	calls := scriptedGH(t, `echo '[{"full_name":"acme/a","default_branch":"main","size":3}]'`)
+	x := exec.NewExecerWithLogger(t.TempDir(), slog.New(slog.DiscardHandler))
+
+	repos, err := githubProvider{}.ListRepositories(context.Background(), x, "acme", []iterator.Page{iterator.AllPages})
+	require.NoError(t, err)
+	require.Len(t, repos, 1)
+
+	content, err := os.ReadFile(calls)
+	require.NoError(t, err)
+	require.Contains(t, string(content), "-f q=user:acme topic:payments topic:go is:internal ")
+	require.Contains(t, string(content), "/search/repositories?per_page=100\n")
+}
+
+func TestGithubProvider_ResolvesOwnerOnce(t *testing.T) {
+	calls := scriptedGH(t, `case "$*" in
+*"/users/acme --jq .type"*) echo Organization ;;
+*) echo '[{"full_name":"acme/a"}]' ;;
+esac
+`)
+
+	x := exec.NewExecerWithLogger(t.TempDir(), slog.New(slog.DiscardHandler))
+	repos, err := githubProvider{}.ListRepositories(context.Background(), x, "acme", []iterator.Page{1, 2, 3})
+	require.NoError(t, err)
+	require.Len(t, repos, 3)
+
+	content, err := os.ReadFile(calls)
+	require.NoError(t, err)
+	require.Equal(t, 1, strings.Count(string(content), "/users/acme --jq .type"))
+	require.Equal(t, 3, strings.Count(string(content), "/orgs/acme/repos?per_page="))
+}
+
+func TestGithubProvider_ListRepositoryPages(t *testing.T) {
+	scriptedGH(t, `case "$*" in
+*"/users/acme --jq .type"*) echo Organization ;;
+*"&page=1"*) echo '[{"full_name":"acme/a"},{"full_name":"acme/b"}]' ;;
+*"&page=2"*) echo '[{"full_name":"acme/c"}]' ;;
+*) echo '[]' ;;
+esac
+`)
+
+	x := exec.NewExecerWithLogger(t.TempDir(), slog.New(slog.DiscardHandler))
+
+	var pages [][]iterator.Repository
+	err := githubProvider{}.ListRepositoryPages(context.Background(), x, "acme", []iterator.Page{iterator.AllPages}, func(repos []iterator.Repository) error {
+		pages = append(pages, repos)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, [][]iterator.Repository{
+		{{Name: "acme/a"}, {Name: "acme/b"}},
+		{{Name: "acme/c"}},
+	}, pages)
+
+	// the listing stops when yield fails.
+	errStop := errors.New("stop")
+	err = githubProvider{}.ListRepositoryPages(context.Background(), x, "acme", []iterator.Page{iterator.AllPages}, func([]iterator.Repository) error {
+		return errStop
+	})
+	require.ErrorIs(t, err, errStop)
+}
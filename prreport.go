@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"text/tabwriter"
+)
+
+// prStatus is the outcome of the pull request creation for a repository.
+type prStatus string
+
+const (
+	prStatusNew     prStatus = "new"
+	prStatusUpdated prStatus = "updated"
+	prStatusSkipped prStatus = "skipped"
+)
+
+type prReportEntry struct {
+	Repository string   `json:"repository"`
+	URL        string   `json:"url,omitempty"`
+	Status     prStatus `json:"status"`
+	// Checks is the outcome of the PR checks when waiting for them.
+	Checks string `json:"checks,omitempty"`
+}
+
+// prReport collects the pull requests created or updated during the run.
+type prReport struct {
+	mu      sync.Mutex
+	entries []prReportEntry
+}
+
+func (r *prReport) add(e prReportEntry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.entries = append(r.entries, e)
+}
+
+// sortedEntries returns the entries sorted by repository.
+func (r *prReport) sortedEntries() []prReportEntry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entries := slices.Clone(r.entries)
+	slices.SortFunc(entries, func(a, b prReportEntry) int {
+		return strings.Compare(a.Repository, b.Repository)
+	})
+
+	return entries
+}
+
+// writeTable prints the report as a table.
+func (r *prReport) writeTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "REPOSITORY\tSTATUS\tCHECKS\tPR")
+	for _, e := range r.sortedEntries() {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", e.Repository, e.Status, e.Checks, e.URL)
+	}
+
+	return tw.Flush()
+}
+
+// writeFile writes the report in path as markdown if the extension is .md, otherwise as JSON.
+func (r *prReport) writeFile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("creating PR report: %w", err)
+	}
+	defer f.Close() //nolint:errcheck
+
+	entries := r.sortedEntries()
+
+	if filepath.Ext(path) == ".md" {
+		fmt.Fprintln(f, "| Repository | Status | Checks | PR |")
+		fmt.Fprintln(f, "| --- | --- | --- | --- |")
+		for _, e := range entries {
+			fmt.Fprintf(f, "| %s | %s | %s | %s |\n", e.Repository, e.Status, e.Checks, e.URL)
+		}
+		return nil
+	}
+
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(entries); err != nil {
+		return fmt.Errorf("writing PR report: %w", err)
+	}
+
+	return nil
+}
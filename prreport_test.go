@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPRReport(t *testing.T) {
+	r := &prReport{}
+	r.add(prReportEntry{Repository: "acme/b", URL: "https://github.com/acme/b/pull/2", Status: prStatusUpdated, Checks: checksFail})
+	r.add(prReportEntry{Repository: "acme/a", URL: "https://github.com/acme/a/pull/1", Status: prStatusNew, Checks: checksPass})
+	r.add(prReportEntry{Repository: "acme/c", Status: prStatusSkipped})
+
+	t.Run("table", func(t *testing.T) {
+		var out bytes.Buffer
+		require.NoError(t, r.writeTable(&out))
+		require.Equal(t, `REPOSITORY  STATUS   CHECKS  PR
+acme/a      new      pass    https://github.com/acme/a/pull/1
+acme/b      updated  fail    https://github.com/acme/b/pull/2
+acme/c      skipped          
+`, out.String())
+	})
+
+	t.Run("markdown", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "prs.md")
+		require.NoError(t, r.writeFile(path))
+
+		content, err := os.ReadFile(path)
+		require.NoError(t, err)
+		require.Contains(t, string(content), "| acme/a | new | pass | https://github.com/acme/a/pull/1 |\n")
+	})
+
+	t.Run("json", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "prs.json")
+		require.NoError(t, r.writeFile(path))
+
+		content, err := os.ReadFile(path)
+		require.NoError(t, err)
+		require.JSONEq(t, `[
+			{"repository": "acme/a", "url": "https://github.com/acme/a/pull/1", "status": "new", "checks": "pass"},
+			{"repository": "acme/b", "url": "https://github.com/acme/b/pull/2", "status": "updated", "checks": "fail"},
+			{"repository": "acme/c", "status": "skipped"}
+		]`, string(content))
+	})
+}
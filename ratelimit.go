@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	iterator "github.com/jcchavezs/gh-iterator"
+	"github.com/jcchavezs/gh-iterator/exec"
+)
+
+const rateLimitCheckInterval = 30 * time.Second
+
+// rateLimiter pauses the workers when the remaining requests of the API rate limit go below
+// the threshold, until the limit resets.
+type rateLimiter struct {
+	mu        sync.Mutex
+	x         exec.Execer
+	threshold int
+	checkedAt time.Time
+	remaining int
+	reset     time.Time
+	// failedAt is the time checking the rate limit last failed, it is not checked again until
+	// rateLimitCheckInterval passes.
+	failedAt time.Time
+}
+
+// wait blocks until there are enough API requests left. Holding the lock while waiting
+// pauses every worker.
+func (l *rateLimiter) wait(ctx context.Context) error {
+	if l.threshold <= 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if time.Since(l.checkedAt) > rateLimitCheckInterval {
+		if time.Since(l.failedAt) <= rateLimitCheckInterval {
+			return nil
+		}
+
+		if err := l.refresh(ctx); err != nil {
+			// not knowing the rate limit should not stop the run, nor be checked before
+			// every repository.
+			l.failedAt = time.Now()
+			l.x.Log(ctx, slog.LevelDebug, "Failed to check the API rate limit", "error", err)
+			return nil
+		}
+	}
+
+	if l.remaining >= l.threshold {
+		return nil
+	}
+
+	l.x.Log(ctx, slog.LevelWarn, "API rate limit is low, pausing until it resets", "remaining", l.remaining, "reset", l.reset)
+	select {
+	case <-time.After(time.Until(l.reset)):
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	// the budget is refreshed in the next call.
+	l.checkedAt = time.Time{}
+	return nil
+}
+
+// low tells whether the last known remaining requests are close to the threshold.
+func (l *rateLimiter) low() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.threshold > 0 && !l.checkedAt.IsZero() && l.remaining < 2*l.threshold
+}
+
+// refresh retrieves the rate limit of the core API, which does not count against it.
+func (l *rateLimiter) refresh(ctx context.Context) error {
+	res, err := l.x.RunX(ctx, "gh", "api",
+		"-H", "Accept: application/vnd.github+json",
+		"-H", "X-GitHub-Api-Version: "+iterator.GithubAPIVersion,
+		"--jq", ".resources.core",
+		"/rate_limit",
+	)
+	if err != nil {
+		return err
+	}
+
+	if l.remaining, l.reset, err = parseRateLimit(res); err != nil {
+		return err
+	}
+	l.checkedAt = time.Now()
+	runMetrics.rateLimitRemaining.Store(int64(l.remaining))
+
+	return nil
+}
+
+// parseRateLimit parses the remaining requests and the reset time out of a rate limit resource.
+func parseRateLimit(res string) (int, time.Time, error) {
+	var rl struct {
+		Remaining int   `json:"remaining"`
+		Reset     int64 `json:"reset"`
+	}
+	if err := json.Unmarshal([]byte(res), &rl); err != nil {
+		return 0, time.Time{}, fmt.Errorf("unmarshaling rate limit: %w", err)
+	}
+
+	return rl.Remaining, time.Unix(rl.Reset, 0), nil
+}
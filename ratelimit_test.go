@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/jcchavezs/gh-iterator/exec"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRateLimit(t *testing.T) {
+	remaining, reset, err := parseRateLimit(`{"limit":5000,"used":4990,"remaining":10,"reset":1700000000}`)
+	require.NoError(t, err)
+	require.Equal(t, 10, remaining)
+	require.Equal(t, time.Unix(1700000000, 0), reset)
+
+	_, _, err = parseRateLimit("not json")
+	require.Error(t, err)
+}
+
+func TestRateLimiterWait(t *testing.T) {
+	l := &rateLimiter{
+		x:         exec.NewExecerWithLogger(".", slog.New(slog.DiscardHandler)),
+		threshold: 100,
+		checkedAt: time.Now(),
+		remaining: 10,
+		reset:     time.Now().Add(50 * time.Millisecond),
+	}
+
+	start := time.Now()
+	require.NoError(t, l.wait(context.Background()))
+	require.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond)
+	require.True(t, l.checkedAt.IsZero())
+
+	l.checkedAt, l.reset = time.Now(), time.Now().Add(time.Hour)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.ErrorIs(t, l.wait(ctx), context.Canceled)
+
+	l.remaining = 200
+	require.NoError(t, l.wait(context.Background()))
+}
+
+func TestRateLimiterWait_RefreshFailure(t *testing.T) {
+	calls := scriptedGH(t, "echo 'gh: Server Error (HTTP 502)' >&2; exit 1\n")
+
+	l := &rateLimiter{x: exec.NewExecerWithLogger(t.TempDir(), slog.New(slog.DiscardHandler)), threshold: 100}
+	for range 3 {
+		require.NoError(t, l.wait(context.Background()))
+	}
+	require.Equal(t, 1, countLines(t, calls))
+
+	l.failedAt = time.Now().Add(-2 * rateLimitCheckInterval)
+	require.NoError(t, l.wait(context.Background()))
+	require.Equal(t, 2, countLines(t, calls))
+}
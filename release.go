@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+
+	iterator "github.com/jcchavezs/gh-iterator"
+	"github.com/jcchavezs/gh-iterator/exec"
+)
+
+// tagAction tags the head of the default branch of the repositories not having the tag yet and,
+// with release, creates a GitHub release for the tag with the notes.
+func tagAction(tag string, release bool, notes string) repoAction {
+	return func(ctx context.Context, x exec.Execer, repo iterator.Repository, dryRun bool) ([]string, error) {
+		var changes []string
+
+		_, err := apiRequest(ctx, x, "GET", "/repos/"+repo.Name+"/git/ref/tags/"+url.PathEscape(tag), nil)
+		if isNotFound(err) {
+			if repo.DefaultBranchName == "" {
+				return nil, errors.New("tagging: no default branch")
+			}
+
+			if !dryRun {
+				sha, err := branchHead(ctx, x, repo.Name, repo.DefaultBranchName)
+				if err != nil {
+					return nil, err
+				}
+
+				if _, err := apiRequest(ctx, x, "POST", "/repos/"+repo.Name+"/git/refs", map[string]string{"ref": "refs/tags/" + tag, "sha": sha}); err != nil {
+					return nil, fmt.Errorf("tagging: %w", err)
+				}
+			}
+			changes = append(changes, fmt.Sprintf("tag %s as %s", repo.DefaultBranchName, tag))
+		} else if err != nil {
+			return nil, fmt.Errorf("fetching tag: %w", err)
+		}
+
+		if !release {
+			return changes, nil
+		}
+
+		_, err = apiRequest(ctx, x, "GET", "/repos/"+repo.Name+"/releases/tags/"+url.PathEscape(tag), nil)
+		if isNotFound(err) {
+			if !dryRun {
+				if _, err := apiRequest(ctx, x, "POST", "/repos/"+repo.Name+"/releases", map[string]string{"tag_name": tag, "name": tag, "body": notes}); err != nil {
+					return nil, fmt.Errorf("creating release: %w", err)
+				}
+			}
+			changes = append(changes, "create release "+tag)
+		} else if err != nil {
+			return nil, fmt.Errorf("fetching release: %w", err)
+		}
+
+		return changes, nil
+	}
+}
+
+// branchHead returns the SHA of the head of the branch.
+func branchHead(ctx context.Context, x exec.Execer, repository, branch string) (string, error) {
+	res, err := apiRequest(ctx, x, "GET", "/repos/"+repository+"/git/ref/heads/"+branch, nil)
+	if err != nil {
+		return "", fmt.Errorf("fetching branch head: %w", err)
+	}
+
+	var ref struct {
+		Object struct {
+			SHA string `json:"sha"`
+		} `json:"object"`
+	}
+	if err := json.Unmarshal([]byte(res), &ref); err != nil {
+		return "", fmt.Errorf("unmarshaling branch head: %w", err)
+	}
+
+	return ref.Object.SHA, nil
+}
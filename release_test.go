@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"testing"
+
+	iterator "github.com/jcchavezs/gh-iterator"
+	"github.com/jcchavezs/gh-iterator/exec"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTagAction(t *testing.T) {
+	calls := scriptedGH(t, routedGH+`"GET /repos/acme/a/git/ref/tags/v1.2.0"|"GET /repos/acme/a/releases/tags/v1.2.0") echo '{"message":"Not Found","status":"404"}'; exit 1;;
+"GET /repos/acme/a/git/ref/heads/main") echo '{"object":{"sha":"abc123"}}';;
+esac
+`)
+	x := exec.NewExecerWithLogger(t.TempDir(), slog.New(slog.DiscardHandler))
+	repo := iterator.Repository{Name: "acme/a", DefaultBranchName: "main"}
+
+	changes, err := tagAction("v1.2.0", true, "notes")(context.Background(), x, repo, false)
+	require.NoError(t, err)
+	require.Equal(t, []string{"tag main as v1.2.0", "create release v1.2.0"}, changes)
+
+	content, err := os.ReadFile(calls)
+	require.NoError(t, err)
+	require.Contains(t, string(content), "-X POST --input - /repos/acme/a/git/refs\n")
+	require.Contains(t, string(content), "-X POST --input - /repos/acme/a/releases\n")
+}
+
+func TestTagAction_Existing(t *testing.T) {
+	calls := scriptedGH(t, routedGH+`"GET /repos/acme/a/git/ref/tags/v1.2.0") echo '{"object":{"sha":"abc123"}}';;
+"GET /repos/acme/a/releases/tags/v1.2.0") echo '{"id":1}';;
+esac
+`)
+	x := exec.NewExecerWithLogger(t.TempDir(), slog.New(slog.DiscardHandler))
+
+	changes, err := tagAction("v1.2.0", true, "")(context.Background(), x, iterator.Repository{Name: "acme/a", DefaultBranchName: "main"}, false)
+	require.NoError(t, err)
+	require.Empty(t, changes)
+	require.Equal(t, 2, countLines(t, calls))
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/afero"
+)
+
+// replacement replaces old with new in the file contents, old being a regular expression
+// when re is set.
+type replacement struct {
+	old string
+	new string
+	re  *regexp.Regexp
+}
+
+// parseReplacements parses replacements in the form 'old=>new'.
+func parseReplacements(specs []string, useRegex bool) ([]replacement, error) {
+	replacements := make([]replacement, 0, len(specs))
+	for _, spec := range specs {
+		old, new, ok := strings.Cut(spec, "=>")
+		if !ok || old == "" {
+			return nil, fmt.Errorf("invalid replacement %q, expected 'old=>new'", spec)
+		}
+
+		r := replacement{old: old, new: new}
+		if useRegex {
+			re, err := regexp.Compile(old)
+			if err != nil {
+				return nil, fmt.Errorf("compiling replacement %q: %w", spec, err)
+			}
+			r.re = re
+		}
+
+		replacements = append(replacements, r)
+	}
+
+	return replacements, nil
+}
+
+func (r replacement) apply(content []byte) []byte {
+	if r.re != nil {
+		return r.re.ReplaceAll(content, []byte(r.new))
+	}
+
+	return bytes.ReplaceAll(content, []byte(r.old), []byte(r.new))
+}
+
+// replaceInFiles applies the replacements to the files matching any of the globs, or to all
+// files if there are no globs, and returns the number of changed files.
+func replaceInFiles(fs afero.Fs, globs []string, replacements []replacement) (int, error) {
+	var changed int
+	err := afero.Walk(fs, ".", func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			if info.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		if len(globs) > 0 && !matchAnyGlob(globs, filepath.ToSlash(path)) {
+			return nil
+		}
+
+		content, err := afero.ReadFile(fs, path)
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", path, err)
+		}
+
+		newContent := content
+		for _, r := range replacements {
+			newContent = r.apply(newContent)
+		}
+
+		if bytes.Equal(content, newContent) {
+			return nil
+		}
+
+		if err := afero.WriteFile(fs, path, newContent, info.Mode()); err != nil {
+			return fmt.Errorf("writing %s: %w", path, err)
+		}
+		changed++
+
+		return nil
+	})
+	if err != nil && !errors.Is(err, filepath.SkipDir) {
+		return changed, fmt.Errorf("replacing in files: %w", err)
+	}
+
+	return changed, nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseReplacements(t *testing.T) {
+	_, err := parseReplacements([]string{"old"}, false)
+	require.Error(t, err)
+
+	_, err = parseReplacements([]string{"=>new"}, false)
+	require.Error(t, err)
+
+	_, err = parseReplacements([]string{"(=>new"}, true)
+	require.Error(t, err)
+
+	rs, err := parseReplacements([]string{"old=>new"}, false)
+	require.NoError(t, err)
+	require.Len(t, rs, 1)
+}
+
+func TestReplaceInFiles(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "go.mod", []byte("go 1.21\n"), 0644))
+	require.NoError(t, afero.WriteFile(fs, "cmd/main.go", []byte("// go 1.21\n"), 0644))
+	require.NoError(t, afero.WriteFile(fs, "README.md", []byte("go 1.21\n"), 0644))
+	require.NoError(t, afero.WriteFile(fs, ".git/config", []byte("go 1.21\n"), 0644))
+
+	t.Run("literal", func(t *testing.T) {
+		rs, err := parseReplacements([]string{"go 1.21=>go 1.22"}, false)
+		require.NoError(t, err)
+
+		changed, err := replaceInFiles(fs, []string{"**/*.go", "go.mod"}, rs)
+		require.NoError(t, err)
+		require.Equal(t, 2, changed)
+
+		content, err := afero.ReadFile(fs, "cmd/main.go")
+		require.NoError(t, err)
+		require.Equal(t, "// go 1.22\n", string(content))
+
+		content, err = afero.ReadFile(fs, "README.md")
+		require.NoError(t, err)
+		require.Equal(t, "go 1.21\n", string(content))
+	})
+
+	t.Run("regex", func(t *testing.T) {
+		rs, err := parseReplacements([]string{`go 1\.\d+=>go 1.23`}, true)
+		require.NoError(t, err)
+
+		changed, err := replaceInFiles(fs, nil, rs)
+		require.NoError(t, err)
+		require.Equal(t, 3, changed)
+
+		content, err := afero.ReadFile(fs, ".git/config")
+		require.NoError(t, err)
+		require.Equal(t, "go 1.21\n", string(content))
+	})
+}
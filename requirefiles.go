@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jcchavezs/gh-iterator/exec"
+)
+
+// missingFiles returns the files of required not present in the clone in root. A required file
+// can list alternative paths separated by | e.g. CODEOWNERS|.github/CODEOWNERS.
+func missingFiles(root string, required []string) []string {
+	var missing []string
+	for _, r := range required {
+		found := false
+		for _, alt := range strings.Split(r, "|") {
+			if _, err := os.Stat(filepath.Join(root, filepath.FromSlash(alt))); err == nil {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			missing = append(missing, r)
+		}
+	}
+
+	return missing
+}
+
+// auditRequiredFiles records the files passed in --require-files missing in the repository and
+// prints them.
+func (p repoProcessor) auditRequiredFiles(ctx context.Context, x exec.Execer, repository string) error {
+	root, err := commandDir(x)
+	if err != nil {
+		return err
+	}
+
+	missing := missingFiles(root, flags.requireFiles)
+	p.results.update(repository, func(r *repoResult) { r.MissingFiles = missing })
+
+	if len(missing) > 0 {
+		outputMux.Lock()
+		defer outputMux.Unlock()
+
+		if _, err := fmt.Fprintf(p.stdout, "%s: missing %s\n", repository, strings.Join(missing, ", ")); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// missingFilesCount counts the repositories missing any of the required files.
+func (r *runResults) missingFilesCount() int {
+	n := 0
+	for _, res := range r.sorted() {
+		if len(res.MissingFiles) > 0 {
+			n++
+		}
+	}
+
+	return n
+}
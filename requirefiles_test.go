@@ -0,0 +1,27 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMissingFiles(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, ".github"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "LICENSE"), nil, 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, ".github", "CODEOWNERS"), nil, 0o644))
+
+	require.Empty(t, missingFiles(root, []string{"LICENSE", "CODEOWNERS|.github/CODEOWNERS"}))
+	require.Equal(t, []string{"SECURITY.md", "CODEOWNERS"}, missingFiles(root, []string{"LICENSE", "SECURITY.md", "CODEOWNERS"}))
+}
+
+func TestRunResultsMissingFilesCount(t *testing.T) {
+	r := newRunResults()
+	r.update("acme/a", func(res *repoResult) { res.MissingFiles = []string{"LICENSE"} })
+	r.update("acme/b", func(*repoResult) {})
+
+	require.Equal(t, 1, r.missingFilesCount())
+}
@@ -0,0 +1,544 @@
+package main
+
+import (
+	"cmp"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	iterator "github.com/jcchavezs/gh-iterator"
+)
+
+// OutputFormat is the format of the run output.
+type OutputFormat int
+
+const (
+	OutputFormatText OutputFormat = iota
+	OutputFormatJSON
+	OutputFormatJSONL
+	OutputFormatCSV
+	OutputFormatJUnit
+	OutputFormatHTML
+)
+
+// OutputFormatIds maps output formats to their corresponding string identifiers.
+var OutputFormatIds = map[OutputFormat][]string{
+	OutputFormatText:  {"text"},
+	OutputFormatJSON:  {"json"},
+	OutputFormatJSONL: {"jsonl"},
+	OutputFormatCSV:   {"csv"},
+	OutputFormatJUnit: {"junit"},
+	OutputFormatHTML:  {"html"},
+}
+
+// maxResultOutput is the number of bytes of the command output kept in the results.
+const maxResultOutput = 4096
+
+// repoResult is the outcome of processing a repository.
+type repoResult struct {
+	Repository string
+	Language   string
+	// Matched tells whether the repository passed the filters and was selected to be processed.
+	Matched bool
+	// Skipped is the reason the repository was skipped, if so.
+	Skipped string
+	// Processed tells whether the repository was processed, successfully or not.
+	Processed  bool
+	CommandRan bool
+	ExitCode   int
+	// Stdout and Stderr are the last bytes of the command output.
+	Stdout string
+	Stderr string
+	// Diff is the first bytes of the diff of the changes with --show-diff.
+	Diff  string
+	PRURL string
+	Error string
+	// Findings are the lines of the command output reported in the SARIF file.
+	Findings []finding
+	// Matches are the lines matching --grep.
+	Matches []grepMatch
+	// MissingFiles are the files passed in --require-files missing in the repository.
+	MissingFiles []string
+	// Dependencies are the dependencies declared in the manifests of the repository.
+	Dependencies []dependency
+	// Changes are the changes made through the API, or the ones that would be made with
+	// --dry-run.
+	Changes []string
+	// Category is the category of the failure, if failed.
+	Category        errorCategory
+	CloneDuration   time.Duration
+	CommandDuration time.Duration
+}
+
+// failed tells whether processing the repository or its command failed.
+func (r repoResult) failed() bool {
+	return r.Error != "" || (r.CommandRan && r.ExitCode != 0)
+}
+
+// status is the outcome of the repository: filtered out, skipped, failed, processed or pending
+// when the run stopped before processing it.
+func (r repoResult) status() string {
+	switch {
+	case !r.Matched:
+		return "filtered"
+	case r.Skipped != "":
+		return "skipped"
+	case r.failed():
+		return "failed"
+	case r.Processed:
+		return "processed"
+	default:
+		return "pending"
+	}
+}
+
+// Duration is the time spent cloning the repository and running the command.
+func (r repoResult) Duration() time.Duration {
+	return r.CloneDuration + r.CommandDuration
+}
+
+// runResults collects the results of the repositories processed during the run.
+type runResults struct {
+	mu     sync.Mutex
+	byRepo map[string]*repoResult
+	// jsonl is where the result of each repository is written as a JSON line once it is
+	// processed, if set.
+	jsonl io.Writer
+	// retried are the failures of the previous run passed in --retry-failed, they are kept in
+	// the failures report unless the repository is processed again.
+	retried []failure
+}
+
+func newRunResults() *runResults {
+	return &runResults{byRepo: map[string]*repoResult{}}
+}
+
+// addRepositories records the repositories found, whether they were selected and why the
+// others were skipped.
+func (r *runResults) addRepositories(found []iterator.Repository, selected []iterator.Repository, skipped map[string]string) {
+	matched := make(map[string]bool, len(selected))
+	for _, repo := range selected {
+		matched[repo.Name] = true
+	}
+
+	for _, repo := range found {
+		r.update(repo.Name, func(res *repoResult) {
+			res.Language = repo.Language
+			res.Matched = matched[repo.Name]
+			res.Skipped = skipped[repo.Name]
+		})
+	}
+}
+
+// reasonCount is the number of repositories skipped for a reason.
+type reasonCount struct {
+	Reason string `json:"reason"`
+	Count  int    `json:"count"`
+}
+
+// skippedCounts returns the number of repositories skipped per reason, the most common first.
+func (r *runResults) skippedCounts() []reasonCount {
+	return countSkipped(r.sorted())
+}
+
+func countSkipped(results []repoResult) []reasonCount {
+	byReason := map[string]int{}
+	for _, res := range results {
+		if res.Skipped != "" {
+			byReason[res.Skipped]++
+		}
+	}
+
+	counts := make([]reasonCount, 0, len(byReason))
+	for reason, n := range byReason {
+		counts = append(counts, reasonCount{Reason: reason, Count: n})
+	}
+
+	slices.SortFunc(counts, func(a, b reasonCount) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), strings.Compare(a.Reason, b.Reason))
+	})
+
+	return counts
+}
+
+// update applies fn to the result of the repository. It is a noop on a nil runResults.
+func (r *runResults) update(repository string, fn func(*repoResult)) {
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	res, ok := r.byRepo[repository]
+	if !ok {
+		res = &repoResult{Repository: repository}
+		r.byRepo[repository] = res
+	}
+	fn(res)
+}
+
+// finish writes the result of the processed repository as a JSON line, if enabled.
+func (r *runResults) finish(repository string) error {
+	if r == nil || r.jsonl == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	res, ok := r.byRepo[repository]
+	if !ok {
+		return nil
+	}
+
+	if err := json.NewEncoder(r.jsonl).Encode(res.toJSON()); err != nil {
+		return fmt.Errorf("encoding result: %w", err)
+	}
+
+	return nil
+}
+
+// get returns the result of the repository. It returns false on a nil runResults or if the
+// repository is unknown.
+func (r *runResults) get(repository string) (repoResult, bool) {
+	if r == nil {
+		return repoResult{}, false
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	res, ok := r.byRepo[repository]
+	if !ok {
+		return repoResult{}, false
+	}
+
+	return *res, true
+}
+
+// cloneDuration returns the time spent cloning the repository, zero if unknown.
+func (r *runResults) cloneDuration(repository string) time.Duration {
+	res, _ := r.get(repository)
+	return res.CloneDuration
+}
+
+// sorted returns the results sorted by repository, none on a nil runResults.
+func (r *runResults) sorted() []repoResult {
+	if r == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	results := make([]repoResult, 0, len(r.byRepo))
+	for _, res := range r.byRepo {
+		results = append(results, *res)
+	}
+
+	slices.SortFunc(results, func(a, b repoResult) int {
+		return strings.Compare(a.Repository, b.Repository)
+	})
+
+	return results
+}
+
+// slowest returns up to n results that took the longest.
+func (r *runResults) slowest(n int) []repoResult {
+	results := r.sorted()
+	slices.SortStableFunc(results, func(a, b repoResult) int {
+		return cmp.Compare(b.Duration(), a.Duration())
+	})
+
+	return results[:min(n, len(results))]
+}
+
+// writeSlowest prints the n repositories that took the longest as a table.
+func (r *runResults) writeSlowest(w io.Writer, n int) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "REPOSITORY\tCLONE\tCOMMAND\tTOTAL")
+	for _, res := range r.slowest(n) {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", res.Repository,
+			res.CloneDuration.Round(time.Millisecond),
+			res.CommandDuration.Round(time.Millisecond),
+			res.Duration().Round(time.Millisecond),
+		)
+	}
+
+	return tw.Flush()
+}
+
+// truncateOutput keeps the last maxResultOutput bytes of the output.
+func truncateOutput(s string) string {
+	if len(s) <= maxResultOutput {
+		return s
+	}
+
+	return s[len(s)-maxResultOutput:]
+}
+
+type jsonRepoResult struct {
+	Repository      string      `json:"repository"`
+	Language        string      `json:"language,omitempty"`
+	Matched         bool        `json:"matched"`
+	Status          string      `json:"status"`
+	Skipped         string      `json:"skipped,omitempty"`
+	ExitCode        *int        `json:"exit_code,omitempty"`
+	CloneSeconds    float64     `json:"clone_seconds,omitempty"`
+	CommandSeconds  float64     `json:"command_seconds,omitempty"`
+	DurationSeconds float64     `json:"duration_seconds,omitempty"`
+	Stdout          string      `json:"stdout,omitempty"`
+	Stderr          string      `json:"stderr,omitempty"`
+	PRURL           string      `json:"pr_url,omitempty"`
+	Error           string      `json:"error,omitempty"`
+	ErrorCategory   string      `json:"error_category,omitempty"`
+	Matches         []grepMatch `json:"matches,omitempty"`
+	MissingFiles    []string    `json:"missing_files,omitempty"`
+	Changes         []string    `json:"changes,omitempty"`
+}
+
+func (r repoResult) toJSON() jsonRepoResult {
+	jr := jsonRepoResult{
+		Repository:      r.Repository,
+		Language:        r.Language,
+		Matched:         r.Matched,
+		Status:          r.status(),
+		Skipped:         r.Skipped,
+		CloneSeconds:    r.CloneDuration.Seconds(),
+		CommandSeconds:  r.CommandDuration.Seconds(),
+		DurationSeconds: r.Duration().Seconds(),
+		Stdout:          r.Stdout,
+		Stderr:          r.Stderr,
+		PRURL:           r.PRURL,
+		Error:           r.Error,
+		ErrorCategory:   string(r.Category),
+		Matches:         r.Matches,
+		MissingFiles:    r.MissingFiles,
+		Changes:         r.Changes,
+	}
+
+	if r.CommandRan {
+		jr.ExitCode = &r.ExitCode
+	}
+
+	return jr
+}
+
+type runTotals struct {
+	Found     int `json:"found"`
+	Matched   int `json:"matched"`
+	Succeeded int `json:"succeeded"`
+	Failed    int `json:"failed"`
+	Skipped   int `json:"skipped"`
+	// SkippedByReason counts all the skipped repositories, matched or not, per reason.
+	SkippedByReason []reasonCount `json:"skipped_by_reason"`
+}
+
+// processedCount returns the number of repositories processed, successfully or not, which is
+// less than the selected ones when the run is interrupted.
+func (r *runResults) processedCount() int {
+	n := 0
+	for _, res := range r.sorted() {
+		if res.Processed {
+			n++
+		}
+	}
+
+	return n
+}
+
+// totals counts the repositories by outcome. The matched repositories not processed because
+// the run stopped are neither succeeded nor failed.
+func totals(results []repoResult) runTotals {
+	t := runTotals{Found: len(results), SkippedByReason: countSkipped(results)}
+	for _, r := range results {
+		if !r.Matched {
+			continue
+		}
+
+		t.Matched++
+		switch {
+		case r.Skipped != "":
+			t.Skipped++
+		case r.failed():
+			t.Failed++
+		case r.Processed:
+			t.Succeeded++
+		}
+	}
+
+	return t
+}
+
+// writeJSON prints the results of every repository and the totals as a JSON document.
+func (r *runResults) writeJSON(w io.Writer) error {
+	results := r.sorted()
+
+	doc := struct {
+		Repositories []jsonRepoResult `json:"repositories"`
+		Totals       runTotals        `json:"totals"`
+	}{
+		Repositories: make([]jsonRepoResult, 0, len(results)),
+		Totals:       totals(results),
+	}
+	for _, res := range results {
+		doc.Repositories = append(doc.Repositories, res.toJSON())
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return fmt.Errorf("encoding results: %w", err)
+	}
+
+	return nil
+}
+
+// csvColumns maps the columns of the CSV output to the value of the result.
+var csvColumns = map[string]func(r repoResult) string{
+	"repository": func(r repoResult) string { return r.Repository },
+	"language":   func(r repoResult) string { return r.Language },
+	"matched":    func(r repoResult) string { return strconv.FormatBool(r.Matched) },
+	"status":     func(r repoResult) string { return r.status() },
+	"skipped":    func(r repoResult) string { return r.Skipped },
+	"exit_code": func(r repoResult) string {
+		if !r.CommandRan {
+			return ""
+		}
+		return strconv.Itoa(r.ExitCode)
+	},
+	"duration":         func(r repoResult) string { return formatSeconds(r.Duration()) },
+	"clone_duration":   func(r repoResult) string { return formatSeconds(r.CloneDuration) },
+	"command_duration": func(r repoResult) string { return formatSeconds(r.CommandDuration) },
+	"pr_url":           func(r repoResult) string { return r.PRURL },
+	"error":            func(r repoResult) string { return r.Error },
+	"error_category":   func(r repoResult) string { return string(r.Category) },
+	"matches":          func(r repoResult) string { return strconv.Itoa(len(r.Matches)) },
+	"missing_files":    func(r repoResult) string { return strings.Join(r.MissingFiles, ";") },
+	"changes":          func(r repoResult) string { return strings.Join(r.Changes, ";") },
+}
+
+// defaultCSVColumns are the columns of the CSV output when none are passed.
+var defaultCSVColumns = []string{"repository", "language", "matched", "exit_code", "duration"}
+
+// validateCSVColumns checks the columns are known.
+func validateCSVColumns(columns []string) error {
+	for _, c := range columns {
+		if _, ok := csvColumns[c]; !ok {
+			return fmt.Errorf("unknown column %q", c)
+		}
+	}
+
+	return nil
+}
+
+func formatSeconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', 3, 64)
+}
+
+// writeCSV prints the results of every repository as CSV with the columns.
+func (r *runResults) writeCSV(w io.Writer, columns []string) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(columns); err != nil {
+		return fmt.Errorf("writing CSV header: %w", err)
+	}
+
+	record := make([]string, len(columns))
+	for _, res := range r.sorted() {
+		for i, c := range columns {
+			record[i] = csvColumns[c](res)
+		}
+
+		if err := cw.Write(record); err != nil {
+			return fmt.Errorf("writing CSV record: %w", err)
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+type junitTestSuite struct {
+	XMLName   xml.Name        `xml:"testsuite"`
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      string          `xml:"time,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Content string `xml:",chardata"`
+}
+
+// writeJUnit prints the results of the matched repositories as a JUnit XML test suite where
+// every repository is a test case.
+func (r *runResults) writeJUnit(w io.Writer) error {
+	suite := junitTestSuite{Name: "gh-iterator-run"}
+
+	var total time.Duration
+	for _, res := range r.sorted() {
+		if !res.Matched {
+			continue
+		}
+
+		owner, _, _ := strings.Cut(res.Repository, "/")
+		tc := junitTestCase{
+			Name:      res.Repository,
+			ClassName: owner,
+			Time:      formatSeconds(res.Duration()),
+			SystemOut: res.Stdout,
+		}
+
+		switch {
+		case res.Skipped != "":
+			tc.Skipped = &junitMessage{Message: res.Skipped}
+			suite.Skipped++
+		case !res.Processed:
+			tc.Skipped = &junitMessage{Message: "not processed"}
+			suite.Skipped++
+		case res.failed():
+			msg := res.Error
+			if msg == "" {
+				msg = fmt.Sprintf("exit code %d", res.ExitCode)
+			}
+			tc.Failure = &junitMessage{Message: msg, Content: res.Stderr}
+			suite.Failures++
+		}
+
+		total += res.Duration()
+		suite.TestCases = append(suite.TestCases, tc)
+	}
+	suite.Tests = len(suite.TestCases)
+	suite.Time = formatSeconds(total)
+
+	io.WriteString(w, xml.Header)
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(suite); err != nil {
+		return fmt.Errorf("encoding JUnit report: %w", err)
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	iterator "github.com/jcchavezs/gh-iterator"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunResultsSlowest(t *testing.T) {
+	r := newRunResults()
+	r.update("acme/a", func(res *repoResult) { res.CloneDuration = time.Second })
+	r.update("acme/b", func(res *repoResult) { res.CommandDuration = 3 * time.Second })
+	r.update("acme/c", func(res *repoResult) { res.CloneDuration = time.Second })
+	r.update("acme/c", func(res *repoResult) { res.CommandDuration = time.Second })
+
+	slowest := r.slowest(2)
+	require.Len(t, slowest, 2)
+	require.Equal(t, "acme/b", slowest[0].Repository)
+	require.Equal(t, "acme/c", slowest[1].Repository)
+	require.Len(t, r.slowest(10), 3)
+
+	out := &bytes.Buffer{}
+	require.NoError(t, r.writeSlowest(out, 1))
+	require.Equal(t, "REPOSITORY  CLONE  COMMAND  TOTAL\nacme/b      0s     3s       3s\n", out.String())
+
+	var nilResults *runResults
+	nilResults.update("acme/a", func(*repoResult) { t.Fatal("unexpected call") })
+}
+
+func TestRunResultsWriteJSON(t *testing.T) {
+	r := newRunResults()
+	r.addRepositories(
+		[]iterator.Repository{{Name: "acme/a", Language: "Go"}, {Name: "acme/b"}, {Name: "acme/c"}, {Name: "acme/d"}},
+		[]iterator.Repository{{Name: "acme/a"}, {Name: "acme/c"}, {Name: "acme/d"}},
+		nil,
+	)
+	r.update("acme/a", func(res *repoResult) {
+		res.Processed, res.CommandRan, res.ExitCode, res.Stdout = true, true, 0, "ok\n"
+		res.CommandDuration = 1500 * time.Millisecond
+	})
+	r.update("acme/c", func(res *repoResult) {
+		res.Processed, res.CommandRan, res.ExitCode = true, true, 2
+	})
+	r.update("acme/d", func(res *repoResult) { res.Skipped = "ref not found" })
+
+	out := &bytes.Buffer{}
+	require.NoError(t, r.writeJSON(out))
+	require.JSONEq(t, `{
+		"repositories": [
+			{"repository": "acme/a", "language": "Go", "matched": true, "status": "processed", "exit_code": 0, "command_seconds": 1.5, "duration_seconds": 1.5, "stdout": "ok\n"},
+			{"repository": "acme/b", "matched": false, "status": "filtered"},
+			{"repository": "acme/c", "matched": true, "status": "failed", "exit_code": 2},
+			{"repository": "acme/d", "matched": true, "status": "skipped", "skipped": "ref not found"}
+		],
+		"totals": {"found": 4, "matched": 3, "succeeded": 1, "failed": 1, "skipped": 1, "skipped_by_reason": [{"reason": "ref not found", "count": 1}]}
+	}`, out.String())
+}
+
+func TestTruncateOutput(t *testing.T) {
+	require.Equal(t, "short", truncateOutput("short"))
+
+	long := strings.Repeat("a", maxResultOutput) + "tail"
+	truncated := truncateOutput(long)
+	require.Len(t, truncated, maxResultOutput)
+	require.True(t, strings.HasSuffix(truncated, "tail"))
+}
+
+func TestRunResultsFinish(t *testing.T) {
+	out := &bytes.Buffer{}
+	r := newRunResults()
+	r.jsonl = out
+
+	r.addRepositories([]iterator.Repository{{Name: "acme/a"}, {Name: "acme/b"}}, []iterator.Repository{{Name: "acme/a"}, {Name: "acme/b"}}, nil)
+	r.update("acme/b", func(res *repoResult) { res.Processed, res.CommandRan, res.ExitCode = true, true, 1 })
+	require.NoError(t, r.finish("acme/b"))
+	r.update("acme/a", func(res *repoResult) { res.Processed, res.Error = true, "boom" })
+	require.NoError(t, r.finish("acme/a"))
+
+	require.Equal(t, `{"repository":"acme/b","matched":true,"status":"failed","exit_code":1}`+"\n"+
+		`{"repository":"acme/a","matched":true,"status":"failed","error":"boom"}`+"\n", out.String())
+}
+
+func TestRunResultsProcessedCount(t *testing.T) {
+	r := newRunResults()
+	repos := []iterator.Repository{{Name: "acme/a"}, {Name: "acme/b"}, {Name: "acme/c"}}
+	r.addRepositories(repos, repos, nil)
+	r.update("acme/a", func(res *repoResult) { res.Processed = true })
+	r.update("acme/b", func(res *repoResult) { res.Processed, res.Error = true, "boom" })
+
+	// acme/c was not processed as the run was interrupted.
+	require.Equal(t, 2, r.processedCount())
+}
+
+func TestRunResultsWriteCSV(t *testing.T) {
+	r := newRunResults()
+	r.addRepositories([]iterator.Repository{{Name: "acme/a", Language: "Go"}, {Name: "acme/b"}}, []iterator.Repository{{Name: "acme/a"}}, nil)
+	r.update("acme/a", func(res *repoResult) {
+		res.Processed, res.CommandRan, res.ExitCode = true, true, 1
+		res.CommandDuration = 2 * time.Second
+	})
+
+	out := &bytes.Buffer{}
+	require.NoError(t, r.writeCSV(out, defaultCSVColumns))
+	require.Equal(t, "repository,language,matched,exit_code,duration\n"+
+		"acme/a,Go,true,1,2.000\n"+
+		"acme/b,,false,,0.000\n", out.String())
+
+	out.Reset()
+	require.NoError(t, r.writeCSV(out, []string{"repository", "status"}))
+	require.Equal(t, "repository,status\nacme/a,failed\nacme/b,filtered\n", out.String())
+
+	require.NoError(t, validateCSVColumns([]string{"repository", "pr_url"}))
+	require.Error(t, validateCSVColumns([]string{"repository", "owner"}))
+}
+
+func TestRunResultsWriteJUnit(t *testing.T) {
+	r := newRunResults()
+	r.addRepositories(
+		[]iterator.Repository{{Name: "acme/a"}, {Name: "acme/b"}, {Name: "acme/c"}, {Name: "acme/d"}},
+		[]iterator.Repository{{Name: "acme/a"}, {Name: "acme/b"}, {Name: "acme/c"}},
+		nil,
+	)
+	r.update("acme/a", func(res *repoResult) {
+		res.Processed, res.CommandRan, res.CommandDuration = true, true, time.Second
+	})
+	r.update("acme/b", func(res *repoResult) {
+		res.Processed, res.CommandRan, res.ExitCode, res.Stderr = true, true, 3, "missing file\n"
+	})
+	r.update("acme/c", func(res *repoResult) { res.Skipped = "no default branch" })
+
+	out := &bytes.Buffer{}
+	require.NoError(t, r.writeJUnit(out))
+	require.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>
+<testsuite name="gh-iterator-run" tests="3" failures="1" skipped="1" time="1.000">
+  <testcase name="acme/a" classname="acme" time="1.000"></testcase>
+  <testcase name="acme/b" classname="acme" time="0.000">
+    <failure message="exit code 3">missing file&#xA;</failure>
+  </testcase>
+  <testcase name="acme/c" classname="acme" time="0.000">
+    <skipped message="no default branch"></skipped>
+  </testcase>
+</testsuite>
+`, out.String())
+}
+
+func TestRunResultsSkippedCounts(t *testing.T) {
+	r := newRunResults()
+	r.addRepositories(
+		[]iterator.Repository{{Name: "acme/a"}, {Name: "acme/b"}, {Name: "acme/c"}, {Name: "acme/d"}},
+		[]iterator.Repository{{Name: "acme/d"}},
+		map[string]string{"acme/a": "empty", "acme/b": "archived", "acme/c": "empty"},
+	)
+	r.update("acme/d", func(res *repoResult) { res.Skipped = "no default branch" })
+
+	require.Equal(t, []reasonCount{
+		{Reason: "empty", Count: 2},
+		{Reason: "archived", Count: 1},
+		{Reason: "no default branch", Count: 1},
+	}, r.skippedCounts())
+}
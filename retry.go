@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jcchavezs/gh-iterator/exec"
+)
+
+var (
+	// retryBaseDelay is the delay before the first retry, it doubles on every retry.
+	retryBaseDelay = 2 * time.Second
+	retryMaxDelay  = time.Minute
+)
+
+var (
+	// rateLimitedGHErrRe matches the gh errors caused by secondary rate limits and abuse
+	// detection, the request was rejected hence it is safe to retry any call.
+	rateLimitedGHErrRe = regexp.MustCompile(`(?i)secondary rate limit|abuse detection|HTTP 429`)
+	// serverGHErrRe matches the transient server errors, the request may have been processed
+	// hence only the idempotent calls are retried.
+	serverGHErrRe = regexp.MustCompile(`HTTP 5\d\d`)
+)
+
+// retryDecorator retries the gh invocations failing with transient API errors, with exponential
+// backoff and jitter. The server errors are only retried for the idempotent calls as the
+// request may have been processed, e.g. retrying an issue creation would open it twice.
+type retryDecorator struct {
+	retries int
+}
+
+// withRetries wraps the execer so the gh invocations are retried up to retries times. The gh
+// invocations are counted in the metrics.
+func withRetries(x exec.Execer, retries int) exec.Execer {
+	return decorate(x, retryDecorator{retries: max(retries, 0)})
+}
+
+func (d retryDecorator) runWithStdin(ctx context.Context, next exec.Execer, stdin io.Reader, command string, args ...string) (exec.Result, error) {
+	if command != "gh" {
+		return next.RunWithStdin(ctx, stdin, command, args...)
+	}
+	runMetrics.apiCalls.Add(1)
+
+	// the stdin is buffered so it can be replayed on every attempt.
+	var in []byte
+	if stdin != nil {
+		var err error
+		if in, err = io.ReadAll(stdin); err != nil {
+			return exec.Result{}, fmt.Errorf("reading stdin: %w", err)
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		var attemptIn io.Reader
+		if stdin != nil {
+			attemptIn = bytes.NewReader(in)
+		}
+
+		res, err := next.RunWithStdin(ctx, attemptIn, command, args...)
+		if err != nil || res.ExitCode == 0 || attempt == d.retries || !retryableGHErr(res.Stderr, args) {
+			return res, err
+		}
+
+		delay := retryDelay(attempt)
+		next.Log(ctx, slog.LevelWarn, "Retrying gh command", "error", strings.TrimSpace(res.Stderr), "attempt", attempt+1, "delay", delay)
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return res, ctx.Err()
+		}
+	}
+}
+
+// retryableGHErr returns true if the gh invocation failing with the stderr can be retried.
+func retryableGHErr(stderr string, args []string) bool {
+	if rateLimitedGHErrRe.MatchString(stderr) {
+		return true
+	}
+
+	return serverGHErrRe.MatchString(stderr) && idempotentGHCall(args)
+}
+
+// idempotentGHCall returns true if the gh invocation can be repeated without side effects:
+// the API calls with the GET, HEAD, PUT or DELETE methods, the GraphQL queries and the read
+// only commands.
+func idempotentGHCall(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+
+	if args[0] != "api" {
+		if args[0] == "search" {
+			return true
+		}
+
+		return len(args) > 1 && slices.Contains([]string{"view", "list", "status", "checks", "diff"}, args[1])
+	}
+
+	// the GraphQL calls are POSTs, only the mutations have side effects.
+	if len(args) > 1 && args[1] == "graphql" {
+		return !slices.ContainsFunc(args, func(arg string) bool {
+			return strings.HasPrefix(strings.TrimSpace(strings.TrimPrefix(arg, "query=")), "mutation")
+		})
+	}
+
+	// gh api defaults to POST when fields or an input are passed.
+	method := "GET"
+	for i, arg := range args {
+		switch {
+		case arg == "-X" || arg == "--method":
+			if i+1 < len(args) {
+				return slices.Contains([]string{"GET", "HEAD", "PUT", "DELETE"}, strings.ToUpper(args[i+1]))
+			}
+		case strings.HasPrefix(arg, "--method="):
+			return slices.Contains([]string{"GET", "HEAD", "PUT", "DELETE"}, strings.ToUpper(strings.TrimPrefix(arg, "--method=")))
+		case slices.Contains([]string{"-f", "-F", "--field", "--raw-field", "--input"}, arg):
+			method = "POST"
+		}
+	}
+
+	return method == "GET"
+}
+
+// retryDelay returns the exponential backoff delay of the attempt with up to 50% of jitter.
+func retryDelay(attempt int) time.Duration {
+	d := min(retryBaseDelay<<attempt, retryMaxDelay)
+	return d + rand.N(d/2+1)
+}
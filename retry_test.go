@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jcchavezs/gh-iterator/exec"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeGH installs a gh script failing with the stderr until it ran failures times.
+func fakeGH(t *testing.T, failures int, stderr string) string {
+	t.Helper()
+
+	counter := filepath.Join(t.TempDir(), "count")
+	installGH(t, "echo x >> "+counter+"\n"+
+		"if [ $(wc -l < "+counter+") -le "+strconv.Itoa(failures)+" ]; then echo '"+stderr+"' >&2; exit 1; fi\n"+
+		"cat\n")
+
+	return counter
+}
+
+func TestRetryExecer(t *testing.T) {
+	defer func(d time.Duration) { retryBaseDelay = d }(retryBaseDelay)
+	retryBaseDelay = time.Millisecond
+
+	x := withRetries(exec.NewExecerWithLogger(t.TempDir(), slog.New(slog.DiscardHandler)), 3)
+
+	t.Run("retries transient errors", func(t *testing.T) {
+		counter := fakeGH(t, 2, "gh: You have exceeded a secondary rate limit (HTTP 403)")
+
+		res, err := x.RunWithStdinX(context.Background(), strings.NewReader("body"), "gh", "issue", "create")
+		require.NoError(t, err)
+		require.Equal(t, "body", res)
+		require.Equal(t, 3, countLines(t, counter))
+	})
+
+	t.Run("gives up after the retries", func(t *testing.T) {
+		counter := fakeGH(t, 9, "gh: Server Error (HTTP 502)")
+
+		_, err := x.RunX(context.Background(), "gh", "api", "/user")
+		require.Error(t, err)
+		require.Equal(t, 4, countLines(t, counter))
+	})
+
+	t.Run("does not retry server errors of non idempotent calls", func(t *testing.T) {
+		for _, args := range [][]string{
+			{"issue", "create", "--title", "x"},
+			{"api", "-X", "POST", "/app/installations/1/access_tokens"},
+			{"api", "/repos/acme/a/releases", "-f", "tag_name=v1"},
+		} {
+			counter := fakeGH(t, 9, "gh: Server Error (HTTP 502)")
+
+			_, err := x.RunX(context.Background(), "gh", args...)
+			require.Error(t, err)
+			require.Equal(t, 1, countLines(t, counter), args)
+		}
+	})
+
+	t.Run("does not retry other errors", func(t *testing.T) {
+		counter := fakeGH(t, 9, "gh: Not Found (HTTP 404)")
+
+		res, err := x.Run(context.Background(), "gh", "api", "/user")
+		require.NoError(t, err)
+		require.Equal(t, 1, res.ExitCode)
+		require.Equal(t, 1, countLines(t, counter))
+	})
+}
+
+func TestIdempotentGHCall(t *testing.T) {
+	require.True(t, idempotentGHCall([]string{"api", "/user"}))
+	require.True(t, idempotentGHCall([]string{"api", "-X", "PUT", "/repos/acme/a/topics", "--input", "-"}))
+	require.True(t, idempotentGHCall([]string{"api", "--method=delete", "/repos/acme/a/git/refs/heads/x"}))
+	require.True(t, idempotentGHCall([]string{"pr", "view", "--json", "url"}))
+	require.True(t, idempotentGHCall([]string{"search", "repos", "topic:go"}))
+	require.False(t, idempotentGHCall([]string{"api", "--method", "PATCH", "/repos/acme/a"}))
+	require.True(t, idempotentGHCall([]string{"api", "graphql", "--paginate", "-f", "query=query($owner: String!) {}"}))
+	require.False(t, idempotentGHCall([]string{"api", "graphql", "-f", "query=mutation{}"}))
+	require.False(t, idempotentGHCall([]string{"pr", "create", "--fill"}))
+	require.False(t, idempotentGHCall([]string{"release", "create", "v1"}))
+}
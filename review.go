@@ -0,0 +1,303 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	iterator "github.com/jcchavezs/gh-iterator"
+	"golang.org/x/term"
+)
+
+// reviewSelection lists the repositories with their metadata and lets the user toggle them in
+// and out, in a list when in is a terminal and otherwise by number until an empty answer. It
+// returns the repositories kept.
+func reviewSelection(in io.Reader, out io.Writer, repos []iterator.Repository) ([]iterator.Repository, error) {
+	if f, ok := in.(*os.File); ok && isTerminal(f) {
+		return reviewList(f, out, repos)
+	}
+
+	keep := make([]bool, len(repos))
+	for i := range keep {
+		keep[i] = true
+	}
+
+	s := bufio.NewScanner(in)
+	for {
+		if err := writeReviewTable(out, repos, keep); err != nil {
+			return nil, err
+		}
+
+		fmt.Fprint(out, "Toggle repositories by number or range e.g. 1 3-5, all or none. Press enter to continue: ")
+		if !s.Scan() {
+			if err := s.Err(); err != nil {
+				return nil, fmt.Errorf("reading selection: %w", err)
+			}
+			break
+		}
+
+		answer := strings.TrimSpace(s.Text())
+		if answer == "" {
+			break
+		}
+
+		if err := toggleSelection(keep, answer); err != nil {
+			fmt.Fprintln(out, err)
+		}
+	}
+
+	return keptRepositories(repos, keep), nil
+}
+
+func keptRepositories(repos []iterator.Repository, keep []bool) []iterator.Repository {
+	var kept []iterator.Repository
+	for i, repo := range repos {
+		if keep[i] {
+			kept = append(kept, repo)
+		}
+	}
+
+	return kept
+}
+
+func writeReviewTable(out io.Writer, repos []iterator.Repository, keep []bool) error {
+	tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	for i, repo := range repos {
+		fmt.Fprintf(tw, "%s\t%d\t%s\n", keepMark(keep[i]), i+1, reviewRow(repo))
+	}
+
+	return tw.Flush()
+}
+
+func keepMark(keep bool) string {
+	if keep {
+		return "[x]"
+	}
+	return "[ ]"
+}
+
+// reviewRow returns the tab separated metadata of the repository shown in the review.
+func reviewRow(repo iterator.Repository) string {
+	pushed := ""
+	if !repo.PushedAt.IsZero() {
+		pushed = repo.PushedAt.Format("2006-01-02")
+	}
+
+	return strings.Join([]string{repo.Name, repo.Language, repo.Visibility, pushed}, "\t")
+}
+
+// toggleSelection toggles the repositories in the answer: numbers, ranges, all or none.
+func toggleSelection(keep []bool, answer string) error {
+	switch answer {
+	case "all", "none":
+		for i := range keep {
+			keep[i] = answer == "all"
+		}
+		return nil
+	}
+
+	// the answer is validated before toggling any repository.
+	var toggle []int
+	for _, field := range strings.Fields(answer) {
+		from, to, isRange := strings.Cut(field, "-")
+		if !isRange {
+			to = from
+		}
+
+		start, err := strconv.Atoi(from)
+		if err != nil {
+			return fmt.Errorf("invalid repository number %q", field)
+		}
+
+		end, err := strconv.Atoi(to)
+		if err != nil {
+			return fmt.Errorf("invalid repository number %q", field)
+		}
+
+		if start < 1 || end > len(keep) || start > end {
+			return fmt.Errorf("repository number %q out of range", field)
+		}
+
+		for n := start; n <= end; n++ {
+			toggle = append(toggle, n-1)
+		}
+	}
+
+	if len(toggle) == 0 {
+		return errors.New("no repositories to toggle")
+	}
+
+	for _, i := range toggle {
+		keep[i] = !keep[i]
+	}
+
+	return nil
+}
+
+// deselect records the selected repositories not kept in skipped with the reason and returns the
+// kept ones.
+func deselect(selected []iterator.Repository, kept []iterator.Repository, skipped map[string]string, reason string) []iterator.Repository {
+	isKept := make(map[string]bool, len(kept))
+	for _, repo := range kept {
+		isKept[repo.Name] = true
+	}
+
+	for _, repo := range selected {
+		if !isKept[repo.Name] {
+			skipped[repo.Name] = reason
+		}
+	}
+
+	return kept
+}
+
+// the keys of the list review, the arrows come as escape sequences.
+const (
+	keyUp    = "\x1b[A"
+	keyDown  = "\x1b[B"
+	keyCtrlC = "\x03"
+)
+
+// reviewList shows the repositories in a list to move through with the arrows or j and k and
+// toggle with space, a keeps them all and n none. Enter continues with the repositories kept
+// and q or ctrl-c aborts the run.
+func reviewList(in *os.File, out io.Writer, repos []iterator.Repository) ([]iterator.Repository, error) {
+	fd := int(in.Fd())
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return nil, fmt.Errorf("setting terminal in raw mode: %w", err)
+	}
+	defer term.Restore(fd, state) //nolint:errcheck
+
+	// the list takes the height of the terminal but the help line.
+	height := 10
+	if _, h, err := term.GetSize(fd); err == nil && h > 2 {
+		height = h - 1
+	}
+
+	return newListReview(repos, height).run(bufio.NewReader(in), out)
+}
+
+// listReview is the state of the list review: the repositories kept, the one under the cursor
+// and the ones in view.
+type listReview struct {
+	repos  []iterator.Repository
+	keep   []bool
+	cursor int
+	offset int
+	height int
+	// drawn is the number of lines rendered, to draw over them.
+	drawn int
+}
+
+func newListReview(repos []iterator.Repository, height int) *listReview {
+	keep := make([]bool, len(repos))
+	for i := range keep {
+		keep[i] = true
+	}
+
+	return &listReview{repos: repos, keep: keep, height: max(height, 1)}
+}
+
+func (l *listReview) run(r *bufio.Reader, out io.Writer) ([]iterator.Repository, error) {
+	// the cursor is hidden while the list is shown.
+	fmt.Fprint(out, "\033[?25l")
+	defer fmt.Fprint(out, "\r\n\033[?25h")
+
+	for {
+		if err := l.render(out); err != nil {
+			return nil, err
+		}
+
+		key, err := readKey(r)
+		if err != nil {
+			return nil, fmt.Errorf("reading selection: %w", err)
+		}
+
+		switch key {
+		case keyUp, "k":
+			l.move(-1)
+		case keyDown, "j":
+			l.move(1)
+		case " ":
+			l.keep[l.cursor] = !l.keep[l.cursor]
+		case "a", "n":
+			for i := range l.keep {
+				l.keep[i] = key == "a"
+			}
+		case "\r", "\n":
+			return keptRepositories(l.repos, l.keep), nil
+		case "q", keyCtrlC:
+			return nil, errors.New("aborted, the selection was not confirmed")
+		}
+	}
+}
+
+// move moves the cursor by delta, scrolling the list to keep it in view.
+func (l *listReview) move(delta int) {
+	l.cursor = min(max(l.cursor+delta, 0), len(l.repos)-1)
+	if l.cursor < l.offset {
+		l.offset = l.cursor
+	} else if l.cursor >= l.offset+l.height {
+		l.offset = l.cursor - l.height + 1
+	}
+}
+
+// render draws the repositories in view over the previous render, followed by the help line.
+func (l *listReview) render(out io.Writer) error {
+	var rows strings.Builder
+	tw := tabwriter.NewWriter(&rows, 0, 4, 2, ' ', 0)
+	for i := l.offset; i < min(l.offset+l.height, len(l.repos)); i++ {
+		pointer := " "
+		if i == l.cursor {
+			pointer = ">"
+		}
+
+		fmt.Fprintf(tw, "%s %s\t%s\n", pointer, keepMark(l.keep[i]), reviewRow(l.repos[i]))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	if l.drawn > 0 {
+		fmt.Fprintf(&b, "\033[%dA", l.drawn)
+	}
+	b.WriteString("\r\033[J")
+	// the terminal is in raw mode so the lines need the carriage return.
+	b.WriteString(strings.ReplaceAll(rows.String(), "\n", "\r\n"))
+	fmt.Fprintf(&b, "%d of %d kept. up/down or j/k move, space toggles, a all, n none, enter continues, q aborts", len(keptRepositories(l.repos, l.keep)), len(l.repos))
+	l.drawn = strings.Count(rows.String(), "\n")
+
+	_, err := io.WriteString(out, b.String())
+	return err
+}
+
+// readKey reads a key press, returning the arrows as the escape sequences in normal mode.
+func readKey(r *bufio.Reader) (string, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return "", err
+	}
+
+	if b != 0x1b || r.Buffered() < 2 {
+		return string(b), nil
+	}
+
+	seq := make([]byte, 2)
+	if _, err := io.ReadFull(r, seq); err != nil {
+		return "", err
+	}
+
+	// the arrows come as ESC O in application mode.
+	if seq[0] == 'O' {
+		seq[0] = '['
+	}
+
+	return "\x1b" + string(seq), nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+
+	iterator "github.com/jcchavezs/gh-iterator"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReviewSelection(t *testing.T) {
+	repos := []iterator.Repository{{Name: "acme/a", Language: "Go"}, {Name: "acme/b"}, {Name: "acme/c"}, {Name: "acme/d"}}
+
+	out := &bytes.Buffer{}
+	kept, err := reviewSelection(strings.NewReader("2-3\n9\n3\n\n"), out, repos)
+	require.NoError(t, err)
+	require.Equal(t, []iterator.Repository{{Name: "acme/a", Language: "Go"}, {Name: "acme/c"}, {Name: "acme/d"}}, kept)
+	require.Contains(t, out.String(), "[x]  1  acme/a  Go")
+	require.Contains(t, out.String(), `repository number "9" out of range`)
+
+	kept, err = reviewSelection(strings.NewReader("none\n4"), &bytes.Buffer{}, repos)
+	require.NoError(t, err)
+	require.Equal(t, []iterator.Repository{{Name: "acme/d"}}, kept)
+}
+
+func TestListReview(t *testing.T) {
+	repos := []iterator.Repository{{Name: "acme/a", Language: "Go"}, {Name: "acme/b"}, {Name: "acme/c"}, {Name: "acme/d"}}
+
+	out := &bytes.Buffer{}
+	l := newListReview(repos, 2)
+	// toggles acme/b with the arrows and acme/d with j, scrolling the list.
+	kept, err := l.run(bufio.NewReader(strings.NewReader("\x1b[B \x1bOBjj \r")), out)
+	require.NoError(t, err)
+	require.Equal(t, []iterator.Repository{{Name: "acme/a", Language: "Go"}, {Name: "acme/c"}}, kept)
+	require.Equal(t, 2, l.offset)
+	require.Contains(t, out.String(), "> [x]  acme/a  Go")
+	require.Contains(t, out.String(), "2 of 4 kept")
+
+	kept, err = newListReview(repos, 10).run(bufio.NewReader(strings.NewReader("nk \r")), &bytes.Buffer{})
+	require.NoError(t, err)
+	require.Equal(t, []iterator.Repository{{Name: "acme/a", Language: "Go"}}, kept)
+
+	_, err = newListReview(repos, 10).run(bufio.NewReader(strings.NewReader("q")), &bytes.Buffer{})
+	require.ErrorContains(t, err, "aborted")
+
+	_, err = newListReview(repos, 10).run(bufio.NewReader(strings.NewReader("")), &bytes.Buffer{})
+	require.Error(t, err)
+}
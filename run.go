@@ -0,0 +1,661 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"regexp"
+	"strconv"
+	"time"
+
+	iterator "github.com/jcchavezs/gh-iterator"
+	"github.com/jcchavezs/gh-iterator/exec"
+	"github.com/spf13/cobra"
+	"github.com/thediveo/enumflag/v2"
+)
+
+// errStopListing stops listing the repositories once the run stops dispatching them.
+var errStopListing = errors.New("stop listing")
+
+func newRunCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "run [OWNER...] [-- ARGS...]",
+		Short: "Run a command in the repositories passing the filter",
+		Long: `Clones the repositories passing the filter and runs the command in each of them, optionally
+committing the changes and opening pull requests. It is the default command.
+
+The arguments after -- are appended to the command e.g. 'run acme -c ./script.sh -- --flag value'.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			if flags.quiet && !cmd.Flag("log-level").Changed {
+				flags.logLevel = slog.LevelWarn
+			}
+
+			logHandler := newLogHandler(cmd.ErrOrStderr(), flags.logLevel)
+			logger := slog.New(logHandler)
+
+			if err := loadCommandFile(); err != nil {
+				return err
+			}
+
+			if n := cmd.ArgsLenAtDash(); n >= 0 {
+				if flags.command == "" {
+					return errors.New("the arguments after -- require --command or --command-file")
+				}
+				flags.command = appendCommandArgs(flags.command, args[n:])
+				args = args[:n]
+			}
+
+			if !flags.noClone {
+				// the working directories of the runs killed or crashed are left behind.
+				removed, err := cleanWorkDirs(workBaseDir(), staleWorkDirAge, false, logger)
+				if err != nil {
+					logger.Warn("Failed to clean the stale working directories", "error", err)
+				} else if len(removed) > 0 {
+					logger.Info("Removed stale working directories", "dirs", removed)
+				}
+			}
+
+			var retried []failure
+			if flags.retryFailed != "" {
+				if len(flags.includeRepos) > 0 {
+					return errors.New("--retry-failed can't be used with --include-repos")
+				}
+
+				fs, err := readFailures(flags.retryFailed)
+				if err != nil {
+					return err
+				}
+				retried = fs
+
+				if len(retried) == 0 {
+					logger.Info("No failed repositories to retry", "file", flags.retryFailed)
+					return nil
+				}
+
+				// only the failed repositories are fetched and processed, the rest of the
+				// filters still apply.
+				for _, f := range retried {
+					flags.includeRepos = append(flags.includeRepos, f.Repository)
+				}
+
+				if flags.failuresReport == "" {
+					flags.failuresReport = flags.retryFailed
+				}
+			}
+
+			owners, err := setupSources(ctx, args, logger)
+			if err != nil {
+				return err
+			}
+
+			if flags.fixtures != "" {
+				// nothing is fetched nor cloned, so the filter and the output can be tested
+				// without network nor credentials. Without a command the processing is a no-op.
+				flags.noClone, flags.skipScopeCheck, flags.yes, flags.minRateLimit = true, true, true, 0
+			}
+
+			stages, err := filterStages(logger)
+			if err != nil {
+				return err
+			}
+
+			processor := repoProcessor{
+				stdin:   cmd.InOrStdin(),
+				stdout:  cmd.OutOrStdout(),
+				stderr:  cmd.ErrOrStderr(),
+				prs:     &prReport{},
+				results: newRunResults(),
+			}
+			processor.results.retried = retried
+
+			if flags.quiet {
+				processor.stdout = io.Discard
+			} else if flags.output != OutputFormatText {
+				// keeps the stdout for the machine readable output.
+				processor.stdout = cmd.ErrOrStderr()
+			}
+
+			if flags.output == OutputFormatJSONL {
+				processor.results.jsonl = cmd.OutOrStdout()
+			}
+
+			if flags.applyPatch != "" {
+				if processor.patchFile, err = resolvePatchFile(flags.applyPatch); err != nil {
+					return err
+				}
+			}
+
+			if processor.replacements, err = parseReplacements(flags.replace, flags.replaceRegex); err != nil {
+				return err
+			}
+
+			if flags.syncFiles != "" {
+				policies, err := parseSyncPolicies(flags.syncPolicies)
+				if err != nil {
+					return err
+				}
+
+				if processor.templateFiles, err = loadTemplateFiles(flags.syncFiles, policies); err != nil {
+					return err
+				}
+			} else if len(flags.syncPolicies) > 0 {
+				return errors.New("--sync-policy requires --sync-files")
+			}
+
+			if flags.createPR {
+				if processor.prOptions, err = makePROptions(); err != nil {
+					return err
+				}
+			}
+
+			if processor.actions, err = repoActions(); err != nil {
+				return err
+			}
+
+			if len(processor.actions) > 0 && onlyActions() {
+				// the repositories are changed through the API, there is nothing to clone.
+				flags.noClone = true
+			}
+
+			if flags.exportProject != "" {
+				project, err := parseProjectRef(flags.exportProject)
+				if err != nil {
+					return err
+				}
+				processor.project = &project
+			}
+
+			if flags.createIssue {
+				if flags.issueTitle == "" {
+					return errors.New("--issue-title is required to create issues")
+				}
+
+				if flags.issueBodyFile != "" {
+					body, err := os.ReadFile(flags.issueBodyFile)
+					if err != nil {
+						return fmt.Errorf("reading issue body file: %w", err)
+					}
+					processor.issueBody = string(body)
+				}
+			}
+
+			if flags.noClone && (flags.applyPatch != "" || len(flags.replace) > 0 || flags.syncFiles != "" || flags.createPR || flags.commitMessage != "" || flags.push || flags.skipIfBranchExists) {
+				return errors.New("--no-clone can't be used with flags changing the repository contents")
+			}
+
+			if flags.tarball && (flags.createPR || flags.commitMessage != "" || flags.push || flags.skipIfBranchExists || flags.allBranches || flags.recurseSubmodules || len(flags.cloningSubset) > 0) {
+				return errors.New("--tarball can't be used with flags requiring a git clone")
+			}
+
+			if flags.cloneFilter != "" {
+				if flags.noClone {
+					return errors.New("--clone-filter can't be used with --no-clone")
+				}
+
+				if cloneFilterIn, err = parseCloneFilter(flags.cloneFilter); err != nil {
+					return fmt.Errorf("parsing clone filter: %w", err)
+				}
+			}
+
+			if flags.serializeBy != "" {
+				if serializeKey, err = parseSerializeKey(flags.serializeBy); err != nil {
+					return fmt.Errorf("parsing --serialize-by: %w", err)
+				}
+			}
+
+			if flags.grep != "" {
+				if flags.noClone {
+					return errors.New("--grep can't be used with --no-clone")
+				}
+
+				if processor.grep, err = regexp.Compile(flags.grep); err != nil {
+					return fmt.Errorf("parsing --grep: %w", err)
+				}
+			} else if len(flags.grepGlobs) > 0 {
+				return errors.New("--glob requires --grep")
+			}
+
+			if len(flags.requireFiles) > 0 && flags.noClone {
+				return errors.New("--require-files can't be used with --no-clone")
+			}
+
+			if flags.dependencyInventory != "" && flags.noClone {
+				return errors.New("--dependency-inventory can't be used with --no-clone")
+			} else if flags.dependency != "" && flags.dependencyInventory == "" {
+				return errors.New("--dependency requires --dependency-inventory")
+			}
+
+			if flags.processor != "" {
+				if flags.command != "" {
+					return errors.New("--processor can't be used with --command or --command-file")
+				}
+
+				if processor.plugin, err = resolvePlugin(flags.processor); err != nil {
+					return err
+				}
+			}
+
+			if flags.forEach != "" && flags.command == "" {
+				return errors.New("--for-each requires --command or --command-file")
+			}
+
+			if flags.showDiff {
+				if flags.createPR || flags.commitMessage != "" || flags.push {
+					return errors.New("--show-diff can't be used with flags committing the changes")
+				}
+
+				if flags.noClone || flags.tarball {
+					return errors.New("--show-diff requires a git clone")
+				}
+			}
+
+			for _, identity := range []string{flags.commitAuthor, flags.committer} {
+				if identity == "" {
+					continue
+				}
+
+				if _, err := parseIdentity(identity); err != nil {
+					return err
+				}
+			}
+
+			if len(flags.envAllow) > 0 && !flags.cleanEnv {
+				return errors.New("--env-allow requires --clean-env")
+			}
+
+			if flags.logStderr && flags.logDir == "" {
+				return errors.New("--log-stderr requires --log-dir")
+			}
+
+			if flags.quiet && flags.interactive {
+				return errors.New("--quiet can't be used with --interactive")
+			}
+
+			if (flags.skipIfBranchExists || flags.skipIfPROpen) && flags.branchName == "" {
+				return errors.New("--branch-name is required to skip repositories with existing branch or PR")
+			}
+
+			if flags.workers != autoWorkers {
+				if n, err := strconv.Atoi(flags.workers); err != nil || n < 1 {
+					return fmt.Errorf("invalid number of workers %q", flags.workers)
+				}
+			}
+
+			if flags.cloneConcurrency < 0 {
+				return fmt.Errorf("invalid clone concurrency %d", flags.cloneConcurrency)
+			} else if flags.cloneConcurrency > 0 && flags.workers == autoWorkers {
+				return errors.New("--clone-concurrency can't be used with --workers auto")
+			}
+
+			if err := validateCSVColumns(flags.columns); err != nil {
+				return err
+			}
+
+			if flags.maxDisk != "" {
+				if clonesDisk.limit, err = parseSize(flags.maxDisk); err != nil {
+					return err
+				}
+			}
+
+			var state *runState
+			if flags.state != "" {
+				if state, err = loadRunState(flags.state); err != nil {
+					return err
+				}
+				stages = append(stages, selectionStage{keep: state.changed, reason: state.unchangedReason})
+			}
+
+			// the repositories are processed as the pages are listed unless a flag needs all of
+			// them first.
+			stream := streamable()
+			listingX := withRetries(exec.NewExecerWithLogger(".", logger), flags.apiRetries)
+
+			var (
+				selected []iterator.Repository
+				found    int
+			)
+			if !stream {
+				var (
+					repos   []iterator.Repository
+					skipped map[string]string
+				)
+				repos, selected, skipped, err = matchingRepositories(ctx, listingX, owners, cmd.InOrStdin(), stages)
+				if err != nil {
+					return err
+				}
+
+				if flags.selectRepos && len(selected) > 0 {
+					if flags.reposFile == "-" || flags.reposJSON == "-" {
+						return errors.New("--select can't be used when the repositories are read from stdin")
+					}
+
+					kept, err := reviewSelection(cmd.InOrStdin(), cmd.ErrOrStderr(), selected)
+					if err != nil {
+						return err
+					}
+					selected = deselect(selected, kept, skipped, "deselected")
+				}
+
+				if flags.pick && len(selected) > 0 {
+					if flags.reposFile == "-" || flags.reposJSON == "-" {
+						return errors.New("--pick can't be used when the repositories are read from stdin")
+					}
+
+					picked, err := pickRepositories(ctx, cmd.InOrStdin(), cmd.ErrOrStderr(), selected)
+					if err != nil {
+						return err
+					}
+					if len(picked) == 0 {
+						return errors.New("aborted, no repositories picked")
+					}
+					selected = deselect(selected, picked, skipped, "not picked")
+				}
+
+				found = len(repos)
+				processor.results.addRepositories(repos, selected, skipped)
+
+				if !flags.skipScopeCheck && len(selected) > 0 {
+					if err := checkTokenScopes(ctx, listingX, selected); err != nil {
+						return err
+					}
+				}
+
+				if !flags.yes && len(selected) > 0 {
+					if flags.reposFile == "-" || flags.reposJSON == "-" {
+						return errors.New("--yes is required when the repositories are read from stdin")
+					}
+
+					ok, err := confirm(cmd.InOrStdin(), cmd.ErrOrStderr(), len(selected))
+					if err != nil {
+						return err
+					}
+
+					if !ok {
+						return errors.New("aborted")
+					}
+				}
+			}
+
+			var (
+				hooks runHooks
+				prog  *progress
+			)
+			if !flags.noProgress && !flags.quiet && !flags.stream && !flags.interactive && isTerminal(cmd.ErrOrStderr()) {
+				prog = newProgress(cmd.ErrOrStderr(), len(selected))
+				hooks = prog.hooks()
+			}
+			hooks = hooks.join(eventCommands(ctx, flags.onFailure, flags.onSuccess, processor.stdout, cmd.ErrOrStderr(), logger))
+
+			process := processor.process
+			if state != nil {
+				process = state.track(selected, process, processor.results)
+				hooks = hooks.join(state.hooks())
+			}
+
+			if flags.metricsListen != "" {
+				shutdown, err := serveMetrics(flags.metricsListen, processor.results)
+				if err != nil {
+					return err
+				}
+				defer shutdown(context.Background()) //nolint:errcheck
+			}
+
+			runCtx, stopShutdown := handleShutdown(ctx, flags.shutdownGrace, logger)
+			opts := iterator.Options{
+				LogHandler:      logHandler,
+				UseHTTPS:        flags.useHTTPS,
+				CloningSubset:   flags.cloningSubset,
+				NumberOfWorkers: numberOfWorkers(),
+				ContextEnricher: withRepository,
+			}
+			if stream {
+				scopes := &scopeChecker{x: listingX}
+				list := func(dispatch func(iterator.Repository) bool) error {
+					err := streamMatchingRepositories(runCtx, listingX, owners, cmd.InOrStdin(), stages, func(repos, selected []iterator.Repository, skipped map[string]string) error {
+						found += len(repos)
+						processor.results.addRepositories(repos, selected, skipped)
+
+						if !flags.skipScopeCheck {
+							if err := scopes.check(runCtx, selected); err != nil {
+								return err
+							}
+						}
+
+						if prog != nil {
+							prog.addTotal(len(selected))
+						}
+
+						for _, repo := range selected {
+							if !dispatch(repo) {
+								return errStopListing
+							}
+						}
+						return nil
+					})
+					if errors.Is(err, errStopListing) {
+						return nil
+					}
+					return err
+				}
+				err = runForRepositoryStream(runCtx, list, nil, process, processor.results, hooks, opts)
+			} else {
+				err = runForRepositories(runCtx, selected, process, processor.results, hooks, opts)
+			}
+			stopShutdown()
+
+			if state != nil {
+				// the state is saved even if the run failed so the repositories processed
+				// successfully are not processed again.
+				if sErr := state.save(flags.state); sErr != nil {
+					return errors.Join(err, sErr)
+				}
+			}
+
+			// the results are written even if the run failed, including the error.
+			var wErr error
+			switch flags.output {
+			case OutputFormatJSON:
+				wErr = processor.results.writeJSON(cmd.OutOrStdout())
+			case OutputFormatCSV:
+				wErr = processor.results.writeCSV(cmd.OutOrStdout(), flags.columns)
+			case OutputFormatJUnit:
+				wErr = processor.results.writeJUnit(cmd.OutOrStdout())
+			case OutputFormatHTML:
+				wErr = processor.results.writeHTML(cmd.OutOrStdout())
+			}
+			if wErr == nil && flags.sarif != "" {
+				wErr = processor.results.writeSARIF(flags.sarif, flags.sarifRuleID)
+			}
+			if wErr == nil && flags.failuresReport != "" {
+				wErr = processor.results.writeFailures(flags.failuresReport, flags.command)
+			}
+			if wErr == nil && flags.archiveResults != "" {
+				wErr = processor.results.writeBundle(flags.archiveResults, flags.command, flags.logDir)
+			}
+			if wErr == nil && flags.dependencyInventory != "" {
+				wErr = processor.results.writeDependencyInventory(flags.dependencyInventory, flags.dependency)
+			}
+			if wErr == nil && flags.metricsPushURL != "" {
+				wErr = pushMetrics(ctx, flags.metricsPushURL, processor.results)
+			}
+			if wErr == nil && flags.notifyURL != "" {
+				wErr = notify(ctx, flags.notifyURL, processor.results, err, flags.notifyFailures)
+			}
+			if wErr != nil {
+				return errors.Join(err, wErr)
+			}
+
+			// the summary of the interrupted runs is printed too.
+			if err != nil && !flags.keepGoing && !errors.Is(err, errInterrupted) {
+				return err
+			}
+
+			if flags.keepGoing {
+				err = errors.Join(err, failedCommandsError(processor.results))
+			}
+
+			if flags.output == OutputFormatText {
+				fmt.Fprintf(cmd.OutOrStdout(), "Processed %d repositories\n", processor.results.processedCount())
+				fmt.Fprintf(cmd.OutOrStdout(), "Filtered %d repositories\n", found)
+				if flags.keepGoing {
+					fmt.Fprintf(cmd.OutOrStdout(), "Failed %d repositories\n", totals(processor.results.sorted()).Failed)
+				}
+				if len(flags.requireFiles) > 0 {
+					fmt.Fprintf(cmd.OutOrStdout(), "Missing required files in %d repositories\n", processor.results.missingFilesCount())
+				}
+				for _, c := range processor.results.skippedCounts() {
+					fmt.Fprintf(cmd.OutOrStdout(), "Skipped %d repositories: %s\n", c.Count, c.Reason)
+				}
+
+				if flags.slowest > 0 {
+					if err := processor.results.writeSlowest(cmd.OutOrStdout(), flags.slowest); err != nil {
+						return err
+					}
+				}
+
+				if flags.createPR {
+					if err := processor.prs.writeTable(cmd.OutOrStdout()); err != nil {
+						return err
+					}
+				}
+			}
+
+			if flags.createPR && flags.prReport != "" {
+				if rErr := processor.prs.writeFile(flags.prReport); rErr != nil {
+					return errors.Join(err, rErr)
+				}
+			}
+
+			// with --keep-going or when interrupted the errors are returned once the summary is
+			// printed.
+			return err
+		},
+	}
+
+	cmd.Flags().StringVar(&flags.state, "state", "", "File to record the last push of the processed repositories in, along with the reason of the ones skipped before running the command, so the next runs only process the repositories pushed since")
+	cmd.Flags().BoolVar(&flags.keepGoing, "keep-going", false, "Keeps processing the repositories when processing one fails instead of stopping the run, the errors are reported at the end. The run fails if any repository failed, including the commands exiting with non zero")
+	cmd.Flags().StringVar(&flags.cloneFilter, "clone-filter", "", "CEL condition evaluated in the clone of each repository before running the command, the ones not matching are skipped. Besides repo, it can parse the files of the clone with fileJSON(path), fileYAML(path) and fileTOML(path) and inspect its history with git.lastCommitAuthor, git.lastCommitDate and git.commitCountSince(duration)")
+	cmd.Flags().StringVar(&flags.campaign, "campaign", "", "Name of the campaign, the repositories listing it in their .github/gh-iterator-ignore or .gh-iterator-ignore file are skipped. The repositories with an empty file are skipped in all the runs")
+	cmd.Flags().BoolVar(&flags.skipScopeCheck, "skip-scope-check", false, "Skips checking the token has the scopes to clone and change the repositories before processing them")
+	cmd.Flags().BoolVar(&flags.selectRepos, "select", false, "Lists the repositories passing the filter to toggle them in and out before processing them: in a terminal, moving through the list with the arrows and toggling with space, otherwise by number")
+	cmd.Flags().BoolVar(&flags.pick, "pick", false, "Picks the repositories to process out of the ones passing the filter with a fuzzy finder, fzf when it is installed")
+	cmd.Flags().BoolVarP(&flags.yes, "yes", "y", false, "Processes the matching repositories without asking for confirmation, as the pages are listed unless --sort, --sample, --limit, --select, --pick, --largest-first, --serialize-by or --state need all of them first")
+	cmd.Flags().StringVarP(&flags.command, "command", "c", "", "Command to run in each repository. {{ .Repository }} is replaced by the repository name and the metadata of the repository is passed in the GH_ITERATOR_REPOSITORY, GH_ITERATOR_REPOSITORY_JSON, GH_ITERATOR_DEFAULT_BRANCH, GH_ITERATOR_LANGUAGE and GH_ITERATOR_VISIBILITY env variables")
+	cmd.Flags().StringVar(&flags.commandFile, "command-file", "", "File to read the command to run in each repository from instead of --command, e.g. a multi-line script with comments. {{ .Repository }} is replaced by the repository name")
+	cmd.Flags().StringVar(&flags.forEach, "for-each", "", "Glob of the directories, or of the files in them, to run the command in once each instead of the repository root e.g. '**/go.mod'. The directory is passed in the GH_ITERATOR_DIR env variable and replaces {{ .Dir }} in the command")
+	cmd.Flags().StringVar(&flags.grep, "grep", "", "Regular expression to search the files of each repository for, the matching lines are printed as repository:file:line:text and included in the json, jsonl and SARIF outputs. It can be used instead of or along with --command")
+	cmd.Flags().StringSliceVar(&flags.grepGlobs, "glob", nil, "Glob of the files searched by --grep e.g. '**/*.go'. It can be repeated, by default all the files are searched")
+	cmd.Flags().StringSliceVar(&flags.requireFiles, "require-files", nil, "Files every repository must have e.g. LICENSE,SECURITY.md,CODEOWNERS|.github/CODEOWNERS, where | separates alternative paths. The missing ones are printed and included in the json, jsonl and csv outputs")
+	cmd.Flags().StringVar(&flags.dependencyInventory, "dependency-inventory", "", "File to write the inventory of the dependencies declared in the go.mod, package.json, requirements.txt and pom.xml files of the repositories to, i.e. the repositories using each version of each dependency, as markdown if it has .md extension, otherwise as JSON")
+	cmd.Flags().StringVar(&flags.dependency, "dependency", "", "Only includes this dependency in the inventory e.g. github.com/pkg/errors or org.apache.logging.log4j:log4j-core")
+	cmd.Flags().StringVar(&flags.processor, "processor", "", "Executable to process each repository with instead of --command. It runs in the repository directory, gets the repository as JSON on stdin and can print a JSON result on stdout with the skipped reason, the error and the findings reported in the SARIF file e.g. {\"findings\": [{\"path\": \"go.mod\", \"line\": 3, \"message\": \"outdated\"}]}")
+	cmd.Flags().StringVar(&flags.preCommand, "pre-command", "", "Command to run in each repository before the command e.g. for setup")
+	cmd.Flags().StringVar(&flags.postCommand, "post-command", "", "Command to run in each repository after the command, even if it failed. The exit code of the command is passed in the GH_ITERATOR_EXIT_CODE env variable")
+	cmd.Flags().StringVar(&flags.applyPatch, "apply-patch", "", "Unified diff file to apply with 'git apply' in each repository before running the command")
+	cmd.Flags().StringArrayVar(&flags.replace, "replace", nil, "Replacement in the form 'old=>new' to apply to the files in each repository before running the command")
+	cmd.Flags().StringArrayVar(&flags.replaceIn, "in", nil, "Glob of the files to apply the replacements to e.g. '**/*.go'. By default, all files")
+	cmd.Flags().BoolVar(&flags.replaceRegex, "regex", false, "Treats the old part of the replacements as a regular expression")
+	cmd.Flags().StringVar(&flags.syncFiles, "sync-files", "", "Directory with the files to copy into each repository at the same paths before running the command e.g. workflows, CODEOWNERS or linters config. The files changed are staged so they are committed with --commit-message or --create-pr")
+	cmd.Flags().StringArrayVar(&flags.syncPolicies, "sync-policy", nil, "Policy of the files of --sync-files matching the glob as 'glob=policy' e.g. 'CODEOWNERS=merge', the policy being overwrite, create to only create the missing files or merge to append the missing lines. It can be repeated, the first matching applies and by default the files are overwritten")
+	cmd.Flags().BoolVar(&flags.showDiff, "show-diff", false, "Prints the diff of the changes made in each repository without committing them, or writes it in <output-dir>/<org>/<repo>/diff with --output-dir, to review a change before opening the pull requests")
+	cmd.Flags().BoolVar(&flags.createPR, "create-pr", false, "Commits the changes made in each repository into a branch and opens or updates a pull request")
+	cmd.Flags().StringVar(&flags.prTitle, "pr-title", "", "Title of the pull request, also used as commit message")
+	cmd.Flags().StringVar(&flags.prBody, "pr-body", "", "Body of the pull request")
+	cmd.Flags().StringVar(&flags.prBodyFile, "pr-body-file", "", "File to read the body of the pull request from")
+	cmd.Flags().BoolVar(&flags.prDraft, "pr-draft", false, "Opens the pull request as draft. Existing pull requests are marked as draft, or as ready for review when the flag is not passed")
+	cmd.Flags().StringVar(&flags.prReport, "pr-report", "", "File to write the report of the pull requests to, as markdown if it has .md extension, otherwise as JSON")
+	cmd.Flags().BoolVar(&flags.prWaitChecks, "pr-wait-checks", false, "Waits for the checks of the pull request to complete and includes the outcome in the report")
+	cmd.Flags().DurationVar(&flags.prWaitChecksTimeout, "pr-wait-checks-timeout", 30*time.Minute, "Maximum time to wait for the checks of each pull request")
+	cmd.Flags().StringVar(&flags.branchName, "branch-name", "", "Name of the branch to create and commit the changes to")
+	cmd.Flags().BoolVar(&flags.skipIfBranchExists, "skip-if-branch-exists", false, "Skips the repositories where the branch passed in --branch-name already exists")
+	cmd.Flags().BoolVar(&flags.skipIfPROpen, "skip-if-pr-open", false, "Skips the repositories with an open pull request for the branch passed in --branch-name")
+	cmd.Flags().StringVar(&flags.commitMessage, "commit-message", "", "Commits the changes made in each repository with this message")
+	cmd.Flags().BoolVar(&flags.commitAll, "commit-all", false, "Stages all the changes in the working tree before committing, otherwise only the changes staged by the command are committed")
+	cmd.Flags().BoolVar(&flags.signCommits, "sign-commits", false, "Signs the commits with the key configured in git, e.g. for the protected branches requiring signed commits")
+	cmd.Flags().StringVar(&flags.signingKey, "signing-key", "", "Key to sign the commits with, the GPG key ID or with --signing-format ssh the path of the SSH key. It implies --sign-commits")
+	cmd.Flags().Var(
+		enumflag.New(&flags.signingFormat, "string", SigningFormatIds, enumflag.EnumCaseInsensitive),
+		"signing-format",
+		"Format of the commit signatures: gpg or ssh",
+	)
+	cmd.Flags().StringVar(&flags.commitAuthor, "commit-author", "", "Author of the commits as 'Name <email>' instead of the one configured in git")
+	cmd.Flags().StringVar(&flags.committer, "committer", "", "Committer of the commits as 'Name <email>' instead of the one configured in git")
+	cmd.Flags().BoolVar(&flags.push, "push", false, "Pushes the current branch after committing")
+	cmd.Flags().StringVar(&flags.exportProject, "export-project", "", "GitHub Project to add the pull request created in each repository to as OWNER/NUMBER e.g. acme/12, or a draft issue named after the repository when no pull request is created. The token needs the project scope")
+	cmd.Flags().StringSliceVar(&flags.addTopics, "add-topic", nil, "Topics to add to each repository through the API e.g. team-payments, it can be repeated. Without flags needing a clone, the repositories are not cloned")
+	cmd.Flags().StringSliceVar(&flags.removeTopics, "remove-topic", nil, "Topics to remove from each repository through the API, it can be repeated")
+	cmd.Flags().StringVar(&flags.applySettings, "apply-settings", "", "YAML file with the settings to apply to each repository through the API out of default_branch, allow_merge_commit, allow_squash_merge, allow_rebase_merge, delete_branch_on_merge, vulnerability_alerts and branch_protection, the latter with required_approving_review_count, require_code_owner_reviews, dismiss_stale_reviews, required_status_checks, strict_status_checks and enforce_admins. The settings drifting are reported as changes")
+	cmd.Flags().StringArrayVar(&flags.grantTeams, "grant-team", nil, "Team to grant access to each repository as ORG/TEAM:PERMISSION e.g. acme/payments:push, the permission being pull, triage, push, maintain or admin. It can be repeated")
+	cmd.Flags().StringArrayVar(&flags.revokeTeams, "revoke-team", nil, "Team to revoke the access to each repository of as ORG/TEAM e.g. acme/legacy, it can be repeated")
+	cmd.Flags().StringVar(&flags.syncLabels, "sync-labels", "", "YAML file with the issue labels each repository must have as a list of name, color and description under labels. The missing ones are created, the differing ones updated and, with prune: true, the others deleted. Pass --dry-run to only print the differences")
+	cmd.Flags().StringVar(&flags.createTag, "create-tag", "", "Tag to create on the head of the default branch of each repository through the API e.g. v1.2.0, the repositories having it already are left as they are")
+	cmd.Flags().BoolVar(&flags.release, "release", false, "Creates a GitHub release for the tag passed in --create-tag")
+	cmd.Flags().StringVar(&flags.releaseNotesFile, "notes-file", "", "File to read the notes of the release passed in --release from")
+	cmd.Flags().StringVar(&flags.dispatchWorkflow, "dispatch-workflow", "", "Workflow to trigger a workflow_dispatch event of in each repository through the API e.g. ci.yml, on the ref passed in --ref or the default branch")
+	cmd.Flags().StringArrayVar(&flags.workflowInputs, "input", nil, "Input of the workflow passed in --dispatch-workflow as key=value, it can be repeated")
+	cmd.Flags().BoolVar(&flags.deleteMerged, "delete-merged-branches", false, "Deletes the branches of each repository merged into the default branch through the API, except the protected ones. The branches squashed or rebased on merge are not detected as merged")
+	cmd.Flags().StringSliceVar(&flags.protectBranches, "protect", nil, "Glob of the branches --delete-merged-branches keeps e.g. 'release/*', it can be repeated")
+	cmd.Flags().StringVar(&flags.mirror, "mirror", "", "Directory to back up the repositories in as mirrors i.e. <mirror>/<org>/<repo>.git, cloned with 'git clone --mirror' or updated when already there. Without flags needing a clone, the repositories are not cloned")
+	cmd.Flags().BoolVar(&flags.archive, "archive", false, "Archives each repository through the API, it requires --yes or --dry-run e.g. with the search filter '!repo.archived && repo.pushedAt < timestamp(\"2023-01-01T00:00:00Z\")' to retire the stale repositories")
+	cmd.Flags().BoolVar(&flags.dryRun, "dry-run", false, "Prints the changes the flags changing the repositories through the API e.g. --add-topic would make without making them")
+	cmd.Flags().BoolVar(&flags.createIssue, "create-issue", false, "Opens an issue in each repository unless an open one with the same title exists")
+	cmd.Flags().StringVar(&flags.issueTitle, "issue-title", "", "Title of the issue")
+	cmd.Flags().StringVar(&flags.issueBodyFile, "issue-body-file", "", "File to read the body of the issue from")
+	cmd.Flags().StringVar(&flags.outputDir, "output-dir", "", "Directory where the stdout, stderr and exit code of the command are written per repository i.e. <output-dir>/<org>/<repo>/")
+	cmd.Flags().StringVar(&flags.logDir, "log-dir", "", "Directory to write the logs of each repository to at debug level instead of stderr i.e. <log-dir>/<org>/<repo>.log")
+	cmd.Flags().BoolVar(&flags.logStderr, "log-stderr", false, "Writes the stderr of the commands to the log file of the repository in --log-dir instead of stderr")
+	cmd.Flags().BoolVarP(&flags.quiet, "quiet", "q", false, "Only prints the final summary or the machine readable output, the output of the commands and the progress are not printed")
+	cmd.Flags().BoolVar(&flags.noProgress, "no-progress", false, "Disables the progress line shown on stderr when it is a terminal")
+	cmd.Flags().IntVar(&flags.slowest, "slowest", 0, "Prints the clone and command times of the N repositories that took the longest at the end of the run")
+	cmd.Flags().StringVar(&flags.serializeBy, "serialize-by", "", "CEL expression computing a key out of the repo variable of the search filter, the repositories with the same key are processed one at a time while the others run concurrently e.g. 'repo.topics.exists(t, t.startsWith(\"team-\")) ? repo.topics.filter(t, t.startsWith(\"team-\"))[0] : \"\"'. An empty key has no constraint")
+	cmd.Flags().IntVar(&flags.cloneConcurrency, "clone-concurrency", 0, "Number of repositories cloned concurrently, independent of --workers which then limits the commands running concurrently. The cloned repositories wait for a free worker to run the command")
+	cmd.Flags().BoolVar(&flags.largestFirst, "largest-first", false, "Dispatches the biggest repositories to the workers first, by their size, so a big one does not start last and extend the run once the rest finished. Unlike --sort size, it does not change which repositories --limit picks")
+	cmd.Flags().StringVar(&flags.workers, "workers", strconv.Itoa(defaultNumberOfWorkers), "Number of repositories processed concurrently, or 'auto' to scale it with the clone times, the API rate limit and the CPU load")
+	cmd.Flags().VarP(
+		enumflag.New(&flags.output, "string", OutputFormatIds, enumflag.EnumCaseInsensitive),
+		"output", "o",
+		"Format of the run output: text, json, jsonl to write a JSON line per repository as soon as it is processed, csv, junit or html for a single-file report with sortable results, summary charts and the output and diff of every repository. With other than text the output of the commands is written to stderr",
+	)
+	cmd.Flags().StringSliceVar(&flags.columns, "columns", defaultCSVColumns, "Columns of the csv output out of repository, language, matched, status, skipped, exit_code, duration, clone_duration, command_duration, pr_url, error, error_category, matches, missing_files and changes")
+	cmd.Flags().StringVar(&flags.onFailure, "on-failure", "", "Shell command to run once a repository fails e.g. to page or open a ticket. It gets GH_ITERATOR_REPOSITORY, GH_ITERATOR_STATUS, GH_ITERATOR_EXIT_CODE, GH_ITERATOR_ERROR, GH_ITERATOR_ERROR_CATEGORY and, if set, the GH_ITERATOR_REPORT path of --failures-report and the GH_ITERATOR_LOG path of the repository log in --log-dir in the env")
+	cmd.Flags().StringVar(&flags.onSuccess, "on-success", "", "Shell command to run once a repository is processed successfully, with the same env as --on-failure")
+	cmd.Flags().StringVar(&flags.archiveResults, "archive-results", "", "File to package the results of the run into as a .tar.gz once it finishes: the JSON results and failures, the diffs captured with --show-diff and the repository logs of --log-dir")
+	cmd.Flags().StringVar(&flags.retryFailed, "retry-failed", "", "JSON failures report of a previous run to process only its repositories again, with the same filters and command. The report, or --failures-report if passed, is updated with the repositories still failing")
+	cmd.Flags().StringVar(&flags.failuresReport, "failures-report", "", "File to write the failed repositories to with their command, exit code and stderr, as markdown if it has .md extension, otherwise as JSON")
+	cmd.Flags().StringVar(&flags.sarif, "sarif", "", "File to write the output lines of the commands to as SARIF findings, lines like path:line[:column]: message are located in the file")
+	cmd.Flags().StringVar(&flags.sarifRuleID, "sarif-rule-id", "gh-iterator-run", "Rule ID of the SARIF findings")
+	cmd.Flags().StringVar(&flags.notifyURL, "notify-url", "", "URL to POST the summary of the run to as JSON once it finishes")
+	cmd.Flags().BoolVar(&flags.notifyFailures, "notify-failures", false, "Includes the failed repositories in the notification sent to --notify-url")
+	cmd.Flags().StringVar(&flags.metricsListen, "metrics-listen", "", "Address to expose the Prometheus metrics of the run in under /metrics e.g. :9090")
+	cmd.Flags().StringVar(&flags.metricsPushURL, "metrics-push-url", "", "URL of a Prometheus Pushgateway to push the metrics of the run to once it finishes")
+	cmd.Flags().BoolVar(&flags.stream, "stream", false, "Streams the command output line by line prefixed with the repository name instead of printing it once the command finishes")
+	cmd.Flags().BoolVar(&flags.interactive, "interactive", false, "Connects the command to the terminal so it can prompt for input. Repositories are processed one at a time")
+	cmd.Flags().BoolVar(&flags.debugShellOnFailure, "debug-shell-on-failure", false, "Starts a shell in the repository directory when the command exits with non zero code. Repositories are processed one at a time")
+	cmd.Flags().DurationVar(&flags.execTimeout, "exec-timeout", 0, "Maximum time of each git and gh invocation made to process a repository e.g. 10m, so a hung clone fails the repository instead of blocking a worker. By default, no limit")
+	cmd.Flags().DurationVar(&flags.shutdownGrace, "shutdown-grace", 30*time.Second, "Time to wait for the repositories being processed to finish on SIGINT or SIGTERM before cancelling them, a second signal cancels them right away. The run exits with code 130")
+	cmd.Flags().BoolVar(&flags.cleanEnv, "clean-env", false, "Runs the command, the hooks and the processor with only PATH, HOME, USER, SHELL, TERM, TMPDIR, LANG, LC_* and TZ out of the environment plus the GH_ITERATOR_* variables, so the scripts don't get the tokens and credentials in it. git and gh still get the full environment")
+	cmd.Flags().StringSliceVar(&flags.envAllow, "env-allow", nil, "Variables of the environment passed to the commands with --clean-env besides the default ones e.g. GOPATH or 'NPM_*', it can be repeated")
+	cmd.Flags().IntVar(&flags.minRateLimit, "min-rate-limit", 100, "Pauses processing repositories when fewer API requests than this remain until the rate limit resets, 0 disables it")
+	cmd.Flags().BoolVar(&flags.noClone, "no-clone", false, "Runs the command in an empty directory instead of a clone of the repository, the repository metadata is available in the GH_ITERATOR_REPOSITORY_JSON env variable")
+	cmd.Flags().StringVar(&flags.ref, "ref", "", "Branch, tag or commit to check out instead of the default branch e.g. release/v2")
+	cmd.Flags().Var(
+		enumflag.New(&flags.refFallback, "string", RefFallbackIds, enumflag.EnumCaseInsensitive),
+		"ref-fallback",
+		"What to do with the repositories where the ref passed in --ref does not exist: skip, default-branch or fail",
+	)
+	cmd.Flags().BoolVar(&flags.allBranches, "all-branches", false, "Fetches all the branches of the repository into the clone, as remote branches of origin")
+	cmd.Flags().BoolVar(&flags.recurseSubmodules, "recurse-submodules", false, "Initializes and updates the submodules of the repository before running the command")
+	cmd.Flags().BoolVar(&flags.keepClones, "keep-clones", false, "Keeps the working directory of each repository after processing it and logs where it is")
+	cmd.Flags().StringVar(&flags.workDir, "workdir", os.Getenv("GH_ITERATOR_WORKDIR"), "Directory to clone the repositories in, it can also be set with the GH_ITERATOR_WORKDIR env variable. By default, a directory in the system temporary directory")
+	cmd.Flags().StringVar(&flags.maxDisk, "max-disk", "", "Maximum disk space used by the clones e.g. 20GB, the run aborts before exceeding it. By default, no limit")
+	cmd.Flags().BoolVar(&flags.tarball, "tarball", false, "Downloads and extracts the archive of the repository instead of cloning it, for read-only scans. It does not require SSH access")
+	cmd.Flags().BoolVar(&flags.useHTTPS, "use-https", false, "Clones the repositories over HTTPS instead of SSH, authenticating with the token in the GH_TOKEN or GITHUB_TOKEN env variables when set, otherwise with the gh credentials")
+	cmd.Flags().StringArrayVar(&flags.cloningSubset, "cloning-subset", nil, "")
+
+	return cmd
+}
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"slices"
+	"strconv"
+	"sync"
+
+	iterator "github.com/jcchavezs/gh-iterator"
+)
+
+const (
+	defaultNumberOfWorkers = 10
+	// maxAutoWorkers is the maximum number of workers when they are scaled automatically.
+	maxAutoWorkers = 32
+)
+
+// runForRepositories runs the processor concurrently for the repositories, recording the clone
+// times in results and notifying the hooks. It stops dispatching repositories at the first error,
+// unless --keep-going is passed in which case all the repositories are processed and the errors
+// are joined. The repositories with the same --serialize-by key are processed one at a time and
+// with --largest-first the biggest repositories are dispatched first. When the context is
+// draining, no more repositories are dispatched and the run fails with errInterrupted once the
+// ones being processed finish.
+func runForRepositories(ctx context.Context, repos []iterator.Repository, processor iterator.Processor, results *runResults, hooks runHooks, opts iterator.Options) error {
+	if flags.largestFirst {
+		// the biggest repositories start first so none of them is left for the end, when
+		// the other workers are idle. The selection and the output are not changed.
+		repos = slices.Clone(repos)
+		sortRepositories(repos, SortSize, OrderDesc)
+	}
+
+	var keys map[string]string
+	if serializeKey != nil {
+		keys = make(map[string]string, len(repos))
+		for _, repo := range repos {
+			key, err := serializeKey(repo)
+			if err != nil {
+				err = fmt.Errorf("processing %q: %w", repo.Name, err)
+				hooks.runFinished(totals(results.sorted()), err)
+				return err
+			}
+			keys[repo.Name] = key
+		}
+		repos = interleaveByKey(repos, keys)
+	}
+
+	list := func(dispatch func(iterator.Repository) bool) error {
+		for _, repo := range repos {
+			if !dispatch(repo) {
+				break
+			}
+		}
+		return nil
+	}
+
+	return runForRepositoryStream(ctx, list, keys, processor, results, hooks, opts)
+}
+
+// runForRepositoryStream is runForRepositories for the repositories passed to dispatch by list as
+// they are listed, which stops listing when dispatch returns false. The error listing them fails
+// the run once the repositories dispatched are processed. keys are the --serialize-by keys of the
+// repositories.
+func runForRepositoryStream(ctx context.Context, list func(dispatch func(iterator.Repository) bool) error, keys map[string]string, processor iterator.Processor, results *runResults, hooks runHooks, opts iterator.Options) (err error) {
+	defer func() { hooks.runFinished(totals(results.sorted()), err) }()
+
+	draining := drainingFromContext(ctx)
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	var (
+		logger = slog.New(opts.LogHandler)
+		repoC  = make(chan iterator.Repository)
+		wg     sync.WaitGroup
+		errsMu sync.Mutex
+		errs   []error
+	)
+
+	limiter := &rateLimiter{x: newExecer(".", logger), threshold: flags.minRateLimit}
+
+	nOfWorkers := defaultNumberOfWorkers
+	if opts.NumberOfWorkers > 0 {
+		nOfWorkers = opts.NumberOfWorkers
+	}
+
+	var keyedLock keyedMutex
+
+	var scaler *adaptiveConcurrency
+	if flags.workers == autoWorkers && opts.NumberOfWorkers == 0 {
+		nOfWorkers = maxAutoWorkers
+		scaler = newAdaptiveConcurrency(runtime.NumCPU(), nOfWorkers)
+	}
+
+	if flags.cloneConcurrency > 0 && !flags.noClone {
+		// the clones and the commands have their own limits: the extra workers clone while
+		// the others run the commands, and the cloned repositories wait for a free slot
+		// to run theirs.
+		cloneSlots = newStageLimiter(flags.cloneConcurrency)
+		defer func() { cloneSlots = nil }()
+
+		processor = newStageLimiter(nOfWorkers).limit(processor)
+		nOfWorkers += flags.cloneConcurrency
+	}
+
+	run := func(ctx context.Context, repo iterator.Repository) error {
+		if opts.ContextEnricher != nil {
+			ctx = opts.ContextEnricher(ctx, repo)
+		}
+
+		if err := limiter.wait(ctx); err != nil {
+			return err
+		}
+
+		logger := logger
+		if flags.logDir != "" {
+			f, err := openRepoLog(flags.logDir, repo.Name)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+
+			// the logs of the repository go to its file only, to keep the console clean.
+			logger = repoLogger(f)
+			ctx = withRepoLog(ctx, f)
+		}
+
+		if flags.noClone {
+			return runWithoutClone(ctx, repo, processor, logger)
+		}
+
+		if repo.Size > 0 && repo.DefaultBranchName == "" && flags.ref == "" {
+			logger.Warn("Repository with no default branch", "repository", repo.Name)
+			results.update(repo.Name, func(r *repoResult) { r.Skipped = "no default branch" })
+			return nil
+		}
+
+		return runWithClone(ctx, repo, processor, logger, results, opts)
+	}
+
+	for range nOfWorkers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for repo := range repoC {
+				if ctx.Err() != nil {
+					// if the context is cancelled we do not process any more repositories
+					continue
+				}
+
+				select {
+				case <-draining:
+					// the repository was dispatched along with the signal.
+					continue
+				default:
+				}
+
+				if scaler != nil {
+					scaler.acquire()
+				}
+
+				// the repositories with the same key are processed one at a time.
+				unlock := keyedLock.lock(keys[repo.Name])
+
+				hooks.repoStart(repo)
+
+				err := run(ctx, repo)
+				unlock()
+				results.update(repo.Name, func(r *repoResult) {
+					r.Processed = true
+					if err != nil {
+						r.Error = err.Error()
+						r.Category = categoryOf(err)
+					} else if r.failed() {
+						r.Category = categoryCommand
+					}
+				})
+				if err != nil {
+					logger.Warn("Failed to process repository", "repository", repo.Name, "category", categoryOf(err), "error", err)
+				}
+				if fErr := results.finish(repo.Name); fErr != nil {
+					err = errors.Join(err, fErr)
+				}
+
+				res, _ := results.get(repo.Name)
+				hooks.repoDone(repo, res, err)
+
+				if err != nil && flags.keepGoing {
+					errsMu.Lock()
+					errs = append(errs, err)
+					errsMu.Unlock()
+				} else if err != nil {
+					cancel(err)
+				}
+
+				if scaler != nil {
+					scaler.release(results.cloneDuration(repo.Name), limiter.low() || cpuOverloaded())
+				}
+			}
+		}()
+	}
+
+	listErr := list(func(repo iterator.Repository) bool {
+		select {
+		case repoC <- repo:
+			return true
+		case <-ctx.Done():
+			return false
+		case <-draining:
+			return false
+		}
+	})
+	close(repoC)
+	wg.Wait()
+
+	if listErr != nil {
+		errs = append(errs, listErr)
+	}
+
+	cause := context.Cause(ctx)
+	select {
+	case <-draining:
+		if !errors.Is(cause, errInterrupted) {
+			errs = append(errs, errInterrupted)
+		}
+	default:
+	}
+
+	return errors.Join(append(errs, cause)...)
+}
+
+// runWithoutClone runs the processor for the repository in an empty temporary directory instead
+// of a clone. The repository metadata is passed in the env variables, as there is no clone.
+func runWithoutClone(ctx context.Context, repo iterator.Repository, processor iterator.Processor, logger *slog.Logger) error {
+	env, err := repositoryEnv(repo)
+	if err != nil {
+		return err
+	}
+
+	dir, err := makeWorkDir(repo.Name)
+	if err != nil {
+		return err
+	}
+	logger = logger.With("repository", repo.Name)
+	defer removeWorkDir(dir, logger)
+
+	x := withRetries(withTimeout(newExecer(dir, logger), flags.execTimeout), flags.apiRetries).WithEnv(env...)
+
+	if err := processor(ctx, repo.Name, repo.Size == 0, x); err != nil {
+		return fmt.Errorf("processing %q: %w", repo.Name, err)
+	}
+
+	return nil
+}
+
+type repositoryContextKey struct{}
+
+// withRepository is the context enricher attaching the repository being processed to the
+// context, so the processor gets its metadata.
+func withRepository(ctx context.Context, repo iterator.Repository) context.Context {
+	return context.WithValue(ctx, repositoryContextKey{}, repo)
+}
+
+// repositoryFromContext returns the repository attached to the context by withRepository.
+func repositoryFromContext(ctx context.Context) (iterator.Repository, bool) {
+	repo, ok := ctx.Value(repositoryContextKey{}).(iterator.Repository)
+	return repo, ok
+}
+
+// repositoryEnv returns the env variables passing the repository metadata to the commands.
+func repositoryEnv(repo iterator.Repository) ([]string, error) {
+	repoJSON, err := json.Marshal(repo)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling repository: %w", err)
+	}
+
+	return []string{
+		"GH_ITERATOR_REPOSITORY", repo.Name,
+		"GH_ITERATOR_REPOSITORY_JSON", string(repoJSON),
+		"GH_ITERATOR_DEFAULT_BRANCH", repo.DefaultBranchName,
+		"GH_ITERATOR_LANGUAGE", repo.Language,
+		"GH_ITERATOR_VISIBILITY", repo.Visibility,
+		"GH_ITERATOR_EMPTY", strconv.FormatBool(repo.Size == 0),
+	}, nil
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	iterator "github.com/jcchavezs/gh-iterator"
+	"github.com/jcchavezs/gh-iterator/exec"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunForRepositories_SkipsNoDefaultBranch(t *testing.T) {
+	var calls atomic.Int32
+
+	err := runForRepositories(
+		context.Background(),
+		[]iterator.Repository{{Name: "acme/a", Size: 10}, {Name: "acme/b", Size: 5}},
+		func(context.Context, string, bool, exec.Execer) error {
+			calls.Add(1)
+			return errors.New("unexpected call")
+		},
+		nil,
+		runHooks{},
+		iterator.Options{LogHandler: slog.DiscardHandler},
+	)
+	require.NoError(t, err)
+	require.Zero(t, calls.Load())
+}
+
+func TestRunWithoutClone(t *testing.T) {
+	var name string
+	err := runWithoutClone(
+		context.Background(),
+		iterator.Repository{Name: "acme/a", Size: 10},
+		func(ctx context.Context, repository string, isEmpty bool, x exec.Execer) error {
+			require.False(t, isEmpty)
+			res, err := x.RunX(ctx, "sh", "-c", "echo $GH_ITERATOR_REPOSITORY")
+			name = res
+			return err
+		},
+		slog.New(slog.DiscardHandler),
+	)
+	require.NoError(t, err)
+	require.Equal(t, "acme/a\n", name)
+}
+
+func TestRunForRepositories_ContextEnricher(t *testing.T) {
+	flags.noClone = true
+	t.Cleanup(func() { flags.noClone = false })
+
+	err := runForRepositories(
+		context.Background(),
+		[]iterator.Repository{{Name: "acme/a", Language: "Go", Size: 10}},
+		func(ctx context.Context, repository string, _ bool, _ exec.Execer) error {
+			repo, ok := repositoryFromContext(ctx)
+			require.True(t, ok)
+			require.Equal(t, iterator.Repository{Name: "acme/a", Language: "Go", Size: 10}, repo)
+			return nil
+		},
+		nil,
+		runHooks{},
+		iterator.Options{LogHandler: slog.DiscardHandler, ContextEnricher: withRepository},
+	)
+	require.NoError(t, err)
+}
+
+func TestRunForRepositories_KeepGoing(t *testing.T) {
+	flags.noClone, flags.keepGoing = true, true
+	t.Cleanup(func() { flags.noClone, flags.keepGoing = false, false })
+
+	var calls atomic.Int32
+	err := runForRepositories(
+		context.Background(),
+		[]iterator.Repository{{Name: "acme/a", Size: 10}, {Name: "acme/b", Size: 10}, {Name: "acme/c", Size: 10}},
+		func(_ context.Context, repository string, _ bool, _ exec.Execer) error {
+			calls.Add(1)
+			if repository == "acme/b" {
+				return nil
+			}
+			return errors.New("failed")
+		},
+		nil,
+		runHooks{},
+		iterator.Options{LogHandler: slog.DiscardHandler},
+	)
+	require.ErrorContains(t, err, `processing "acme/a": failed`)
+	require.ErrorContains(t, err, `processing "acme/c": failed`)
+	require.EqualValues(t, 3, calls.Load())
+}
+
+func TestRunForRepositories_Hooks(t *testing.T) {
+	flags.noClone = true
+	t.Cleanup(func() { flags.noClone = false })
+
+	results := newRunResults()
+	repos := []iterator.Repository{{Name: "acme/a", Size: 10}, {Name: "acme/b", Size: 10}}
+	results.addRepositories(repos, repos, nil)
+
+	var (
+		mu       sync.Mutex
+		started  []string
+		finished = map[string]bool{}
+		runTotal runTotals
+	)
+	err := runForRepositories(
+		context.Background(),
+		repos,
+		func(_ context.Context, repository string, _ bool, _ exec.Execer) error {
+			if repository == "acme/b" {
+				results.update(repository, func(r *repoResult) { r.Skipped = "ref not found" })
+			}
+			return nil
+		},
+		results,
+		runHooks{
+			OnRepoStart: func(repo iterator.Repository) {
+				mu.Lock()
+				defer mu.Unlock()
+				started = append(started, repo.Name)
+			},
+			OnRepoFinished: func(repo iterator.Repository, res repoResult, err error) {
+				mu.Lock()
+				defer mu.Unlock()
+				finished[repo.Name] = res.Processed && err == nil
+			},
+			OnRepoSkipped: func(repo iterator.Repository, reason string) {
+				require.Equal(t, "acme/b", repo.Name)
+				require.Equal(t, "ref not found", reason)
+			},
+			OnRunFinished: func(t runTotals, _ error) { runTotal = t },
+		},
+		iterator.Options{LogHandler: slog.DiscardHandler},
+	)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"acme/a", "acme/b"}, started)
+	require.Equal(t, map[string]bool{"acme/a": true}, finished)
+	require.Equal(t, 1, runTotal.Succeeded)
+	require.Equal(t, 1, runTotal.Skipped)
+}
+
+func TestRunForRepositories_LargestFirst(t *testing.T) {
+	flags.noClone, flags.largestFirst = true, true
+	t.Cleanup(func() { flags.noClone, flags.largestFirst = false, false })
+
+	repos := []iterator.Repository{{Name: "acme/a", Size: 10}, {Name: "acme/b", Size: 300}, {Name: "acme/c", Size: 20}}
+
+	var order []string
+	err := runForRepositories(
+		context.Background(),
+		repos,
+		func(_ context.Context, repository string, _ bool, _ exec.Execer) error {
+			order = append(order, repository)
+			return nil
+		},
+		nil,
+		runHooks{},
+		iterator.Options{LogHandler: slog.DiscardHandler, NumberOfWorkers: 1},
+	)
+	require.NoError(t, err)
+	require.Equal(t, []string{"acme/b", "acme/c", "acme/a"}, order)
+	require.Equal(t, "acme/a", repos[0].Name)
+}
+
+func TestRunForRepositoryStream(t *testing.T) {
+	flags.noClone = true
+	t.Cleanup(func() { flags.noClone = false })
+
+	t.Run("processes the repositories while listing", func(t *testing.T) {
+		processed := make(chan string)
+		err := runForRepositoryStream(
+			context.Background(),
+			func(dispatch func(iterator.Repository) bool) error {
+				require.True(t, dispatch(iterator.Repository{Name: "acme/a", Size: 10}))
+				// the first page is processed before the next one is listed.
+				require.Equal(t, "acme/a", <-processed)
+				require.True(t, dispatch(iterator.Repository{Name: "acme/b", Size: 10}))
+				require.Equal(t, "acme/b", <-processed)
+				return nil
+			},
+			nil,
+			func(_ context.Context, repository string, _ bool, _ exec.Execer) error {
+				processed <- repository
+				return nil
+			},
+			nil,
+			runHooks{},
+			iterator.Options{LogHandler: slog.DiscardHandler},
+		)
+		require.NoError(t, err)
+	})
+
+	t.Run("fails when listing fails", func(t *testing.T) {
+		var calls atomic.Int32
+		errList := errors.New("listing failed")
+		err := runForRepositoryStream(
+			context.Background(),
+			func(dispatch func(iterator.Repository) bool) error {
+				dispatch(iterator.Repository{Name: "acme/a", Size: 10})
+				return errList
+			},
+			nil,
+			func(context.Context, string, bool, exec.Execer) error {
+				calls.Add(1)
+				return nil
+			},
+			nil,
+			runHooks{},
+			iterator.Options{LogHandler: slog.DiscardHandler},
+		)
+		require.ErrorIs(t, err, errList)
+		require.Equal(t, int32(1), calls.Load())
+	})
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// finding is a line of the command output, or a finding of a processor plugin, reported in the
+// SARIF file.
+type finding struct {
+	Path    string `json:"path"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Message string `json:"message"`
+}
+
+// findingRe matches the path:line[:column]: message convention of linters and compilers.
+var findingRe = regexp.MustCompile(`^([^:\s][^:]*):(\d+)(?::(\d+))?:\s*(.+)$`)
+
+// parseFindings parses a finding out of every non empty line of the output. The lines not
+// following the path:line[:column]: message convention are findings about the repository.
+func parseFindings(output string) []finding {
+	var findings []finding
+	for line := range strings.Lines(output) {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		m := findingRe.FindStringSubmatch(line)
+		if m == nil {
+			findings = append(findings, finding{Message: line})
+			continue
+		}
+
+		f := finding{Path: m[1], Message: m[4]}
+		f.Line, _ = strconv.Atoi(m[2])
+		f.Column, _ = strconv.Atoi(m[3])
+		findings = append(findings, f)
+	}
+
+	return findings
+}
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name  string      `json:"name"`
+	Rules []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID string `json:"id"`
+}
+
+type sarifResult struct {
+	RuleID     string            `json:"ruleId"`
+	Level      string            `json:"level"`
+	Message    sarifMessage      `json:"message"`
+	Locations  []sarifLocation   `json:"locations,omitempty"`
+	Properties map[string]string `json:"properties"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
+
+// writeSARIF writes the findings of every repository into path as a SARIF log. The repository
+// of each finding is in its properties.
+func (r *runResults) writeSARIF(path string, ruleID string) error {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:  "gh-iterator-run",
+			Rules: []sarifRule{{ID: ruleID}},
+		}},
+		Results: []sarifResult{},
+	}
+
+	for _, res := range r.sorted() {
+		for _, f := range res.Findings {
+			sr := sarifResult{
+				RuleID:     ruleID,
+				Level:      "warning",
+				Message:    sarifMessage{Text: f.Message},
+				Properties: map[string]string{"repository": res.Repository},
+			}
+
+			if f.Path != "" {
+				loc := sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: f.Path}}
+				if f.Line > 0 {
+					loc.Region = &sarifRegion{StartLine: f.Line, StartColumn: f.Column}
+				}
+				sr.Locations = []sarifLocation{{PhysicalLocation: loc}}
+			}
+
+			run.Results = append(run.Results, sr)
+		}
+	}
+
+	content, err := json.MarshalIndent(sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling SARIF: %w", err)
+	}
+
+	if err := os.WriteFile(path, append(content, '\n'), 0644); err != nil {
+		return fmt.Errorf("writing SARIF file: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	iterator "github.com/jcchavezs/gh-iterator"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFindings(t *testing.T) {
+	findings := parseFindings("main.go:12:5: unused variable x\n\ngo.mod:3: outdated go version\nno CODEOWNERS file\n")
+	require.Equal(t, []finding{
+		{Path: "main.go", Line: 12, Column: 5, Message: "unused variable x"},
+		{Path: "go.mod", Line: 3, Message: "outdated go version"},
+		{Message: "no CODEOWNERS file"},
+	}, findings)
+}
+
+func TestRunResultsWriteSARIF(t *testing.T) {
+	r := newRunResults()
+	r.addRepositories([]iterator.Repository{{Name: "acme/a"}}, []iterator.Repository{{Name: "acme/a"}}, nil)
+	r.update("acme/a", func(res *repoResult) {
+		res.Findings = parseFindings("main.go:12: unused variable x\nno CODEOWNERS file\n")
+	})
+
+	path := filepath.Join(t.TempDir(), "results.sarif")
+	require.NoError(t, r.writeSARIF(path, "codeowners"))
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	var log sarifLog
+	require.NoError(t, json.Unmarshal(content, &log))
+	require.Equal(t, "2.1.0", log.Version)
+	require.Len(t, log.Runs, 1)
+	require.Len(t, log.Runs[0].Results, 2)
+
+	res := log.Runs[0].Results[0]
+	require.Equal(t, "codeowners", res.RuleID)
+	require.Equal(t, "unused variable x", res.Message.Text)
+	require.Equal(t, "main.go", res.Locations[0].PhysicalLocation.ArtifactLocation.URI)
+	require.Equal(t, 12, res.Locations[0].PhysicalLocation.Region.StartLine)
+	require.Equal(t, "acme/a", res.Properties["repository"])
+	require.Empty(t, log.Runs[0].Results[1].Locations)
+}
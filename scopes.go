@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	iterator "github.com/jcchavezs/gh-iterator"
+	"github.com/jcchavezs/gh-iterator/exec"
+	"github.com/jcchavezs/gh-iterator/github"
+)
+
+// scopeRequirement is a need of the run of the token: any of the OAuth scopes and what it is
+// needed for.
+type scopeRequirement struct {
+	anyOf  []string
+	reason string
+}
+
+// requiredScopes returns the OAuth scopes the token needs to process the repositories with the
+// flags passed, derived from every flag cloning, pushing or changing the repositories through
+// the API. Being admin of the repositories to change their settings, archive them or grant
+// teams access is a permission of the user, not a scope, so it can't be checked upfront.
+func requiredScopes(repos []iterator.Repository) []scopeRequirement {
+	private := slices.ContainsFunc(repos, func(r iterator.Repository) bool {
+		return r.Visibility != "" && r.Visibility != "public"
+	})
+
+	// the public repositories can be changed with public_repo, the private ones need repo.
+	write := func(reason string) scopeRequirement {
+		if private {
+			return scopeRequirement{anyOf: []string{"repo"}, reason: reason + " of private repositories"}
+		}
+		return scopeRequirement{anyOf: []string{"repo", "public_repo"}, reason: reason}
+	}
+
+	var reqs []scopeRequirement
+	if private && !flags.noClone {
+		reqs = append(reqs, scopeRequirement{anyOf: []string{"repo"}, reason: "cloning private repositories"})
+	}
+
+	pushes := flags.push || flags.createPR
+	for _, w := range []struct {
+		enabled bool
+		reason  string
+	}{
+		{pushes, "pushing changes"},
+		{flags.createIssue, "opening issues"},
+		{len(flags.addTopics) > 0 || len(flags.removeTopics) > 0, "changing the topics"},
+		{flags.applySettings != "", "changing the settings"},
+		{flags.syncLabels != "", "changing the labels"},
+		{flags.createTag != "", "creating tags and releases"},
+		{flags.deleteMerged, "deleting the merged branches"},
+		{flags.archive, "archiving"},
+	} {
+		if w.enabled {
+			reqs = append(reqs, write(w.reason))
+		}
+	}
+
+	if len(flags.grantTeams) > 0 || len(flags.revokeTeams) > 0 {
+		reqs = append(reqs, scopeRequirement{anyOf: []string{"admin:org"}, reason: "changing the access of the teams"})
+	}
+
+	if flags.dispatchWorkflow != "" {
+		// dispatching needs repo even for the public repositories.
+		reqs = append(reqs, scopeRequirement{anyOf: []string{"repo"}, reason: "dispatching workflows"})
+	}
+
+	if flags.exportProject != "" {
+		reqs = append(reqs, scopeRequirement{anyOf: []string{"project"}, reason: "exporting to projects"})
+	}
+
+	if pushes && flags.syncFiles != "" {
+		if info, err := os.Stat(filepath.Join(flags.syncFiles, ".github", "workflows")); err == nil && info.IsDir() {
+			reqs = append(reqs, scopeRequirement{anyOf: []string{"workflow"}, reason: "pushing workflow files"})
+		}
+	}
+
+	return reqs
+}
+
+// checkTokenScopes fails if the token gh authenticates with lacks the scopes required to process
+// the repositories, so the run does not fail halfway. Only classic and OAuth tokens have scopes,
+// fine-grained and app installation tokens are not checked.
+func checkTokenScopes(ctx context.Context, x exec.Execer, repos []iterator.Repository) error {
+	return (&scopeChecker{x: x}).check(ctx, repos)
+}
+
+// scopeChecker checks the scopes of the token for the batches of repositories streamed to the
+// run, fetching them once.
+type scopeChecker struct {
+	x      exec.Execer
+	header textproto.MIMEHeader
+}
+
+// check fails if the token lacks the scopes required to process the repositories.
+func (c *scopeChecker) check(ctx context.Context, repos []iterator.Repository) error {
+	reqs := requiredScopes(repos)
+	if len(reqs) == 0 {
+		return nil
+	}
+
+	if c.header == nil {
+		res, err := c.x.RunX(ctx, "gh", "api", "--include",
+			"-H", "Accept: application/vnd.github+json",
+			"-H", "X-GitHub-Api-Version: "+iterator.GithubAPIVersion,
+			"/rate_limit",
+		)
+		if err != nil {
+			return fmt.Errorf("checking token scopes: %w", github.ErrOrGHAPIErr(res, err))
+		}
+
+		if c.header, _, err = splitIncludedResponse(res); err != nil {
+			return fmt.Errorf("checking token scopes: %w", err)
+		}
+	}
+
+	if _, ok := c.header["X-Oauth-Scopes"]; !ok {
+		c.x.Log(ctx, slog.LevelDebug, "Skipping token scopes check, the token has no scopes")
+		return nil
+	}
+
+	var errs []error
+	for _, req := range reqs {
+		errs = append(errs, missingScopes(c.header, req.anyOf, req.reason))
+	}
+
+	return errors.Join(errs...)
+}
+
+// missingScopes returns an error naming the scope to grant when the scopes in the response
+// headers include none of anyOf.
+func missingScopes(header textproto.MIMEHeader, anyOf []string, reason string) error {
+	var scopes []string
+	for _, v := range header.Values("X-Oauth-Scopes") {
+		for _, s := range strings.Split(v, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				scopes = append(scopes, s)
+			}
+		}
+	}
+
+	for _, s := range anyOf {
+		if slices.Contains(scopes, s) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("the token lacks the %q scope required for %s, it has %q; grant it with 'gh auth refresh -s %s' or pass --skip-scope-check",
+		anyOf[0], reason, strings.Join(scopes, ", "), anyOf[0])
+}
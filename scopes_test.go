@@ -0,0 +1,133 @@
+package main
+
+import (
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"testing"
+
+	iterator "github.com/jcchavezs/gh-iterator"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequiredScopes(t *testing.T) {
+	public := []iterator.Repository{{Name: "acme/a", Visibility: "public"}}
+	private := []iterator.Repository{{Name: "acme/a", Visibility: "public"}, {Name: "acme/b", Visibility: "internal"}}
+
+	workflows := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(workflows, ".github", "workflows"), 0o755))
+
+	testCases := map[string]struct {
+		set      func()
+		repos    []iterator.Repository
+		expected []scopeRequirement
+	}{
+		"read only": {
+			set:   func() {},
+			repos: public,
+		},
+		"clone private": {
+			set:      func() {},
+			repos:    private,
+			expected: []scopeRequirement{{anyOf: []string{"repo"}, reason: "cloning private repositories"}},
+		},
+		"no clone private": {
+			set:   func() { flags.noClone = true },
+			repos: private,
+		},
+		"push": {
+			set:      func() { flags.push = true },
+			repos:    public,
+			expected: []scopeRequirement{{anyOf: []string{"repo", "public_repo"}, reason: "pushing changes"}},
+		},
+		"create pr private": {
+			set:   func() { flags.createPR = true },
+			repos: private,
+			expected: []scopeRequirement{
+				{anyOf: []string{"repo"}, reason: "cloning private repositories"},
+				{anyOf: []string{"repo"}, reason: "pushing changes of private repositories"},
+			},
+		},
+		"create issue": {
+			set:      func() { flags.createIssue = true },
+			repos:    public,
+			expected: []scopeRequirement{{anyOf: []string{"repo", "public_repo"}, reason: "opening issues"}},
+		},
+		"topics": {
+			set:      func() { flags.noClone, flags.removeTopics = true, []string{"old"} },
+			repos:    public,
+			expected: []scopeRequirement{{anyOf: []string{"repo", "public_repo"}, reason: "changing the topics"}},
+		},
+		"apply settings": {
+			set:      func() { flags.noClone, flags.applySettings = true, "settings.json" },
+			repos:    public,
+			expected: []scopeRequirement{{anyOf: []string{"repo", "public_repo"}, reason: "changing the settings"}},
+		},
+		"labels": {
+			set:      func() { flags.noClone, flags.syncLabels = true, "labels.json" },
+			repos:    public,
+			expected: []scopeRequirement{{anyOf: []string{"repo", "public_repo"}, reason: "changing the labels"}},
+		},
+		"tag": {
+			set:      func() { flags.noClone, flags.createTag = true, "v1.0.0" },
+			repos:    public,
+			expected: []scopeRequirement{{anyOf: []string{"repo", "public_repo"}, reason: "creating tags and releases"}},
+		},
+		"delete merged": {
+			set:      func() { flags.noClone, flags.deleteMerged = true, true },
+			repos:    public,
+			expected: []scopeRequirement{{anyOf: []string{"repo", "public_repo"}, reason: "deleting the merged branches"}},
+		},
+		"archive private": {
+			set:      func() { flags.noClone, flags.archive = true, true },
+			repos:    private,
+			expected: []scopeRequirement{{anyOf: []string{"repo"}, reason: "archiving of private repositories"}},
+		},
+		"grant team": {
+			set:      func() { flags.noClone, flags.grantTeams = true, []string{"core:push"} },
+			repos:    public,
+			expected: []scopeRequirement{{anyOf: []string{"admin:org"}, reason: "changing the access of the teams"}},
+		},
+		"dispatch workflow": {
+			set:      func() { flags.noClone, flags.dispatchWorkflow = true, "ci.yml" },
+			repos:    public,
+			expected: []scopeRequirement{{anyOf: []string{"repo"}, reason: "dispatching workflows"}},
+		},
+		"export project": {
+			set:      func() { flags.noClone, flags.exportProject = true, "acme/1" },
+			repos:    public,
+			expected: []scopeRequirement{{anyOf: []string{"project"}, reason: "exporting to projects"}},
+		},
+		"sync workflow files": {
+			set:   func() { flags.push, flags.syncFiles = true, workflows },
+			repos: public,
+			expected: []scopeRequirement{
+				{anyOf: []string{"repo", "public_repo"}, reason: "pushing changes"},
+				{anyOf: []string{"workflow"}, reason: "pushing workflow files"},
+			},
+		},
+		"sync other files": {
+			set:      func() { flags.push, flags.syncFiles = true, t.TempDir() },
+			repos:    public,
+			expected: []scopeRequirement{{anyOf: []string{"repo", "public_repo"}, reason: "pushing changes"}},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			saved := flags
+			t.Cleanup(func() { flags = saved })
+
+			tc.set()
+			require.Equal(t, tc.expected, requiredScopes(tc.repos))
+		})
+	}
+}
+
+func TestMissingScopes(t *testing.T) {
+	header := textproto.MIMEHeader{"X-Oauth-Scopes": {"gist, read:org, public_repo"}}
+
+	require.NoError(t, missingScopes(header, []string{"repo", "public_repo"}, "changing repositories"))
+	require.EqualError(t, missingScopes(header, []string{"repo"}, "cloning private repositories"),
+		`the token lacks the "repo" scope required for cloning private repositories, it has "gist, read:org, public_repo"; grant it with 'gh auth refresh -s repo' or pass --skip-scope-check`)
+}
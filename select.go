@@ -0,0 +1,269 @@
+package main
+
+import (
+	"cmp"
+	"errors"
+	"log/slog"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"time"
+
+	iterator "github.com/jcchavezs/gh-iterator"
+)
+
+// SortField is the field to sort the repositories by.
+type SortField int
+
+const (
+	SortNone SortField = iota
+	SortPushed
+	SortName
+	SortSize
+)
+
+// SortFieldIds maps sort fields to their corresponding string identifiers.
+var SortFieldIds = map[SortField][]string{
+	SortNone:   {"none"},
+	SortPushed: {"pushed"},
+	SortName:   {"name"},
+	SortSize:   {"size"},
+}
+
+// SortOrder is the order to sort the repositories in.
+type SortOrder int
+
+const (
+	OrderAsc SortOrder = iota
+	OrderDesc
+)
+
+// SortOrderIds maps sort orders to their corresponding string identifiers.
+var SortOrderIds = map[SortOrder][]string{
+	OrderAsc:  {"asc"},
+	OrderDesc: {"desc"},
+}
+
+// sortRepositories sorts the repositories in place by the field in the given order. The sort is
+// stable so repositories with the same value keep their relative order.
+func sortRepositories(repos []iterator.Repository, field SortField, order SortOrder) {
+	var compare func(a, b iterator.Repository) int
+	switch field {
+	case SortPushed:
+		compare = func(a, b iterator.Repository) int { return a.PushedAt.Compare(b.PushedAt) }
+	case SortName:
+		compare = func(a, b iterator.Repository) int { return strings.Compare(a.Name, b.Name) }
+	case SortSize:
+		compare = func(a, b iterator.Repository) int { return cmp.Compare(a.Size, b.Size) }
+	default:
+		return
+	}
+
+	slices.SortStableFunc(repos, func(a, b iterator.Repository) int {
+		if order == OrderDesc {
+			return compare(b, a)
+		}
+		return compare(a, b)
+	})
+}
+
+// apiSortQuery returns the query parameters to sort the listed repositories in the API, empty
+// if the API does not support sorting by the field.
+func apiSortQuery(field SortField, order SortOrder) string {
+	var sort string
+	switch field {
+	case SortPushed:
+		sort = "pushed"
+	case SortName:
+		sort = "full_name"
+	default:
+		return ""
+	}
+
+	return "sort=" + sort + "&direction=" + SortOrderIds[order][0]
+}
+
+// sampleRepositories randomly picks n repositories keeping their order. A zero seed picks a
+// different sample on every call.
+func sampleRepositories(repos []iterator.Repository, n int, seed uint64) []iterator.Repository {
+	if n <= 0 || n >= len(repos) {
+		return repos
+	}
+
+	var r *rand.Rand
+	if seed == 0 {
+		r = rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))
+	} else {
+		r = rand.New(rand.NewPCG(seed, seed))
+	}
+
+	indexes := r.Perm(len(repos))[:n]
+	slices.Sort(indexes)
+
+	sample := make([]iterator.Repository, 0, n)
+	for _, i := range indexes {
+		sample = append(sample, repos[i])
+	}
+
+	return sample
+}
+
+// limitRepositories returns the first limit repositories, all of them if limit is zero.
+func limitRepositories(repos []iterator.Repository, limit int) []iterator.Repository {
+	if limit > 0 && limit < len(repos) {
+		return repos[:limit]
+	}
+
+	return repos
+}
+
+// selectionStage filters the repositories, the ones it leaves out are skipped for the reason.
+type selectionStage struct {
+	keep   func(iterator.Repository) bool
+	reason func(iterator.Repository) string
+}
+
+// because returns a reason function for a fixed reason.
+func because(reason string) func(iterator.Repository) string {
+	return func(iterator.Repository) string { return reason }
+}
+
+// acceptAll is a filter keeping every repository.
+func acceptAll(iterator.Repository) bool { return true }
+
+// defaultFilterReason tells why the default search filter left out the repository.
+func defaultFilterReason(r iterator.Repository) string {
+	switch {
+	case r.Archived:
+		return "archived"
+	case r.Fork:
+		return "fork"
+	case r.Size == 0:
+		return "empty"
+	default:
+		return "filtered out"
+	}
+}
+
+// filterStages returns the stages of the filters passed by flag: the exclude and include
+// patterns, the pushed dates, the empty repositories and the search filter.
+func filterStages(logger *slog.Logger) ([]selectionStage, error) {
+	var stages []selectionStage
+
+	if len(flags.excludeRepos) > 0 {
+		keep, err := excludeRepos(acceptAll, flags.excludeRepos)
+		if err != nil {
+			return nil, err
+		}
+		stages = append(stages, selectionStage{keep: keep, reason: because("excluded")})
+	}
+
+	if len(flags.includeRepos) > 0 {
+		keep, err := includeRepos(acceptAll, flags.includeRepos)
+		if err != nil {
+			return nil, err
+		}
+		stages = append(stages, selectionStage{keep: keep, reason: because("not included")})
+	}
+
+	if flags.pushedSince != "" {
+		since, err := parseDate(flags.pushedSince)
+		if err != nil {
+			return nil, err
+		}
+		stages = append(stages, selectionStage{keep: pushedAfter(acceptAll, since), reason: because("not pushed recently")})
+	}
+
+	if flags.pushedWithin != "" {
+		within, err := parseDuration(flags.pushedWithin)
+		if err != nil {
+			return nil, err
+		}
+		stages = append(stages, selectionStage{keep: pushedAfter(acceptAll, time.Now().Add(-within)), reason: because("not pushed recently")})
+	}
+
+	if flags.includeEmpty && flags.skipEmpty {
+		return nil, errors.New("--include-empty and --skip-empty can't be used together")
+	}
+
+	if flags.skipEmpty {
+		stages = append(stages, selectionStage{keep: func(r iterator.Repository) bool { return r.Size > 0 }, reason: because("empty")})
+	}
+
+	searchFilterIn, err := parseSearchFilterIn(flags.searchFilter, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	reason := because("filtered out")
+	if flags.searchFilter == "" {
+		reason = defaultFilterReason
+		if flags.includeEmpty {
+			searchFilterIn = func(r iterator.Repository) bool { return !r.Archived && !r.Fork }
+		}
+	}
+	stages = append(stages, selectionStage{keep: searchFilterIn, reason: reason})
+
+	return stages, nil
+}
+
+// selectRepositories applies the stages and then sorts, samples and limits the repositories as
+// passed by flag. It returns the selected repositories and why each of the others is skipped.
+func selectRepositories(repos []iterator.Repository, stages []selectionStage) ([]iterator.Repository, map[string]string) {
+	selected, skipped := applyStages(repos, stages)
+
+	sortRepositories(selected, flags.sort, flags.order)
+
+	sampled := sampleRepositories(selected, flags.sample, flags.seed)
+	skipDropped(skipped, selected, sampled, "not sampled")
+
+	limited := limitRepositories(sampled, flags.limit)
+	skipDropped(skipped, sampled, limited, "over the limit")
+
+	return limited, skipped
+}
+
+// streamable tells whether the repositories can be processed as they are listed, i.e. none of the
+// flags passed needs all the selected repositories before processing the first one.
+func streamable() bool {
+	return flags.sort == SortNone && flags.sample == 0 && flags.limit == 0 && flags.yes &&
+		!flags.selectRepos && !flags.pick && !flags.largestFirst && flags.serializeBy == "" && flags.state == ""
+}
+
+// applyStages returns the repositories passing all the stages and why each of the others is
+// skipped, the reason of the first stage leaving it out.
+func applyStages(repos []iterator.Repository, stages []selectionStage) ([]iterator.Repository, map[string]string) {
+	skipped := map[string]string{}
+
+	var selected []iterator.Repository
+repos:
+	for _, repo := range repos {
+		for _, s := range stages {
+			if !s.keep(repo) {
+				skipped[repo.Name] = s.reason(repo)
+				continue repos
+			}
+		}
+		selected = append(selected, repo)
+	}
+
+	return selected, skipped
+}
+
+// skipDropped records the reason for the repositories in before and not in after.
+func skipDropped(skipped map[string]string, before, after []iterator.Repository, reason string) {
+	if len(before) == len(after) {
+		return
+	}
+
+	kept := make(map[string]bool, len(after))
+	for _, r := range after {
+		kept[r.Name] = true
+	}
+
+	for _, r := range before {
+		if !kept[r.Name] {
+			skipped[r.Name] = reason
+		}
+	}
+}
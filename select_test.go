@@ -0,0 +1,153 @@
+package main
+
+import (
+	"log/slog"
+	"testing"
+	"time"
+
+	iterator "github.com/jcchavezs/gh-iterator"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSampleRepositories(t *testing.T) {
+	var repos []iterator.Repository
+	for _, name := range []string{"a", "b", "c", "d", "e", "f", "g", "h"} {
+		repos = append(repos, iterator.Repository{Name: "acme/" + name})
+	}
+
+	require.Len(t, sampleRepositories(repos, 0, 1), 8)
+	require.Len(t, sampleRepositories(repos, 10, 1), 8)
+
+	sample := sampleRepositories(repos, 3, 42)
+	require.Len(t, sample, 3)
+	require.Equal(t, sample, sampleRepositories(repos, 3, 42), "same seed should pick the same sample")
+
+	for i := 1; i < len(sample); i++ {
+		require.Less(t, sample[i-1].Name, sample[i].Name, "sample should keep the order")
+	}
+}
+
+func TestLimitRepositories(t *testing.T) {
+	repos := []iterator.Repository{{Name: "acme/a"}, {Name: "acme/b"}, {Name: "acme/c"}}
+
+	require.Len(t, limitRepositories(repos, 0), 3)
+	require.Len(t, limitRepositories(repos, 2), 2)
+	require.Len(t, limitRepositories(repos, 5), 3)
+}
+
+func TestSortRepositories(t *testing.T) {
+	newRepos := func() []iterator.Repository {
+		return []iterator.Repository{
+			{Name: "acme/b", Size: 30, PushedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+			{Name: "acme/c", Size: 10, PushedAt: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+			{Name: "acme/a", Size: 20, PushedAt: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)},
+		}
+	}
+
+	names := func(repos []iterator.Repository) []string {
+		var ns []string
+		for _, r := range repos {
+			ns = append(ns, r.Name)
+		}
+		return ns
+	}
+
+	testCases := []struct {
+		field    SortField
+		order    SortOrder
+		expected []string
+	}{
+		{SortNone, OrderDesc, []string{"acme/b", "acme/c", "acme/a"}},
+		{SortName, OrderAsc, []string{"acme/a", "acme/b", "acme/c"}},
+		{SortPushed, OrderDesc, []string{"acme/c", "acme/b", "acme/a"}},
+		{SortSize, OrderAsc, []string{"acme/c", "acme/a", "acme/b"}},
+	}
+
+	for _, tc := range testCases {
+		repos := newRepos()
+		sortRepositories(repos, tc.field, tc.order)
+		require.Equal(t, tc.expected, names(repos))
+	}
+}
+
+func TestAPISortQuery(t *testing.T) {
+	require.Equal(t, "sort=pushed&direction=desc", apiSortQuery(SortPushed, OrderDesc))
+	require.Equal(t, "sort=full_name&direction=asc", apiSortQuery(SortName, OrderAsc))
+	require.Empty(t, apiSortQuery(SortSize, OrderAsc))
+}
+
+func TestSelectRepositories(t *testing.T) {
+	t.Cleanup(func() { flags.limit = 0 })
+	flags.limit = 1
+
+	stages := []selectionStage{
+		{keep: func(r iterator.Repository) bool { return r.Name != "acme/legacy" }, reason: because("excluded")},
+		{keep: defaultSearchFilterIn, reason: defaultFilterReason},
+	}
+
+	selected, skipped := selectRepositories([]iterator.Repository{
+		{Name: "acme/a", Size: 1},
+		{Name: "acme/legacy", Size: 1},
+		{Name: "acme/empty"},
+		{Name: "acme/old", Size: 1, Archived: true},
+		{Name: "acme/b", Size: 1},
+	}, stages)
+
+	require.Equal(t, []iterator.Repository{{Name: "acme/a", Size: 1}}, selected)
+	require.Equal(t, map[string]string{
+		"acme/legacy": "excluded",
+		"acme/empty":  "empty",
+		"acme/old":    "archived",
+		"acme/b":      "over the limit",
+	}, skipped)
+}
+
+func TestFilterStagesEmptyRepositories(t *testing.T) {
+	t.Cleanup(func() { flags.includeEmpty, flags.skipEmpty, flags.searchFilter = false, false, "" })
+
+	repos := []iterator.Repository{{Name: "acme/a", Size: 1}, {Name: "acme/empty"}}
+
+	flags.includeEmpty = true
+	stages, err := filterStages(slog.New(slog.DiscardHandler))
+	require.NoError(t, err)
+	selected, _ := selectRepositories(repos, stages)
+	require.Len(t, selected, 2)
+
+	flags.includeEmpty, flags.skipEmpty, flags.searchFilter = false, true, "true"
+	stages, err = filterStages(slog.New(slog.DiscardHandler))
+	require.NoError(t, err)
+	selected, skipped := selectRepositories(repos, stages)
+	require.Equal(t, []iterator.Repository{{Name: "acme/a", Size: 1}}, selected)
+	require.Equal(t, map[string]string{"acme/empty": "empty"}, skipped)
+
+	flags.includeEmpty = true
+	_, err = filterStages(slog.New(slog.DiscardHandler))
+	require.Error(t, err)
+}
+
+func TestStreamable(t *testing.T) {
+	saved := flags
+	t.Cleanup(func() { flags = saved })
+
+	flags.yes = true
+	require.True(t, streamable())
+
+	for name, set := range map[string]func(){
+		"sort":         func() { flags.sort = SortName },
+		"sample":       func() { flags.sample = 3 },
+		"limit":        func() { flags.limit = 3 },
+		"confirm":      func() { flags.yes = false },
+		"select":       func() { flags.selectRepos = true },
+		"pick":         func() { flags.pick = true },
+		"largest":      func() { flags.largestFirst = true },
+		"serialize-by": func() { flags.serializeBy = "repo.language" },
+		"state":        func() { flags.state = "state.json" },
+	} {
+		t.Run(name, func(t *testing.T) {
+			flags = saved
+			flags.yes = true
+			set()
+			require.False(t, streamable())
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/google/cel-go/cel"
+	iterator "github.com/jcchavezs/gh-iterator"
+)
+
+// serializeKey returns the key of the repositories processed one at a time, nil unless
+// --serialize-by is passed.
+var serializeKey func(repo iterator.Repository) (string, error)
+
+// parseSerializeKey compiles the CEL expression computing the key of each repository out of the
+// repo variable of the search filter e.g. repo.topics.filter(t, t.startsWith("team-")). The
+// repositories with the same key are processed one at a time, an empty key has no constraint.
+func parseSerializeKey(expr string) (func(iterator.Repository) (string, error), error) {
+	env, err := cel.NewEnv(
+		cel.Variable("repo", cel.MapType(cel.StringType, cel.DynType)),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	ast, issues := env.Compile(expr)
+	if issues != nil && issues.Err() != nil {
+		return nil, issues.Err()
+	}
+
+	prg, err := env.Program(ast)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(r iterator.Repository) (string, error) {
+		out, _, err := prg.Eval(map[string]any{"repo": repoVariable(r)})
+		if err != nil {
+			return "", fmt.Errorf("evaluating serialization key: %w", err)
+		}
+
+		return fmt.Sprint(out.Value()), nil
+	}, nil
+}
+
+// interleaveByKey spreads the repositories with the same key out, taking one of each key in
+// turn, so the workers don't wait for each other while repositories with other keys are
+// pending. The order of the repositories with the same key is kept.
+func interleaveByKey(repos []iterator.Repository, keys map[string]string) []iterator.Repository {
+	var (
+		order  []string
+		groups = map[string][]iterator.Repository{}
+	)
+	for _, r := range repos {
+		k := keys[r.Name]
+		if _, ok := groups[k]; !ok {
+			order = append(order, k)
+		}
+		groups[k] = append(groups[k], r)
+	}
+
+	interleaved := make([]iterator.Repository, 0, len(repos))
+	for len(interleaved) < len(repos) {
+		for _, k := range order {
+			if len(groups[k]) > 0 {
+				interleaved = append(interleaved, groups[k][0])
+				groups[k] = groups[k][1:]
+			}
+		}
+	}
+
+	return interleaved
+}
+
+// keyedMutex holds a lock per key.
+type keyedMutex struct {
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex
+}
+
+// lock locks the key and returns the function unlocking it. The empty key is not locked.
+func (km *keyedMutex) lock(key string) func() {
+	if key == "" {
+		return func() {}
+	}
+
+	km.mu.Lock()
+	if km.locks == nil {
+		km.locks = map[string]*sync.Mutex{}
+	}
+	l, ok := km.locks[key]
+	if !ok {
+		l = &sync.Mutex{}
+		km.locks[key] = l
+	}
+	km.mu.Unlock()
+
+	l.Lock()
+	return l.Unlock
+}
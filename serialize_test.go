@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	iterator "github.com/jcchavezs/gh-iterator"
+	"github.com/jcchavezs/gh-iterator/exec"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSerializeKey(t *testing.T) {
+	_, err := parseSerializeKey("repo.")
+	require.Error(t, err)
+
+	key, err := parseSerializeKey(`repo.language == "" ? "" : repo.language`)
+	require.NoError(t, err)
+
+	k, err := key(iterator.Repository{Name: "acme/a", Language: "Go"})
+	require.NoError(t, err)
+	require.Equal(t, "Go", k)
+
+	k, err = key(iterator.Repository{Name: "acme/b"})
+	require.NoError(t, err)
+	require.Empty(t, k)
+}
+
+func TestInterleaveByKey(t *testing.T) {
+	repos := []iterator.Repository{{Name: "a1"}, {Name: "a2"}, {Name: "a3"}, {Name: "b1"}, {Name: "c1"}, {Name: "c2"}}
+	keys := map[string]string{"a1": "a", "a2": "a", "a3": "a", "b1": "b", "c1": "c", "c2": "c"}
+
+	var names []string
+	for _, r := range interleaveByKey(repos, keys) {
+		names = append(names, r.Name)
+	}
+	require.Equal(t, []string{"a1", "b1", "c1", "a2", "c2", "a3"}, names)
+}
+
+func TestRunForRepositories_SerializeBy(t *testing.T) {
+	flags.noClone = true
+	t.Cleanup(func() { flags.noClone, serializeKey = false, nil })
+
+	var err error
+	serializeKey, err = parseSerializeKey(`repo.language`)
+	require.NoError(t, err)
+
+	var (
+		mu         sync.Mutex
+		running    = map[string]int{}
+		maxRunning = map[string]int{}
+	)
+	err = runForRepositories(
+		context.Background(),
+		[]iterator.Repository{
+			{Name: "acme/go-1", Language: "Go", Size: 1}, {Name: "acme/go-2", Language: "Go", Size: 1}, {Name: "acme/go-3", Language: "Go", Size: 1},
+			{Name: "acme/rust-1", Language: "Rust", Size: 1}, {Name: "acme/rust-2", Language: "Rust", Size: 1},
+		},
+		func(_ context.Context, repository string, _ bool, _ exec.Execer) error {
+			key, _, _ := strings.Cut(repository, "-")
+
+			mu.Lock()
+			running[key]++
+			maxRunning[key] = max(maxRunning[key], running[key])
+			mu.Unlock()
+
+			time.Sleep(20 * time.Millisecond)
+
+			mu.Lock()
+			running[key]--
+			mu.Unlock()
+			return nil
+		},
+		nil,
+		runHooks{},
+		iterator.Options{LogHandler: slog.DiscardHandler, NumberOfWorkers: 5},
+	)
+	require.NoError(t, err)
+	require.Equal(t, map[string]int{"acme/go": 1, "acme/rust": 1}, maxRunning)
+}
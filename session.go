@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/jcchavezs/gh-iterator/exec"
+)
+
+// apiSession holds the responses of the gh invocations listing the repositories, recorded with
+// --record and replayed with --replay.
+type apiSession struct {
+	mu        sync.Mutex
+	Responses []recordedResponse `json:"responses"`
+}
+
+type recordedResponse struct {
+	Args     []string `json:"args"`
+	Stdout   string   `json:"stdout"`
+	Stderr   string   `json:"stderr,omitempty"`
+	ExitCode int      `json:"exit_code"`
+}
+
+func loadAPISession(path string) (*apiSession, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading API session: %w", err)
+	}
+
+	s := &apiSession{}
+	if err := json.Unmarshal(content, s); err != nil {
+		return nil, fmt.Errorf("unmarshaling API session: %w", err)
+	}
+
+	return s, nil
+}
+
+func (s *apiSession) save(path string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	content, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling API session: %w", err)
+	}
+
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		return fmt.Errorf("writing API session: %w", err)
+	}
+
+	return nil
+}
+
+func (s *apiSession) record(args []string, res exec.Result) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Responses = append(s.Responses, recordedResponse{Args: args, Stdout: res.Stdout, Stderr: res.Stderr, ExitCode: res.ExitCode})
+}
+
+// lookup returns the last response recorded for the arguments.
+func (s *apiSession) lookup(args []string) (exec.Result, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := len(s.Responses) - 1; i >= 0; i-- {
+		if r := s.Responses[i]; slices.Equal(r.Args, args) {
+			return exec.Result{Stdout: r.Stdout, Stderr: r.Stderr, ExitCode: r.ExitCode}, true
+		}
+	}
+
+	return exec.Result{}, false
+}
+
+// sessionDecorator records the gh invocations in the session, or replays them from it without
+// calling gh when replay is set.
+type sessionDecorator struct {
+	session *apiSession
+	replay  bool
+}
+
+// withAPISession wraps the execer to record the gh invocations with --record or replay them with
+// --replay. The returned function saves the recorded session.
+func withAPISession(x exec.Execer) (exec.Execer, func() error, error) {
+	switch {
+	case flags.record != "" && flags.replay != "":
+		return nil, nil, errors.New("--record can't be used with --replay")
+	case flags.replay != "":
+		s, err := loadAPISession(flags.replay)
+		if err != nil {
+			return nil, nil, err
+		}
+		return decorate(x, sessionDecorator{session: s, replay: true}), func() error { return nil }, nil
+	case flags.record != "":
+		s := &apiSession{}
+		return decorate(x, sessionDecorator{session: s}), func() error { return s.save(flags.record) }, nil
+	default:
+		return x, func() error { return nil }, nil
+	}
+}
+
+func (d sessionDecorator) runWithStdin(ctx context.Context, next exec.Execer, stdin io.Reader, command string, args ...string) (exec.Result, error) {
+	if command != "gh" {
+		return next.RunWithStdin(ctx, stdin, command, args...)
+	}
+
+	if d.replay {
+		res, ok := d.session.lookup(args)
+		if !ok {
+			return exec.Result{}, fmt.Errorf("no response recorded for 'gh %s'", strings.Join(args, " "))
+		}
+		return res, nil
+	}
+
+	res, err := next.RunWithStdin(ctx, stdin, command, args...)
+	if err == nil {
+		d.session.record(args, res)
+	}
+
+	return res, err
+}
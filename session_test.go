@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jcchavezs/gh-iterator/exec"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPISession(t *testing.T) {
+	t.Cleanup(func() { flags.record, flags.replay = "", "" })
+	counter := fakeGH(t, 0, "")
+	session := filepath.Join(t.TempDir(), "session.json")
+	x := exec.NewExecerWithLogger(t.TempDir(), slog.New(slog.DiscardHandler))
+
+	flags.record = session
+	rx, save, err := withAPISession(x)
+	require.NoError(t, err)
+
+	out, err := rx.RunWithStdinX(context.Background(), strings.NewReader("[{\"full_name\":\"acme/a\"}]"), "gh", "api", "/orgs/acme/repos")
+	require.NoError(t, err)
+	require.Equal(t, `[{"full_name":"acme/a"}]`, out)
+	require.NoError(t, save())
+
+	flags.record, flags.replay = "", session
+	px, _, err := withAPISession(x)
+	require.NoError(t, err)
+
+	out, err = px.RunX(context.Background(), "gh", "api", "/orgs/acme/repos")
+	require.NoError(t, err)
+	require.Equal(t, `[{"full_name":"acme/a"}]`, out)
+
+	_, err = px.RunX(context.Background(), "gh", "api", "/orgs/other/repos")
+	require.ErrorContains(t, err, "no response recorded for 'gh api /orgs/other/repos'")
+
+	// gh is only called while recording.
+	require.Equal(t, 1, countLines(t, counter))
+
+	flags.record = session
+	_, _, err = withAPISession(x)
+	require.Error(t, err)
+}
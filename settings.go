@@ -0,0 +1,309 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	iterator "github.com/jcchavezs/gh-iterator"
+	"github.com/jcchavezs/gh-iterator/exec"
+	"gopkg.in/yaml.v3"
+)
+
+// repoSettings are the settings to apply to the repositories, the ones not set are left as they
+// are e.g.
+//
+//	default_branch: main
+//	allow_squash_merge: true
+//	allow_merge_commit: false
+//	delete_branch_on_merge: true
+//	vulnerability_alerts: true
+//	branch_protection:
+//	  required_approving_review_count: 1
+//	  required_status_checks: [ci]
+type repoSettings struct {
+	DefaultBranch       *string           `yaml:"default_branch"`
+	AllowMergeCommit    *bool             `yaml:"allow_merge_commit"`
+	AllowSquashMerge    *bool             `yaml:"allow_squash_merge"`
+	AllowRebaseMerge    *bool             `yaml:"allow_rebase_merge"`
+	DeleteBranchOnMerge *bool             `yaml:"delete_branch_on_merge"`
+	VulnerabilityAlerts *bool             `yaml:"vulnerability_alerts"`
+	BranchProtection    *branchProtection `yaml:"branch_protection"`
+}
+
+// branchProtection is the protection of the default branch.
+type branchProtection struct {
+	RequiredApprovingReviewCount int      `yaml:"required_approving_review_count" json:"required_approving_review_count"`
+	RequireCodeOwnerReviews      bool     `yaml:"require_code_owner_reviews" json:"require_code_owner_reviews"`
+	DismissStaleReviews          bool     `yaml:"dismiss_stale_reviews" json:"dismiss_stale_reviews"`
+	RequiredStatusChecks         []string `yaml:"required_status_checks" json:"required_status_checks"`
+	StrictStatusChecks           bool     `yaml:"strict_status_checks" json:"strict_status_checks"`
+	EnforceAdmins                bool     `yaml:"enforce_admins" json:"enforce_admins"`
+}
+
+func loadRepoSettings(path string) (repoSettings, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return repoSettings{}, fmt.Errorf("reading settings: %w", err)
+	}
+
+	var s repoSettings
+	dec := yaml.NewDecoder(bytes.NewReader(content))
+	dec.KnownFields(true)
+	if err := dec.Decode(&s); err != nil {
+		return repoSettings{}, fmt.Errorf("parsing settings: %w", err)
+	}
+
+	return s, nil
+}
+
+// currentRepoSettings are the settings of the repository as returned by the API.
+type currentRepoSettings struct {
+	DefaultBranch       string `json:"default_branch"`
+	AllowMergeCommit    *bool  `json:"allow_merge_commit"`
+	AllowSquashMerge    *bool  `json:"allow_squash_merge"`
+	AllowRebaseMerge    *bool  `json:"allow_rebase_merge"`
+	DeleteBranchOnMerge *bool  `json:"delete_branch_on_merge"`
+}
+
+// settingsDrift returns the fields to patch to apply the settings along with the changes, in
+// the order of the settings.
+func settingsDrift(want repoSettings, current currentRepoSettings) (map[string]any, []string) {
+	patch := map[string]any{}
+	var changes []string
+
+	if want.DefaultBranch != nil && *want.DefaultBranch != current.DefaultBranch {
+		patch["default_branch"] = *want.DefaultBranch
+		changes = append(changes, fmt.Sprintf("set default_branch to %s (was %s)", *want.DefaultBranch, current.DefaultBranch))
+	}
+
+	for _, f := range []struct {
+		name          string
+		want, current *bool
+	}{
+		{"allow_merge_commit", want.AllowMergeCommit, current.AllowMergeCommit},
+		{"allow_squash_merge", want.AllowSquashMerge, current.AllowSquashMerge},
+		{"allow_rebase_merge", want.AllowRebaseMerge, current.AllowRebaseMerge},
+		{"delete_branch_on_merge", want.DeleteBranchOnMerge, current.DeleteBranchOnMerge},
+	} {
+		// the merge options are only returned to admins, so unknown ones are applied.
+		if f.want == nil || (f.current != nil && *f.want == *f.current) {
+			continue
+		}
+
+		patch[f.name] = *f.want
+		was := "unknown"
+		if f.current != nil {
+			was = strconv.FormatBool(*f.current)
+		}
+		changes = append(changes, fmt.Sprintf("set %s to %t (was %s)", f.name, *f.want, was))
+	}
+
+	return patch, changes
+}
+
+// settingsAction applies the settings to the repositories, reporting the ones drifting.
+func settingsAction(want repoSettings) repoAction {
+	return func(ctx context.Context, x exec.Execer, repo iterator.Repository, dryRun bool) ([]string, error) {
+		res, err := apiRequest(ctx, x, "GET", "/repos/"+repo.Name, nil)
+		if err != nil {
+			return nil, fmt.Errorf("fetching settings: %w", err)
+		}
+
+		var current currentRepoSettings
+		if err := json.Unmarshal([]byte(res), &current); err != nil {
+			return nil, fmt.Errorf("unmarshaling settings: %w", err)
+		}
+
+		patch, changes := settingsDrift(want, current)
+		if len(patch) > 0 && !dryRun {
+			if _, err := apiRequest(ctx, x, "PATCH", "/repos/"+repo.Name, patch); err != nil {
+				return nil, fmt.Errorf("applying settings: %w", err)
+			}
+		}
+
+		if want.VulnerabilityAlerts != nil {
+			change, err := applyVulnerabilityAlerts(ctx, x, repo.Name, *want.VulnerabilityAlerts, dryRun)
+			if err != nil {
+				return nil, err
+			}
+			if change != "" {
+				changes = append(changes, change)
+			}
+		}
+
+		if want.BranchProtection != nil {
+			branch := current.DefaultBranch
+			if want.DefaultBranch != nil {
+				branch = *want.DefaultBranch
+			}
+
+			change, err := applyBranchProtection(ctx, x, repo.Name, branch, *want.BranchProtection, dryRun)
+			if err != nil {
+				return nil, err
+			}
+			if change != "" {
+				changes = append(changes, change)
+			}
+		}
+
+		return changes, nil
+	}
+}
+
+// applyVulnerabilityAlerts enables or disables the vulnerability alerts, returning the change.
+func applyVulnerabilityAlerts(ctx context.Context, x exec.Execer, repository string, enable bool, dryRun bool) (string, error) {
+	path := "/repos/" + repository + "/vulnerability-alerts"
+
+	_, err := apiRequest(ctx, x, "GET", path, nil)
+	if err != nil && !isNotFound(err) {
+		return "", fmt.Errorf("fetching vulnerability alerts: %w", err)
+	}
+
+	// the API answers 404 when they are disabled.
+	if enabled := err == nil; enabled == enable {
+		return "", nil
+	}
+
+	change, method := "enable vulnerability_alerts", "PUT"
+	if !enable {
+		change, method = "disable vulnerability_alerts", "DELETE"
+	}
+
+	if !dryRun {
+		if _, err := apiRequest(ctx, x, method, path, nil); err != nil {
+			return "", fmt.Errorf("changing vulnerability alerts: %w", err)
+		}
+	}
+
+	return change, nil
+}
+
+// apiBranchProtection is the protection of a branch as returned by the API.
+type apiBranchProtection struct {
+	RequiredPullRequestReviews *struct {
+		RequiredApprovingReviewCount int  `json:"required_approving_review_count"`
+		RequireCodeOwnerReviews      bool `json:"require_code_owner_reviews"`
+		DismissStaleReviews          bool `json:"dismiss_stale_reviews"`
+	} `json:"required_pull_request_reviews"`
+	RequiredStatusChecks *struct {
+		Strict   bool     `json:"strict"`
+		Contexts []string `json:"contexts"`
+	} `json:"required_status_checks"`
+	EnforceAdmins *struct {
+		Enabled bool `json:"enabled"`
+	} `json:"enforce_admins"`
+}
+
+// toBranchProtection returns the protection in the terms of the settings.
+func (p apiBranchProtection) toBranchProtection() branchProtection {
+	var bp branchProtection
+	if r := p.RequiredPullRequestReviews; r != nil {
+		bp.RequiredApprovingReviewCount = r.RequiredApprovingReviewCount
+		bp.RequireCodeOwnerReviews = r.RequireCodeOwnerReviews
+		bp.DismissStaleReviews = r.DismissStaleReviews
+	}
+
+	if c := p.RequiredStatusChecks; c != nil {
+		bp.RequiredStatusChecks = c.Contexts
+		bp.StrictStatusChecks = c.Strict
+	}
+
+	if p.EnforceAdmins != nil {
+		bp.EnforceAdmins = p.EnforceAdmins.Enabled
+	}
+
+	return bp
+}
+
+func (bp branchProtection) equal(other branchProtection) bool {
+	return bp.RequiredApprovingReviewCount == other.RequiredApprovingReviewCount &&
+		bp.RequireCodeOwnerReviews == other.RequireCodeOwnerReviews &&
+		bp.DismissStaleReviews == other.DismissStaleReviews &&
+		slices.Equal(slices.Sorted(slices.Values(bp.RequiredStatusChecks)), slices.Sorted(slices.Values(other.RequiredStatusChecks))) &&
+		bp.StrictStatusChecks == other.StrictStatusChecks &&
+		bp.EnforceAdmins == other.EnforceAdmins
+}
+
+// request returns the body of the request updating the protection of a branch.
+func (bp branchProtection) request() map[string]any {
+	req := map[string]any{
+		"enforce_admins":                bp.EnforceAdmins,
+		"required_status_checks":        nil,
+		"required_pull_request_reviews": nil,
+		"restrictions":                  nil,
+	}
+
+	if len(bp.RequiredStatusChecks) > 0 {
+		req["required_status_checks"] = map[string]any{"strict": bp.StrictStatusChecks, "contexts": bp.RequiredStatusChecks}
+	}
+
+	if bp.RequiredApprovingReviewCount > 0 || bp.RequireCodeOwnerReviews || bp.DismissStaleReviews {
+		req["required_pull_request_reviews"] = map[string]any{
+			"required_approving_review_count": bp.RequiredApprovingReviewCount,
+			"require_code_owner_reviews":      bp.RequireCodeOwnerReviews,
+			"dismiss_stale_reviews":           bp.DismissStaleReviews,
+		}
+	}
+
+	return req
+}
+
+// applyBranchProtection protects the branch as passed, returning the change.
+func applyBranchProtection(ctx context.Context, x exec.Execer, repository string, branch string, want branchProtection, dryRun bool) (string, error) {
+	if branch == "" {
+		return "", errors.New("protecting branch: unknown default branch")
+	}
+	path := "/repos/" + repository + "/branches/" + branch + "/protection"
+
+	res, err := apiRequest(ctx, x, "GET", path, nil)
+	change := "protect branch " + branch
+	switch {
+	case isNotFound(err):
+	case err != nil:
+		return "", fmt.Errorf("fetching branch protection: %w", err)
+	default:
+		var current apiBranchProtection
+		if err := json.Unmarshal([]byte(res), &current); err != nil {
+			return "", fmt.Errorf("unmarshaling branch protection: %w", err)
+		}
+
+		if want.equal(current.toBranchProtection()) {
+			return "", nil
+		}
+		change = "update protection of branch " + branch
+	}
+
+	if !dryRun {
+		if _, err := apiRequest(ctx, x, "PUT", path, want.request()); err != nil {
+			return "", fmt.Errorf("protecting branch: %w", err)
+		}
+	}
+
+	return change + " (" + strings.Join(want.summary(), ", ") + ")", nil
+}
+
+// summary describes the protection.
+func (bp branchProtection) summary() []string {
+	s := []string{fmt.Sprintf("%d approvals", bp.RequiredApprovingReviewCount)}
+	if bp.RequireCodeOwnerReviews {
+		s = append(s, "code owner reviews")
+	}
+	if bp.DismissStaleReviews {
+		s = append(s, "dismiss stale reviews")
+	}
+	if len(bp.RequiredStatusChecks) > 0 {
+		s = append(s, "checks "+strings.Join(bp.RequiredStatusChecks, " "))
+	}
+	if bp.EnforceAdmins {
+		s = append(s, "enforced for admins")
+	}
+
+	return s
+}
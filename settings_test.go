@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	iterator "github.com/jcchavezs/gh-iterator"
+	"github.com/jcchavezs/gh-iterator/exec"
+	"github.com/stretchr/testify/require"
+)
+
+// routedGH is the body of a gh script answering the API requests by method and path.
+const routedGH = `method=GET; prev=
+for a; do [ "$prev" = -X ] && method=$a; prev=$a; done
+cat > /dev/null
+case "$method $prev" in
+`
+
+func TestLoadRepoSettings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "settings.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("allow_squash_merge: true\nbranch_protection:\n  required_approving_review_count: 2\n"), 0644))
+
+	s, err := loadRepoSettings(path)
+	require.NoError(t, err)
+	require.True(t, *s.AllowSquashMerge)
+	require.Nil(t, s.AllowMergeCommit)
+	require.Equal(t, 2, s.BranchProtection.RequiredApprovingReviewCount)
+
+	require.NoError(t, os.WriteFile(path, []byte("allow_squash: true\n"), 0644))
+	_, err = loadRepoSettings(path)
+	require.Error(t, err)
+}
+
+func TestSettingsDrift(t *testing.T) {
+	yes, no, main := true, false, "main"
+	patch, changes := settingsDrift(
+		repoSettings{DefaultBranch: &main, AllowMergeCommit: &no, AllowSquashMerge: &yes, DeleteBranchOnMerge: &yes},
+		currentRepoSettings{DefaultBranch: "master", AllowMergeCommit: &yes, AllowSquashMerge: &yes},
+	)
+	require.Equal(t, map[string]any{"default_branch": "main", "allow_merge_commit": false, "delete_branch_on_merge": true}, patch)
+	require.Equal(t, []string{
+		"set default_branch to main (was master)",
+		"set allow_merge_commit to false (was true)",
+		"set delete_branch_on_merge to true (was unknown)",
+	}, changes)
+}
+
+func TestSettingsAction(t *testing.T) {
+	calls := scriptedGH(t, routedGH+`"GET /repos/acme/a") echo '{"default_branch":"main","allow_squash_merge":false}';;
+"GET /repos/acme/a/vulnerability-alerts") echo '{"message":"Not Found","status":"404"}'; exit 1;;
+"GET /repos/acme/a/branches/main/protection") echo '{"required_pull_request_reviews":{"required_approving_review_count":1},"enforce_admins":{"enabled":false}}';;
+esac
+`)
+
+	yes := true
+	action := settingsAction(repoSettings{
+		AllowSquashMerge:    &yes,
+		VulnerabilityAlerts: &yes,
+		BranchProtection:    &branchProtection{RequiredApprovingReviewCount: 2, RequiredStatusChecks: []string{"ci"}},
+	})
+	x := exec.NewExecerWithLogger(t.TempDir(), slog.New(slog.DiscardHandler))
+
+	changes, err := action(context.Background(), x, iterator.Repository{Name: "acme/a"}, true)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"set allow_squash_merge to true (was false)",
+		"enable vulnerability_alerts",
+		"update protection of branch main (2 approvals, checks ci)",
+	}, changes)
+
+	content, err := os.ReadFile(calls)
+	require.NoError(t, err)
+	require.NotContains(t, string(content), "PATCH")
+	require.NotContains(t, string(content), "PUT")
+
+	_, err = action(context.Background(), x, iterator.Repository{Name: "acme/a"}, false)
+	require.NoError(t, err)
+
+	content, err = os.ReadFile(calls)
+	require.NoError(t, err)
+	require.Contains(t, string(content), "-X PATCH --input - /repos/acme/a\n")
+	require.Contains(t, string(content), "-X PUT /repos/acme/a/vulnerability-alerts\n")
+	require.Contains(t, string(content), "-X PUT --input - /repos/acme/a/branches/main/protection\n")
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// exitCodeInterrupted is the exit code of the runs stopped by SIGINT or SIGTERM, as the shells do
+// for the processes killed by SIGINT.
+const exitCodeInterrupted = 130
+
+// errInterrupted is the error of the runs stopped by SIGINT or SIGTERM.
+var errInterrupted = errors.New("interrupted")
+
+type drainingContextKey struct{}
+
+// withDraining attaches to the context the channel closed once no more repositories have to be
+// dispatched, the ones being processed keep running.
+func withDraining(ctx context.Context, draining <-chan struct{}) context.Context {
+	return context.WithValue(ctx, drainingContextKey{}, draining)
+}
+
+// drainingFromContext returns the channel attached by withDraining, nil if none.
+func drainingFromContext(ctx context.Context) <-chan struct{} {
+	draining, _ := ctx.Value(drainingContextKey{}).(<-chan struct{})
+	return draining
+}
+
+// handleShutdown handles SIGINT and SIGTERM until stop is called. On the first signal the
+// returned context starts draining, so no more repositories are dispatched, and once the grace
+// period passes or on a second signal it is cancelled with errInterrupted, cancelling the
+// commands being run.
+func handleShutdown(ctx context.Context, grace time.Duration, logger *slog.Logger) (context.Context, func()) {
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	draining := make(chan struct{})
+	done := make(chan struct{})
+
+	go func() {
+		select {
+		case sig := <-signals:
+			logger.Warn("Stopping, waiting for the repositories being processed, interrupt again to cancel them", "signal", sig.String(), "grace_period", grace.String())
+			close(draining)
+		case <-done:
+			return
+		}
+
+		select {
+		case <-signals:
+		case <-time.After(grace):
+		case <-done:
+			return
+		}
+
+		logger.Warn("Cancelling the repositories being processed")
+		cancel(errInterrupted)
+	}()
+
+	stop := func() {
+		signal.Stop(signals)
+		close(done)
+		cancel(nil)
+	}
+
+	return withDraining(ctx, draining), stop
+}
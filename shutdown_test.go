@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	iterator "github.com/jcchavezs/gh-iterator"
+	"github.com/jcchavezs/gh-iterator/exec"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleShutdown(t *testing.T) {
+	ctx, stop := handleShutdown(context.Background(), 50*time.Millisecond, slog.New(slog.DiscardHandler))
+	defer stop()
+
+	draining := drainingFromContext(ctx)
+	require.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGINT))
+
+	select {
+	case <-draining:
+	case <-time.After(time.Second):
+		t.Fatal("context not draining after the signal")
+	}
+	require.NoError(t, ctx.Err())
+
+	<-ctx.Done()
+	require.ErrorIs(t, context.Cause(ctx), errInterrupted)
+}
+
+func TestRunForRepositories_Draining(t *testing.T) {
+	flags.noClone = true
+	t.Cleanup(func() { flags.noClone = false })
+
+	draining := make(chan struct{})
+	var calls atomic.Int32
+
+	err := runForRepositories(
+		withDraining(context.Background(), draining),
+		[]iterator.Repository{{Name: "acme/a", Size: 10}, {Name: "acme/b", Size: 10}, {Name: "acme/c", Size: 10}},
+		func(ctx context.Context, _ string, _ bool, _ exec.Execer) error {
+			calls.Add(1)
+			close(draining)
+			// the repository being processed is not cancelled.
+			require.NoError(t, ctx.Err())
+			return nil
+		},
+		newRunResults(),
+		runHooks{},
+		iterator.Options{LogHandler: slog.DiscardHandler, NumberOfWorkers: 1},
+	)
+	require.ErrorIs(t, err, errInterrupted)
+	require.Equal(t, int32(1), calls.Load())
+}
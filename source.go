@@ -0,0 +1,718 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	iterator "github.com/jcchavezs/gh-iterator"
+	"github.com/jcchavezs/gh-iterator/exec"
+	"github.com/jcchavezs/gh-iterator/github"
+)
+
+// OwnerType is the type of the account owning the repositories.
+type OwnerType int
+
+const (
+	OwnerTypeAuto OwnerType = iota
+	OwnerTypeOrg
+	OwnerTypeUser
+)
+
+// OwnerTypeIds maps owner types to their corresponding string identifiers.
+var OwnerTypeIds = map[OwnerType][]string{
+	OwnerTypeAuto: {"auto"},
+	OwnerTypeOrg:  {"org"},
+	OwnerTypeUser: {"user"},
+}
+
+// Visibility is the visibility of the repositories to list.
+type Visibility int
+
+const (
+	VisibilityAll Visibility = iota
+	VisibilityPublic
+	VisibilityPrivate
+	VisibilityInternal
+)
+
+// VisibilityIds maps visibilities to their corresponding string identifiers.
+var VisibilityIds = map[Visibility][]string{
+	VisibilityAll:      {"all"},
+	VisibilityPublic:   {"public"},
+	VisibilityPrivate:  {"private"},
+	VisibilityInternal: {"internal"},
+}
+
+const (
+	defaultPerPage = 100
+	// maxPerPage is the most repositories the API returns per page, the page math relies on it.
+	maxPerPage = 100
+	// maxSearchResults is the number of results the search API returns at most.
+	maxSearchResults = 1000
+)
+
+// repositoryFields are the fields of the repositories retrieved from the API.
+const repositoryFields = "full_name,clone_url,ssh_url,default_branch,archived,language,visibility,fork,size,pushed_at"
+
+// collectRepositories returns the repositories to process out of the owners, the GitHub App
+// installation, the search query, the repositories JSON and the repositories file passed by flag,
+// skipping duplicates.
+func collectRepositories(ctx context.Context, x exec.Execer, owners []string, stdin io.Reader, pages []iterator.Page) ([]iterator.Repository, error) {
+	var repos []iterator.Repository
+	err := streamRepositories(ctx, x, owners, stdin, pages, false, func(rs []iterator.Repository) error {
+		repos = append(repos, rs...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return repos, nil
+}
+
+// streamRepositories calls yield with the repositories of collectRepositories as they are listed,
+// skipping duplicates. With byPage the repositories of the owners are yielded page by page when
+// the provider supports it.
+func streamRepositories(ctx context.Context, x exec.Execer, owners []string, stdin io.Reader, pages []iterator.Page, byPage bool, yield func([]iterator.Repository) error) error {
+	seen := map[string]bool{}
+
+	add := func(rs ...iterator.Repository) error {
+		var added []iterator.Repository
+		for _, r := range rs {
+			if !seen[r.Name] {
+				seen[r.Name] = true
+				added = append(added, r)
+			}
+		}
+
+		if len(added) == 0 {
+			return nil
+		}
+
+		return yield(added)
+	}
+
+	// when the included repositories are known there is no need to list all the owners repositories.
+	if names, ok := literalRepoNames(flags.includeRepos, owners); ok && len(names) > 0 {
+		for _, name := range names {
+			repo, err := forge.FetchRepository(ctx, x, name)
+			if err != nil {
+				return err
+			}
+			if err := add(repo); err != nil {
+				return err
+			}
+		}
+
+		owners = nil
+	}
+
+	for _, owner := range owners {
+		count, err := listOwnerRepositories(ctx, x, owner, pages, byPage, add)
+		if err != nil {
+			return err
+		}
+
+		x.Log(ctx, slog.LevelInfo, "Listed repositories", "owner", owner, "count", count)
+	}
+
+	if flags.appInstallationID != "" {
+		installationRepos, err := listInstallationRepositories(ctx, x)
+		if err != nil {
+			return err
+		}
+
+		x.Log(ctx, slog.LevelInfo, "Listed installation repositories", "count", len(installationRepos))
+		if err := add(installationRepos...); err != nil {
+			return err
+		}
+	}
+
+	if flags.search != "" {
+		searchRepos, err := listPages(pages, func(page iterator.Page) ([]iterator.Repository, error) {
+			return searchRepositories(ctx, x, flags.search, page)
+		})
+		if err != nil {
+			return err
+		}
+
+		x.Log(ctx, slog.LevelInfo, "Searched repositories", "query", flags.search, "count", len(searchRepos))
+		if err := add(searchRepos...); err != nil {
+			return err
+		}
+	}
+
+	if flags.reposJSON != "" {
+		r, closeFn, err := openInput(flags.reposJSON, stdin)
+		if err != nil {
+			return fmt.Errorf("opening repositories JSON: %w", err)
+		}
+		defer closeFn()
+
+		jsonRepos, err := decodeRepositoriesJSON(r)
+		if err != nil {
+			return err
+		}
+		if err := add(jsonRepos...); err != nil {
+			return err
+		}
+	}
+
+	if flags.reposFile != "" {
+		r, closeFn, err := openInput(flags.reposFile, stdin)
+		if err != nil {
+			return fmt.Errorf("opening repositories file: %w", err)
+		}
+		defer closeFn()
+
+		names, err := readRepositoryNames(r)
+		if err != nil {
+			return err
+		}
+
+		for _, name := range names {
+			if seen[name] {
+				continue
+			}
+
+			repo, err := forge.FetchRepository(ctx, x, name)
+			if err != nil {
+				return err
+			}
+			if err := add(repo); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// listOwnerRepositories calls yield with the repositories of the owner, page by page with byPage
+// when the provider is a pageLister. It returns the number of repositories listed.
+func listOwnerRepositories(ctx context.Context, x exec.Execer, owner string, pages []iterator.Page, byPage bool, yield func(...iterator.Repository) error) (int, error) {
+	if l, ok := forge.(pageLister); ok && byPage {
+		var (
+			count    int
+			yieldErr error
+		)
+		err := l.ListRepositoryPages(ctx, x, owner, pages, func(repos []iterator.Repository) error {
+			count += len(repos)
+			yieldErr = yield(repos...)
+			return yieldErr
+		})
+		if yieldErr != nil {
+			return 0, yieldErr
+		} else if err != nil {
+			return 0, fmt.Errorf("listing repositories for %q: %w", owner, err)
+		}
+
+		return count, nil
+	}
+
+	repos, err := forge.ListRepositories(ctx, x, owner, pages)
+	if err != nil {
+		return 0, fmt.Errorf("listing repositories for %q: %w", owner, err)
+	}
+
+	return len(repos), yield(repos...)
+}
+
+// parsePages parses the pages to fetch: a page number, a range of pages e.g. 3-7 or 'all'.
+func parsePages(s string) ([]iterator.Page, error) {
+	switch s {
+	case "all":
+		return []iterator.Page{iterator.AllPages}, nil
+	case "":
+		return []iterator.Page{0}, nil
+	}
+
+	from, to, isRange := strings.Cut(s, "-")
+	first, err := strconv.Atoi(from)
+	if err != nil || first < 1 {
+		return nil, fmt.Errorf("invalid page %q", s)
+	}
+
+	last := first
+	if isRange {
+		if last, err = strconv.Atoi(to); err != nil || last < first {
+			return nil, fmt.Errorf("invalid page range %q", s)
+		}
+	}
+
+	pages := make([]iterator.Page, 0, last-first+1)
+	for p := first; p <= last; p++ {
+		pages = append(pages, iterator.PageN(p))
+	}
+
+	return pages, nil
+}
+
+// listPages lists the repositories in each of the pages, it stops at the first empty page.
+func listPages(pages []iterator.Page, list func(iterator.Page) ([]iterator.Repository, error)) ([]iterator.Repository, error) {
+	var repos []iterator.Repository
+	for _, page := range pages {
+		pageRepos, err := list(page)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(pageRepos) == 0 {
+			break
+		}
+
+		repos = append(repos, pageRepos...)
+	}
+
+	return repos, nil
+}
+
+// apiCacheArgs returns the gh api arguments to cache the responses when --api-cache is set.
+func apiCacheArgs() []string {
+	if flags.apiCache <= 0 {
+		return nil
+	}
+
+	return []string{"--cache", flags.apiCache.String()}
+}
+
+// searchRepositories lists the repositories matching the query using the search API. Notice
+// the search API returns up to 1000 results.
+func searchRepositories(ctx context.Context, x exec.Execer, query string, page iterator.Page) ([]iterator.Repository, error) {
+	target := fmt.Sprintf("/search/repositories?per_page=%d", defaultPerPage)
+
+	ghArgs := []string{"api",
+		"-H", "Accept: application/vnd.github+json",
+		"-H", "X-GitHub-Api-Version: " + iterator.GithubAPIVersion,
+		"-X", "GET",
+		"-f", "q=" + query,
+		"--jq", ".items | map({" + repositoryFields + "})",
+	}
+	ghArgs = append(ghArgs, apiCacheArgs()...)
+
+	if page == iterator.AllPages {
+		ghArgs = append(ghArgs, "--paginate")
+	} else if page > 0 {
+		target = withQuery(target, fmt.Sprintf("page=%d", page))
+	}
+
+	res, err := x.RunX(ctx, "gh", append(ghArgs, target)...)
+	if err != nil {
+		return nil, fmt.Errorf("searching repositories: %w", github.ErrOrGHAPIErr(res, err))
+	}
+
+	repos, err := decodeRepositoryPages(strings.NewReader(res))
+	if err != nil {
+		return nil, fmt.Errorf("processing search results: %w", err)
+	}
+
+	return repos, nil
+}
+
+// ownerSearchQuery returns the search query listing the repositories of the owner with the
+// topics, the visibility and without the forks as passed by flag, empty when none is passed.
+func ownerSearchQuery(owner string) string {
+	if len(flags.topics) == 0 && flags.visibility == VisibilityAll && !flags.noForks {
+		return ""
+	}
+
+	qualifiers := []string{"user:" + owner}
+	for _, t := range flags.topics {
+		qualifiers = append(qualifiers, "topic:"+t)
+	}
+
+	if flags.visibility != VisibilityAll {
+		qualifiers = append(qualifiers, "is:"+VisibilityIds[flags.visibility][0])
+	}
+
+	// the search leaves the forks out unless asked for.
+	if !flags.noForks {
+		qualifiers = append(qualifiers, "fork:true")
+	}
+
+	return strings.Join(qualifiers, " ")
+}
+
+// openInput opens the file or returns stdin when name is '-'.
+func openInput(name string, stdin io.Reader) (io.Reader, func(), error) {
+	if name == "-" {
+		return stdin, func() {}, nil
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return f, func() { _ = f.Close() }, nil
+}
+
+// repositoryJSON is a repository as returned by the REST API or by the gh CLI commands
+// supporting --json e.g. gh search repos or gh repo list.
+type repositoryJSON struct {
+	iterator.Repository
+
+	FullName         string                `json:"fullName"`
+	NameWithOwner    string                `json:"nameWithOwner"`
+	IsArchived       bool                  `json:"isArchived"`
+	IsFork           bool                  `json:"isFork"`
+	DefaultBranch    string                `json:"defaultBranch"`
+	DefaultBranchRef struct{ Name string } `json:"defaultBranchRef"`
+	PrimaryLanguage  struct{ Name string } `json:"primaryLanguage"`
+	DiskUsage        int                   `json:"diskUsage"`
+	PushedAtCamel    time.Time             `json:"pushedAt"`
+	SSHURLCamel      string                `json:"sshUrl"`
+}
+
+func (rj repositoryJSON) toRepository() iterator.Repository {
+	r := rj.Repository
+	r.Name = cmp.Or(r.Name, rj.FullName, rj.NameWithOwner)
+	r.Archived = r.Archived || rj.IsArchived
+	r.Fork = r.Fork || rj.IsFork
+	r.DefaultBranchName = cmp.Or(r.DefaultBranchName, rj.DefaultBranch, rj.DefaultBranchRef.Name)
+	r.Language = cmp.Or(r.Language, rj.PrimaryLanguage.Name)
+	r.Visibility = strings.ToLower(r.Visibility)
+	r.Size = cmp.Or(r.Size, rj.DiskUsage)
+	r.SSHURL = cmp.Or(r.SSHURL, rj.SSHURLCamel)
+	if r.PushedAt.IsZero() {
+		r.PushedAt = rj.PushedAtCamel
+	}
+
+	return r
+}
+
+// decodeRepositoriesJSON decodes a stream of repositories, either as objects e.g. NDJSON or as
+// arrays of objects.
+func decodeRepositoriesJSON(r io.Reader) ([]iterator.Repository, error) {
+	var repos []iterator.Repository
+
+	dec := json.NewDecoder(r)
+	for {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("decoding repositories JSON: %w", err)
+		}
+
+		var values []repositoryJSON
+		if trimmed := bytes.TrimSpace(raw); len(trimmed) > 0 && trimmed[0] == '[' {
+			if err := json.Unmarshal(raw, &values); err != nil {
+				return nil, fmt.Errorf("unmarshaling repositories: %w", err)
+			}
+		} else {
+			var value repositoryJSON
+			if err := json.Unmarshal(raw, &value); err != nil {
+				return nil, fmt.Errorf("unmarshaling repository: %w", err)
+			}
+			values = append(values, value)
+		}
+
+		for _, v := range values {
+			repo := v.toRepository()
+			if repo.Name == "" {
+				return nil, errors.New("repository without name in repositories JSON")
+			}
+			repos = append(repos, repo)
+		}
+	}
+
+	return repos, nil
+}
+
+// readRepositoryNames reads the owner/repo names, one per line, skipping empty lines and
+// comments starting with #.
+func readRepositoryNames(r io.Reader) ([]string, error) {
+	var names []string
+
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		name := strings.TrimSpace(s.Text())
+		if name == "" || strings.HasPrefix(name, "#") {
+			continue
+		}
+
+		if strings.Count(name, "/") != 1 {
+			return nil, fmt.Errorf("invalid repository name %q, expected owner/repo", name)
+		}
+
+		names = append(names, name)
+	}
+
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("reading repository names: %w", err)
+	}
+
+	return names, nil
+}
+
+// fetchRepository retrieves the metadata of a single repository.
+func fetchRepository(ctx context.Context, x exec.Execer, name string) (iterator.Repository, error) {
+	ghArgs := append([]string{"api",
+		"-H", "Accept: application/vnd.github+json",
+		"-H", "X-GitHub-Api-Version: " + iterator.GithubAPIVersion,
+		"-X", "GET",
+		"--jq", "{" + repositoryFields + "}",
+	}, apiCacheArgs()...)
+
+	res, err := x.RunX(ctx, "gh", append(ghArgs, "/repos/"+name)...)
+	if err != nil {
+		return iterator.Repository{}, fmt.Errorf("fetching repository %q: %w", name, github.ErrOrGHAPIErr(res, err))
+	}
+
+	var repo iterator.Repository
+	if err := json.Unmarshal([]byte(res), &repo); err != nil {
+		return iterator.Repository{}, fmt.Errorf("unmarshaling repository: %w", err)
+	}
+
+	return repo, nil
+}
+
+// listRepositories lists the page of the repositories in target, the API path returned by
+// listTarget.
+func listRepositories(ctx context.Context, x exec.Execer, target string, page iterator.Page) ([]iterator.Repository, error) {
+	ghArgs := []string{"api",
+		"-H", "Accept: application/vnd.github+json",
+		"-H", "X-GitHub-Api-Version: " + iterator.GithubAPIVersion,
+		"-X", "GET",
+		"--jq", ". | map({" + repositoryFields + "})",
+	}
+	ghArgs = append(ghArgs, apiCacheArgs()...)
+
+	if page == iterator.AllPages {
+		ghArgs = append(ghArgs, "--paginate")
+	} else if page > 0 {
+		target = withQuery(target, fmt.Sprintf("page=%d", page))
+	} else if page != 0 {
+		return nil, errors.New("invalid negative page")
+	}
+
+	res, err := x.RunX(ctx, "gh", append(ghArgs, target)...)
+	if err != nil {
+		return nil, fmt.Errorf("fetching repositories: %w", github.ErrOrGHAPIErr(res, err))
+	}
+
+	repos, err := decodeRepositoryPages(strings.NewReader(res))
+	if err != nil {
+		return nil, fmt.Errorf("processing repositories pages: %w", err)
+	}
+
+	return repos, nil
+}
+
+// listTarget returns the API path to list the repositories of the owner with the page size
+// and the sorting.
+func listTarget(ctx context.Context, x exec.Execer, owner string, ownerType OwnerType, perPage int) (string, error) {
+	target, err := reposPath(ctx, x, owner, ownerType)
+	if err != nil {
+		return "", err
+	}
+
+	if perPage == 0 || perPage > maxPerPage {
+		perPage = defaultPerPage
+	} else if perPage < 0 {
+		return "", errors.New("invalid negative per page")
+	}
+	target = withQuery(target, fmt.Sprintf("per_page=%d", perPage))
+
+	// sorting in the API makes the pages deterministic when fetching a single page.
+	if sortQuery := apiSortQuery(flags.sort, flags.order); sortQuery != "" {
+		target = withQuery(target, sortQuery)
+	}
+
+	return target, nil
+}
+
+// reposPath returns the API path to list the repositories of the owner. When the owner is the
+// authenticated user the private repositories are included.
+func reposPath(ctx context.Context, x exec.Execer, owner string, ownerType OwnerType) (string, error) {
+	if ownerType == OwnerTypeAuto {
+		var err error
+		if ownerType, err = detectOwnerType(ctx, x, owner); err != nil {
+			return "", err
+		}
+	}
+
+	switch ownerType {
+	case OwnerTypeOrg:
+		return fmt.Sprintf("/orgs/%s/repos", owner), nil
+	case OwnerTypeUser:
+		if login, err := exec.TrimStdout(x.RunX(ctx, "gh", "api", "user", "--jq", ".login")); err == nil && strings.EqualFold(login, owner) {
+			return "/user/repos?affiliation=owner", nil
+		}
+		return fmt.Sprintf("/users/%s/repos?type=owner", owner), nil
+	default:
+		return "", fmt.Errorf("unknown owner type %d", ownerType)
+	}
+}
+
+// detectOwnerType returns whether the owner is an organization or a user.
+func detectOwnerType(ctx context.Context, x exec.Execer, owner string) (OwnerType, error) {
+	res, err := exec.TrimStdout(x.RunX(ctx, "gh", "api", "/users/"+owner, "--jq", ".type"))
+	if err != nil {
+		return OwnerTypeAuto, fmt.Errorf("detecting owner type: %w", github.ErrOrGHAPIErr(res, err))
+	}
+
+	if res == "Organization" {
+		return OwnerTypeOrg, nil
+	}
+
+	return OwnerTypeUser, nil
+}
+
+func withQuery(path string, query string) string {
+	if strings.Contains(path, "?") {
+		return path + "&" + query
+	}
+
+	return path + "?" + query
+}
+
+// decodeRepositoryPages decodes the pages of repositories as returned by gh api.
+func decodeRepositoryPages(r io.Reader) ([]iterator.Repository, error) {
+	var repos []iterator.Repository
+
+	dec := json.NewDecoder(r)
+	for {
+		var page []iterator.Repository
+		if err := dec.Decode(&page); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("unmarshaling repositories: %w", err)
+		}
+
+		repos = append(repos, page...)
+	}
+
+	return repos, nil
+}
+
+// setupSources validates the sources of the repositories passed by flag and args, points gh to
+// the host and authenticates as the app installation when passed. It returns the owners to list
+// the repositories of.
+func setupSources(ctx context.Context, args []string, logger *slog.Logger) ([]string, error) {
+	if err := setupProvider(flags.provider); err != nil {
+		return nil, err
+	}
+
+	if flags.metadataCache != "" {
+		cache, err := loadMetadataCache(flags.metadataCache)
+		if err != nil {
+			return nil, err
+		}
+		forge = cachingProvider{provider: forge, cache: cache}
+	}
+
+	if flags.hostname != "" {
+		// gh honors GH_HOST in every API call, also in the ones run by the commands, and
+		// the clone URLs returned by the API already point to the host.
+		if err := os.Setenv("GH_HOST", flags.hostname); err != nil {
+			return nil, fmt.Errorf("setting GH_HOST: %w", err)
+		}
+	}
+
+	// the args are copied so appending does not write into the backing array of the caller.
+	owners := append(slices.Clone(args), flags.owners...)
+	if flags.fixtures != "" {
+		if len(owners) > 0 || flags.reposFile != "" || flags.reposJSON != "" || flags.search != "" || flags.appInstallationID != "" || len(flags.includeRepos) > 0 {
+			return nil, errors.New("--fixtures can't be used with other sources of repositories")
+		}
+
+		// the fixtures are read like the repositories JSON, so nothing is fetched.
+		flags.reposJSON = flags.fixtures
+	}
+
+	if len(owners) == 0 && flags.reposFile == "" && flags.reposJSON == "" && flags.search == "" && flags.appInstallationID == "" && len(flags.includeRepos) == 0 {
+		return nil, errors.New("at least one owner, a search query, a repositories file or an app installation is required")
+	}
+
+	if flags.reposFile == "-" && flags.reposJSON == "-" {
+		return nil, errors.New("only one of --repos-file and --repos-json can read from stdin")
+	}
+
+	if flags.appInstallationID != "" {
+		if flags.appID == "" || flags.appPrivateKey == "" {
+			return nil, errors.New("--app-id and --app-private-key are required to use an app installation")
+		}
+
+		x := withRetries(exec.NewExecerWithLogger(".", logger), flags.apiRetries)
+		token, expiresAt, err := installationToken(ctx, x, flags.appID, flags.appPrivateKey, flags.appInstallationID)
+		if err != nil {
+			return nil, err
+		}
+
+		// every gh invocation from now on, including the ones in the commands, authenticates
+		// as the app installation.
+		if err := os.Setenv("GH_TOKEN", token); err != nil {
+			return nil, fmt.Errorf("setting GH_TOKEN: %w", err)
+		}
+
+		go refreshInstallationToken(ctx, x, flags.appID, flags.appPrivateKey, flags.appInstallationID, expiresAt)
+	}
+
+	return owners, nil
+}
+
+// matchingRepositories collects the repositories from the owners and the sources passed by flag
+// and selects the ones passing the stages. It returns the reason each repository was left out.
+// The API responses are recorded with --record and replayed with --replay.
+func matchingRepositories(ctx context.Context, x exec.Execer, owners []string, stdin io.Reader, stages []selectionStage) ([]iterator.Repository, []iterator.Repository, map[string]string, error) {
+	pages, err := parsePages(flags.page)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	x, saveSession, err := withAPISession(x)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	repos, err := collectRepositories(ctx, x, owners, stdin, pages)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if err := saveSession(); err != nil {
+		return nil, nil, nil, err
+	}
+
+	selected, skipped := selectRepositories(repos, stages)
+	return repos, selected, skipped, nil
+}
+
+// streamMatchingRepositories calls yield with the repositories of matchingRepositories as the
+// pages are listed, the selected ones and why the others were left out. Unlike
+// matchingRepositories, the repositories are not sorted, sampled nor limited.
+func streamMatchingRepositories(ctx context.Context, x exec.Execer, owners []string, stdin io.Reader, stages []selectionStage, yield func(found, selected []iterator.Repository, skipped map[string]string) error) error {
+	pages, err := parsePages(flags.page)
+	if err != nil {
+		return err
+	}
+
+	x, saveSession, err := withAPISession(x)
+	if err != nil {
+		return err
+	}
+
+	err = streamRepositories(ctx, x, owners, stdin, pages, true, func(repos []iterator.Repository) error {
+		selected, skipped := applyStages(repos, stages)
+		return yield(repos, selected, skipped)
+	})
+	if err != nil {
+		return err
+	}
+
+	return saveSession()
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	iterator "github.com/jcchavezs/gh-iterator"
+	"github.com/jcchavezs/gh-iterator/exec"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeRepositoryPages(t *testing.T) {
+	pages := `[{"full_name":"acme/a","default_branch":"main","size":10},{"full_name":"acme/b","archived":true}]
+[{"full_name":"acme/c","language":"Go","pushed_at":"2024-06-01T00:00:00Z"}]
+[]
+`
+
+	repos, err := decodeRepositoryPages(strings.NewReader(pages))
+	require.NoError(t, err)
+	require.Len(t, repos, 3)
+	require.Equal(t, "acme/a", repos[0].Name)
+	require.Equal(t, "main", repos[0].DefaultBranchName)
+	require.True(t, repos[1].Archived)
+	require.Equal(t, "Go", repos[2].Language)
+	require.Equal(t, 2024, repos[2].PushedAt.Year())
+
+	_, err = decodeRepositoryPages(strings.NewReader(`[{"full_name":`))
+	require.Error(t, err)
+}
+
+func TestWithQuery(t *testing.T) {
+	require.Equal(t, "/orgs/acme/repos?per_page=10", withQuery("/orgs/acme/repos", "per_page=10"))
+	require.Equal(t, "/user/repos?affiliation=owner&per_page=10", withQuery("/user/repos?affiliation=owner", "per_page=10"))
+}
+
+func TestReadRepositoryNames(t *testing.T) {
+	names, err := readRepositoryNames(strings.NewReader(`# campaign repos
+acme/a
+
+  acme/b
+`))
+	require.NoError(t, err)
+	require.Equal(t, []string{"acme/a", "acme/b"}, names)
+
+	_, err = readRepositoryNames(strings.NewReader("acme"))
+	require.Error(t, err)
+}
+
+func TestDecodeRepositoriesJSON(t *testing.T) {
+	input := `{"full_name":"acme/a","default_branch":"main","archived":true,"size":3}
+{"fullName":"acme/b","defaultBranch":"dev","isFork":true,"language":"Go","visibility":"public","pushedAt":"2024-06-01T00:00:00Z"}
+[{"nameWithOwner":"acme/c","isArchived":true,"defaultBranchRef":{"name":"trunk"},"primaryLanguage":{"name":"Rust"},"visibility":"PRIVATE","diskUsage":42}]
+`
+
+	repos, err := decodeRepositoriesJSON(strings.NewReader(input))
+	require.NoError(t, err)
+	require.Len(t, repos, 3)
+
+	require.Equal(t, "acme/a", repos[0].Name)
+	require.Equal(t, "main", repos[0].DefaultBranchName)
+	require.True(t, repos[0].Archived)
+	require.Equal(t, 3, repos[0].Size)
+
+	require.Equal(t, "acme/b", repos[1].Name)
+	require.Equal(t, "dev", repos[1].DefaultBranchName)
+	require.True(t, repos[1].Fork)
+	require.Equal(t, "Go", repos[1].Language)
+	require.Equal(t, 2024, repos[1].PushedAt.Year())
+
+	require.Equal(t, "acme/c", repos[2].Name)
+	require.Equal(t, "trunk", repos[2].DefaultBranchName)
+	require.True(t, repos[2].Archived)
+	require.Equal(t, "Rust", repos[2].Language)
+	require.Equal(t, "private", repos[2].Visibility)
+	require.Equal(t, 42, repos[2].Size)
+
+	_, err = decodeRepositoriesJSON(strings.NewReader(`{"language":"Go"}`))
+	require.Error(t, err)
+}
+
+func TestParsePages(t *testing.T) {
+	pages, err := parsePages("all")
+	require.NoError(t, err)
+	require.Equal(t, []iterator.Page{iterator.AllPages}, pages)
+
+	pages, err = parsePages("2")
+	require.NoError(t, err)
+	require.Equal(t, []iterator.Page{2}, pages)
+
+	pages, err = parsePages("3-5")
+	require.NoError(t, err)
+	require.Equal(t, []iterator.Page{3, 4, 5}, pages)
+
+	for _, invalid := range []string{"0", "x", "5-3", "3-", "-3"} {
+		_, err = parsePages(invalid)
+		require.Error(t, err, invalid)
+	}
+}
+
+func TestFixtures(t *testing.T) {
+	t.Cleanup(func() { flags.fixtures, flags.reposJSON, flags.provider = "", "", "" })
+	flags.provider = "github"
+	flags.fixtures = filepath.Join(t.TempDir(), "repos.json")
+	require.NoError(t, os.WriteFile(flags.fixtures, []byte(`[
+  {"full_name":"acme/a","default_branch":"main","language":"Go","size":3},
+  {"full_name":"acme/b","default_branch":"main","archived":true,"size":3}
+]`), 0644))
+
+	logger := slog.New(slog.DiscardHandler)
+
+	owners, err := setupSources(context.Background(), nil, logger)
+	require.NoError(t, err)
+	require.Empty(t, owners)
+
+	stages, err := filterStages(logger)
+	require.NoError(t, err)
+
+	// no gh invocation is needed.
+	t.Setenv("PATH", t.TempDir())
+	repos, selected, skipped, err := matchingRepositories(context.Background(), exec.NewExecerWithLogger(".", logger), owners, nil, stages)
+	require.NoError(t, err)
+	require.Len(t, repos, 2)
+	require.Len(t, selected, 1)
+	require.Equal(t, "acme/a", selected[0].Name)
+	require.Contains(t, skipped, "acme/b")
+
+	_, err = setupSources(context.Background(), []string{"acme"}, logger)
+	require.Error(t, err)
+}
+
+func TestSetupSources_Owners(t *testing.T) {
+	saved := flags
+	t.Cleanup(func() { flags = saved })
+	flags.provider, flags.owners = "github", []string{"globex"}
+
+	// the spare capacity of the args is not written into.
+	args := make([]string, 1, 2)
+	args[0] = "acme"
+
+	owners, err := setupSources(context.Background(), args, slog.New(slog.DiscardHandler))
+	require.NoError(t, err)
+	require.Equal(t, []string{"acme", "globex"}, owners)
+	require.Empty(t, args[:2][1])
+}
+
+func TestListTarget_PerPage(t *testing.T) {
+	scriptedGH(t, `echo Organization`)
+	x := exec.NewExecerWithLogger(t.TempDir(), slog.New(slog.DiscardHandler))
+
+	target, err := listTarget(context.Background(), x, "acme", OwnerTypeAuto, 500)
+	require.NoError(t, err)
+	require.Equal(t, "/orgs/acme/repos?per_page=100", target)
+
+	target, err = listTarget(context.Background(), x, "acme", OwnerTypeAuto, 50)
+	require.NoError(t, err)
+	require.Equal(t, "/orgs/acme/repos?per_page=50", target)
+}
+
+func TestCollectRepositories_Owners(t *testing.T) {
+	t.Cleanup(func() { forge = githubProvider{} })
+	forge = fakeProvider{repos: map[string][]iterator.Repository{
+		"acme":   {{Name: "acme/a"}, {Name: "acme/b"}},
+		"globex": {{Name: "globex/c"}, {Name: "acme/a"}},
+	}}
+
+	// the repositories of all the owners are listed once, in the order of the owners.
+	repos, err := collectRepositories(context.Background(), exec.NewExecerWithLogger(".", slog.New(slog.DiscardHandler)), []string{"acme", "globex"}, nil, []iterator.Page{iterator.AllPages})
+	require.NoError(t, err)
+	require.Equal(t, []iterator.Repository{{Name: "acme/a"}, {Name: "acme/b"}, {Name: "globex/c"}}, repos)
+}
+
+func TestCollectRepositories_Search(t *testing.T) {
+	t.Cleanup(func() { flags.search = "" })
+	flags.search = "org:acme language:go"
+
+	calls := scriptedGH(t, `case "$*" in
+*"&page=1"*) echo '[{"full_name":"acme/a","language":"Go","size":3}]' ;;
+*) echo '[]' ;;
+esac
+`)
+	x := exec.NewExecerWithLogger(t.TempDir(), slog.New(slog.DiscardHandler))
+
+	repos, err := collectRepositories(context.Background(), x, nil, nil, []iterator.Page{iterator.PageN(1), iterator.PageN(2), iterator.PageN(3)})
+	require.NoError(t, err)
+	require.Equal(t, []iterator.Repository{{Name: "acme/a", Language: "Go", Size: 3}}, repos)
+
+	// the query is passed as is and the listing stops at the first empty page.
+	content, err := os.ReadFile(calls)
+	require.NoError(t, err)
+	require.Equal(t, 2, strings.Count(string(content), "-f q=org:acme language:go "))
+	require.Contains(t, string(content), "/search/repositories?per_page=100&page=2\n")
+	require.NotContains(t, string(content), "page=3")
+}
+
+func TestSetupSources_Hostname(t *testing.T) {
+	saved := flags
+	t.Cleanup(func() { flags = saved })
+	// restores GH_HOST once the test ends.
+	t.Setenv("GH_HOST", "")
+	flags.provider, flags.hostname = "github", "ghe.acme.com"
+
+	_, err := setupSources(context.Background(), []string{"acme"}, slog.New(slog.DiscardHandler))
+	require.NoError(t, err)
+
+	// gh gets the host in all the invocations, also in the ones run by the commands.
+	scriptedGH(t, `echo "$GH_HOST"`)
+	res, err := exec.NewExecerWithLogger(t.TempDir(), slog.New(slog.DiscardHandler)).RunX(context.Background(), "gh", "api", "/user")
+	require.NoError(t, err)
+	require.Equal(t, "ghe.acme.com\n", res)
+}
+
+func TestListRepositories_APICache(t *testing.T) {
+	t.Cleanup(func() { flags.apiCache = 0 })
+
+	calls := scriptedGH(t, `echo '[{"full_name":"acme/a"}]'`)
+	x := exec.NewExecerWithLogger(t.TempDir(), slog.New(slog.DiscardHandler))
+
+	_, err := listRepositories(context.Background(), x, "/orgs/acme/repos?per_page=100", iterator.AllPages)
+	require.NoError(t, err)
+
+	flags.apiCache = time.Hour
+	_, err = listRepositories(context.Background(), x, "/orgs/acme/repos?per_page=100", iterator.AllPages)
+	require.NoError(t, err)
+
+	content, err := os.ReadFile(calls)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	require.Len(t, lines, 2)
+	require.NotContains(t, lines[0], "--cache")
+	require.Contains(t, lines[1], "--cache 1h0m0s ")
+}
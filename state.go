@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	iterator "github.com/jcchavezs/gh-iterator"
+	"github.com/jcchavezs/gh-iterator/exec"
+)
+
+// runState records the last push of the processed repositories so the next runs only process
+// the repositories that got new pushes since. The repositories skipped before running the
+// processor e.g. opted out or filtered out after clone are recorded along with the reason, so
+// they are not cloned again either.
+type runState struct {
+	mu           sync.Mutex
+	Repositories map[string]time.Time         `json:"repositories"`
+	Skipped      map[string]skippedRepository `json:"skipped,omitempty"`
+
+	// processed are the repositories the processor ran for in this run.
+	processed map[string]bool
+}
+
+type skippedRepository struct {
+	PushedAt time.Time `json:"pushed_at"`
+	Reason   string    `json:"reason"`
+}
+
+// loadRunState reads the state from path, an empty state is returned if the file does not exist.
+func loadRunState(path string) (*runState, error) {
+	s := &runState{Repositories: map[string]time.Time{}, Skipped: map[string]skippedRepository{}, processed: map[string]bool{}}
+
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("reading state: %w", err)
+	}
+
+	if err := json.Unmarshal(content, s); err != nil {
+		return nil, fmt.Errorf("unmarshaling state: %w", err)
+	}
+
+	if s.Repositories == nil {
+		s.Repositories = map[string]time.Time{}
+	}
+
+	if s.Skipped == nil {
+		s.Skipped = map[string]skippedRepository{}
+	}
+
+	return s, nil
+}
+
+// changed returns true if the repository is new or was pushed since it was processed.
+func (s *runState) changed(r iterator.Repository) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if pushedAt, ok := s.Repositories[r.Name]; ok {
+		return !pushedAt.Equal(r.PushedAt)
+	}
+
+	skipped, ok := s.Skipped[r.Name]
+	return !ok || !skipped.PushedAt.Equal(r.PushedAt)
+}
+
+// unchangedReason tells why the repository is left out of the run, including the reason it was
+// skipped in the last run.
+func (s *runState) unchangedReason(r iterator.Repository) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if skipped, ok := s.Skipped[r.Name]; ok {
+		return "unchanged since the last run, " + skipped.Reason
+	}
+
+	return "unchanged since the last run"
+}
+
+func (s *runState) record(repository string, pushedAt time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Repositories[repository] = pushedAt
+	delete(s.Skipped, repository)
+}
+
+func (s *runState) recordSkipped(repository string, pushedAt time.Time, reason string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Skipped[repository] = skippedRepository{PushedAt: pushedAt, Reason: reason}
+	delete(s.Repositories, repository)
+}
+
+// ran records that the processor ran for the repository.
+func (s *runState) ran(repository string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.processed[repository] = true
+}
+
+// hooks returns the hooks recording the repositories skipped before running the processor. The
+// ones skipped by the processor e.g. because the branch already exists are processed again in
+// the next run.
+func (s *runState) hooks() runHooks {
+	return runHooks{
+		OnRepoSkipped: func(repo iterator.Repository, reason string) {
+			s.mu.Lock()
+			processed := s.processed[repo.Name]
+			s.mu.Unlock()
+
+			if !processed {
+				s.recordSkipped(repo.Name, repo.PushedAt, reason)
+			}
+		},
+	}
+}
+
+// track wraps the processor to record the repositories processed successfully: the command
+// exited with 0 and they were not skipped, as per their results.
+func (s *runState) track(repos []iterator.Repository, processor iterator.Processor, results *runResults) iterator.Processor {
+	pushedAt := make(map[string]time.Time, len(repos))
+	for _, r := range repos {
+		pushedAt[r.Name] = r.PushedAt
+	}
+
+	return func(ctx context.Context, repository string, isEmpty bool, x exec.Execer) error {
+		s.ran(repository)
+		if err := processor(ctx, repository, isEmpty, x); err != nil {
+			return err
+		}
+
+		if res, _ := results.get(repository); res.failed() || res.Skipped != "" {
+			// they are processed again in the next run.
+			return nil
+		}
+
+		s.record(repository, pushedAt[repository])
+		return nil
+	}
+}
+
+// save writes the state into path.
+func (s *runState) save(path string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	content, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling state: %w", err)
+	}
+
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		return fmt.Errorf("writing state: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	iterator "github.com/jcchavezs/gh-iterator"
+	"github.com/jcchavezs/gh-iterator/exec"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	pushedAt := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	repo := iterator.Repository{Name: "acme/a", PushedAt: pushedAt}
+
+	s, err := loadRunState(path)
+	require.NoError(t, err)
+	require.True(t, s.changed(repo))
+
+	process := s.track([]iterator.Repository{repo}, func(context.Context, string, bool, exec.Execer) error {
+		return nil
+	}, newRunResults())
+	require.NoError(t, process(context.Background(), "acme/a", false, nil))
+	require.NoError(t, s.save(path))
+
+	s, err = loadRunState(path)
+	require.NoError(t, err)
+	require.False(t, s.changed(repo))
+
+	repo.PushedAt = pushedAt.Add(time.Hour)
+	require.True(t, s.changed(repo))
+	require.True(t, s.changed(iterator.Repository{Name: "acme/new"}))
+}
+
+func TestRunState_TrackFailures(t *testing.T) {
+	repos := []iterator.Repository{{Name: "acme/a"}, {Name: "acme/b"}, {Name: "acme/c"}, {Name: "acme/d"}}
+	results := newRunResults()
+	results.addRepositories(repos, repos, nil)
+
+	s, err := loadRunState(filepath.Join(t.TempDir(), "state.json"))
+	require.NoError(t, err)
+
+	process := s.track(repos, func(_ context.Context, repository string, _ bool, _ exec.Execer) error {
+		switch repository {
+		case "acme/a":
+			results.update(repository, func(r *repoResult) { r.CommandRan = true })
+		case "acme/b":
+			results.update(repository, func(r *repoResult) { r.CommandRan, r.ExitCode = true, 1 })
+		case "acme/c":
+			results.update(repository, func(r *repoResult) { r.Skipped = "branch exists" })
+		case "acme/d":
+			return errors.New("push rejected")
+		}
+		return nil
+	}, results)
+
+	for _, repo := range repos {
+		_ = process(context.Background(), repo.Name, false, nil)
+	}
+
+	require.False(t, s.changed(repos[0]))
+	require.True(t, s.changed(repos[1]), "failed command")
+	require.True(t, s.changed(repos[2]), "skipped")
+	require.True(t, s.changed(repos[3]), "processing error")
+}
+
+func TestRunState_TrackSkipped(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	pushedAt := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	optedOut := iterator.Repository{Name: "acme/a", PushedAt: pushedAt}
+	branchExists := iterator.Repository{Name: "acme/b", PushedAt: pushedAt}
+	repos := []iterator.Repository{optedOut, branchExists}
+	results := newRunResults()
+	results.addRepositories(repos, repos, nil)
+
+	s, err := loadRunState(path)
+	require.NoError(t, err)
+
+	process := s.track(repos, func(_ context.Context, repository string, _ bool, _ exec.Execer) error {
+		results.update(repository, func(r *repoResult) { r.Skipped = "branch already exists" })
+		return nil
+	}, results)
+	hooks := s.hooks()
+
+	// acme/a is skipped before running the processor.
+	hooks.repoDone(optedOut, repoResult{Skipped: "opted out"}, nil)
+
+	require.NoError(t, process(context.Background(), branchExists.Name, false, nil))
+	res, _ := results.get(branchExists.Name)
+	hooks.repoDone(branchExists, res, nil)
+
+	require.NoError(t, s.save(path))
+
+	s, err = loadRunState(path)
+	require.NoError(t, err)
+	require.False(t, s.changed(optedOut))
+	require.Equal(t, "unchanged since the last run, opted out", s.unchangedReason(optedOut))
+	require.True(t, s.changed(branchExists), "skipped by the processor")
+
+	optedOut.PushedAt = pushedAt.Add(time.Hour)
+	require.True(t, s.changed(optedOut))
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	iterator "github.com/jcchavezs/gh-iterator"
+	"github.com/jcchavezs/gh-iterator/exec"
+	"github.com/spf13/cobra"
+)
+
+// statsBucket is the number of repositories in a group of the statistics.
+type statsBucket struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// repoStats are the summary statistics of a set of repositories.
+type repoStats struct {
+	Total      int           `json:"total"`
+	Languages  []statsBucket `json:"languages"`
+	Visibility []statsBucket `json:"visibility"`
+	Status     []statsBucket `json:"status"`
+	Size       []statsBucket `json:"size"`
+	Pushed     []statsBucket `json:"pushed"`
+}
+
+// sizeBuckets are the upper bounds in KB, as the API reports the size, of the size buckets.
+var sizeBuckets = []struct {
+	name string
+	max  int
+}{
+	{"empty", 0},
+	{"<1MB", 1 << 10},
+	{"1-10MB", 10 << 10},
+	{"10-100MB", 100 << 10},
+	{"100MB-1GB", 1 << 20},
+}
+
+// pushedBuckets are the upper bounds of the time since the last push of the push buckets.
+var pushedBuckets = []struct {
+	name string
+	max  time.Duration
+}{
+	{"<30d", 30 * 24 * time.Hour},
+	{"30-90d", 90 * 24 * time.Hour},
+	{"90-180d", 180 * 24 * time.Hour},
+	{"180d-1y", 365 * 24 * time.Hour},
+	{"1-2y", 2 * 365 * 24 * time.Hour},
+}
+
+func newStatsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "stats [OWNER...]",
+		Short: "Print statistics of the repositories passing the filter",
+		Long: `Prints the number of repositories passing the filter by language, visibility, archived and
+fork status, size and time since the last push, without cloning them e.g. for estate reports.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			logger := slog.New(newLogHandler(cmd.ErrOrStderr(), flags.logLevel))
+
+			owners, err := setupSources(ctx, args, logger)
+			if err != nil {
+				return err
+			}
+
+			stages, err := filterStages(logger)
+			if err != nil {
+				return err
+			}
+
+			_, selected, _, err := matchingRepositories(ctx, withRetries(exec.NewExecerWithLogger(".", logger), flags.apiRetries), owners, cmd.InOrStdin(), stages)
+			if err != nil {
+				return err
+			}
+
+			stats := computeStats(selected, time.Now())
+			if flags.statsJSON {
+				return stats.writeJSON(cmd.OutOrStdout())
+			}
+
+			return stats.writeTable(cmd.OutOrStdout())
+		},
+	}
+
+	cmd.Flags().BoolVar(&flags.statsJSON, "json", false, "Prints the statistics as JSON")
+
+	return cmd
+}
+
+// computeStats aggregates the repositories, the push recency being relative to now.
+func computeStats(repos []iterator.Repository, now time.Time) repoStats {
+	var (
+		languages  = map[string]int{}
+		visibility = map[string]int{}
+		status     = map[string]int{}
+		size       = map[string]int{}
+		pushed     = map[string]int{}
+	)
+
+	for _, r := range repos {
+		languages[cmp.Or(r.Language, "none")]++
+		visibility[cmp.Or(r.Visibility, "unknown")]++
+
+		switch {
+		case r.Archived && r.Fork:
+			status["archived fork"]++
+		case r.Archived:
+			status["archived"]++
+		case r.Fork:
+			status["fork"]++
+		default:
+			status["active"]++
+		}
+
+		size[sizeBucket(r.Size)]++
+		pushed[pushedBucket(r.PushedAt, now)]++
+	}
+
+	sizeOrder := make([]string, 0, len(sizeBuckets)+1)
+	for _, b := range sizeBuckets {
+		sizeOrder = append(sizeOrder, b.name)
+	}
+
+	pushedOrder := make([]string, 0, len(pushedBuckets)+2)
+	for _, b := range pushedBuckets {
+		pushedOrder = append(pushedOrder, b.name)
+	}
+
+	return repoStats{
+		Total:      len(repos),
+		Languages:  largestFirst(languages),
+		Visibility: largestFirst(visibility),
+		Status:     inOrder(status, []string{"active", "archived", "fork", "archived fork"}),
+		Size:       inOrder(size, append(sizeOrder, ">1GB")),
+		Pushed:     inOrder(pushed, append(pushedOrder, ">2y", "never")),
+	}
+}
+
+func sizeBucket(kb int) string {
+	for _, b := range sizeBuckets {
+		if kb <= b.max {
+			return b.name
+		}
+	}
+
+	return ">1GB"
+}
+
+func pushedBucket(pushedAt time.Time, now time.Time) string {
+	if pushedAt.IsZero() {
+		return "never"
+	}
+
+	for _, b := range pushedBuckets {
+		if now.Sub(pushedAt) < b.max {
+			return b.name
+		}
+	}
+
+	return ">2y"
+}
+
+// largestFirst returns the buckets of the counts, the largest first.
+func largestFirst(counts map[string]int) []statsBucket {
+	buckets := make([]statsBucket, 0, len(counts))
+	for name, count := range counts {
+		buckets = append(buckets, statsBucket{Name: name, Count: count})
+	}
+
+	slices.SortFunc(buckets, func(a, b statsBucket) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), strings.Compare(a.Name, b.Name))
+	})
+
+	return buckets
+}
+
+// inOrder returns the non empty buckets of the counts in the order.
+func inOrder(counts map[string]int, order []string) []statsBucket {
+	buckets := []statsBucket{}
+	for _, name := range order {
+		if counts[name] > 0 {
+			buckets = append(buckets, statsBucket{Name: name, Count: counts[name]})
+		}
+	}
+
+	return buckets
+}
+
+func (s repoStats) writeJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(s); err != nil {
+		return fmt.Errorf("writing stats: %w", err)
+	}
+
+	return nil
+}
+
+// writeTable writes a section per statistic with the count and the percentage of each bucket,
+// aligned within the section.
+func (s repoStats) writeTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Repositories: %d\n", s.Total)
+
+	for _, section := range []struct {
+		title   string
+		buckets []statsBucket
+	}{
+		{"Language", s.Languages},
+		{"Visibility", s.Visibility},
+		{"Status", s.Status},
+		{"Size", s.Size},
+		{"Last push", s.Pushed},
+	} {
+		fmt.Fprintf(tw, "\n%s\n", section.title)
+		for _, b := range section.buckets {
+			fmt.Fprintf(tw, "  %s\t%d\t%s%%\n", b.Name, b.Count, strconv.FormatFloat(100*float64(b.Count)/float64(max(s.Total, 1)), 'f', 1, 64))
+		}
+	}
+
+	if err := tw.Flush(); err != nil {
+		return fmt.Errorf("writing stats: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	iterator "github.com/jcchavezs/gh-iterator"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComputeStats(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	repos := []iterator.Repository{
+		{Name: "acme/a", Language: "Go", Visibility: "public", Size: 500, PushedAt: now.Add(-24 * time.Hour)},
+		{Name: "acme/b", Language: "Go", Visibility: "private", Size: 50 << 10, Archived: true, PushedAt: now.AddDate(-3, 0, 0)},
+		{Name: "acme/c", Language: "Java", Visibility: "private", Size: 2 << 20, Fork: true, PushedAt: now.AddDate(0, -2, 0)},
+		{Name: "acme/d", Visibility: "private"},
+	}
+
+	stats := computeStats(repos, now)
+	require.Equal(t, 4, stats.Total)
+	require.Equal(t, []statsBucket{{"Go", 2}, {"Java", 1}, {"none", 1}}, stats.Languages)
+	require.Equal(t, []statsBucket{{"private", 3}, {"public", 1}}, stats.Visibility)
+	require.Equal(t, []statsBucket{{"active", 2}, {"archived", 1}, {"fork", 1}}, stats.Status)
+	require.Equal(t, []statsBucket{{"empty", 1}, {"<1MB", 1}, {"10-100MB", 1}, {">1GB", 1}}, stats.Size)
+	require.Equal(t, []statsBucket{{"<30d", 1}, {"30-90d", 1}, {">2y", 1}, {"never", 1}}, stats.Pushed)
+
+	out := &bytes.Buffer{}
+	require.NoError(t, stats.writeTable(out))
+	require.Contains(t, out.String(), "Repositories: 4\n\nLanguage\n  Go    2  50.0%\n  Java  1  25.0%\n  none  1  25.0%\n")
+
+	out.Reset()
+	require.NoError(t, stats.writeJSON(out))
+	require.Contains(t, out.String(), `"total": 4`)
+}
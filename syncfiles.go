@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jcchavezs/gh-iterator/exec"
+	"github.com/spf13/afero"
+)
+
+// syncPolicy is how a template file is synchronized into the repositories.
+type syncPolicy string
+
+const (
+	// syncOverwrite replaces the file with the template one.
+	syncOverwrite syncPolicy = "overwrite"
+	// syncCreate only creates the file when it does not exist.
+	syncCreate syncPolicy = "create"
+	// syncMerge appends the lines of the template file missing in the file e.g. to
+	// CODEOWNERS or .gitignore.
+	syncMerge syncPolicy = "merge"
+)
+
+// templateFile is a file to synchronize into the repositories.
+type templateFile struct {
+	path    string
+	content []byte
+	mode    fs.FileMode
+	policy  syncPolicy
+}
+
+// syncRule applies the policy to the template files matching the glob.
+type syncRule struct {
+	glob   string
+	policy syncPolicy
+}
+
+// parseSyncPolicies parses the policies in the form 'glob=policy'.
+func parseSyncPolicies(specs []string) ([]syncRule, error) {
+	rules := make([]syncRule, 0, len(specs))
+	for _, spec := range specs {
+		glob, policy, ok := strings.Cut(spec, "=")
+		if !ok || glob == "" {
+			return nil, fmt.Errorf("invalid sync policy %q, expected 'glob=policy'", spec)
+		}
+
+		switch syncPolicy(policy) {
+		case syncOverwrite, syncCreate, syncMerge:
+		default:
+			return nil, fmt.Errorf("invalid sync policy %q, expected overwrite, create or merge", policy)
+		}
+
+		rules = append(rules, syncRule{glob: glob, policy: syncPolicy(policy)})
+	}
+
+	return rules, nil
+}
+
+// loadTemplateFiles reads the files in the template directory with the policy of the first
+// glob matching their path, overwrite by default.
+func loadTemplateFiles(dir string, rules []syncRule) ([]templateFile, error) {
+	var files []templateFile
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		f := templateFile{path: filepath.ToSlash(rel), content: content, mode: info.Mode().Perm(), policy: syncOverwrite}
+		for _, r := range rules {
+			if matchGlob(r.glob, f.path) {
+				f.policy = r.policy
+				break
+			}
+		}
+
+		files = append(files, f)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reading template files: %w", err)
+	}
+
+	if len(files) == 0 {
+		return nil, fmt.Errorf("reading template files: no files in %s", dir)
+	}
+
+	return files, nil
+}
+
+// syncFiles writes the template files into the filesystem according to their policy and
+// returns the paths of the files changed.
+func syncFiles(fsys afero.Fs, files []templateFile) ([]string, error) {
+	var changed []string
+	for _, f := range files {
+		current, err := afero.ReadFile(fsys, f.path)
+		exists := err == nil
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("reading %s: %w", f.path, err)
+		}
+
+		content := f.content
+		switch {
+		case !exists:
+		case f.policy == syncCreate:
+			continue
+		case f.policy == syncMerge:
+			content = mergeLines(current, f.content)
+		}
+
+		if exists && bytes.Equal(current, content) {
+			continue
+		}
+
+		if err := fsys.MkdirAll(filepath.Dir(f.path), 0755); err != nil {
+			return nil, fmt.Errorf("creating directory of %s: %w", f.path, err)
+		}
+
+		if err := afero.WriteFile(fsys, f.path, content, f.mode); err != nil {
+			return nil, fmt.Errorf("writing %s: %w", f.path, err)
+		}
+
+		changed = append(changed, f.path)
+	}
+
+	return changed, nil
+}
+
+// mergeLines appends the lines of the template missing in the current content.
+func mergeLines(current, template []byte) []byte {
+	lines := map[string]bool{}
+	for _, l := range strings.Split(string(current), "\n") {
+		lines[strings.TrimRight(l, "\r")] = true
+	}
+
+	merged := current
+	for _, l := range strings.Split(string(template), "\n") {
+		l = strings.TrimRight(l, "\r")
+		if l == "" || lines[l] {
+			continue
+		}
+
+		if len(merged) > 0 && merged[len(merged)-1] != '\n' {
+			merged = append(merged, '\n')
+		}
+		merged = append(merged, l+"\n"...)
+		lines[l] = true
+	}
+
+	return merged
+}
+
+// syncTemplateFiles synchronizes the template files into the repository and stages them so
+// they are committed with --commit-message or --create-pr.
+func (p repoProcessor) syncTemplateFiles(ctx context.Context, x exec.Execer, repository string) error {
+	changed, err := syncFiles(x.GenerateFS(), p.templateFiles)
+	if err != nil {
+		return fmt.Errorf("syncing files: %w", err)
+	}
+
+	if len(changed) == 0 {
+		return nil
+	}
+
+	if _, err := x.RunX(ctx, "git", append([]string{"add", "--"}, changed...)...); err != nil {
+		return fmt.Errorf("staging synced files: %w", err)
+	}
+
+	fmt.Fprintf(p.stdout, "%s: %d files synced\n", repository, len(changed))
+	p.results.update(repository, func(r *repoResult) {
+		for _, path := range changed {
+			r.Changes = append(r.Changes, "sync "+path)
+		}
+	})
+
+	return nil
+}
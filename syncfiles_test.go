@@ -0,0 +1,54 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSyncPolicies(t *testing.T) {
+	_, err := parseSyncPolicies([]string{"CODEOWNERS"})
+	require.Error(t, err)
+
+	_, err = parseSyncPolicies([]string{"CODEOWNERS=replace"})
+	require.Error(t, err)
+
+	rules, err := parseSyncPolicies([]string{"CODEOWNERS=merge"})
+	require.NoError(t, err)
+	require.Equal(t, []syncRule{{glob: "CODEOWNERS", policy: syncMerge}}, rules)
+}
+
+func TestSyncFiles(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, ".github", "workflows"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".github", "workflows", "ci.yml"), []byte("on: push\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "CODEOWNERS"), []byte("* @acme/core\n/docs @acme/docs\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".golangci.yml"), []byte("linters: {}\n"), 0644))
+
+	files, err := loadTemplateFiles(dir, []syncRule{{glob: "CODEOWNERS", policy: syncMerge}, {glob: ".golangci.yml", policy: syncCreate}})
+	require.NoError(t, err)
+	require.Len(t, files, 3)
+
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "CODEOWNERS", []byte("* @acme/core"), 0644))
+	require.NoError(t, afero.WriteFile(fs, ".golangci.yml", []byte("linters: {enable: [vet]}\n"), 0644))
+
+	changed, err := syncFiles(fs, files)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{".github/workflows/ci.yml", "CODEOWNERS"}, changed)
+
+	content, err := afero.ReadFile(fs, "CODEOWNERS")
+	require.NoError(t, err)
+	require.Equal(t, "* @acme/core\n/docs @acme/docs\n", string(content))
+
+	content, err = afero.ReadFile(fs, ".golangci.yml")
+	require.NoError(t, err)
+	require.Equal(t, "linters: {enable: [vet]}\n", string(content))
+
+	changed, err = syncFiles(fs, files)
+	require.NoError(t, err)
+	require.Empty(t, changed)
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	osexec "os/exec"
+	"path/filepath"
+	"strings"
+
+	iterator "github.com/jcchavezs/gh-iterator"
+)
+
+// downloadTarball downloads the archive of the ref passed by flag, by default the default
+// branch, and extracts it into dir.
+func downloadTarball(ctx context.Context, repo iterator.Repository, dir string, logger *slog.Logger) error {
+	if flags.ref != "" {
+		err := extractRemoteTarball(ctx, repo.Name, flags.ref, dir)
+		if !errors.Is(err, errRefNotFound) || flags.refFallback != RefFallbackDefaultBranch {
+			return err
+		}
+
+		logger.Warn("Ref not found, downloading the default branch", "ref", flags.ref)
+	}
+
+	return extractRemoteTarball(ctx, repo.Name, "", dir)
+}
+
+// extractRemoteTarball streams the archive of the ref from the API into extractTarball.
+func extractRemoteTarball(ctx context.Context, repository string, ref string, dir string) error {
+	target := "/repos/" + repository + "/tarball"
+	if ref != "" {
+		target += "/" + ref
+	}
+
+	c := osexec.CommandContext(ctx, "gh", "api",
+		"-H", "X-GitHub-Api-Version: "+iterator.GithubAPIVersion,
+		target,
+	)
+
+	var stderr bytes.Buffer
+	c.Stderr = &stderr
+	startInProcessGroup(c)
+
+	stdout, err := c.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("downloading tarball: %w", err)
+	}
+
+	if err := c.Start(); err != nil {
+		return fmt.Errorf("downloading tarball: %w", err)
+	}
+
+	xErr := extractTarball(stdout, dir)
+	// drains the output so gh does not block writing it if the extraction failed.
+	_, _ = io.Copy(io.Discard, stdout)
+
+	if err := c.Wait(); err != nil {
+		if ref != "" && strings.Contains(stderr.String(), "HTTP 404") {
+			return fmt.Errorf("%w: %s", errRefNotFound, ref)
+		}
+
+		return fmt.Errorf("downloading tarball: %s", strings.TrimSpace(stderr.String()))
+	}
+
+	return xErr
+}
+
+// extractTarball extracts a gzipped tarball into dir, stripping the top level directory the
+// GitHub archives wrap the files in.
+func extractTarball(r io.Reader, dir string) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("reading tarball: %w", err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		h, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("reading tarball: %w", err)
+		}
+
+		_, name, _ := strings.Cut(h.Name, "/")
+		if name == "" {
+			continue
+		}
+
+		if !filepath.IsLocal(name) {
+			return fmt.Errorf("invalid path in tarball: %s", h.Name)
+		}
+		path := filepath.Join(dir, filepath.FromSlash(name))
+
+		switch h.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(path, 0755); err != nil {
+				return fmt.Errorf("creating directory: %w", err)
+			}
+		case tar.TypeReg:
+			if err := writeTarballFile(tr, path, h.FileInfo().Mode().Perm()); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			if err := os.Symlink(h.Linkname, path); err != nil {
+				return fmt.Errorf("creating symlink: %w", err)
+			}
+		}
+	}
+}
+
+func writeTarballFile(r io.Reader, path string, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("creating directory: %w", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return fmt.Errorf("creating file: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(f, r); err != nil {
+		return fmt.Errorf("writing file: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func makeTarball(t *testing.T, files map[string]string) *bytes.Buffer {
+	t.Helper()
+
+	buf := &bytes.Buffer{}
+	gz := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gz)
+
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "acme-a-abc123/", Typeflag: tar.TypeDir, Mode: 0755}))
+	for name, content := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(content))}))
+		_, err := tw.Write([]byte(content))
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	return buf
+}
+
+func TestExtractTarball(t *testing.T) {
+	dir := t.TempDir()
+	err := extractTarball(makeTarball(t, map[string]string{
+		"acme-a-abc123/README.md":   "hello",
+		"acme-a-abc123/cmd/main.go": "package main",
+	}), dir)
+	require.NoError(t, err)
+
+	content, err := os.ReadFile(filepath.Join(dir, "cmd", "main.go"))
+	require.NoError(t, err)
+	require.Equal(t, "package main", string(content))
+	require.FileExists(t, filepath.Join(dir, "README.md"))
+}
+
+func TestExtractTarball_RejectsPathTraversal(t *testing.T) {
+	err := extractTarball(makeTarball(t, map[string]string{
+		"acme-a-abc123/../../evil": "x",
+	}), t.TempDir())
+	require.Error(t, err)
+}
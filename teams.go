@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	iterator "github.com/jcchavezs/gh-iterator"
+	"github.com/jcchavezs/gh-iterator/exec"
+	"github.com/jcchavezs/gh-iterator/github"
+)
+
+// teamPermissions are the permissions of a team on a repository, the lowest first.
+var teamPermissions = []string{"pull", "triage", "push", "maintain", "admin"}
+
+// teamAccess is the permission of a team passed as ORG/TEAM:PERMISSION.
+type teamAccess struct {
+	Org        string
+	Team       string
+	Permission string
+}
+
+func (a teamAccess) String() string {
+	return a.Org + "/" + a.Team
+}
+
+// parseTeamAccess parses ORG/TEAM:PERMISSION, or ORG/TEAM when not withPermission.
+func parseTeamAccess(s string, withPermission bool) (teamAccess, error) {
+	team, permission, hasPermission := strings.Cut(s, ":")
+	org, slug, ok := strings.Cut(team, "/")
+	if !ok || org == "" || slug == "" || hasPermission != withPermission {
+		if withPermission {
+			return teamAccess{}, fmt.Errorf("invalid team %q, expected ORG/TEAM:PERMISSION e.g. acme/payments:push", s)
+		}
+		return teamAccess{}, fmt.Errorf("invalid team %q, expected ORG/TEAM e.g. acme/payments", s)
+	}
+
+	if withPermission && !slices.Contains(teamPermissions, permission) {
+		return teamAccess{}, fmt.Errorf("invalid permission %q of team %q, expected one of %s", permission, team, strings.Join(teamPermissions, ", "))
+	}
+
+	return teamAccess{Org: org, Team: slug, Permission: permission}, nil
+}
+
+// teamPermission returns the permission of the team on the repository, empty if it has none.
+func teamPermission(ctx context.Context, x exec.Execer, team teamAccess, repository string) (string, error) {
+	res, err := x.RunX(ctx, "gh", "api",
+		"-H", "Accept: application/vnd.github.v3.repository+json",
+		"-H", "X-GitHub-Api-Version: "+iterator.GithubAPIVersion,
+		"--jq", ".permissions",
+		fmt.Sprintf("/orgs/%s/teams/%s/repos/%s", team.Org, team.Team, repository),
+	)
+	if err != nil {
+		if err = github.ErrOrGHAPIErr(res, err); isNotFound(err) {
+			return "", nil
+		}
+		return "", fmt.Errorf("fetching permission of team %s: %w", team, err)
+	}
+
+	var permissions map[string]bool
+	if err := json.Unmarshal([]byte(res), &permissions); err != nil {
+		return "", fmt.Errorf("unmarshaling permission of team %s: %w", team, err)
+	}
+
+	for i := len(teamPermissions) - 1; i >= 0; i-- {
+		if permissions[teamPermissions[i]] {
+			return teamPermissions[i], nil
+		}
+	}
+
+	return "", nil
+}
+
+// teamsAction grants and revokes the access of the teams to the repositories.
+func teamsAction(grant, revoke []teamAccess) repoAction {
+	return func(ctx context.Context, x exec.Execer, repo iterator.Repository, dryRun bool) ([]string, error) {
+		var changes []string
+
+		for _, team := range grant {
+			current, err := teamPermission(ctx, x, team, repo.Name)
+			if err != nil {
+				return nil, err
+			}
+
+			if current == team.Permission {
+				continue
+			}
+
+			if !dryRun {
+				path := fmt.Sprintf("/orgs/%s/teams/%s/repos/%s", team.Org, team.Team, repo.Name)
+				if _, err := apiRequest(ctx, x, "PUT", path, map[string]string{"permission": team.Permission}); err != nil {
+					return nil, fmt.Errorf("granting team %s: %w", team, err)
+				}
+			}
+
+			change := fmt.Sprintf("grant %s to team %s", team.Permission, team)
+			if current != "" {
+				change += " (was " + current + ")"
+			}
+			changes = append(changes, change)
+		}
+
+		for _, team := range revoke {
+			current, err := teamPermission(ctx, x, team, repo.Name)
+			if err != nil {
+				return nil, err
+			}
+
+			if current == "" {
+				continue
+			}
+
+			if !dryRun {
+				path := fmt.Sprintf("/orgs/%s/teams/%s/repos/%s", team.Org, team.Team, repo.Name)
+				if _, err := apiRequest(ctx, x, "DELETE", path, nil); err != nil {
+					return nil, fmt.Errorf("revoking team %s: %w", team, err)
+				}
+			}
+
+			changes = append(changes, fmt.Sprintf("revoke access of team %s (was %s)", team, current))
+		}
+
+		return changes, nil
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"testing"
+
+	iterator "github.com/jcchavezs/gh-iterator"
+	"github.com/jcchavezs/gh-iterator/exec"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTeamAccess(t *testing.T) {
+	team, err := parseTeamAccess("acme/payments:push", true)
+	require.NoError(t, err)
+	require.Equal(t, teamAccess{Org: "acme", Team: "payments", Permission: "push"}, team)
+
+	team, err = parseTeamAccess("acme/legacy", false)
+	require.NoError(t, err)
+	require.Equal(t, teamAccess{Org: "acme", Team: "legacy"}, team)
+
+	for _, s := range []string{"acme/payments", "acme/payments:write", "payments:push", "acme/:push"} {
+		_, err := parseTeamAccess(s, true)
+		require.Error(t, err, s)
+	}
+
+	_, err = parseTeamAccess("acme/legacy:push", false)
+	require.Error(t, err)
+}
+
+func TestTeamsAction(t *testing.T) {
+	calls := scriptedGH(t, routedGH+`"GET /orgs/acme/teams/payments/repos/acme/a") echo '{"pull":true,"triage":true,"push":false}';;
+"GET /orgs/acme/teams/legacy/repos/acme/a") echo '{"pull":true}';;
+"GET /orgs/acme/teams/gone/repos/acme/a") echo '{"message":"Not Found","status":"404"}'; exit 1;;
+esac
+`)
+
+	action := teamsAction(
+		[]teamAccess{{Org: "acme", Team: "payments", Permission: "push"}},
+		[]teamAccess{{Org: "acme", Team: "legacy"}, {Org: "acme", Team: "gone"}},
+	)
+	x := exec.NewExecerWithLogger(t.TempDir(), slog.New(slog.DiscardHandler))
+
+	changes, err := action(context.Background(), x, iterator.Repository{Name: "acme/a"}, false)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"grant push to team acme/payments (was triage)",
+		"revoke access of team acme/legacy (was pull)",
+	}, changes)
+
+	content, err := os.ReadFile(calls)
+	require.NoError(t, err)
+	require.Contains(t, string(content), "-X PUT --input - /orgs/acme/teams/payments/repos/acme/a\n")
+	require.Contains(t, string(content), "-X DELETE /orgs/acme/teams/legacy/repos/acme/a\n")
+	require.NotContains(t, string(content), "-X DELETE /orgs/acme/teams/gone")
+}
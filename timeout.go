@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/jcchavezs/gh-iterator/exec"
+)
+
+// errExecTimeout is returned when a git or gh invocation exceeds the timeout.
+var errExecTimeout = errors.New("timed out")
+
+// timeoutDecorator bounds the git and gh invocations with a timeout, so a hung clone or API call
+// fails the repository instead of blocking a worker until the run is cancelled.
+type timeoutDecorator struct {
+	timeout time.Duration
+}
+
+// withTimeout wraps the execer so the git and gh invocations are killed after timeout, a zero
+// timeout disables it.
+func withTimeout(x exec.Execer, timeout time.Duration) exec.Execer {
+	if timeout <= 0 {
+		return x
+	}
+
+	return decorate(x, timeoutDecorator{timeout: timeout})
+}
+
+func (d timeoutDecorator) runWithStdin(ctx context.Context, next exec.Execer, stdin io.Reader, command string, args ...string) (exec.Result, error) {
+	if command != "git" && command != "gh" {
+		return next.RunWithStdin(ctx, stdin, command, args...)
+	}
+
+	tctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+
+	res, err := next.RunWithStdin(tctx, stdin, command, args...)
+	if err != nil && ctx.Err() == nil && errors.Is(tctx.Err(), context.DeadlineExceeded) {
+		return res, fmt.Errorf("%s: %w after %s", strings.Join(append([]string{command}, args...), " "), errExecTimeout, d.timeout)
+	}
+
+	return res, err
+}
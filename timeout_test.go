@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/jcchavezs/gh-iterator/exec"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimeoutExecer(t *testing.T) {
+	installGH(t, "sleep \"$1\"\n")
+
+	x := withTimeout(exec.NewExecerWithLogger(t.TempDir(), slog.New(slog.DiscardHandler)), 200*time.Millisecond)
+
+	_, err := x.RunX(context.Background(), "gh", "0")
+	require.NoError(t, err)
+
+	_, err = x.RunX(context.Background(), "gh", "5")
+	require.ErrorIs(t, err, errExecTimeout)
+
+	// other commands are not bounded.
+	_, err = x.RunX(context.Background(), "sleep", "0.5")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = x.RunX(ctx, "gh", "5")
+	require.Error(t, err)
+	require.NotErrorIs(t, err, errExecTimeout)
+}
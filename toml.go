@@ -0,0 +1,483 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// unmarshalTOML parses the subset of TOML the manifests use e.g. Cargo.toml or pyproject.toml into
+// v, which must be a *any: tables, arrays of tables, dotted keys, strings, integers, floats,
+// booleans, arrays and inline tables. Dates are kept as strings.
+func unmarshalTOML(content []byte, v any) error {
+	out, ok := v.(*any)
+	if !ok {
+		return errors.New("toml: unmarshaling into a non *any value")
+	}
+
+	p := &tomlParser{s: string(content), line: 1}
+	root, err := p.parse()
+	if err != nil {
+		return fmt.Errorf("toml: line %d: %w", p.line, err)
+	}
+
+	*out = root
+	return nil
+}
+
+type tomlParser struct {
+	s    string
+	i    int
+	line int
+}
+
+func (p *tomlParser) parse() (map[string]any, error) {
+	root := map[string]any{}
+	current := root
+
+	for {
+		p.skipSpaceAndComments(true)
+		if p.eof() {
+			return root, nil
+		}
+
+		switch {
+		case strings.HasPrefix(p.s[p.i:], "[["):
+			p.i += 2
+			path, err := p.parseKeyPath("]]")
+			if err != nil {
+				return nil, err
+			}
+
+			parent, err := tomlTable(root, path[:len(path)-1])
+			if err != nil {
+				return nil, err
+			}
+
+			last := path[len(path)-1]
+			tables, _ := parent[last].([]any)
+			if _, exists := parent[last]; exists && tables == nil {
+				return nil, fmt.Errorf("key %q is not an array of tables", last)
+			}
+
+			current = map[string]any{}
+			parent[last] = append(tables, current)
+		case p.s[p.i] == '[':
+			p.i++
+			path, err := p.parseKeyPath("]")
+			if err != nil {
+				return nil, err
+			}
+
+			if current, err = tomlTable(root, path); err != nil {
+				return nil, err
+			}
+		default:
+			path, err := p.parseKeyPath("=")
+			if err != nil {
+				return nil, err
+			}
+
+			p.skipSpace()
+			value, err := p.parseValue()
+			if err != nil {
+				return nil, err
+			}
+
+			if err := tomlSet(current, path, value); err != nil {
+				return nil, err
+			}
+		}
+
+		p.skipSpaceAndComments(false)
+		if !p.eof() && p.s[p.i] != '\n' && p.s[p.i] != '\r' {
+			return nil, fmt.Errorf("unexpected %q after value", p.s[p.i])
+		}
+	}
+}
+
+// tomlTable returns the table at the path, creating the missing ones. Arrays of tables resolve
+// to their last table.
+func tomlTable(m map[string]any, path []string) (map[string]any, error) {
+	for _, k := range path {
+		switch t := m[k].(type) {
+		case nil:
+			next := map[string]any{}
+			m[k] = next
+			m = next
+		case map[string]any:
+			m = t
+		case []any:
+			last, ok := t[len(t)-1].(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("key %q is not a table", k)
+			}
+			m = last
+		default:
+			return nil, fmt.Errorf("key %q is not a table", k)
+		}
+	}
+
+	return m, nil
+}
+
+func tomlSet(m map[string]any, path []string, value any) error {
+	parent, err := tomlTable(m, path[:len(path)-1])
+	if err != nil {
+		return err
+	}
+
+	last := path[len(path)-1]
+	if _, exists := parent[last]; exists {
+		return fmt.Errorf("duplicated key %q", last)
+	}
+
+	parent[last] = value
+	return nil
+}
+
+func (p *tomlParser) eof() bool {
+	return p.i >= len(p.s)
+}
+
+func (p *tomlParser) skipSpace() {
+	for !p.eof() && (p.s[p.i] == ' ' || p.s[p.i] == '\t') {
+		p.i++
+	}
+}
+
+// skipSpaceAndComments skips the spaces and the comment until the end of the line, and the new
+// lines too if newLines is set.
+func (p *tomlParser) skipSpaceAndComments(newLines bool) {
+	for !p.eof() {
+		switch c := p.s[p.i]; {
+		case c == ' ' || c == '\t':
+			p.i++
+		case c == '#':
+			for !p.eof() && p.s[p.i] != '\n' {
+				p.i++
+			}
+		case newLines && (c == '\n' || c == '\r'):
+			if c == '\n' {
+				p.line++
+			}
+			p.i++
+		default:
+			return
+		}
+	}
+}
+
+// parseKeyPath parses a dotted key up to the terminator, which is consumed.
+func (p *tomlParser) parseKeyPath(terminator string) ([]string, error) {
+	var path []string
+	for {
+		p.skipSpace()
+		if p.eof() {
+			return nil, errors.New("unexpected end of key")
+		}
+
+		var (
+			key string
+			err error
+		)
+		switch p.s[p.i] {
+		case '"':
+			key, err = p.parseBasicString()
+		case '\'':
+			key, err = p.parseLiteralString()
+		default:
+			start := p.i
+			for !p.eof() && isBareKeyChar(p.s[p.i]) {
+				p.i++
+			}
+			key = p.s[start:p.i]
+			if key == "" {
+				err = fmt.Errorf("invalid key character %q", p.s[p.i])
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		path = append(path, key)
+
+		p.skipSpace()
+		if strings.HasPrefix(p.s[p.i:], terminator) {
+			p.i += len(terminator)
+			return path, nil
+		}
+
+		if p.eof() || p.s[p.i] != '.' {
+			return nil, fmt.Errorf("expected %q after key %q", terminator, key)
+		}
+		p.i++
+	}
+}
+
+func isBareKeyChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
+
+func (p *tomlParser) parseValue() (any, error) {
+	if p.eof() {
+		return nil, errors.New("missing value")
+	}
+
+	switch p.s[p.i] {
+	case '"':
+		return p.parseBasicString()
+	case '\'':
+		return p.parseLiteralString()
+	case '[':
+		return p.parseArray()
+	case '{':
+		return p.parseInlineTable()
+	}
+
+	start := p.i
+	for !p.eof() && !strings.ContainsRune(" \t\r\n,]}#", rune(p.s[p.i])) {
+		p.i++
+	}
+	token := p.s[start:p.i]
+
+	switch token {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "inf", "+inf":
+		return math.Inf(1), nil
+	case "-inf":
+		return math.Inf(-1), nil
+	case "nan", "+nan", "-nan":
+		return math.NaN(), nil
+	}
+
+	number := strings.ReplaceAll(token, "_", "")
+	if n, err := strconv.ParseInt(number, 0, 64); err == nil {
+		return n, nil
+	}
+
+	if f, err := strconv.ParseFloat(number, 64); err == nil {
+		return f, nil
+	}
+
+	// dates and times e.g. 1979-05-27T07:32:00Z.
+	if token != "" && token[0] >= '0' && token[0] <= '9' && strings.ContainsAny(token, "-:") {
+		if !p.eof() && p.s[p.i] == ' ' && p.i+1 < len(p.s) && p.s[p.i+1] >= '0' && p.s[p.i+1] <= '9' {
+			// the date and the time can be separated by a space.
+			end := p.i + 1
+			for end < len(p.s) && !strings.ContainsRune(" \t\r\n,]}#", rune(p.s[end])) {
+				end++
+			}
+			token, p.i = p.s[start:end], end
+		}
+		return token, nil
+	}
+
+	return nil, fmt.Errorf("invalid value %q", token)
+}
+
+func (p *tomlParser) parseArray() ([]any, error) {
+	p.i++ // [
+
+	values := []any{}
+	for {
+		p.skipSpaceAndComments(true)
+		if p.eof() {
+			return nil, errors.New("unterminated array")
+		}
+
+		if p.s[p.i] == ']' {
+			p.i++
+			return values, nil
+		}
+
+		v, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+
+		p.skipSpaceAndComments(true)
+		if !p.eof() && p.s[p.i] == ',' {
+			p.i++
+		} else if p.eof() || p.s[p.i] != ']' {
+			return nil, errors.New("expected ',' or ']' in array")
+		}
+	}
+}
+
+func (p *tomlParser) parseInlineTable() (map[string]any, error) {
+	p.i++ // {
+
+	table := map[string]any{}
+	p.skipSpace()
+	if !p.eof() && p.s[p.i] == '}' {
+		p.i++
+		return table, nil
+	}
+
+	for {
+		path, err := p.parseKeyPath("=")
+		if err != nil {
+			return nil, err
+		}
+
+		p.skipSpace()
+		v, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+
+		if err := tomlSet(table, path, v); err != nil {
+			return nil, err
+		}
+
+		p.skipSpace()
+		if p.eof() {
+			return nil, errors.New("unterminated inline table")
+		}
+
+		switch p.s[p.i] {
+		case ',':
+			p.i++
+		case '}':
+			p.i++
+			return table, nil
+		default:
+			return nil, errors.New("expected ',' or '}' in inline table")
+		}
+	}
+}
+
+func (p *tomlParser) parseLiteralString() (string, error) {
+	if strings.HasPrefix(p.s[p.i:], "'''") {
+		p.i += 3
+		end := strings.Index(p.s[p.i:], "'''")
+		if end < 0 {
+			return "", errors.New("unterminated multi-line literal string")
+		}
+
+		s := strings.TrimPrefix(strings.TrimPrefix(p.s[p.i:p.i+end], "\r"), "\n")
+		p.line += strings.Count(p.s[p.i:p.i+end], "\n")
+		p.i += end + 3
+		return s, nil
+	}
+
+	p.i++
+	end := strings.IndexAny(p.s[p.i:], "'\n")
+	if end < 0 || p.s[p.i+end] != '\'' {
+		return "", errors.New("unterminated literal string")
+	}
+
+	s := p.s[p.i : p.i+end]
+	p.i += end + 1
+	return s, nil
+}
+
+func (p *tomlParser) parseBasicString() (string, error) {
+	multiline := strings.HasPrefix(p.s[p.i:], `"""`)
+	if multiline {
+		p.i += 3
+		if strings.HasPrefix(p.s[p.i:], "\r\n") {
+			p.i += 2
+			p.line++
+		} else if strings.HasPrefix(p.s[p.i:], "\n") {
+			p.i++
+			p.line++
+		}
+	} else {
+		p.i++
+	}
+
+	var b strings.Builder
+	for {
+		if p.eof() {
+			return "", errors.New("unterminated string")
+		}
+
+		c := p.s[p.i]
+		switch {
+		case multiline && strings.HasPrefix(p.s[p.i:], `"""`):
+			p.i += 3
+			return b.String(), nil
+		case !multiline && c == '"':
+			p.i++
+			return b.String(), nil
+		case !multiline && c == '\n':
+			return "", errors.New("unterminated string")
+		case c == '\\':
+			if err := p.parseEscape(&b, multiline); err != nil {
+				return "", err
+			}
+		default:
+			if c == '\n' {
+				p.line++
+			}
+			b.WriteByte(c)
+			p.i++
+		}
+	}
+}
+
+func (p *tomlParser) parseEscape(b *strings.Builder, multiline bool) error {
+	p.i++ // \
+	if p.eof() {
+		return errors.New("unterminated escape sequence")
+	}
+
+	c := p.s[p.i]
+	p.i++
+
+	switch c {
+	case 'b':
+		b.WriteByte('\b')
+	case 't':
+		b.WriteByte('\t')
+	case 'n':
+		b.WriteByte('\n')
+	case 'f':
+		b.WriteByte('\f')
+	case 'r':
+		b.WriteByte('\r')
+	case '"', '\\':
+		b.WriteByte(c)
+	case 'u', 'U':
+		size := 4
+		if c == 'U' {
+			size = 8
+		}
+
+		if p.i+size > len(p.s) {
+			return errors.New("invalid unicode escape")
+		}
+
+		r, err := strconv.ParseUint(p.s[p.i:p.i+size], 16, 32)
+		if err != nil || !utf8.ValidRune(rune(r)) {
+			return errors.New("invalid unicode escape")
+		}
+		b.WriteRune(rune(r))
+		p.i += size
+	case ' ', '\t', '\r', '\n':
+		if !multiline {
+			return fmt.Errorf("invalid escape sequence \\%c", c)
+		}
+
+		// a line ending backslash trims the whitespace up to the next non whitespace character.
+		p.i--
+		for !p.eof() && strings.ContainsRune(" \t\r\n", rune(p.s[p.i])) {
+			if p.s[p.i] == '\n' {
+				p.line++
+			}
+			p.i++
+		}
+	default:
+		return fmt.Errorf("invalid escape sequence \\%c", c)
+	}
+
+	return nil
+}
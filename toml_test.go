@@ -0,0 +1,80 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnmarshalTOML(t *testing.T) {
+	content := `# Cargo manifest
+[package]
+name = "api"   # the crate
+version = "0.1.0"
+edition = 2021
+authors = [
+  "Jane <jane@example.com>", # maintainer
+  'John',
+]
+
+[dependencies]
+serde = { version = "1.0", features = ["derive"] }
+tokio.version = "1"
+"quoted-key" = true
+ratio = 1_000.5
+released = 1979-05-27T07:32:00Z
+
+[[bin]]
+name = "server"
+
+[[bin]]
+name = "cli"
+
+[tool.poetry.scripts]
+run = """
+api.main:run"""
+`
+
+	var v any
+	require.NoError(t, unmarshalTOML([]byte(content), &v))
+	require.Equal(t, map[string]any{
+		"package": map[string]any{
+			"name":    "api",
+			"version": "0.1.0",
+			"edition": int64(2021),
+			"authors": []any{"Jane <jane@example.com>", "John"},
+		},
+		"dependencies": map[string]any{
+			"serde":      map[string]any{"version": "1.0", "features": []any{"derive"}},
+			"tokio":      map[string]any{"version": "1"},
+			"quoted-key": true,
+			"ratio":      1000.5,
+			"released":   "1979-05-27T07:32:00Z",
+		},
+		"bin": []any{
+			map[string]any{"name": "server"},
+			map[string]any{"name": "cli"},
+		},
+		"tool": map[string]any{
+			"poetry": map[string]any{"scripts": map[string]any{"run": "api.main:run"}},
+		},
+	}, v)
+}
+
+func TestUnmarshalTOML_Errors(t *testing.T) {
+	for name, content := range map[string]string{
+		"duplicated key":        "a = 1\na = 2\n",
+		"unterminated string":   "a = \"b\n",
+		"missing value":         "a =\n",
+		"garbage after value":   "a = 1 b\n",
+		"unterminated array":    "a = [1, 2\n",
+		"table over value":      "a = 1\n[a]\n",
+		"invalid escape":        `a = "\x"`,
+		"invalid key character": "a! = 1\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			var v any
+			require.Error(t, unmarshalTOML([]byte(content), &v))
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	iterator "github.com/jcchavezs/gh-iterator"
+	"github.com/jcchavezs/gh-iterator/exec"
+	"github.com/jcchavezs/gh-iterator/github"
+)
+
+// repositoryTopics returns the topics of the repository.
+func repositoryTopics(ctx context.Context, x exec.Execer, repository string) ([]string, error) {
+	res, err := x.RunX(ctx, "gh", "api",
+		"-H", "Accept: application/vnd.github+json",
+		"-H", "X-GitHub-Api-Version: "+iterator.GithubAPIVersion,
+		"--jq", ".names",
+		"/repos/"+repository+"/topics",
+	)
+	if err != nil {
+		return nil, fmt.Errorf("fetching topics: %w", github.ErrOrGHAPIErr(res, err))
+	}
+
+	var topics []string
+	if err := json.Unmarshal([]byte(res), &topics); err != nil {
+		return nil, fmt.Errorf("unmarshaling topics: %w", err)
+	}
+
+	return topics, nil
+}
+
+// topicChanges returns the topics to add and to remove out of the current ones.
+func topicChanges(current, add, remove []string) ([]string, []string) {
+	var toAdd, toRemove []string
+	for _, t := range add {
+		if !slices.Contains(current, t) && !slices.Contains(toAdd, t) {
+			toAdd = append(toAdd, t)
+		}
+	}
+
+	for _, t := range remove {
+		if slices.Contains(current, t) && !slices.Contains(toRemove, t) {
+			toRemove = append(toRemove, t)
+		}
+	}
+
+	return toAdd, toRemove
+}
+
+// topicsAction adds and removes the topics of the repositories, only the missing ones are added
+// and the existing ones removed.
+func topicsAction(add, remove []string) repoAction {
+	return func(ctx context.Context, x exec.Execer, repo iterator.Repository, dryRun bool) ([]string, error) {
+		current, err := repositoryTopics(ctx, x, repo.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		toAdd, toRemove := topicChanges(current, add, remove)
+		if len(toAdd) == 0 && len(toRemove) == 0 {
+			return nil, nil
+		}
+
+		var changes []string
+		args := []string{"repo", "edit", repo.Name}
+		if len(toAdd) > 0 {
+			args = append(args, "--add-topic", strings.Join(toAdd, ","))
+			changes = append(changes, "add topics "+strings.Join(toAdd, ", "))
+		}
+		if len(toRemove) > 0 {
+			args = append(args, "--remove-topic", strings.Join(toRemove, ","))
+			changes = append(changes, "remove topics "+strings.Join(toRemove, ", "))
+		}
+
+		if dryRun {
+			return changes, nil
+		}
+
+		if res, err := x.RunX(ctx, "gh", args...); err != nil {
+			return nil, fmt.Errorf("editing topics: %w", github.ErrOrGHAPIErr(res, err))
+		}
+
+		return changes, nil
+	}
+}
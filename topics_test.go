@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"os"
+	"testing"
+
+	iterator "github.com/jcchavezs/gh-iterator"
+	"github.com/jcchavezs/gh-iterator/exec"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTopicChanges(t *testing.T) {
+	toAdd, toRemove := topicChanges([]string{"go", "legacy"}, []string{"go", "payments", "payments"}, []string{"legacy", "java"})
+	require.Equal(t, []string{"payments"}, toAdd)
+	require.Equal(t, []string{"legacy"}, toRemove)
+}
+
+func TestTopicsAction(t *testing.T) {
+	t.Cleanup(func() { flags.dryRun = false })
+
+	calls := scriptedGH(t, `if [ "$1" = api ]; then echo '["go","legacy"]'; fi
+`)
+
+	out := &bytes.Buffer{}
+	p := repoProcessor{stdout: out, results: newRunResults(), actions: []repoAction{topicsAction([]string{"payments"}, []string{"legacy"})}}
+	x := exec.NewExecerWithLogger(t.TempDir(), slog.New(slog.DiscardHandler))
+	ctx := withRepository(context.Background(), iterator.Repository{Name: "acme/a"})
+
+	flags.dryRun = true
+	require.NoError(t, p.runActions(ctx, x, "acme/a"))
+	require.Equal(t, "acme/a: would add topics payments\nacme/a: would remove topics legacy\n", out.String())
+
+	content, err := os.ReadFile(calls)
+	require.NoError(t, err)
+	require.NotContains(t, string(content), "repo edit")
+
+	flags.dryRun = false
+	out.Reset()
+	require.NoError(t, p.runActions(ctx, x, "acme/a"))
+	require.Equal(t, "acme/a: add topics payments\nacme/a: remove topics legacy\n", out.String())
+
+	content, err = os.ReadFile(calls)
+	require.NoError(t, err)
+	require.Contains(t, string(content), "repo edit acme/a --add-topic payments --remove-topic legacy\n")
+
+	res, _ := p.results.get("acme/a")
+	require.Len(t, res.Changes, 4)
+}
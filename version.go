@@ -0,0 +1,75 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"runtime/debug"
+
+	"github.com/spf13/cobra"
+)
+
+// the build metadata can be injected with ldflags e.g.
+// -ldflags "-X main.version=v1.2.3 -X main.commit=abc123 -X main.date=2024-01-02T03:04:05Z",
+// otherwise it is read from the build info.
+var (
+	version string
+	commit  string
+	date    string
+)
+
+const iteratorModule = "github.com/jcchavezs/gh-iterator"
+
+type versionInfo struct {
+	Version         string
+	Commit          string
+	Date            string
+	IteratorVersion string
+}
+
+// buildVersion returns the build metadata, preferring the values injected with ldflags over the
+// ones in the build info.
+func buildVersion(info *debug.BuildInfo) versionInfo {
+	v := versionInfo{Version: version, Commit: commit, Date: date}
+	if info == nil {
+		return v
+	}
+
+	v.Version = cmp.Or(v.Version, info.Main.Version)
+
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			v.Commit = cmp.Or(v.Commit, s.Value)
+		case "vcs.time":
+			v.Date = cmp.Or(v.Date, s.Value)
+		}
+	}
+
+	for _, dep := range info.Deps {
+		if dep.Path == iteratorModule {
+			v.IteratorVersion = dep.Version
+			if dep.Replace != nil {
+				v.IteratorVersion = dep.Replace.Version
+			}
+		}
+	}
+
+	return v
+}
+
+func newVersionCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "version",
+		Short: "Print the version and the build metadata",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, _ []string) {
+			info, _ := debug.ReadBuildInfo()
+			v := buildVersion(info)
+
+			fmt.Fprintf(cmd.OutOrStdout(), "version: %s\n", cmp.Or(v.Version, "unknown"))
+			fmt.Fprintf(cmd.OutOrStdout(), "commit: %s\n", cmp.Or(v.Commit, "unknown"))
+			fmt.Fprintf(cmd.OutOrStdout(), "built: %s\n", cmp.Or(v.Date, "unknown"))
+			fmt.Fprintf(cmd.OutOrStdout(), "gh-iterator: %s\n", cmp.Or(v.IteratorVersion, "unknown"))
+		},
+	}
+}
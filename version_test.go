@@ -0,0 +1,30 @@
+package main
+
+import (
+	"runtime/debug"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildVersion(t *testing.T) {
+	info := &debug.BuildInfo{
+		Main: debug.Module{Version: "v1.2.3"},
+		Deps: []*debug.Module{{Path: iteratorModule, Version: "v0.4.1"}},
+		Settings: []debug.BuildSetting{
+			{Key: "vcs.revision", Value: "abc123"},
+			{Key: "vcs.time", Value: "2024-01-02T03:04:05Z"},
+		},
+	}
+
+	require.Equal(t, versionInfo{
+		Version:         "v1.2.3",
+		Commit:          "abc123",
+		Date:            "2024-01-02T03:04:05Z",
+		IteratorVersion: "v0.4.1",
+	}, buildVersion(info))
+
+	commit = "def456"
+	t.Cleanup(func() { commit = "" })
+	require.Equal(t, "def456", buildVersion(info).Commit)
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	// workDirLockSuffix is the suffix of the file next to each working directory holding the PID
+	// of the run using it.
+	workDirLockSuffix = ".lock"
+	// workDirKeepSuffix is the suffix of the file marking the working directories kept with
+	// --keep-clones.
+	workDirKeepSuffix = ".keep"
+	// staleWorkDirAge is the age of the working directories of dead runs removed on startup.
+	staleWorkDirAge = 24 * time.Hour
+)
+
+// workBaseDir is the directory the working directories are created in, passed in --workdir and
+// by default <tmp>/gh-iterator-run.
+func workBaseDir() string {
+	if flags.workDir != "" {
+		return flags.workDir
+	}
+
+	return filepath.Join(os.TempDir(), "gh-iterator-run")
+}
+
+// lockWorkDir records the run using the working directory.
+func lockWorkDir(dir string) error {
+	if err := os.WriteFile(dir+workDirLockSuffix, []byte(strconv.Itoa(os.Getpid())), 0644); err != nil {
+		return fmt.Errorf("locking working directory: %w", err)
+	}
+
+	return nil
+}
+
+// unlockWorkDir removes the lock of the working directory, marking it as kept if so.
+func unlockWorkDir(dir string, keep bool) {
+	os.Remove(dir + workDirLockSuffix) //nolint:errcheck
+
+	if keep {
+		os.WriteFile(dir+workDirKeepSuffix, nil, 0644) //nolint:errcheck
+	}
+}
+
+// staleWorkDirs returns the working directories in base older than olderThan not in use by a
+// running process, the ones kept with --keep-clones only if includeKept. Only the directories
+// with a lock or keep file are working directories, the rest in base is left alone.
+func staleWorkDirs(base string, olderThan time.Duration, includeKept bool, now time.Time) ([]string, error) {
+	entries, err := os.ReadDir(base)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("reading working directories: %w", err)
+	}
+
+	var stale []string
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+
+		dir := filepath.Join(base, e.Name())
+		_, lockErr := os.Stat(dir + workDirLockSuffix)
+		_, keepErr := os.Stat(dir + workDirKeepSuffix)
+		if lockErr != nil && keepErr != nil {
+			// not created by a run.
+			continue
+		}
+
+		if workDirInUse(dir) {
+			continue
+		}
+
+		if keepErr == nil && !includeKept {
+			continue
+		}
+
+		info, err := e.Info()
+		if err != nil || now.Sub(info.ModTime()) < olderThan {
+			continue
+		}
+
+		stale = append(stale, dir)
+	}
+
+	return stale, nil
+}
+
+// workDirInUse tells whether the process holding the lock of the working directory is running.
+func workDirInUse(dir string) bool {
+	content, err := os.ReadFile(dir + workDirLockSuffix)
+	if err != nil {
+		return false
+	}
+
+	pid, err := strconv.Atoi(strings.TrimSpace(string(content)))
+	if err != nil {
+		return false
+	}
+
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+
+	// signal 0 checks the process exists without signaling it.
+	err = p.Signal(syscall.Signal(0))
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// cleanWorkDirs removes the stale working directories in base along with their lock and keep
+// files, returning the removed ones.
+func cleanWorkDirs(base string, olderThan time.Duration, includeKept bool, logger *slog.Logger) ([]string, error) {
+	stale, err := staleWorkDirs(base, olderThan, includeKept, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	var removed []string
+	for _, dir := range stale {
+		if err := os.RemoveAll(dir); err != nil {
+			logger.Warn("Failed to remove stale working directory", "dir", dir, "error", err)
+			continue
+		}
+
+		os.Remove(dir + workDirLockSuffix) //nolint:errcheck
+		os.Remove(dir + workDirKeepSuffix) //nolint:errcheck
+		removed = append(removed, dir)
+	}
+
+	return removed, nil
+}
+
+func newCleanCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "clean",
+		Short: "Remove the working directories left by previous runs",
+		Long: `Removes the working directories of the runs that are no longer running e.g. killed ones,
+under the directory passed in --workdir. Only the directories created by a run are removed, the
+directories kept with --keep-clones only with --include-kept.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			logger := slog.New(newLogHandler(cmd.ErrOrStderr(), flags.logLevel))
+
+			removed, err := cleanWorkDirs(workBaseDir(), flags.cleanOlderThan, flags.cleanIncludeKept, logger)
+			if err != nil {
+				return err
+			}
+
+			for _, dir := range removed {
+				fmt.Fprintln(cmd.OutOrStdout(), dir)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&flags.workDir, "workdir", os.Getenv("GH_ITERATOR_WORKDIR"), "Directory the repositories were cloned in, it can also be set with the GH_ITERATOR_WORKDIR env variable. By default, a directory in the system temporary directory")
+	cmd.Flags().DurationVar(&flags.cleanOlderThan, "older-than", 0, "Only removes the working directories older than this e.g. 12h")
+	cmd.Flags().BoolVar(&flags.cleanIncludeKept, "include-kept", false, "Also removes the working directories kept with --keep-clones")
+
+	return cmd
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStaleWorkDirs(t *testing.T) {
+	base := t.TempDir()
+
+	mkdir := func(name string) string {
+		dir := filepath.Join(base, name)
+		require.NoError(t, os.Mkdir(dir, 0755))
+		return dir
+	}
+
+	running := mkdir("acme-running-1")
+	require.NoError(t, lockWorkDir(running))
+
+	dead := mkdir("acme-dead-1")
+	// PIDs are capped way below this one.
+	require.NoError(t, os.WriteFile(dead+workDirLockSuffix, []byte("2147483647"), 0644))
+
+	kept := mkdir("acme-kept-1")
+	unlockWorkDir(kept, true)
+
+	// not created by a run e.g. when --workdir is a shared directory.
+	mkdir("unrelated")
+	now := time.Now()
+
+	stale, err := staleWorkDirs(base, 0, false, now)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{dead}, stale)
+
+	stale, err = staleWorkDirs(base, 0, true, now)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{dead, kept}, stale)
+
+	stale, err = staleWorkDirs(base, time.Hour, false, now)
+	require.NoError(t, err)
+	require.Empty(t, stale)
+
+	stale, err = staleWorkDirs(filepath.Join(base, "missing"), 0, false, now)
+	require.NoError(t, err)
+	require.Empty(t, stale)
+}
+
+func TestCleanWorkDirs(t *testing.T) {
+	t.Cleanup(func() { flags.workDir = "" })
+	flags.workDir = t.TempDir()
+
+	dir, err := makeWorkDir("acme/a")
+	require.NoError(t, err)
+	require.FileExists(t, dir+workDirLockSuffix)
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	// the directory is locked by this process.
+	removed, err := cleanWorkDirs(flags.workDir, 0, false, logger)
+	require.NoError(t, err)
+	require.Empty(t, removed)
+
+	unrelated := filepath.Join(flags.workDir, "unrelated")
+	require.NoError(t, os.Mkdir(unrelated, 0755))
+
+	// the run died without removing it.
+	require.NoError(t, os.WriteFile(dir+workDirLockSuffix, []byte("2147483647"), 0644))
+
+	removed, err = cleanWorkDirs(flags.workDir, 0, false, logger)
+	require.NoError(t, err)
+	require.Equal(t, []string{dir}, removed)
+	require.NoDirExists(t, dir)
+	require.NoFileExists(t, dir+workDirLockSuffix)
+	require.DirExists(t, unrelated)
+}